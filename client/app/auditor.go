@@ -0,0 +1,59 @@
+package app
+
+import (
+	"encoding/hex"
+	"fmt"
+	"log"
+	"strings"
+
+	mt "github.com/ja88a/vrfs-go-merkletree/libs/merkletree"
+)
+
+// VerifyFilesetConsistency is the method for auditing that a fileset is an append-only extension of an older
+// version of it, i.e. that the files of the older fileset have not been rewritten by the remote storage.
+//
+// The specified fileset IDs are the ones provided by the VRFS service when the filesets were uploaded.
+// The consistency proof retrieved from VRFS is verified against both filesets' merkle tree roots.
+//
+// An error is returned in case an issue is met or if the proof does not verify.
+func (ctx *ClientContext) VerifyFilesetConsistency(oldFileSetID string, newFileSetID string) error {
+	// Inputs validation
+	for _, fileSetID := range []string{oldFileSetID, newFileSetID} {
+		if !strings.HasPrefix(fileSetID, FilesetNamePrefix) {
+			return fmt.Errorf("unsupported fileset ID `%v`: it must be prefixed with `%v` and made of its merkletree root hash value as provided by the VRFS when uploaded", fileSetID, FilesetNamePrefix)
+		}
+	}
+
+	log.Printf("Verifying that fileset '%v' is an append-only extension of fileset '%v'", newFileSetID, oldFileSetID)
+
+	// 1. Retrieve the consistency proof from VRFS
+	mtProof, hashAlgo, err := ctx.Vrfs.HandleFilesetConsistencyReq(TenantIDMock, oldFileSetID, newFileSetID)
+	if err != nil {
+		return fmt.Errorf("failed at retrieving the consistency proof from VRFS for filesets '%v' and '%v'\n%w", oldFileSetID, newFileSetID, err)
+	}
+
+	// 2. Verify the proof against both filesets' MerkleTree roots, using their hash algorithm
+	oldRootHash, err := hex.DecodeString(strings.TrimPrefix(oldFileSetID, FilesetNamePrefix))
+	if err != nil {
+		return fmt.Errorf("failed to convert root hash to hex for fileset '%v'\n%w", oldFileSetID, err)
+	}
+	newRootHash, err := hex.DecodeString(strings.TrimPrefix(newFileSetID, FilesetNamePrefix))
+	if err != nil {
+		return fmt.Errorf("failed to convert root hash to hex for fileset '%v'\n%w", newFileSetID, err)
+	}
+
+	mtConfig := mt.MerkleTreeDefaultConfig(false)
+	if hashAlgo != "" {
+		mtConfig.HashAlgorithm = hashAlgo
+	}
+	consistent, err := mt.VerifyConsistency(mtProof, oldRootHash, newRootHash, mtConfig)
+	if err != nil {
+		return fmt.Errorf("failed to verify the consistency proof between filesets '%v' and '%v'\n%w", oldFileSetID, newFileSetID, err)
+	}
+	if !consistent {
+		return fmt.Errorf("fileset '%v' fails the verification process as an append-only extension of fileset '%v' - Sizes: %d -> %d", newFileSetID, oldFileSetID, mtProof.OldSize, mtProof.NewSize)
+	}
+	log.Printf("Fileset '%v' successfully verified as an append-only extension of fileset '%v' (%d -> %d files)", newFileSetID, oldFileSetID, mtProof.OldSize, mtProof.NewSize)
+
+	return nil
+}
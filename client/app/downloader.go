@@ -1,6 +1,7 @@
 package app
 
 import (
+	"bytes"
 	"encoding/hex"
 	"fmt"
 	"log"
@@ -9,6 +10,7 @@ import (
 
 	mt "github.com/ja88a/vrfs-go-merkletree/libs/merkletree"
 	mtutils "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/utils"
+	rpcfile "github.com/ja88a/vrfs-go-merkletree/libs/rpcapi/file"
 )
 
 // DownloadFile is the method for downloading a file, from its index as part of a known fileset, to the specified local directory
@@ -18,6 +20,12 @@ import (
 // The specified fileset ID is the one provided by the VRFS service when the fileset was initially uploaded.
 // The local directory FS path for the file to be downloaded must also to specified, e.g. './localdowndir/'.
 //
+// If the fileset leaves are file chunk tree roots, the file chunks are verified while the file is being downloaded,
+// only verified chunks being written to the local file.
+//
+// If the fileset has a manifest, the file leaf is the one of its manifest entry: the size and the content hash
+// of the downloaded file are checked against the entry, the entry being verified against the fileset root.
+//
 // An error is returned in case an issue is met.
 func (ctx *ClientContext) DownloadFile(fileSetID string, fileIndex int, downDirPath string) error {
 	// Inputs validation
@@ -27,7 +35,7 @@ func (ctx *ClientContext) DownloadFile(fileSetID string, fileIndex int, downDirP
 	if !strings.HasPrefix(fileSetID, "fs-") {
 		return fmt.Errorf("unsupported fileset ID `%v`: it must be prefixed with `fs-` and made of its merkletree root hash value as provided by the VRFS when uploaded", fileSetID)
 	}
-	if fileIndex <= 0 {
+	if fileIndex < 0 {
 		return fmt.Errorf("unsupported file index `%v`: it must be a positive integer >= 0", fileIndex)
 	}
 	if len(downDirPath) == 0 {
@@ -37,7 +45,7 @@ func (ctx *ClientContext) DownloadFile(fileSetID string, fileIndex int, downDirP
 	log.Printf("Downloading file #%v part of fileset '%v'", fileIndex, fileSetID)
 
 	// 1. Retrieve the necessary download info & verification proofs from VRFS
-	bucketID, mtProof, err := ctx.Vrfs.HandleDownloadFileInfoReq(TenantIDMock, fileSetID, fileIndex)
+	bucketID, mtProof, hashAlgo, fileChunkSize, manifestEntry, err := ctx.Vrfs.HandleDownloadFileInfoReq(TenantIDMock, fileSetID, fileIndex)
 	if err != nil {
 		return fmt.Errorf("failed at retrieving file download info from VRFS for file %d in fileset '%v'\n%w", fileIndex, fileSetID, err)
 	}
@@ -45,6 +53,20 @@ func (ctx *ClientContext) DownloadFile(fileSetID string, fileIndex int, downDirP
 		// Trigger an actual error & end the download process as long as file is not verifiable
 		return fmt.Errorf("missing the merkle tree proofs from VRFS to check for the consistency of file %4d in fileset '%v'", fileIndex, fileSetID)
 	}
+	if mtProof.Envelope != nil && mtProof.Envelope.LeafIndex != uint64(fileIndex) {
+		return fmt.Errorf("the merkle tree proof from VRFS is the one of file %d, not of file %d in fileset '%v'", mtProof.Envelope.LeafIndex, fileIndex, fileSetID)
+	}
+
+	// Trick for avoiding the local storage of the fileset's MT root by the client
+	rootHashS := strings.TrimPrefix(fileSetID, FilesetNamePrefix)
+	rootHash, err := hex.DecodeString(rootHashS)
+	if err != nil {
+		return fmt.Errorf("failed to convert root hash to hex '%v'\n%w", rootHashS, err)
+	}
+	mtConfig := mt.MerkleTreeDefaultConfig(false)
+	if hashAlgo != "" {
+		mtConfig.HashAlgorithm = hashAlgo
+	}
 
 	// 2. Save the file locally, in the client download dir
 	// Initiate the file download process from the File Storage server
@@ -52,18 +74,13 @@ func (ctx *ClientContext) DownloadFile(fileSetID string, fileIndex int, downDirP
 	if err != nil {
 		return fmt.Errorf("download process has failed for file %d of fileset '%v'\n%w", fileIndex, fileSetID, err)
 	}
-
-	localDirPath := computeFilesetDownloadDir(downDirPath, fileSetID)
-	if _, err := os.Stat(localDirPath); os.IsNotExist(err) {
-		err = os.MkdirAll(localDirPath, os.ModePerm) // 511
-		if err != nil {
-			return fmt.Errorf("failed to create the target local download directory `%v`\n%w", localDirPath, err)
-		}
+	if fileChunkSize > 0 {
+		return ctx.downloadFileChunks(dFile, bucketID, fileIndex, fileChunkSize, mtProof, rootHash, mtConfig, manifestEntry, downDirPath, fileSetID)
 	}
-	localFilePath := localDirPath + "/" + dFile.Name
-	localFile, err := os.Create(localFilePath)
+
+	localFile, localFilePath, err := createDownloadFile(downDirPath, fileSetID, dFile.Name)
 	if err != nil {
-		return fmt.Errorf("failed to create local file for download '%v' \n%w", localFilePath, err)
+		return err
 	}
 	defer localFile.Close()
 
@@ -73,36 +90,134 @@ func (ctx *ClientContext) DownloadFile(fileSetID string, fileIndex int, downDirP
 	}
 
 	// 3. Verify the downloaded file's hash based on the fileset's MerkleTree root
-	// and the MerkleTree proofs retrieved from VRFS
-	fileHashes, err := mtutils.ComputeFileHashes([]string{localFilePath})
-	if err != nil {
-		return fmt.Errorf("failed to compute hash for file '%v' \n%w", localFilePath, err)
-	}
-	log.Printf("File '%v' Downloaded. Hash: %x", localFilePath, fileHashes[0])
-
-	// Trick for avoiding the local storage of the fileset's MT root by the client
-	rootHashS := strings.TrimPrefix(fileSetID, FilesetNamePrefix)
-	rootHash, err := hex.DecodeString(rootHashS)
-	if err != nil {
-		return fmt.Errorf("failed to convert root hash to hex '%v'\n%w", rootHashS, err)
+	// and the MerkleTree proofs retrieved from VRFS, using the hash algorithm of the fileset
+	var fileLeaf []byte
+	if manifestEntry != nil {
+		contentHash, err := mtutils.ComputeFileContentHash(localFilePath, mtConfig.HashAlgorithm)
+		if err != nil {
+			return fmt.Errorf("failed to compute hash for file '%v' \n%w", localFilePath, err)
+		}
+		info, err := os.Stat(localFilePath)
+		if err != nil {
+			return fmt.Errorf("failed to read the info of file '%v' \n%w", localFilePath, err)
+		}
+		log.Printf("File '%v' Downloaded. Hash: %x", localFilePath, contentHash)
+		if fileLeaf, err = manifestEntryLeaf(manifestEntry, mtConfig.HashAlgorithm, uint64(info.Size()), contentHash); err != nil {
+			return fmt.Errorf("downloaded file '%v' does not match its fileset manifest entry\n%w", localFilePath, err)
+		}
+	} else {
+		fileHashes, err := mtutils.ComputeFileHashes([]string{localFilePath}, hashAlgo)
+		if err != nil {
+			return fmt.Errorf("failed to compute hash for file '%v' \n%w", localFilePath, err)
+		}
+		log.Printf("File '%v' Downloaded. Hash: %x", localFilePath, fileHashes[0])
+		fileLeaf = fileHashes[0]
 	}
 
 	fileBlock := &mt.DataBlock{
-		Data: fileHashes[0],
+		Data: fileLeaf,
 	}
-	mtConfig := mt.MerkleTreeDefaultConfig(false)
 	fileValid, err := mt.Verify(fileBlock, mtProof, rootHash, mtConfig)
 	if err != nil {
 		return fmt.Errorf("failed to verify the downloaded file '%v' \n%w", localFilePath, err)
 	}
 	if !fileValid {
-		return fmt.Errorf("downloaded file '%v' fails the verification process - Root: %x  ProofSiblings: %d  Hash: %x", localFilePath, rootHash, len(mtProof.Siblings), fileHashes[0])
+		return fmt.Errorf("downloaded file '%v' fails the verification process - Root: %x  ProofSiblings: %d  Leaf: %x", localFilePath, rootHash, len(mtProof.Siblings), fileLeaf)
 	}
 	log.Printf("Downloaded file '%v': Successfully verified", localFilePath)
 
 	return nil
 }
 
+// Number of file chunk proofs retrieved at once from the File Storage server while downloading a file
+const fileChunkProofsBatchSize = 256
+
+// downloadFileChunks saves a downloaded file in the client download dir, verifying each of its chunks while
+// it is being streamed. The file leaf, i.e. its file chunk tree root or the leaf of its manifest entry if any,
+// is first verified against the fileset root
+func (ctx *ClientContext) downloadFileChunks(dFile *rpcfile.File, bucketID string, fileIndex int, fileChunkSize int, mtProof *mt.Proof, rootHash []byte, mtConfig *mt.Config, manifestEntry *mtutils.ManifestEntry, downDirPath string, fileSetID string) error {
+	// Retrieve and verify the file chunk tree root, i.e. the file leaf, against the fileset root
+	chunkRoot, fileSize, firstProofs, err := ctx.Nfs.DownloadFileChunkProofs(bucketID, fileIndex, mtConfig.HashAlgorithm, fileChunkSize, 0, 0)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the file chunk proofs of file %d in fileset '%v'\n%w", fileIndex, fileSetID, err)
+	}
+	fileLeaf := chunkRoot
+	if manifestEntry != nil {
+		if fileLeaf, err = manifestEntryLeaf(manifestEntry, mtConfig.HashAlgorithm, uint64(fileSize), chunkRoot); err != nil {
+			return fmt.Errorf("file %d in fileset '%v' does not match its fileset manifest entry\n%w", fileIndex, fileSetID, err)
+		}
+	}
+	leafValid, err := mt.Verify(&mt.DataBlock{Data: fileLeaf}, mtProof, rootHash, mtConfig)
+	if err != nil {
+		return fmt.Errorf("failed to verify the file chunk root of file %d in fileset '%v'\n%w", fileIndex, fileSetID, err)
+	}
+	if !leafValid {
+		return fmt.Errorf("file chunk root %x of file %d fails the verification process - Root: %x", chunkRoot, fileIndex, rootHash)
+	}
+
+	// Chunk proofs are retrieved per batch, as the file is being streamed
+	verifier := &mtutils.FileChunkVerifier{Root: chunkRoot, FileSize: fileSize, ChunkSize: fileChunkSize, HashAlgo: mtConfig.HashAlgorithm}
+	numChunks := mtutils.NumFileChunks(fileSize, fileChunkSize)
+	batchFirst, batchProofs := 0, firstProofs
+	chunkProofs := func(chunkIndex int) (*mt.Proof, error) {
+		if chunkIndex >= batchFirst+len(batchProofs) {
+			batchLast := min(chunkIndex+fileChunkProofsBatchSize, numChunks) - 1
+			_, _, proofs, err := ctx.Nfs.DownloadFileChunkProofs(bucketID, fileIndex, mtConfig.HashAlgorithm, fileChunkSize, chunkIndex, batchLast)
+			if err != nil {
+				return nil, err
+			}
+			batchFirst, batchProofs = chunkIndex, proofs
+		}
+		return batchProofs[chunkIndex-batchFirst], nil
+	}
+	verifiedFile, err := verifier.NewReader(dFile, 0, numChunks-1, chunkProofs)
+	if err != nil {
+		return fmt.Errorf("failed to init the verification of file %d in fileset '%v'\n%w", fileIndex, fileSetID, err)
+	}
+
+	localFile, localFilePath, err := createDownloadFile(downDirPath, fileSetID, dFile.Name)
+	if err != nil {
+		return err
+	}
+	defer localFile.Close()
+
+	if _, err = localFile.ReadFrom(verifiedFile); err != nil {
+		return fmt.Errorf("failed to stream and verify file data to '%v' \n%w", localFilePath, err)
+	}
+	log.Printf("Downloaded file '%v': Successfully verified its %d chunks - File chunk root: %x", localFilePath, numChunks, chunkRoot)
+
+	return nil
+}
+
+// Compute the leaf of a file manifest entry, once its size and content hash checked against the ones
+// of the downloaded file
+func manifestEntryLeaf(entry *mtutils.ManifestEntry, hashAlgo string, fileSize uint64, contentHash []byte) ([]byte, error) {
+	if entry.Size != fileSize {
+		return nil, fmt.Errorf("file size %d differs from the manifest entry '%v' size %d", fileSize, entry.Path, entry.Size)
+	}
+	if !bytes.Equal(entry.ContentHash, contentHash) {
+		return nil, fmt.Errorf("file content hash %x differs from the manifest entry '%v' content hash %x", contentHash, entry.Path, entry.ContentHash)
+	}
+	return mtutils.ManifestEntryLeaf(mtutils.FilesetManifestVersion, hashAlgo, entry)
+}
+
+// Create the local file of a downloaded file, in the fileset download dir
+func createDownloadFile(downDirPath string, fileSetID string, fileName string) (*os.File, string, error) {
+	localDirPath := computeFilesetDownloadDir(downDirPath, fileSetID)
+	if _, err := os.Stat(localDirPath); os.IsNotExist(err) {
+		err = os.MkdirAll(localDirPath, os.ModePerm) // 511
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to create the target local download directory `%v`\n%w", localDirPath, err)
+		}
+	}
+	localFilePath := localDirPath + "/" + fileName
+	localFile, err := os.Create(localFilePath)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to create local file for download '%v' \n%w", localFilePath, err)
+	}
+	return localFile, localFilePath, nil
+}
+
 // Compute the FS path where files of a fileset are locally stored
 func computeFilesetDownloadDir(localFileDownloadRepo string, fileSetID string) string {
 	return localFileDownloadRepo + "/" + fileSetID
@@ -5,13 +5,17 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"sync"
 
 	mtutils "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/utils"
 )
 
 // UploadFileset is the method for initiating the verified upload protocol of all files found under the specified local directory path
 // The maximum batch size of files to be concurrently uploaded is specified, it must be greater than 0
-func (ctx *ClientContext) UploadFileset(localDirPath string, concurrencyMax int) error {
+// The name of the hash algorithm used for computing the file hashes and the fileset MerkleTree is specified, default if empty
+// If a file chunk size is specified, the fileset leaves are the roots of the Merkle Trees of the file chunks
+// instead of the whole file hashes, enabling the verification of the files chunk per chunk once downloaded
+func (ctx *ClientContext) UploadFileset(localDirPath string, concurrencyMax int, hashAlgo string, fileChunkSize int) error {
 	// Inputs validation
 	if len(localDirPath) == 0 {
 		return fmt.Errorf("unsupported local upload directory path `%v`: it must be specified", localDirPath)
@@ -19,35 +23,40 @@ func (ctx *ClientContext) UploadFileset(localDirPath string, concurrencyMax int)
 	if concurrencyMax < 1 {
 		return fmt.Errorf("unsupported max batch size value `%v`: it must be a positive integer >= 1", concurrencyMax)
 	}
-
-	// Get the list of available local file paths
-	files, err := mtutils.ListDirFilePaths(localDirPath)
-	if err != nil || len(files) == 0 {
-		return fmt.Errorf("no local files found in dir '%v'\n%w", localDirPath, err)
+	if fileChunkSize < 0 {
+		return fmt.Errorf("unsupported file chunk size value `%v`: it must be a positive integer, or 0 for whole file hashes", fileChunkSize)
 	}
 
-	log.Printf("Upload - Found %d files in local dir '%v'", len(files), localDirPath)
+	// Compute the fileset manifest: the local files sorted by relative path, with their content hashes
+	// or file chunk tree roots
+	manifest, err := mtutils.ComputeFilesetManifest(localDirPath, hashAlgo, fileChunkSize, false)
+	if err != nil {
+		return fmt.Errorf("failure while computing the fileset manifest for '%v'\n%w", localDirPath, err)
+	}
+	if len(manifest.Entries) == 0 {
+		return fmt.Errorf("no local files found in dir '%v'", localDirPath)
+	}
+	log.Printf("Upload - Found %d files in local dir '%v'", len(manifest.Entries), localDirPath)
 
-	// Compute the local file hashes
-	fileHashes, err := mtutils.ComputeFileHashes(files)
+	manifestData, err := manifest.Marshal()
 	if err != nil {
-		return fmt.Errorf("failure while computing file hashes for '%v'\n%w", localDirPath, err)
+		return fmt.Errorf("failure while encoding the fileset manifest for '%v'\n%w", localDirPath, err)
 	}
 
-	// Build the Merkle Tree with the file hashes as leaf values
-	tree, err := mtutils.GenerateMerkleTree(fileHashes, false)
+	// Compute the root of the Merkle Tree with the manifest entry leaves, without building its nodes
+	root, err := manifest.Root()
 	if err != nil {
 		return fmt.Errorf("failure while computing the merkletree for files in '%v'\n%w", localDirPath, err)
 	}
 
 	// Obtain the MerkleTree root hash
-	if tree.Root == nil || len(tree.Root) == 0 {
-		return fmt.Errorf("invalid empty MerkleTree root '%v' for fileset in '%v'", tree.Root, localDirPath)
+	if len(root) == 0 {
+		return fmt.Errorf("invalid empty MerkleTree root '%v' for fileset in '%v'", root, localDirPath)
 	}
-	log.Printf("Computed fileset MerkleTree root: %x", tree.Root)
+	log.Printf("Computed fileset MerkleTree root: %x", root)
 
 	// Request to VRFS for a FS bucket into which files can be remotely stored
-	fileSetID := FilesetNamePrefix + hex.EncodeToString(tree.Root)
+	fileSetID := FilesetNamePrefix + hex.EncodeToString(root)
 	status, bucketID, err := ctx.Vrfs.HandleFileBucketReq(TenantIDMock, fileSetID)
 	if err != nil || status < 0 {
 		return fmt.Errorf("missing a bucket ref to upload the fileset '%v'\n%w", fileSetID, err)
@@ -55,15 +64,20 @@ func (ctx *ClientContext) UploadFileset(localDirPath string, concurrencyMax int)
 	log.Printf("Bucket '%v' available for uploading files (%d)", bucketID, status)
 
 	// Upload the local files to the Remote Files Server
-	err = ctx.uploadFilesConcurrently(bucketID, files, concurrencyMax)
+	err = ctx.uploadFilesConcurrently(bucketID, localDirPath, manifest, concurrencyMax)
 	if err != nil || status < 0 {
 		return fmt.Errorf("failed to upload all local files to bucket '%v'\n%w", bucketID, err)
 	}
 
+	// Upload the fileset manifest, from which the remote fileset MerkleTree root gets computed
+	if err := ctx.Nfs.UploadManifest(bucketID, manifestData); err != nil {
+		return fmt.Errorf("failed to upload the fileset manifest to bucket '%v'\n%w", bucketID, err)
+	}
+
 	// Confirm from VRFS that the files have been correctly uploaded, by comparing the file hashes' MerkleTree roots
-	filesMatch, err := ctx.confirmAndVerifyFilesetUpload(fileSetID, tree.Root)
+	filesMatch, err := ctx.confirmAndVerifyFilesetUpload(fileSetID, root, hashAlgo, fileChunkSize)
 	if err != nil {
-		return fmt.Errorf("failed to verify the remotely stored files for fileset '%v' with MT root %x\n%w", fileSetID, tree.Root, err)
+		return fmt.Errorf("failed to verify the remotely stored files for fileset '%v' with MT root %x\n%w", fileSetID, root, err)
 	}
 	if !filesMatch {
 		return fmt.Errorf("remotely stored files could not be verified: failure or mismatch - fileset '%v' bucket '%v'\n%w", fileSetID, bucketID, err)
@@ -81,42 +95,54 @@ func (ctx *ClientContext) UploadFileset(localDirPath string, concurrencyMax int)
 	return nil
 }
 
-// uploadFile is a client method for uploading a local file to the remote FileStore bucket
-func (ctx *ClientContext) uploadFile(bucketID string, filePath string) error {
-	if err := ctx.Nfs.UploadFile(bucketID, filePath); err != nil {
+// uploadFile is a client method for uploading a local file to the remote FileStore bucket,
+// under its slash separated path relative to the fileset root dir
+func (ctx *ClientContext) uploadFile(bucketID string, filePath string, filesetPath string) error {
+	if err := ctx.Nfs.UploadFile(bucketID, filePath, filesetPath); err != nil {
 		return fmt.Errorf("failed to upload the file `%v`\n%w", filePath, err)
 	}
 
 	return nil
 }
 
-func (ctx *ClientContext) uploadFilesConcurrently(bucketID string, localFilePaths []string, maxConcurrentUploads int) error {
+// uploadFilesConcurrently uploads the local files of the fileset manifest entries, the fileset subdirs being preserved
+// in the remote FileStore bucket
+func (ctx *ClientContext) uploadFilesConcurrently(bucketID string, localDirPath string, manifest *mtutils.FilesetManifest, maxConcurrentUploads int) error {
+	localFilePaths := manifest.FilePaths(localDirPath)
+
 	// Buffered channel for concurrency control to upload the files
 	sem := make(chan bool, maxConcurrentUploads)
 
 	// Channel for collecting file upload errors
 	errChan := make(chan error, len(localFilePaths))
 
+	// Wait group of the running uploads
+	var wg sync.WaitGroup
+
 	// Loop over the files
-	for _, file := range localFilePaths {
+	for i, file := range localFilePaths {
 		// Acquire a slot
 		sem <- true
 
 		// Start a new goroutine to upload this file
-		go func(file string) {
+		wg.Add(1)
+		go func(file string, filesetPath string) {
 			defer func() {
 				// Release the slot in the semaphore
 				<-sem
+				wg.Done()
 			}()
 
 			// Upload the file and send any errors to the error channel
-			if err := ctx.uploadFile(bucketID, file); err != nil {
+			if err := ctx.uploadFile(bucketID, file, filesetPath); err != nil {
 				errChan <- err
 			}
-		}(file)
+		}(file, manifest.Entries[i].Path)
 	}
 
-	// Check if any errors occurred during the uploads
+	// Wait for all the uploads to complete, for the fileset to be confirmed only once all its files are stored,
+	// then check if any errors occurred during the uploads
+	wg.Wait()
 	close(errChan)
 	for err := range errChan {
 		if err != nil {
@@ -132,10 +158,10 @@ func (ctx *ClientContext) uploadFilesConcurrently(bucketID string, localFilePath
 // Confirm from the VRFS API that all local files have been properly uploaded to the File Storage server
 //
 // Provide the locally generated MerkleTree root so that the VRFS API compares it with its own generated
-// MerkleTree root hash for the remotely stored fileset.
-func (ctx *ClientContext) confirmAndVerifyFilesetUpload(fileSetID string, rootHash []byte) (bool, error) {
+// MerkleTree root hash for the remotely stored fileset, computed with the same hash algorithm and file chunk size.
+func (ctx *ClientContext) confirmAndVerifyFilesetUpload(fileSetID string, rootHash []byte, hashAlgo string, fileChunkSize int) (bool, error) {
 	// Notify VRFS that files upload is done
-	status, message, err := ctx.Vrfs.HandleUploadDoneReq(TenantIDMock, fileSetID, rootHash)
+	status, message, err := ctx.Vrfs.HandleUploadDoneReq(TenantIDMock, fileSetID, rootHash, hashAlgo, fileChunkSize)
 	if err != nil {
 		return false, fmt.Errorf("failed to confirm that remotely stored files for fileset '%v' match with local ones (root: %v)\n%w", fileSetID, rootHash, err)
 	}
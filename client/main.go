@@ -9,19 +9,23 @@ import (
 	"strings"
 
 	app "github.com/ja88a/vrfs-go-merkletree/client/app"
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
 )
 
 // CLI command parameters
 var (
-	action      = flag.String("action", "", "Expected client action: `ping` (default); `upload` or `download` of files in the specified `dir`")
+	action      = flag.String("action", "", "Expected client action: `ping` (default); `upload` or `download` of files in the specified `dir`; `consistency` check between 2 filesets")
 	updir       = flag.String("updir", "fs-playground/forupload/catyclops", "Upload - The local directory where to find the files to upload")
 	downdir     = flag.String("downdir", "fs-playground/downloaded", "Download - The local directory where client files are downloaded to")
 	fileSet     = flag.String("fileset", "fs-10f652e11f5e2f799481ed02d45a74bcf3d62dea3200ad08120bba43c242f5fb", "Download* - The fileset ID to request for a file download")
+	oldFileSet  = flag.String("oldfileset", "", "Consistency* - The older fileset ID of which `fileset` must be an append-only extension")
 	index       = flag.String("index", "0", "Download* - The index number of the file to be downloaded in the specified local dir")
 	apiEndpoint = flag.String("api", "localhost:50051", "The gRPC endpoint (host & port) for the VRFS Service API")
 	rfsEndpoint = flag.String("fs", "localhost:9000", "The gRPC endpoint (host & port) for the Remote File Storage service")
 	batchSize   = flag.String("batch", "5", "Upload - Batch size, the max number of concurrent file uploads")
 	chunkSize   = flag.String("chunk", "1048576", "Max size of data chunks transfer for each file upload or download")
+	fileChunk   = flag.String("filechunk", "0", "Upload - Size of the file chunks forming a merkle tree per file, for verifying files chunk per chunk once downloaded; 0 for whole file hashes")
+	hashAlgo    = flag.String("hash", hash.DefaultAlgo, "Upload - The hash algorithm for the file hashes & the fileset merkle tree: "+strings.Join(hash.SupportedAlgos(), ", "))
 )
 
 // Main is the Client CLI entry point method
@@ -47,9 +51,17 @@ func main() {
 		if err != nil || batchSizeNb < 1 {
 			log.Fatalf("Unsupported value %v for parameter 'batch' - must be a positive integer >= 1", *batchSize)
 		}
+		if _, err := hash.HashFuncByName(*hashAlgo); err != nil {
+			log.Fatalf("Unsupported value %v for parameter 'hash' - must be one of: %v", *hashAlgo, strings.Join(hash.SupportedAlgos(), ", "))
+		}
+
+		fileChunkNb, err := strconv.Atoi(*fileChunk)
+		if err != nil || fileChunkNb < 0 {
+			log.Fatalf("Unsupported value %v for parameter 'filechunk' - must be a positive integer, or 0 to disable", *fileChunk)
+		}
 
 		// Upload the files of a fileset and confirm its consistency
-		err = appCtx.UploadFileset(*updir, batchSizeNb)
+		err = appCtx.UploadFileset(*updir, batchSizeNb, *hashAlgo, fileChunkNb)
 		if err != nil {
 			log.Fatalf("Failed to remotely store and verify the fileset\n%v", err)
 		}
@@ -74,6 +86,18 @@ func main() {
 			log.Fatalf("File download & verification process has failed\n%v", err)
 		}
 
+	case "consistency":
+		// CLI parameters minimal checks
+		if !strings.HasPrefix(*fileSet, "fs-") || !strings.HasPrefix(*oldFileSet, "fs-") {
+			log.Fatalf("Both `fileset` and `oldfileset` parameters must be specified using the pattern 'fs-HexFilesetRootHash'. The HexFilesetRootHash and the complete fileset IDs are provided in logs of previous fileset upload processes")
+		}
+
+		// Verify that the fileset is an append-only extension of the older one
+		err = appCtx.VerifyFilesetConsistency(*oldFileSet, *fileSet)
+		if err != nil {
+			log.Fatalf("Fileset consistency verification process has failed\n%v", err)
+		}
+
 	default:
 		// Default command line info
 		fmt.Printf("VRFS Client v0.1.0 2023-11\n\nNo action specified.\n\nHelp command: `vrfs-client -h`\n\n")
@@ -4,12 +4,14 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"math"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
 	mt "github.com/ja88a/vrfs-go-merkletree/libs/merkletree"
+	mtutils "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/utils"
 	pbvrfs "github.com/ja88a/vrfs-go-merkletree/libs/rpcapi/protos/v1/vrfs-api"
 )
 
@@ -19,10 +21,16 @@ type VrfsService interface {
 	HandleFileBucketReq(tenantId string, fileSetId string) (int32, string, error)
 
 	// Handle the request to VRFS for confirming the fileset has been correctly uploaded & stored
-	HandleUploadDoneReq(tenantId string, fileSetId string, mtRootHash []byte) (int32, string, error)
+	HandleUploadDoneReq(tenantId string, fileSetId string, mtRootHash []byte, hashAlgo string, fileChunkSize int) (int32, string, error)
 
-	// Handle the request to VRFS for retrieving the info to download a file and check/prove it is untampered
-	HandleDownloadFileInfoReq(tenantId string, fileSetId string, fileIndex int) (string, *mt.Proof, error)
+	// Handle the request to VRFS for retrieving the info to download a file and check/prove it is untampered,
+	// along with the name of the hash algorithm and the file chunk size of the fileset, 0 if not chunked,
+	// and the fileset manifest entry of the file, nil if the fileset has no manifest
+	HandleDownloadFileInfoReq(tenantId string, fileSetId string, fileIndex int) (string, *mt.Proof, string, int, *mtutils.ManifestEntry, error)
+
+	// Handle the request to VRFS for retrieving the proof that a fileset is an append-only extension of an older one,
+	// along with the name of the hash algorithm of the filesets
+	HandleFilesetConsistencyReq(tenantId string, oldFileSetId string, newFileSetId string) (*mt.ConsistencyProof, string, error)
 
 	// Handle a VRFS API ping request, to check for the service availability
 	HandlePingReq() error
@@ -65,11 +73,11 @@ func (apiCtx *vrfsService) HandleFileBucketReq(tenantId string, fileSetId string
 }
 
 // Handle the request to VRFS for confirming the fileset has been correctly uploaded & stored
-func (apiCtx *vrfsService) HandleUploadDoneReq(tenantId string, fileSetId string, mtRootHash []byte) (int32, string, error) {
+func (apiCtx *vrfsService) HandleUploadDoneReq(tenantId string, fileSetId string, mtRootHash []byte, hashAlgo string, fileChunkSize int) (int32, string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), apiCtx.vrfsTimeout)
 	defer cancel()
 
-	resp, err := apiCtx.client.UploadDone(ctx, &pbvrfs.UploadDoneRequest{TenantId: tenantId, FilesetId: fileSetId, MtRoot: mtRootHash})
+	resp, err := apiCtx.client.UploadDone(ctx, &pbvrfs.UploadDoneRequest{TenantId: tenantId, FilesetId: fileSetId, MtRoot: mtRootHash, HashAlgo: hashAlgo, ChunkSize: uint32(fileChunkSize)})
 	if err != nil {
 		return -1, resp.GetMessage(), fmt.Errorf("failed to request for files upload correctness - fileset: '%v'\n%w", fileSetId, err)
 	}
@@ -78,21 +86,68 @@ func (apiCtx *vrfsService) HandleUploadDoneReq(tenantId string, fileSetId string
 }
 
 // Handle the request to VRFS for retrieving the info to download a file and check/proove it is untampered
-func (apiCtx *vrfsService) HandleDownloadFileInfoReq(tenantId string, fileSetId string, fileIndex int) (string, *mt.Proof, error) {
+func (apiCtx *vrfsService) HandleDownloadFileInfoReq(tenantId string, fileSetId string, fileIndex int) (string, *mt.Proof, string, int, *mtutils.ManifestEntry, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), apiCtx.vrfsTimeout)
 	defer cancel()
 
 	resp, err := apiCtx.client.DownloadFileInfo(ctx, &pbvrfs.DownloadFileInfoRequest{TenantId: tenantId, FilesetId: fileSetId, FileIndex: int32(fileIndex)})
 	if err != nil {
-		return resp.GetBucketId(), nil, fmt.Errorf("failed to retrieve download info for file #%4d in fileset '%v'\n%w", fileIndex, fileSetId, err)
+		return resp.GetBucketId(), nil, "", 0, nil, fmt.Errorf("failed to retrieve download info for file #%4d in fileset '%v'\n%w", fileIndex, fileSetId, err)
 	}
 
 	mtProof := &mt.Proof{
 		Siblings: resp.GetMtProof().GetSiblings(),
 		Path:     resp.GetMtProof().GetPath(),
 	}
+	if envelope := resp.GetMtProof().GetEnvelope(); envelope != nil {
+		mtProof.Envelope = &mt.ProofEnvelope{
+			Version:       envelope.GetVersion(),
+			LeafIndex:     envelope.GetLeafIndex(),
+			TreeSize:      envelope.GetTreeSize(),
+			HashAlgorithm: envelope.GetHashAlgo(),
+			Flags:         envelope.GetFlags(),
+		}
+	}
+
+	var manifestEntry *mtutils.ManifestEntry
+	if resp.GetManifestVersion() != 0 {
+		if resp.GetManifestVersion() != mtutils.FilesetManifestVersion || resp.GetManifestEntry() == nil {
+			return resp.GetBucketId(), nil, "", 0, nil, fmt.Errorf("unsupported manifest version %d for file #%4d in fileset '%v'\n%w", resp.GetManifestVersion(), fileIndex, fileSetId, mtutils.ErrManifestUnsupportedVersion)
+		}
+		entry := resp.GetManifestEntry()
+		manifestEntry = &mtutils.ManifestEntry{
+			Path:        entry.GetPath(),
+			Size:        entry.GetSize(),
+			Mode:        entry.GetMode(),
+			ModTime:     entry.GetModTime(),
+			ContentHash: entry.GetContentHash(),
+		}
+	}
+
+	return resp.GetBucketId(), mtProof, resp.GetHashAlgo(), int(resp.GetChunkSize()), manifestEntry, nil
+}
+
+// Handle the request to VRFS for retrieving the proof that a fileset is an append-only extension of an older one
+func (apiCtx *vrfsService) HandleFilesetConsistencyReq(tenantId string, oldFileSetId string, newFileSetId string) (*mt.ConsistencyProof, string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), apiCtx.vrfsTimeout)
+	defer cancel()
+
+	resp, err := apiCtx.client.FilesetConsistency(ctx, &pbvrfs.FilesetConsistencyRequest{TenantId: tenantId, OldFilesetId: oldFileSetId, NewFilesetId: newFileSetId})
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to retrieve the consistency proof between filesets '%v' and '%v'\n%w", oldFileSetId, newFileSetId, err)
+	}
+
+	oldSize, newSize := resp.GetMtProof().GetOldSize(), resp.GetMtProof().GetNewSize()
+	if oldSize > math.MaxInt || newSize > math.MaxInt {
+		return nil, "", fmt.Errorf("unsupported sizes %d and %d of the consistency proof between filesets '%v' and '%v'", oldSize, newSize, oldFileSetId, newFileSetId)
+	}
+	mtProof := &mt.ConsistencyProof{
+		OldSize: int(oldSize),
+		NewSize: int(newSize),
+		Nodes:   resp.GetMtProof().GetNodes(),
+	}
 
-	return resp.GetBucketId(), mtProof, nil
+	return mtProof, resp.GetHashAlgo(), nil
 }
 
 // Handle the VRFS API ping request, to check for its availability
@@ -1,19 +1,22 @@
 package rservice
 
 import (
+	"bytes"
 	"context"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/signal"
-	"path/filepath"
+	"path"
 	"syscall"
 	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/credentials/insecure"
 
+	mt "github.com/ja88a/vrfs-go-merkletree/libs/merkletree"
+	mtutils "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/utils"
 	rpcfile "github.com/ja88a/vrfs-go-merkletree/libs/rpcapi/file"
 	pb "github.com/ja88a/vrfs-go-merkletree/libs/rpcapi/protos/v1/vrfs-fs"
 )
@@ -21,11 +24,19 @@ import (
 // FTService is the client API for FTService
 type FTService interface {
 	// Local file data transfer protocol based on gRPC streaming, in chunks
-	UploadFile(bucketId string, localFilePath string) error
+	// The file is stored in the bucket under its slash separated path relative to the fileset root dir
+	UploadFile(bucketId string, localFilePath string, filesetPath string) error
+
+	// Upload the canonical encoding of a fileset manifest to the bucket of the fileset files
+	UploadManifest(bucketId string, manifest []byte) error
 
 	// Handle a file download request towards the FS server, based on a bucket ID (previously loaded) and a file index
 	// Consider the file index towards a lexically sorted list order of the target files directory
 	DownloadFile(bucketId string, fileIndex int) (*rpcfile.File, error)
+
+	// Retrieve the file chunk tree root and size of a file part of a bucket, with the proofs of its chunks
+	// from `first` to `last`, bounds included, computed with the specified hash algorithm and chunk size
+	DownloadFileChunkProofs(bucketId string, fileIndex int, hashAlgo string, chunkSize int, first int, last int) ([]byte, int64, []*mt.Proof, error)
 }
 
 // File transfer service's client and context info
@@ -65,7 +76,8 @@ func (s *fTService) initClientConnection() (*grpc.ClientConn, error) {
 }
 
 // Local file data transfer protocol based on gRPC streaming, in chunks
-func (s *fTService) UploadFile(bucketId string, filePath string) error {
+// The file is stored in the bucket under its slash separated path relative to the fileset root dir
+func (s *fTService) UploadFile(bucketId string, filePath string, filesetPath string) error {
 	if s.debug {
 		log.Printf("Sending file '%v' to FS bucket '%v' at '%v'", filePath, bucketId, s.endpoint)
 	}
@@ -92,7 +104,7 @@ func (s *fTService) UploadFile(bucketId string, filePath string) error {
 	defer cancel()
 
 	go func(s *fTService) {
-		if err := s.upload(ctx, cancel, bucketId, filePath); err != nil {
+		if err := s.upload(ctx, cancel, bucketId, filePath, filesetPath); err != nil {
 			log.Fatalf("Upload of file '%v' to FS bucket '%v' has failed\n%v", filePath, bucketId, err)
 			cancel()
 		}
@@ -107,24 +119,55 @@ func (s *fTService) UploadFile(bucketId string, filePath string) error {
 	return nil
 }
 
-// Upload 1 file, using chunks with a max size, to the specified file storage's bucket
-func (s *fTService) upload(ctx context.Context, cancel context.CancelFunc, bucketId string, filePath string) error {
-	stream, err := s.client.Upload(ctx)
+// Upload the canonical encoding of a fileset manifest to the bucket of the fileset files,
+// under the reserved manifest file name
+func (s *fTService) UploadManifest(bucketId string, manifest []byte) error {
+	if s.debug {
+		log.Printf("Sending the fileset manifest to FS bucket '%v' at '%v'", bucketId, s.endpoint)
+	}
+
+	conn, err := s.initClientConnection()
 	if err != nil {
-		return err
+		return fmt.Errorf("failed to init upload of the fileset manifest to the FileStorage service \n%w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	if err := s.uploadContent(ctx, bucketId, mtutils.FilesetManifestName, true, bytes.NewReader(manifest)); err != nil {
+		return fmt.Errorf("upload of the fileset manifest to FS bucket '%v' has failed\n%w", bucketId, err)
 	}
+	return nil
+}
+
+// Upload 1 file, using chunks with a max size, to the specified file storage's bucket
+func (s *fTService) upload(ctx context.Context, cancel context.CancelFunc, bucketId string, filePath string, filesetPath string) error {
 	file, err := os.Open(filePath)
 	if err != nil {
 		return err
 	}
+	defer file.Close()
 
-	fileName := filepath.Base(filePath)
+	if err := s.uploadContent(ctx, bucketId, filesetPath, false, file); err != nil {
+		return err
+	}
+	cancel()
+	return nil
+}
+
+// Upload a content read from the provided reader, using chunks with a max size, to the specified file storage's bucket,
+// under the specified slash separated path relative to the bucket dir, or as the fileset manifest
+func (s *fTService) uploadContent(ctx context.Context, bucketId string, filesetPath string, manifest bool, content io.Reader) error {
+	stream, err := s.client.Upload(ctx)
+	if err != nil {
+		return err
+	}
 
 	// Loop over the file data per the specified max batch size
 	buf := make([]byte, s.dataChunkMaxSize)
 	batchNumber := 1
 	for {
-		num, err := file.Read(buf)
+		num, err := content.Read(buf)
 		if err == io.EOF {
 			break
 		}
@@ -134,7 +177,7 @@ func (s *fTService) upload(ctx context.Context, cancel context.CancelFunc, bucke
 		chunk := buf[:num]
 
 		// Send the file chunk over gRPC
-		if err := stream.Send(&pb.FileUploadRequest{BucketId: bucketId, FileName: fileName, Chunk: chunk}); err != nil {
+		if err := stream.Send(&pb.FileUploadRequest{BucketId: bucketId, FileName: path.Base(filesetPath), FilePath: filesetPath, Manifest: manifest, Chunk: chunk}); err != nil {
 			return err
 		}
 		if s.debug {
@@ -143,6 +186,13 @@ func (s *fTService) upload(ctx context.Context, cancel context.CancelFunc, bucke
 		batchNumber += 1
 	}
 
+	// An empty content is sent as a single empty chunk, for the empty file to be stored as well
+	if batchNumber == 1 {
+		if err := stream.Send(&pb.FileUploadRequest{BucketId: bucketId, FileName: path.Base(filesetPath), FilePath: filesetPath, Manifest: manifest}); err != nil {
+			return err
+		}
+	}
+
 	res, err := stream.CloseAndRecv()
 	if err != nil {
 		return err
@@ -150,7 +200,6 @@ func (s *fTService) upload(ctx context.Context, cancel context.CancelFunc, bucke
 	if s.debug {
 		log.Printf("Sent %6d bytes for file %s\n", res.GetSize(), res.GetFileName())
 	}
-	cancel()
 	return nil
 }
 
@@ -187,6 +236,48 @@ func (s *fTService) DownloadFile(bucketId string, fileIndex int) (*rpcfile.File,
 	return rFile, nil
 }
 
+// Retrieve the file chunk tree root and size of a file part of a bucket, with the proofs of its chunks
+// from `first` to `last`, bounds included, computed with the specified hash algorithm and chunk size
+func (s *fTService) DownloadFileChunkProofs(bucketId string, fileIndex int, hashAlgo string, chunkSize int, first int, last int) ([]byte, int64, []*mt.Proof, error) {
+	if s.debug {
+		log.Printf("Retrieving the proofs of chunks [%d, %d] of file #%d from FS bucket '%v' at '%v'", first, last, fileIndex, bucketId, s.endpoint)
+	}
+
+	conn, err := s.initClientConnection()
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to init the FileStorage file chunk proofs service \n%w", err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(2)*time.Second)
+	defer cancel()
+
+	resp, err := s.client.FileChunkProofs(ctx, &pb.FileChunkProofsRequest{
+		BucketId:   bucketId,
+		FileIndex:  int32(fileIndex),
+		HashAlgo:   hashAlgo,
+		ChunkSize:  uint32(chunkSize),
+		FirstChunk: uint32(first),
+		LastChunk:  uint32(last),
+	})
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf("failed to retrieve the proofs of chunks [%d, %d] of file #%d from FS bucket '%v' \n%w", first, last, fileIndex, bucketId, err)
+	}
+	if len(resp.GetProofs()) != last-first+1 {
+		return nil, 0, nil, fmt.Errorf("unexpected number of chunk proofs %d for chunks [%d, %d] of file #%d from FS bucket '%v'", len(resp.GetProofs()), first, last, fileIndex, bucketId)
+	}
+
+	proofs := make([]*mt.Proof, len(resp.GetProofs()))
+	for i, proof := range resp.GetProofs() {
+		proofs[i] = &mt.Proof{
+			Siblings: proof.GetSiblings(),
+			Path:     proof.GetPath(),
+		}
+	}
+
+	return resp.GetChunkRoot(), int64(resp.GetFileSize()), proofs, nil
+}
+
 // Aggregate the chunks of a data stream to a local file
 func copyFromResponse(w *io.PipeWriter, stream pb.FileService_DownloadClient) {
 	message := new(pb.FileDownloadResponse)
@@ -31,7 +31,12 @@ type (
 
 	// FilesStorage is the structure for local files management settings
 	FilesStorage struct {
+		// Storage backend of the files: `disk` for files stored under Location, the default, or `memory` for
+		// files held in memory, e.g. for tests
+		Backend  string `yaml:"backend" env:"FILES_BACKEND"`
 		Location string `yaml:"location" env:"FILES_LOCATION"`
+		// Number of concurrent workers for computing the file hashes, the number of CPUs if not set
+		HashWorkers int `yaml:"hash_workers" env:"FILES_HASH_WORKERS"`
 	}
 
 	// Log is the structure for the log management settings
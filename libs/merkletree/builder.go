@@ -0,0 +1,123 @@
+package merkletree
+
+import (
+	"errors"
+
+	"github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// ErrBuilderFinished is the error for a streaming tree builder already finished.
+var ErrBuilderFinished = errors.New("merkle tree builder is finished")
+
+// Builder computes the root of a Merkle Tree out of data blocks added one after the other, without holding
+// the leaves in memory: the added leaves are folded into a frontier of O(log n) pending nodes.
+// The root is the one of the tree generated by New with the same configuration and data blocks.
+//
+// The configuration Mode and parallelization settings are ignored, no proof being generated.
+// A Builder is not safe for concurrent use.
+type Builder struct {
+	config Config
+	hasher *nodeHasher
+	// frontier holds the pending node of each level, the root of a complete subtree of 2^level leaves
+	// waiting for its right sibling, nil if none.
+	frontier  [][]byte
+	numLeaves int
+	finished  bool
+}
+
+// NewBuilder creates a streaming builder of the root of a Merkle Tree.
+func NewBuilder(config *Config) (*Builder, error) {
+	if config == nil {
+		config = new(Config)
+	}
+	b := &Builder{config: *config}
+	var newDigest hash.NewDigestFunc
+	if b.config.HashFunc == nil {
+		var err error
+		if newDigest, err = hash.NewDigestFuncByName(b.config.HashAlgorithm); err != nil {
+			return nil, err
+		}
+		if err = b.config.initHashFunc(); err != nil {
+			return nil, err
+		}
+	}
+	b.hasher = newNodeHasher(b.config.HashFunc, concatHashFuncFor(&b.config), newDigest)
+	return b, nil
+}
+
+// Add folds the leaves of the data blocks into the frontier: every complete pair of subtrees is hashed
+// as soon as its right subtree is added, as New does for the nodes of even-length levels.
+func (b *Builder) Add(blocks ...IDataBlock) error {
+	if b.finished {
+		return ErrBuilderFinished
+	}
+	for _, block := range blocks {
+		if block == nil {
+			return ErrDataBlockIsNil
+		}
+		node, err := b.hasher.dataBlockToLeaf(nil, block, &b.config)
+		if err != nil {
+			return err
+		}
+		level := 0
+		for ; level < len(b.frontier) && b.frontier[level] != nil; level++ {
+			if node, err = b.hasher.hashPair(nil, b.frontier[level], node); err != nil {
+				return err
+			}
+			b.frontier[level] = nil
+		}
+		if level == len(b.frontier) {
+			b.frontier = append(b.frontier, nil)
+		}
+		b.frontier[level] = node
+		b.numLeaves++
+	}
+	return nil
+}
+
+// NumLeaves returns the number of leaves added so far.
+func (b *Builder) NumLeaves() int {
+	return b.numLeaves
+}
+
+// Finish returns the root of the tree of the added leaves, EmptyRoot if none.
+//
+// The pending nodes are the last nodes of odd-length levels. They are hashed level after level with
+// the node carried from the levels below, the last node of an odd-length level being duplicated,
+// or promoted to the upper level in RFC 6962 mode.
+func (b *Builder) Finish() ([]byte, error) {
+	if b.finished {
+		return nil, ErrBuilderFinished
+	}
+	b.finished = true
+	if b.numLeaves == 0 {
+		return b.config.HashFunc(nil)
+	}
+
+	var (
+		depth = treeDepth(b.numLeaves)
+		carry []byte
+		err   error
+	)
+	for level := 0; level < depth; level++ {
+		pending := b.frontier[level]
+		switch {
+		case pending != nil && carry != nil:
+			carry, err = b.hasher.hashPair(nil, pending, carry)
+		case pending != nil && b.config.RFC6962:
+			carry = pending
+		case pending != nil:
+			carry, err = b.hasher.hashPair(nil, pending, pending)
+		case carry != nil && !b.config.RFC6962:
+			carry, err = b.hasher.hashPair(nil, carry, carry)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if carry == nil {
+		// The tree is complete, its root is the pending node of the top level.
+		return b.frontier[depth], nil
+	}
+	return carry, nil
+}
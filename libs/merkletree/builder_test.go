@@ -0,0 +1,134 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+func TestBuilder(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name:   "test_nil_config",
+			config: nil,
+		},
+		{
+			name:   "test_default_config",
+			config: MerkleTreeDefaultConfig(false),
+		},
+		{
+			name:   "test_rfc6962",
+			config: MerkleTreeRFC6962Config(false),
+		},
+		{
+			name:   "test_evm",
+			config: MerkleTreeEVMConfig(false),
+		},
+		{
+			name:   "test_sorted_rfc6962_blake3",
+			config: &Config{HashAlgorithm: hash.AlgoBLAKE3, SortSiblingPairs: true, RFC6962: true},
+		},
+		{
+			name:   "test_custom_hash_func",
+			config: &Config{HashFunc: hash.DefaultHashFunc, SortSiblingPairs: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for numBlocks := 0; numBlocks <= 70; numBlocks++ {
+				blocks := generatedTestDataBlocks(numBlocks)
+				want, err := New(tt.config, blocks)
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+
+				// The data blocks are added one by one, and by batches
+				for _, batchSize := range []int{1, 7} {
+					b, err := NewBuilder(tt.config)
+					if err != nil {
+						t.Fatalf("NewBuilder() error = %v", err)
+					}
+					for i := 0; i < numBlocks; i += batchSize {
+						if err := b.Add(blocks[i:min(i+batchSize, numBlocks)]...); err != nil {
+							t.Fatalf("Add() error = %v", err)
+						}
+					}
+					if b.NumLeaves() != numBlocks {
+						t.Fatalf("NumLeaves() = %d, want %d", b.NumLeaves(), numBlocks)
+					}
+					root, err := b.Finish()
+					if err != nil {
+						t.Fatalf("Finish() error = %v", err)
+					}
+					if !bytes.Equal(root, want.Root) {
+						t.Fatalf("Finish() %d blocks root = %x, want %x", numBlocks, root, want.Root)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestBuilder_goldenTestVectors(t *testing.T) {
+	for _, tt := range loadGoldenTestVectors(t) {
+		b, err := NewBuilder(tt.Config.config(0, false))
+		if err != nil {
+			t.Fatalf("NewBuilder() error = %v", err)
+		}
+		for _, block := range tt.Blocks {
+			if err := b.Add(&DataBlock{Data: block}); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+		}
+		if root, err := b.Finish(); err != nil || !bytes.Equal(root, tt.Root) {
+			t.Errorf("%s: Finish() root = %x, error = %v, want %x", tt.Name, root, err, tt.Root)
+		}
+	}
+}
+
+func TestBuilder_errors(t *testing.T) {
+	if _, err := NewBuilder(&Config{HashAlgorithm: "unknown"}); !errors.Is(err, hash.ErrUnsupportedAlgo) {
+		t.Errorf("NewBuilder() error = %v, wantErr %v", err, hash.ErrUnsupportedAlgo)
+	}
+
+	b, err := NewBuilder(nil)
+	if err != nil {
+		t.Fatalf("NewBuilder() error = %v", err)
+	}
+	if err := b.Add(generatedTestDataBlocks(1)[0], nil); !errors.Is(err, ErrDataBlockIsNil) {
+		t.Errorf("Add() error = %v, wantErr %v", err, ErrDataBlockIsNil)
+	}
+	if _, err := b.Finish(); err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+
+	// A builder can not be used once finished
+	if err := b.Add(generatedTestDataBlocks(1)...); !errors.Is(err, ErrBuilderFinished) {
+		t.Errorf("Add() error = %v, wantErr %v", err, ErrBuilderFinished)
+	}
+	if _, err := b.Finish(); !errors.Is(err, ErrBuilderFinished) {
+		t.Errorf("Finish() error = %v, wantErr %v", err, ErrBuilderFinished)
+	}
+}
+
+func BenchmarkBuilder(b *testing.B) {
+	blocks := generatedTestDataBlocks(benchSize)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		builder, err := NewBuilder(nil)
+		if err != nil {
+			b.Fatalf("NewBuilder() error = %v", err)
+		}
+		if err = builder.Add(blocks...); err != nil {
+			b.Fatalf("Add() error = %v", err)
+		}
+		if _, err = builder.Finish(); err != nil {
+			b.Fatalf("Finish() error = %v", err)
+		}
+	}
+}
@@ -3,13 +3,15 @@ package merkletree
 import "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
 
 // Commonly shared default config for generating Merkle Trees from filesets' file hashes
+//
+// The default hash algorithm can be replaced by setting the config `HashAlgorithm`
 func MerkleTreeDefaultConfig(proofsGen bool) *Config {
 	treeGenMode := ModeTreeBuild
 	if proofsGen {
 		treeGenMode = ModeProofGenAndTreeBuild
 	}
 	return &Config{
-		HashFunc:           hash.DefaultHashFuncParallel,
+		HashAlgorithm:      hash.DefaultAlgo,
 		NumRoutines:        0,
 		Mode:               treeGenMode,
 		RunInParallel:      true,
@@ -17,3 +19,25 @@ func MerkleTreeDefaultConfig(proofsGen bool) *Config {
 		DisableLeafHashing: true,
 	}
 }
+
+// Config for generating RFC 6962 compliant Merkle Trees from filesets' file hashes.
+// The file hashes are hashed as domain separated leaves, and odd nodes are promoted instead of duplicated
+func MerkleTreeRFC6962Config(proofsGen bool) *Config {
+	config := MerkleTreeDefaultConfig(proofsGen)
+	config.DisableLeafHashing = false
+	config.RFC6962 = true
+	return config
+}
+
+// Config for generating EVM compatible Merkle Trees from filesets' file hashes, whose proofs are verifiable
+// on-chain by OpenZeppelin's `MerkleProof.verify` and `multiProofVerify`: the Keccak-256 hash function is used
+// for the leaves and the sorted sibling pairs.
+// The file hashes are hashed as leaves, i.e. `keccak256(abi.encodePacked(fileHash))`, so that a leaf
+// can not be mistaken for an internal node
+func MerkleTreeEVMConfig(proofsGen bool) *Config {
+	config := MerkleTreeDefaultConfig(proofsGen)
+	config.HashAlgorithm = hash.AlgoKeccak256
+	config.SortSiblingPairs = true
+	config.DisableLeafHashing = false
+	return config
+}
@@ -0,0 +1,146 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+)
+
+// ErrConsistencyProofInvalidSizes is the error for tree sizes not supported by a consistency proof.
+var ErrConsistencyProofInvalidSizes = errors.New("the old tree size must be positive and not exceed the new tree size")
+
+// ConsistencyProof represents a proof that a Merkle Tree is an append-only extension of an older one,
+// i.e. that the first OldSize leaves of the newer tree are the leaves of the older tree.
+//
+// It is made of the last leaf of the older tree and the siblings of its path in the newer tree.
+// The siblings on the left of the path are shared by both trees, they are used along with the last leaf
+// to compute the older root, while all of them are used to compute the newer root.
+//
+// This format is specific to this library: it is not the RFC 6962 / RFC 9162 consistency proof,
+// and can not be verified by the verifiers of those RFCs, even for the trees in RFC 6962 mode.
+type ConsistencyProof struct {
+	// OldSize is the number of leaves of the older tree.
+	OldSize int
+	// NewSize is the number of leaves of the newer tree.
+	NewSize int
+	// Nodes are the last leaf of the older tree followed by the siblings of its path in the newer tree, bottom-up.
+	// Siblings duplicated to fix the odd length of a level, or missing for promoted nodes, are omitted.
+	Nodes [][]byte
+}
+
+// ConsistencyProof generates the proof that the Merkle Tree is an append-only extension of the tree
+// made of its first oldSize leaves.
+// This method is only available when the configuration mode is ModeTreeBuild or ModeProofGenAndTreeBuild.
+func (m *MerkleTree) ConsistencyProof(oldSize int) (*ConsistencyProof, error) {
+	if m.Mode != ModeTreeBuild && m.Mode != ModeProofGenAndTreeBuild {
+		return nil, ErrProofInvalidModeTreeNotBuilt
+	}
+	if oldSize < 1 || oldSize > m.NumLeaves {
+		return nil, ErrConsistencyProofInvalidSizes
+	}
+
+	idx := oldSize - 1
+	nodes := make([][]byte, 1, m.Depth+1)
+	nodes[0] = m.Leaves[idx]
+	for level := 0; level < m.Depth; level++ {
+		if idx&1 == 1 {
+			nodes = append(nodes, m.node(level, idx-1))
+		} else if idx+1 < levelLength(m.NumLeaves, level) {
+			nodes = append(nodes, m.node(level, idx+1))
+		}
+		idx >>= 1
+	}
+	return &ConsistencyProof{
+		OldSize: oldSize,
+		NewSize: m.NumLeaves,
+		Nodes:   cloneNodes(nodes),
+	}, nil
+}
+
+// VerifyConsistency checks that the tree of root newRoot is an append-only extension of the tree
+// of root oldRoot, using the consistency proof generated from the newer tree.
+// It returns true if the proof is valid for both roots, false otherwise. An error is returned in case of
+// any issues during the verification process.
+func VerifyConsistency(proof *ConsistencyProof, oldRoot, newRoot []byte, config *Config) (bool, error) {
+	// Validate input parameters.
+	if proof == nil {
+		return false, ErrProofIsNil
+	}
+	if proof.OldSize < 1 || proof.OldSize > proof.NewSize {
+		return false, ErrConsistencyProofInvalidSizes
+	}
+	if len(proof.Nodes) == 0 {
+		return false, nil
+	}
+	if config == nil {
+		config = new(Config)
+	}
+	if err := config.initHashFunc(); err != nil {
+		return false, err
+	}
+
+	// Determine the concatenation function based on the configuration.
+	concatFunc := concatHashFuncFor(config)
+
+	// Compute both roots along the path of the last leaf of the older tree.
+	var (
+		oldDepth = bits.Len(uint(proof.OldSize - 1))
+		newDepth = bits.Len(uint(proof.NewSize - 1))
+		idx      = proof.OldSize - 1
+		oldHash  = proof.Nodes[0]
+		newHash  = proof.Nodes[0]
+		pos      = 1
+		err      error
+	)
+	nextNode := func() []byte {
+		if pos >= len(proof.Nodes) {
+			return nil
+		}
+		pos++
+		return proof.Nodes[pos-1]
+	}
+	for level := 0; level < newDepth; level++ {
+		if idx&1 == 1 {
+			// The left sibling is part of both trees.
+			left := nextNode()
+			if left == nil {
+				return false, nil
+			}
+			if level < oldDepth {
+				if oldHash, err = config.HashFunc(concatFunc(nil, left, oldHash)); err != nil {
+					return false, err
+				}
+			}
+			if newHash, err = config.HashFunc(concatFunc(nil, left, newHash)); err != nil {
+				return false, err
+			}
+		} else {
+			// The node is the last one of the older tree level, hence duplicated,
+			// or promoted in RFC 6962 mode.
+			if level < oldDepth && !config.RFC6962 {
+				if oldHash, err = config.HashFunc(concatFunc(nil, oldHash, oldHash)); err != nil {
+					return false, err
+				}
+			}
+			right := newHash
+			if idx+1 < levelLength(proof.NewSize, level) {
+				if right = nextNode(); right == nil {
+					return false, nil
+				}
+			} else if config.RFC6962 {
+				idx >>= 1
+				continue
+			}
+			if newHash, err = config.HashFunc(concatFunc(nil, newHash, right)); err != nil {
+				return false, err
+			}
+		}
+		idx >>= 1
+	}
+
+	// All the provided nodes must have been consumed to compute the roots.
+	if pos != len(proof.Nodes) {
+		return false, nil
+	}
+	return bytes.Equal(oldHash, oldRoot) && bytes.Equal(newHash, newRoot), nil
+}
@@ -0,0 +1,259 @@
+package merkletree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMerkleTree_ConsistencyProof(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  *Config
+		oldSize int
+		newSize int
+	}{
+		{
+			name:    "test_1_1",
+			config:  &Config{Mode: ModeTreeBuild},
+			oldSize: 1,
+			newSize: 1,
+		},
+		{
+			name:    "test_1_5",
+			config:  &Config{Mode: ModeTreeBuild},
+			oldSize: 1,
+			newSize: 5,
+		},
+		{
+			name:    "test_2_2",
+			config:  &Config{Mode: ModeTreeBuild},
+			oldSize: 2,
+			newSize: 2,
+		},
+		{
+			name:    "test_2_3",
+			config:  &Config{Mode: ModeTreeBuild},
+			oldSize: 2,
+			newSize: 3,
+		},
+		{
+			name:    "test_3_4",
+			config:  &Config{Mode: ModeTreeBuild},
+			oldSize: 3,
+			newSize: 4,
+		},
+		{
+			name:    "test_4_8",
+			config:  &Config{Mode: ModeTreeBuild},
+			oldSize: 4,
+			newSize: 8,
+		},
+		{
+			name:    "test_5_7",
+			config:  &Config{Mode: ModeTreeBuild},
+			oldSize: 5,
+			newSize: 7,
+		},
+		{
+			name:    "test_6_100",
+			config:  &Config{Mode: ModeTreeBuild},
+			oldSize: 6,
+			newSize: 100,
+		},
+		{
+			name:    "test_sorted_7_9",
+			config:  &Config{Mode: ModeTreeBuild, SortSiblingPairs: true},
+			oldSize: 7,
+			newSize: 9,
+		},
+		{
+			name:    "test_disable_leaf_hashing_999_1001",
+			config:  &Config{Mode: ModeProofGenAndTreeBuild, DisableLeafHashing: true},
+			oldSize: 999,
+			newSize: 1001,
+		},
+		{
+			name:    "test_parallel_64_65",
+			config:  &Config{Mode: ModeTreeBuild, RunInParallel: true, NumRoutines: 4},
+			oldSize: 64,
+			newSize: 65,
+		},
+		{
+			name:    "test_rfc6962_1_6",
+			config:  &Config{Mode: ModeTreeBuild, RFC6962: true},
+			oldSize: 1,
+			newSize: 6,
+		},
+		{
+			name:    "test_rfc6962_3_4",
+			config:  &Config{Mode: ModeTreeBuild, RFC6962: true},
+			oldSize: 3,
+			newSize: 4,
+		},
+		{
+			name:    "test_rfc6962_5_7",
+			config:  &Config{Mode: ModeTreeBuild, RFC6962: true},
+			oldSize: 5,
+			newSize: 7,
+		},
+		{
+			name:    "test_rfc6962_sorted_6_100",
+			config:  &Config{Mode: ModeProofGenAndTreeBuild, RFC6962: true, SortSiblingPairs: true},
+			oldSize: 6,
+			newSize: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := generatedTestDataBlocks(tt.newSize)
+			oldTree, err := New(tt.config, blocks[:tt.oldSize])
+			if err != nil {
+				t.Fatalf("New() old tree error = %v", err)
+			}
+			newTree, err := New(tt.config, blocks)
+			if err != nil {
+				t.Fatalf("New() new tree error = %v", err)
+			}
+			proof, err := newTree.ConsistencyProof(tt.oldSize)
+			if err != nil {
+				t.Fatalf("ConsistencyProof() error = %v", err)
+			}
+			if proof.OldSize != tt.oldSize || proof.NewSize != tt.newSize {
+				t.Fatalf("ConsistencyProof() sizes = %d %d, want %d %d", proof.OldSize, proof.NewSize, tt.oldSize, tt.newSize)
+			}
+			got, err := VerifyConsistency(proof, oldTree.Root, newTree.Root, tt.config)
+			if err != nil || !got {
+				t.Fatalf("VerifyConsistency() got = %v, error = %v, want true", got, err)
+			}
+
+			// Swapped roots must not verify, unless the trees are the same.
+			if tt.oldSize != tt.newSize {
+				if got, _ := VerifyConsistency(proof, newTree.Root, oldTree.Root, tt.config); got {
+					t.Errorf("VerifyConsistency() swapped roots got = %v, want false", got)
+				}
+			}
+
+			// Tampered nodes must not verify.
+			for i := range proof.Nodes {
+				tampered := &ConsistencyProof{
+					OldSize: proof.OldSize,
+					NewSize: proof.NewSize,
+					Nodes:   append([][]byte{}, proof.Nodes...),
+				}
+				tampered.Nodes[i] = []byte("tampered")
+				if got, _ := VerifyConsistency(tampered, oldTree.Root, newTree.Root, tt.config); got {
+					t.Errorf("VerifyConsistency() tampered node %d got = %v, want false", i, got)
+				}
+			}
+
+			// A newer tree rewriting one of the older leaves must not verify.
+			rewritten := make([]IDataBlock, tt.newSize)
+			copy(rewritten, blocks)
+			rewritten[0] = &DataBlock{Data: []byte("rewritten")}
+			rewrittenTree, err := New(tt.config, rewritten)
+			if err != nil {
+				t.Fatalf("New() rewritten tree error = %v", err)
+			}
+			rewrittenProof, err := rewrittenTree.ConsistencyProof(tt.oldSize)
+			if err != nil {
+				t.Fatalf("ConsistencyProof() rewritten tree error = %v", err)
+			}
+			if got, _ := VerifyConsistency(rewrittenProof, oldTree.Root, rewrittenTree.Root, tt.config); got {
+				t.Errorf("VerifyConsistency() rewritten tree got = %v, want false", got)
+			}
+		})
+	}
+}
+
+func TestMerkleTree_ConsistencyProof_errors(t *testing.T) {
+	blocks := generatedTestDataBlocks(5)
+	m, err := New(&Config{Mode: ModeProofGen}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := m.ConsistencyProof(3); !errors.Is(err, ErrProofInvalidModeTreeNotBuilt) {
+		t.Errorf("ConsistencyProof() error = %v, wantErr %v", err, ErrProofInvalidModeTreeNotBuilt)
+	}
+	m, err = New(&Config{Mode: ModeTreeBuild}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	for _, oldSize := range []int{-1, 0, 6} {
+		if _, err := m.ConsistencyProof(oldSize); !errors.Is(err, ErrConsistencyProofInvalidSizes) {
+			t.Errorf("ConsistencyProof(%d) error = %v, wantErr %v", oldSize, err, ErrConsistencyProofInvalidSizes)
+		}
+	}
+}
+
+func TestVerifyConsistency(t *testing.T) {
+	blocks := generatedTestDataBlocks(11)
+	oldTree, err := New(&Config{Mode: ModeTreeBuild}, blocks[:6])
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	newTree, err := New(&Config{Mode: ModeTreeBuild}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	proof, err := newTree.ConsistencyProof(6)
+	if err != nil {
+		t.Fatalf("ConsistencyProof() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		proof   *ConsistencyProof
+		want    bool
+		wantErr error
+	}{
+		{
+			name:  "test_ok",
+			proof: proof,
+			want:  true,
+		},
+		{
+			name:    "test_proof_nil",
+			wantErr: ErrProofIsNil,
+		},
+		{
+			name:    "test_invalid_sizes",
+			proof:   &ConsistencyProof{OldSize: 12, NewSize: 11, Nodes: proof.Nodes},
+			wantErr: ErrConsistencyProofInvalidSizes,
+		},
+		{
+			name:  "test_wrong_old_size",
+			proof: &ConsistencyProof{OldSize: 5, NewSize: 11, Nodes: proof.Nodes},
+			want:  false,
+		},
+		{
+			name:  "test_wrong_new_size",
+			proof: &ConsistencyProof{OldSize: 6, NewSize: 17, Nodes: proof.Nodes},
+			want:  false,
+		},
+		{
+			name:  "test_missing_node",
+			proof: &ConsistencyProof{OldSize: 6, NewSize: 11, Nodes: proof.Nodes[:len(proof.Nodes)-1]},
+			want:  false,
+		},
+		{
+			name:  "test_extra_node",
+			proof: &ConsistencyProof{OldSize: 6, NewSize: 11, Nodes: append(append([][]byte{}, proof.Nodes...), newTree.Root)},
+			want:  false,
+		},
+		{
+			name:  "test_no_node",
+			proof: &ConsistencyProof{OldSize: 6, NewSize: 11},
+			want:  false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyConsistency(tt.proof, oldTree.Root, newTree.Root, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyConsistency() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyConsistency() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
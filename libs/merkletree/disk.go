@@ -0,0 +1,707 @@
+package merkletree
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"os"
+
+	"github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// Disk-backed store of the tree nodes.
+//
+// The nodes of each level are stored back to back in a flat file, level after level from the leaves,
+// every node being of the same size. The nodes are followed by the binary encoded descriptor of the tree:
+// its configuration flags, hash algorithm, number of leaves, node size and root, then by the size of
+// the descriptor as a 4 bytes big endian integer.
+const (
+	// binaryKindDiskStore is the kind of the encoded descriptor of a disk store.
+	binaryKindDiskStore byte = 'd'
+	// diskStoreFooterSize is the size of the footer of a disk store, holding the size of its descriptor.
+	diskStoreFooterSize = 4
+	// diskStoreScanLength is the number of leaves read at once when searching a leaf in a disk store
+	// that is not memory-mapped.
+	diskStoreScanLength = 4096
+
+	// DefaultDiskMemoryBudget is the default size in bytes of the buffers used to build a DiskMerkleTree.
+	DefaultDiskMemoryBudget = 64 << 20
+)
+
+var (
+	// ErrDiskNodeSize is the error for a leaf or a hash whose size differs from the one of the other nodes
+	// of a disk-backed tree.
+	ErrDiskNodeSize = errors.New("the nodes of a disk-backed merkle tree must all be of the same size")
+	// ErrDiskBuilderFinished is the error for a disk-backed tree builder already finished or discarded.
+	ErrDiskBuilderFinished = errors.New("disk-backed merkle tree builder is finished")
+	// ErrDiskStoreClosed is the error for a disk-backed tree whose store is closed.
+	ErrDiskStoreClosed = errors.New("disk-backed merkle tree store is closed")
+)
+
+// DiskStoreConfig is the configuration of the disk-backed store of a DiskMerkleTree.
+type DiskStoreConfig struct {
+	// Path of the store file, created or truncated by the builder.
+	Path string
+	// Maximum size in bytes of the buffers used to write the leaves and hash the levels of the tree,
+	// DefaultDiskMemoryBudget if not set. The memory used does not depend on the number of leaves:
+	// the store is memory-mapped once built, its pages being loaded and evicted by the operating system.
+	MemoryBudget int
+}
+
+// DataBlockIterator iterates over the data blocks of a tree, in their order.
+type DataBlockIterator interface {
+	// Next returns the next data block, or io.EOF once all the data blocks are returned.
+	Next() (IDataBlock, error)
+}
+
+// DiskMerkleTree is a Merkle Tree whose nodes are kept in a disk-backed store instead of memory,
+// for trees of hundreds of millions of leaves.
+// It is the tree generated by New in ModeTreeBuild, with the same Root and proofs.
+//
+// Its proofs can be generated concurrently. It must be closed once no longer used.
+type DiskMerkleTree struct {
+	Config
+	// concatHashFunc is the function for concatenating two hashes.
+	concatHashFunc typeConcatHashFunc
+	// store is the file of the nodes, data its memory-mapped content, nil if not supported by the platform.
+	store *os.File
+	data  []byte
+	// nodeSize is the size of every node.
+	nodeSize int
+	// levelLengths and levelOffsets are the number of nodes of each level and its offset in the store.
+	levelLengths []int
+	levelOffsets []int64
+	// Root is the hash of the Merkle root node.
+	Root []byte
+	// Depth is the depth of the Merkle Tree.
+	Depth int
+	// NumLeaves is the number of leaves in the Merkle Tree.
+	NumLeaves int
+}
+
+// diskLevelLengths returns the number of nodes of each level of a tree, as stored: the last node of
+// an odd-length level is duplicated, or promoted to the upper level in RFC 6962 mode.
+// The single leaf of a tree of depth 0 is stored as its only level, a tree of no leaf has no level.
+func diskLevelLengths(numLeaves int, rfc6962 bool) []int {
+	if numLeaves == 0 {
+		return nil
+	}
+	if numLeaves == 1 {
+		return []int{1}
+	}
+	lengths := make([]int, bits.Len(uint(numLeaves-1)))
+	levelLength := numLeaves
+	for i := range lengths {
+		if levelLength&1 == 1 && !rfc6962 {
+			levelLength++
+		}
+		lengths[i] = levelLength
+		levelLength = (levelLength + 1) >> 1
+	}
+	return lengths
+}
+
+// diskLevelOffsets returns the offset of each level in the store, and the size of the stored nodes.
+func diskLevelOffsets(levelLengths []int, nodeSize int) ([]int64, int64) {
+	offsets := make([]int64, len(levelLengths))
+	var offset int64
+	for i, length := range levelLengths {
+		offsets[i] = offset
+		offset += int64(length) * int64(nodeSize)
+	}
+	return offsets, offset
+}
+
+// DiskBuilder builds a DiskMerkleTree out of data blocks added one after the other, the leaves being
+// written to the store as they are added. The levels of the tree are computed once finished.
+// A DiskBuilder is not safe for concurrent use.
+type DiskBuilder struct {
+	config         Config
+	concatHashFunc typeConcatHashFunc
+	storeConfig    DiskStoreConfig
+	store          *os.File
+	writer         *bufio.Writer
+	// wp is the worker pool hashing the levels in parallel, only set while finishing.
+	wp *WorkerPool
+	// nodeSize is the size of the leaves, set by the first one.
+	nodeSize  int
+	numLeaves int
+	lastLeaf  []byte
+	finished  bool
+}
+
+// NewDiskBuilder creates the builder of a DiskMerkleTree, whose store file is created.
+// The configuration Mode is ignored, the tree being built as in ModeTreeBuild.
+func NewDiskBuilder(config *Config, storeConfig DiskStoreConfig) (*DiskBuilder, error) {
+	if config == nil {
+		config = new(Config)
+	}
+	b := &DiskBuilder{
+		config:      *config,
+		storeConfig: storeConfig,
+	}
+	b.config.Mode = ModeTreeBuild
+	if b.config.HashFunc == nil {
+		if b.config.RunInParallel && b.config.HashAlgorithm == "" {
+			// Use a concurrent safe hash function for parallel execution.
+			b.config.HashFunc, b.config.hashFuncByName = hash.DefaultHashFuncParallel, true
+		} else if err := b.config.initHashFunc(); err != nil {
+			return nil, err
+		}
+	}
+	b.concatHashFunc = concatHashFuncFor(&b.config)
+	if b.storeConfig.MemoryBudget <= 0 {
+		b.storeConfig.MemoryBudget = DefaultDiskMemoryBudget
+	}
+
+	store, err := os.Create(storeConfig.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create the merkle tree store '%s'\n%w", storeConfig.Path, err)
+	}
+	b.store = store
+	b.writer = bufio.NewWriterSize(store, b.storeConfig.MemoryBudget)
+	return b, nil
+}
+
+// Add appends the leaves of the data blocks to the store. The leaves must all be of the same size,
+// the one of the hashes, e.g. file hashes of the tree hash algorithm when DisableLeafHashing is set.
+func (b *DiskBuilder) Add(blocks ...IDataBlock) error {
+	if b.finished {
+		return ErrDiskBuilderFinished
+	}
+	for _, block := range blocks {
+		if block == nil {
+			return ErrDataBlockIsNil
+		}
+		leaf, err := dataBlockToLeaf(block, &b.config)
+		if err != nil {
+			return err
+		}
+		if b.numLeaves == 0 {
+			b.nodeSize = len(leaf)
+		}
+		if len(leaf) == 0 || len(leaf) != b.nodeSize {
+			return ErrDiskNodeSize
+		}
+		if _, err = b.writer.Write(leaf); err != nil {
+			return fmt.Errorf("failed to write to the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+		}
+		b.lastLeaf = leaf
+		b.numLeaves++
+	}
+	return nil
+}
+
+// Discard stops the building and removes the store.
+func (b *DiskBuilder) Discard() error {
+	if b.finished {
+		return ErrDiskBuilderFinished
+	}
+	b.finished = true
+	b.writer = nil
+	b.store.Close()
+	return os.Remove(b.storeConfig.Path)
+}
+
+// Finish computes the levels of the tree out of the added leaves, and returns the tree served by the store.
+// The store is removed in case of failure.
+func (b *DiskBuilder) Finish() (*DiskMerkleTree, error) {
+	return b.FinishWithContext(context.Background())
+}
+
+// FinishWithContext computes the levels of the tree out of the added leaves, and returns the tree served
+// by the store. The computation is aborted with the context error once the context is done.
+// The store is removed in case of failure.
+func (b *DiskBuilder) FinishWithContext(ctx context.Context) (t *DiskMerkleTree, err error) {
+	if b.finished {
+		return nil, ErrDiskBuilderFinished
+	}
+	defer func() {
+		if err != nil {
+			b.store.Close()
+			os.Remove(b.storeConfig.Path)
+		}
+	}()
+	b.finished = true
+
+	// Complete the leaves level with the duplicated last leaf, if any.
+	levelLengths := diskLevelLengths(b.numLeaves, b.config.RFC6962)
+	if b.numLeaves > 1 && levelLengths[0] > b.numLeaves {
+		if _, err = b.writer.Write(b.lastLeaf); err != nil {
+			return nil, fmt.Errorf("failed to write to the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+		}
+	}
+	if err = b.writer.Flush(); err != nil {
+		return nil, fmt.Errorf("failed to write to the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+	}
+	b.writer = nil
+
+	// The root of a single leaf tree is the leaf itself, the one of no leaf is the hash of empty data, as for New.
+	levelOffsets, storeSize := diskLevelOffsets(levelLengths, b.nodeSize)
+	var root []byte
+	switch b.numLeaves {
+	case 0:
+		if root, err = b.config.HashFunc(nil); err != nil {
+			return nil, err
+		}
+	case 1:
+		root = append([]byte{}, b.lastLeaf...)
+	default:
+		if root, err = b.hashLevels(ctx, levelLengths, levelOffsets); err != nil {
+			return nil, err
+		}
+	}
+
+	// Append the descriptor of the tree.
+	desc := newBinaryEncoder(binaryKindDiskStore)
+	desc.WriteByte(b.config.binaryFlags())
+	desc.writeBytes([]byte(b.config.HashAlgorithm))
+	desc.writeUvarint(uint64(b.numLeaves))
+	desc.writeUvarint(uint64(b.nodeSize))
+	desc.writeBytes(root)
+	desc.Write(binary.BigEndian.AppendUint32(nil, uint32(desc.Len())))
+	if _, err = b.store.WriteAt(desc.Bytes(), storeSize); err != nil {
+		return nil, fmt.Errorf("failed to write to the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+	}
+	if err = b.store.Sync(); err != nil {
+		return nil, fmt.Errorf("failed to write to the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+	}
+
+	t = &DiskMerkleTree{
+		Config:         b.config,
+		concatHashFunc: b.concatHashFunc,
+		store:          b.store,
+		nodeSize:       b.nodeSize,
+		levelLengths:   levelLengths,
+		levelOffsets:   levelOffsets,
+		Root:           root,
+		Depth:          treeDepth(b.numLeaves),
+		NumLeaves:      b.numLeaves,
+	}
+	if t.data, err = mapStore(b.store, storeSize); err != nil {
+		return nil, fmt.Errorf("failed to map the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+	}
+	return t, nil
+}
+
+// hashLevels computes the levels of a tree of at least 2 leaves out of the stored leaves, returning its root.
+func (b *DiskBuilder) hashLevels(ctx context.Context, levelLengths []int, levelOffsets []int64) ([]byte, error) {
+	if b.config.RunInParallel {
+		var releasePool func()
+		b.wp, releasePool = acquireWorkerPool(&b.config)
+		defer releasePool()
+	}
+
+	// Compute each level out of the level below, the top level being made of the 2 children of the root.
+	// The levels are read and hashed by chunks of pairs bounded by the memory budget, each pair taking
+	// 2 nodes in the input buffer and 1 node in the output buffer.
+	var (
+		chunkPairs = min(max(b.storeConfig.MemoryBudget/(3*b.nodeSize), 1), levelLengths[0]>>1)
+		in         = make([]byte, 2*chunkPairs*b.nodeSize)
+		out        = make([]byte, chunkPairs*b.nodeSize)
+	)
+	for i := 0; i < len(levelLengths)-1; i++ {
+		if err := b.hashLevel(ctx, in, out, levelOffsets[i], levelLengths[i], levelOffsets[i+1], levelLengths[i+1]); err != nil {
+			return nil, err
+		}
+	}
+	top := make([]byte, 2*b.nodeSize)
+	if _, err := b.store.ReadAt(top, levelOffsets[len(levelOffsets)-1]); err != nil {
+		return nil, fmt.Errorf("failed to read from the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+	}
+	return b.config.HashFunc(b.concatHashFunc(nil, top[:b.nodeSize], top[b.nodeSize:]))
+}
+
+// hashLevel computes the nodes of a level out of the level below, read and hashed by chunks of pairs
+// through the input and output buffers.
+func (b *DiskBuilder) hashLevel(ctx context.Context, in, out []byte, inOffset int64, inLength int, outOffset int64, outLength int) error {
+	var (
+		nodeSize   = b.nodeSize
+		chunkPairs = len(out) / nodeSize
+		numPairs   = (inLength + 1) >> 1
+		lastNode   []byte
+	)
+	for p := 0; p < numPairs; p += chunkPairs {
+		if err := ctxErr(ctx); err != nil {
+			return err
+		}
+		n := min(chunkPairs, numPairs-p)
+		numNodes := min(2*n, inLength-2*p)
+		if _, err := b.store.ReadAt(in[:numNodes*nodeSize], inOffset+int64(2*p*nodeSize)); err != nil {
+			return fmt.Errorf("failed to read from the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+		}
+		if err := b.hashPairs(ctx, in[:numNodes*nodeSize], out[:n*nodeSize]); err != nil {
+			return err
+		}
+		if _, err := b.store.WriteAt(out[:n*nodeSize], outOffset+int64(p*nodeSize)); err != nil {
+			return fmt.Errorf("failed to write to the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+		}
+		lastNode = out[(n-1)*nodeSize : n*nodeSize]
+	}
+
+	// Duplicate the last node of an odd-length level.
+	if outLength > numPairs {
+		if _, err := b.store.WriteAt(lastNode, outOffset+int64(numPairs*nodeSize)); err != nil {
+			return fmt.Errorf("failed to write to the merkle tree store '%s'\n%w", b.storeConfig.Path, err)
+		}
+	}
+	return nil
+}
+
+// hashPairs hashes the pairs of nodes of the input buffer into the output buffer, in parallel if configured.
+// The last node of an odd number of nodes is promoted.
+func (b *DiskBuilder) hashPairs(ctx context.Context, in, out []byte) error {
+	numPairs := len(out) / b.nodeSize
+	if b.wp == nil || numPairs < 2 {
+		return hashDiskPairs(b, in, out, 0, 1)
+	}
+	numRoutines := min(b.config.NumRoutines, numPairs)
+	argList := make([]workerArgs, numRoutines)
+	for i := 0; i < numRoutines; i++ {
+		argList[i] = workerArgs{
+			hashDiskPairs: &workerArgsHashDiskPairs{
+				builder:     b,
+				in:          in,
+				out:         out,
+				startIdx:    i,
+				numRoutines: numRoutines,
+			},
+		}
+	}
+	_, err := b.wp.MapCtx(ctx, func(ctx context.Context, args workerArgs) error {
+		args.ctx = ctx
+		return workerHashDiskPairs(args)
+	}, argList)
+	return err
+}
+
+// workerArgsHashDiskPairs contains arguments for the workerHashDiskPairs function.
+type workerArgsHashDiskPairs struct {
+	builder     *DiskBuilder
+	in          []byte
+	out         []byte
+	startIdx    int
+	numRoutines int
+}
+
+// workerHashDiskPairs is the worker function that hashes the pairs of nodes of a disk-backed tree level in parallel.
+func workerHashDiskPairs(args workerArgs) error {
+	chosenArgs := args.hashDiskPairs
+	return hashDiskPairs(chosenArgs.builder, chosenArgs.in, chosenArgs.out, chosenArgs.startIdx, chosenArgs.numRoutines)
+}
+
+// hashDiskPairs hashes the pairs of nodes of the input buffer from the start pair, strided by the number of routines.
+func hashDiskPairs(b *DiskBuilder, in, out []byte, start, numRoutines int) error {
+	nodeSize := b.nodeSize
+	numNodes := len(in) / nodeSize
+	for i := start; 2*i < numNodes; i += numRoutines {
+		left := in[2*i*nodeSize : (2*i+1)*nodeSize : (2*i+1)*nodeSize]
+		if 2*i+1 == numNodes {
+			copy(out[i*nodeSize:], left)
+			continue
+		}
+		right := in[(2*i+1)*nodeSize : (2*i+2)*nodeSize : (2*i+2)*nodeSize]
+		node, err := b.config.HashFunc(b.concatHashFunc(nil, left, right))
+		if err != nil {
+			return err
+		}
+		if len(node) != nodeSize {
+			return ErrDiskNodeSize
+		}
+		copy(out[i*nodeSize:], node)
+	}
+	return nil
+}
+
+// NewDisk builds a DiskMerkleTree out of the data blocks of the iterator, with the specified configuration
+// and store. The building is aborted with the context error once the context is done, the store being removed.
+func NewDisk(ctx context.Context, config *Config, storeConfig DiskStoreConfig, blocks DataBlockIterator) (*DiskMerkleTree, error) {
+	b, err := NewDiskBuilder(config, storeConfig)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if err = ctx.Err(); err != nil {
+			b.Discard()
+			return nil, err
+		}
+		block, err := blocks.Next()
+		if err == io.EOF {
+			break
+		}
+		if err == nil {
+			err = b.Add(block)
+		}
+		if err != nil {
+			b.Discard()
+			return nil, err
+		}
+	}
+	return b.FinishWithContext(ctx)
+}
+
+// NewDiskFromChan builds a DiskMerkleTree out of the data blocks received from the channel until it is closed,
+// with the specified configuration and store. The building is aborted with the context error once the context
+// is done, the store being removed.
+func NewDiskFromChan(ctx context.Context, config *Config, storeConfig DiskStoreConfig, blocks <-chan IDataBlock) (*DiskMerkleTree, error) {
+	return NewDisk(ctx, config, storeConfig, &chanDataBlockIterator{ctx: ctx, blocks: blocks})
+}
+
+// chanDataBlockIterator iterates over the data blocks received from a channel.
+type chanDataBlockIterator struct {
+	ctx    context.Context
+	blocks <-chan IDataBlock
+}
+
+// Next returns the next data block received, or io.EOF once the channel is closed.
+func (it *chanDataBlockIterator) Next() (IDataBlock, error) {
+	select {
+	case block, ok := <-it.blocks:
+		if !ok {
+			return nil, io.EOF
+		}
+		return block, nil
+	case <-it.ctx.Done():
+		return nil, it.ctx.Err()
+	}
+}
+
+// OpenDisk opens the store of a DiskMerkleTree previously built, without recomputing its nodes.
+//
+// The tree configuration is the stored one, except for the hash function, set from the provided
+// configuration if any.
+func OpenDisk(config *Config, path string) (t *DiskMerkleTree, err error) {
+	store, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the merkle tree store '%s'\n%w", path, err)
+	}
+	defer func() {
+		if err != nil {
+			store.Close()
+		}
+	}()
+	info, err := store.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open the merkle tree store '%s'\n%w", path, err)
+	}
+
+	// Read the descriptor out of the end of the store.
+	footer := make([]byte, diskStoreFooterSize)
+	if info.Size() < diskStoreFooterSize {
+		return nil, ErrBinaryCorrupted
+	}
+	if _, err = store.ReadAt(footer, info.Size()-diskStoreFooterSize); err != nil {
+		return nil, fmt.Errorf("failed to read from the merkle tree store '%s'\n%w", path, err)
+	}
+	descSize := int64(binary.BigEndian.Uint32(footer))
+	storeSize := info.Size() - diskStoreFooterSize - descSize
+	if storeSize < 0 {
+		return nil, ErrBinaryCorrupted
+	}
+	descData := make([]byte, descSize)
+	if _, err = store.ReadAt(descData, storeSize); err != nil {
+		return nil, fmt.Errorf("failed to read from the merkle tree store '%s'\n%w", path, err)
+	}
+	dec, err := newBinaryDecoder(descData, binaryKindDiskStore)
+	if err != nil {
+		return nil, err
+	}
+	flags, err := dec.ReadByte()
+	if err != nil {
+		return nil, ErrBinaryCorrupted
+	}
+	hashAlgorithm, err := dec.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	numLeaves, err := dec.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	nodeSize, err := dec.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	root, err := dec.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	if err = dec.finish(); err != nil {
+		return nil, err
+	}
+	if (numLeaves == 0) != (nodeSize == 0) || numLeaves > uint64(storeSize) || nodeSize > uint64(storeSize) {
+		return nil, ErrBinaryCorrupted
+	}
+
+	t = &DiskMerkleTree{
+		store:     store,
+		nodeSize:  int(nodeSize),
+		Root:      root,
+		NumLeaves: int(numLeaves),
+	}
+	t.Mode = ModeTreeBuild
+	t.HashAlgorithm = string(hashAlgorithm)
+	t.setBinaryFlags(flags)
+	t.levelLengths = diskLevelLengths(t.NumLeaves, t.RFC6962)
+	t.Depth = treeDepth(t.NumLeaves)
+	var nodesSize int64
+	if t.levelOffsets, nodesSize = diskLevelOffsets(t.levelLengths, t.nodeSize); nodesSize != storeSize {
+		return nil, ErrBinaryCorrupted
+	}
+	if config != nil {
+		if config.HashAlgorithm != "" && config.HashAlgorithm != t.HashAlgorithm {
+			return nil, fmt.Errorf("%w: hash algorithm '%s' instead of '%s'", ErrLoadConfigMismatch, t.HashAlgorithm, config.HashAlgorithm)
+		}
+		t.HashFunc, t.hashFuncByName = config.HashFunc, config.hashFuncByName
+	}
+	if err = t.initHashFunc(); err != nil {
+		return nil, err
+	}
+	t.concatHashFunc = concatHashFuncFor(&t.Config)
+	if t.data, err = mapStore(store, storeSize); err != nil {
+		return nil, fmt.Errorf("failed to map the merkle tree store '%s'\n%w", path, err)
+	}
+	return t, nil
+}
+
+// Close releases the store of the tree.
+func (t *DiskMerkleTree) Close() error {
+	if t.store == nil {
+		return ErrDiskStoreClosed
+	}
+	err := unmapStore(t.data)
+	if closeErr := t.store.Close(); err == nil {
+		err = closeErr
+	}
+	t.data, t.store = nil, nil
+	return err
+}
+
+// readNodes returns the nodes of a level from the specified index, sliced out of the memory-mapped store
+// or read into the buffer.
+func (t *DiskMerkleTree) readNodes(level, idx, count int, buffer []byte) ([]byte, error) {
+	if t.store == nil {
+		return nil, ErrDiskStoreClosed
+	}
+	offset := t.levelOffsets[level] + int64(idx)*int64(t.nodeSize)
+	size := count * t.nodeSize
+	if t.data != nil {
+		return t.data[offset : offset+int64(size) : offset+int64(size)], nil
+	}
+	if _, err := t.store.ReadAt(buffer[:size], offset); err != nil {
+		return nil, err
+	}
+	return buffer[:size], nil
+}
+
+// readNode returns a copy of the node of a level at the specified index.
+func (t *DiskMerkleTree) readNode(level, idx int) ([]byte, error) {
+	node := make([]byte, t.nodeSize)
+	data, err := t.readNodes(level, idx, 1, node)
+	if err != nil {
+		return nil, err
+	}
+	copy(node, data)
+	return node, nil
+}
+
+// Leaf returns the leaf at the specified index.
+func (t *DiskMerkleTree) Leaf(idx int) ([]byte, error) {
+	if idx < 0 || idx >= t.NumLeaves {
+		return nil, ErrLeafIndexOutOfRange
+	}
+	return t.readNode(0, idx)
+}
+
+// ProofByIndex generates the Merkle proof of the leaf at the specified index, out of the store.
+func (t *DiskMerkleTree) ProofByIndex(idx int) (*Proof, error) {
+	if idx < 0 || idx >= t.NumLeaves {
+		return nil, ErrLeafIndexOutOfRange
+	}
+	envelope := newProofEnvelope(&t.Config, idx, t.NumLeaves)
+
+	// Compute the path and siblings for the proof.
+	// Promoted nodes in RFC 6962 mode have no sibling.
+	var (
+		path     uint32
+		siblings = make([][]byte, 0, t.Depth)
+	)
+	for i := 0; i < t.Depth; i++ {
+		siblingIdx := -1
+		if idx&1 == 1 {
+			siblingIdx = idx - 1
+		} else if idx+1 < t.levelLengths[i] {
+			siblingIdx = idx + 1
+		}
+		if siblingIdx >= 0 {
+			path = proofPathWithSibling(path, len(siblings), siblingIdx > idx)
+			sibling, err := t.readNode(i, siblingIdx)
+			if err != nil {
+				return nil, err
+			}
+			siblings = append(siblings, sibling)
+		}
+		idx >>= 1
+	}
+	return &Proof{
+		Path:     path,
+		Siblings: siblings,
+		Envelope: envelope,
+	}, nil
+}
+
+// Proof generates the Merkle proof of a data block, out of the store.
+// The leaf of the data block is searched among the stored leaves, which takes O(n) reads: ProofByIndex
+// is to be preferred when the index of the leaf is known.
+func (t *DiskMerkleTree) Proof(dataBlock IDataBlock) (*Proof, error) {
+	if dataBlock == nil {
+		return nil, ErrDataBlockIsNil
+	}
+	leaf, err := dataBlockToLeaf(dataBlock, &t.Config)
+	if err != nil {
+		return nil, err
+	}
+	idx, err := t.leafIndex(leaf)
+	if err != nil {
+		return nil, err
+	}
+	return t.ProofByIndex(idx)
+}
+
+// leafIndex searches the index of a leaf in the store, the last one if the leaf is duplicated
+// as for the proofs of a MerkleTree.
+func (t *DiskMerkleTree) leafIndex(leaf []byte) (int, error) {
+	if len(leaf) != t.nodeSize {
+		return -1, ErrProofInvalidDataBlock
+	}
+	var buffer []byte
+	if t.data == nil {
+		buffer = make([]byte, diskStoreScanLength*t.nodeSize)
+	}
+	for end := t.NumLeaves; end > 0; end -= diskStoreScanLength {
+		start := max(end-diskStoreScanLength, 0)
+		nodes, err := t.readNodes(0, start, end-start, buffer)
+		if err != nil {
+			return -1, err
+		}
+		for i := end - start - 1; i >= 0; i-- {
+			if bytes.Equal(nodes[i*t.nodeSize:(i+1)*t.nodeSize], leaf) {
+				return start + i, nil
+			}
+		}
+	}
+	return -1, ErrProofInvalidDataBlock
+}
+
+// Verify checks if the data block is valid using the Merkle Tree proof and the Merkle root hash of the tree.
+func (t *DiskMerkleTree) Verify(dataBlock IDataBlock, proof *Proof) (bool, error) {
+	if proof != nil && proof.Envelope != nil && proof.Envelope.TreeSize != uint64(t.NumLeaves) {
+		return false, ErrProofEnvelopeMismatch
+	}
+	return Verify(dataBlock, proof, t.Root, &t.Config)
+}
@@ -0,0 +1,16 @@
+//go:build !unix
+
+package merkletree
+
+import "os"
+
+// mapStore does not map the nodes of a disk store on the platforms not supporting it,
+// they are read from the store file instead.
+func mapStore(store *os.File, size int64) ([]byte, error) {
+	return nil, nil
+}
+
+// unmapStore is a no-op, the nodes of a disk store not being mapped.
+func unmapStore(data []byte) error {
+	return nil
+}
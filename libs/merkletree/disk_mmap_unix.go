@@ -0,0 +1,25 @@
+//go:build unix
+
+package merkletree
+
+import (
+	"math"
+	"os"
+	"syscall"
+)
+
+// mapStore maps the nodes of a disk store in memory, read-only.
+func mapStore(store *os.File, size int64) ([]byte, error) {
+	if size == 0 || size > math.MaxInt {
+		return nil, nil
+	}
+	return syscall.Mmap(int(store.Fd()), 0, int(size), syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+// unmapStore unmaps the nodes of a disk store.
+func unmapStore(data []byte) error {
+	if data == nil {
+		return nil
+	}
+	return syscall.Munmap(data)
+}
@@ -0,0 +1,362 @@
+package merkletree
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// sliceDataBlockIterator iterates over a slice of data blocks.
+type sliceDataBlockIterator struct {
+	blocks []IDataBlock
+	err    error
+}
+
+func (it *sliceDataBlockIterator) Next() (IDataBlock, error) {
+	if len(it.blocks) == 0 {
+		if it.err != nil {
+			return nil, it.err
+		}
+		return nil, io.EOF
+	}
+	block := it.blocks[0]
+	it.blocks = it.blocks[1:]
+	return block, nil
+}
+
+func TestNewDisk(t *testing.T) {
+	tests := []struct {
+		name         string
+		config       *Config
+		numBlocks    int
+		memoryBudget int
+	}{
+		{
+			name:      "test_2_blocks",
+			config:    nil,
+			numBlocks: 2,
+		},
+		{
+			name:         "test_odd_blocks_small_budget",
+			config:       &Config{},
+			numBlocks:    1025,
+			memoryBudget: 100,
+		},
+		{
+			name:         "test_odd_blocks_parallel",
+			config:       &Config{RunInParallel: true, NumRoutines: 4},
+			numBlocks:    999,
+			memoryBudget: 1000,
+		},
+		{
+			name:         "test_rfc6962",
+			config:       MerkleTreeRFC6962Config(false),
+			numBlocks:    777,
+			memoryBudget: 500,
+		},
+		{
+			name:      "test_default_config",
+			config:    MerkleTreeDefaultConfig(false),
+			numBlocks: 300,
+		},
+		{
+			name:         "test_evm",
+			config:       MerkleTreeEVMConfig(false),
+			numBlocks:    129,
+			memoryBudget: 64,
+		},
+		{
+			name:      "test_sorted_rfc6962_blake3",
+			config:    &Config{HashAlgorithm: hash.AlgoBLAKE3, SortSiblingPairs: true, RFC6962: true},
+			numBlocks: 33,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := generatedTestDataBlocks(tt.numBlocks)
+			if tt.config != nil && tt.config.DisableLeafHashing {
+				// The leaves are expected to be hashes, e.g. file hashes
+				for _, block := range blocks {
+					block.(*DataBlock).Data, _ = hash.DefaultHashFunc(block.(*DataBlock).Data)
+				}
+			}
+			var treeConfig Config
+			if tt.config != nil {
+				treeConfig = *tt.config
+			}
+			treeConfig.Mode = ModeTreeBuild
+			want, err := New(&treeConfig, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+
+			storeConfig := DiskStoreConfig{Path: filepath.Join(t.TempDir(), "tree.store"), MemoryBudget: tt.memoryBudget}
+			tree, err := NewDisk(context.Background(), tt.config, storeConfig, &sliceDataBlockIterator{blocks: blocks})
+			if err != nil {
+				t.Fatalf("NewDisk() error = %v", err)
+			}
+			defer tree.Close()
+			if !bytes.Equal(tree.Root, want.Root) || tree.Depth != want.Depth || tree.NumLeaves != want.NumLeaves {
+				t.Fatalf("NewDisk() root = %x, depth = %d, want %x, %d", tree.Root, tree.Depth, want.Root, want.Depth)
+			}
+
+			// The proofs are the ones of the tree built in memory
+			reopened, err := OpenDisk(tt.config, storeConfig.Path)
+			if err != nil {
+				t.Fatalf("OpenDisk() error = %v", err)
+			}
+			defer reopened.Close()
+			for _, diskTree := range []*DiskMerkleTree{tree, reopened} {
+				if !bytes.Equal(diskTree.Root, want.Root) {
+					t.Fatalf("OpenDisk() root = %x, want %x", diskTree.Root, want.Root)
+				}
+				for i, block := range blocks {
+					wantProof, err := want.Proof(block)
+					if err != nil {
+						t.Fatalf("Proof() error = %v", err)
+					}
+					proof, err := diskTree.ProofByIndex(i)
+					if err != nil {
+						t.Fatalf("ProofByIndex() error = %v", err)
+					}
+					if !reflect.DeepEqual(proof, wantProof) {
+						t.Fatalf("ProofByIndex() leaf #%d = %v, want %v", i, proof, wantProof)
+					}
+					if i%50 == 0 {
+						if proof, err = diskTree.Proof(block); err != nil || !reflect.DeepEqual(proof, wantProof) {
+							t.Fatalf("Proof() leaf #%d = %v, error = %v, want %v", i, proof, err, wantProof)
+						}
+						if leaf, err := diskTree.Leaf(i); err != nil || !bytes.Equal(leaf, want.Leaves[i]) {
+							t.Errorf("Leaf() #%d = %x, error = %v, want %x", i, leaf, err, want.Leaves[i])
+						}
+					}
+					if valid, err := diskTree.Verify(block, proof); err != nil || !valid {
+						t.Errorf("Verify() leaf #%d = %v, error = %v", i, valid, err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestNewDisk_singleLeafAndEmpty(t *testing.T) {
+	configs := map[string]*Config{
+		"default":              nil,
+		"rfc6962":              MerkleTreeRFC6962Config(false),
+		"disable_leaf_hashing": {DisableLeafHashing: true},
+	}
+	for name, config := range configs {
+		for _, numBlocks := range []int{0, 1} {
+			t.Run(fmt.Sprintf("%s_%d", name, numBlocks), func(t *testing.T) {
+				blocks := generatedTestDataBlocks(numBlocks)
+				for _, block := range blocks {
+					block.(*DataBlock).Data, _ = hash.DefaultHashFunc(block.(*DataBlock).Data)
+				}
+				var treeConfig Config
+				if config != nil {
+					treeConfig = *config
+				}
+				treeConfig.Mode = ModeTreeBuild
+				want, err := New(&treeConfig, blocks)
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+
+				storeConfig := DiskStoreConfig{Path: filepath.Join(t.TempDir(), "tree.store")}
+				tree, err := NewDisk(context.Background(), config, storeConfig, &sliceDataBlockIterator{blocks: blocks})
+				if err != nil {
+					t.Fatalf("NewDisk() error = %v", err)
+				}
+				defer tree.Close()
+				reopened, err := OpenDisk(config, storeConfig.Path)
+				if err != nil {
+					t.Fatalf("OpenDisk() error = %v", err)
+				}
+				defer reopened.Close()
+
+				// The root of no leaf is the empty root, the one of a single leaf is the leaf, with a proof of no sibling
+				for _, diskTree := range []*DiskMerkleTree{tree, reopened} {
+					if !bytes.Equal(diskTree.Root, want.Root) || diskTree.Depth != 0 || diskTree.NumLeaves != numBlocks {
+						t.Fatalf("NewDisk() root = %x, depth = %d, %d leaves, want %x, 0, %d", diskTree.Root, diskTree.Depth, diskTree.NumLeaves, want.Root, numBlocks)
+					}
+					if _, err := diskTree.ProofByIndex(numBlocks); !errors.Is(err, ErrLeafIndexOutOfRange) {
+						t.Errorf("ProofByIndex() error = %v, wantErr %v", err, ErrLeafIndexOutOfRange)
+					}
+					if numBlocks == 0 {
+						if emptyRoot, err := EmptyRoot(config); err != nil || !bytes.Equal(diskTree.Root, emptyRoot) {
+							t.Errorf("NewDisk() root = %x, want the empty root %x", diskTree.Root, emptyRoot)
+						}
+						continue
+					}
+					if leaf, err := diskTree.Leaf(0); err != nil || !bytes.Equal(leaf, diskTree.Root) {
+						t.Errorf("Leaf() = %x, error = %v, want %x", leaf, err, diskTree.Root)
+					}
+					proof, err := diskTree.Proof(blocks[0])
+					if err != nil {
+						t.Fatalf("Proof() error = %v", err)
+					}
+					if len(proof.Siblings) != 0 || proof.Path != 0 {
+						t.Errorf("Proof() = %v, want no sibling", proof)
+					}
+					if valid, err := diskTree.Verify(blocks[0], proof); err != nil || !valid {
+						t.Errorf("Verify() = %v, error = %v", valid, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestNewDiskFromChan(t *testing.T) {
+	blocks := generatedTestDataBlocks(100)
+	want, err := New(&Config{Mode: ModeTreeBuild}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	blockChan := make(chan IDataBlock)
+	go func() {
+		defer close(blockChan)
+		for _, block := range blocks {
+			blockChan <- block
+		}
+	}()
+	tree, err := NewDiskFromChan(context.Background(), nil, DiskStoreConfig{Path: filepath.Join(t.TempDir(), "tree.store")}, blockChan)
+	if err != nil {
+		t.Fatalf("NewDiskFromChan() error = %v", err)
+	}
+	if !bytes.Equal(tree.Root, want.Root) {
+		t.Errorf("NewDiskFromChan() root = %x, want %x", tree.Root, want.Root)
+	}
+	if err := tree.Close(); err != nil {
+		t.Errorf("Close() error = %v", err)
+	}
+	if _, err := tree.ProofByIndex(0); !errors.Is(err, ErrDiskStoreClosed) {
+		t.Errorf("ProofByIndex() error = %v, wantErr %v", err, ErrDiskStoreClosed)
+	}
+
+	// The building is aborted once the context is done, the store being removed
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	storePath := filepath.Join(t.TempDir(), "cancelled.store")
+	if _, err := NewDiskFromChan(ctx, nil, DiskStoreConfig{Path: storePath}, make(chan IDataBlock)); !errors.Is(err, context.Canceled) {
+		t.Errorf("NewDiskFromChan() error = %v, wantErr %v", err, context.Canceled)
+	}
+	if _, err := os.Stat(storePath); !os.IsNotExist(err) {
+		t.Errorf("NewDiskFromChan() store not removed, error = %v", err)
+	}
+}
+
+func TestNewDisk_errors(t *testing.T) {
+	dir := t.TempDir()
+	errNext := errors.New("test_next_err")
+	tests := []struct {
+		name    string
+		config  *Config
+		blocks  []IDataBlock
+		nextErr error
+		wantErr error
+	}{
+		{
+			name:    "test_nil_block",
+			blocks:  []IDataBlock{generatedTestDataBlocks(1)[0], nil},
+			wantErr: ErrDataBlockIsNil,
+		},
+		{
+			name:    "test_leaves_of_different_sizes",
+			config:  &Config{DisableLeafHashing: true},
+			blocks:  []IDataBlock{&DataBlock{Data: []byte("a")}, &DataBlock{Data: []byte("bc")}},
+			wantErr: ErrDiskNodeSize,
+		},
+		{
+			name:    "test_leaves_not_of_the_hash_size",
+			config:  &Config{DisableLeafHashing: true},
+			blocks:  []IDataBlock{&DataBlock{Data: []byte("a")}, &DataBlock{Data: []byte("b")}, &DataBlock{Data: []byte("c")}},
+			wantErr: ErrDiskNodeSize,
+		},
+		{
+			name:    "test_iterator_error",
+			blocks:  generatedTestDataBlocks(10),
+			nextErr: errNext,
+			wantErr: errNext,
+		},
+		{
+			name:    "test_unsupported_hash_algorithm",
+			config:  &Config{HashAlgorithm: "unknown"},
+			blocks:  generatedTestDataBlocks(2),
+			wantErr: hash.ErrUnsupportedAlgo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storePath := filepath.Join(dir, tt.name)
+			_, err := NewDisk(context.Background(), tt.config, DiskStoreConfig{Path: storePath},
+				&sliceDataBlockIterator{blocks: tt.blocks, err: tt.nextErr})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewDisk() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if _, err := os.Stat(storePath); !os.IsNotExist(err) {
+				t.Errorf("NewDisk() store not removed, error = %v", err)
+			}
+		})
+	}
+
+	// A builder can not be used once finished
+	b, err := NewDiskBuilder(nil, DiskStoreConfig{Path: filepath.Join(dir, "finished")})
+	if err != nil {
+		t.Fatalf("NewDiskBuilder() error = %v", err)
+	}
+	if err := b.Add(generatedTestDataBlocks(2)...); err != nil {
+		t.Fatalf("Add() error = %v", err)
+	}
+	tree, err := b.Finish()
+	if err != nil {
+		t.Fatalf("Finish() error = %v", err)
+	}
+	defer tree.Close()
+	if err := b.Add(generatedTestDataBlocks(1)...); !errors.Is(err, ErrDiskBuilderFinished) {
+		t.Errorf("Add() error = %v, wantErr %v", err, ErrDiskBuilderFinished)
+	}
+	if _, err := tree.ProofByIndex(2); !errors.Is(err, ErrLeafIndexOutOfRange) {
+		t.Errorf("ProofByIndex() error = %v, wantErr %v", err, ErrLeafIndexOutOfRange)
+	}
+	if _, err := tree.Proof(&DataBlock{Data: []byte("not a member")}); !errors.Is(err, ErrProofInvalidDataBlock) {
+		t.Errorf("Proof() error = %v, wantErr %v", err, ErrProofInvalidDataBlock)
+	}
+
+	// A truncated store can not be opened
+	data, err := os.ReadFile(filepath.Join(dir, "finished"))
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	truncatedPath := filepath.Join(dir, "truncated")
+	if err := os.WriteFile(truncatedPath, data[1:], 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if _, err := OpenDisk(nil, truncatedPath); !errors.Is(err, ErrBinaryCorrupted) {
+		t.Errorf("OpenDisk() error = %v, wantErr %v", err, ErrBinaryCorrupted)
+	}
+	if _, err := OpenDisk(&Config{HashAlgorithm: hash.AlgoBLAKE3}, filepath.Join(dir, "finished")); !errors.Is(err, ErrLoadConfigMismatch) {
+		t.Errorf("OpenDisk() error = %v, wantErr %v", err, ErrLoadConfigMismatch)
+	}
+}
+
+func BenchmarkNewDisk(b *testing.B) {
+	blocks := generatedTestDataBlocks(benchSize)
+	storeConfig := DiskStoreConfig{Path: filepath.Join(b.TempDir(), "tree.store"), MemoryBudget: 1 << 20}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tree, err := NewDisk(context.Background(), &Config{RunInParallel: true}, storeConfig, &sliceDataBlockIterator{blocks: blocks})
+		if err != nil {
+			b.Fatalf("NewDisk() error = %v", err)
+		}
+		tree.Close()
+	}
+}
@@ -0,0 +1,574 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Binary encoding of the proofs and trees.
+//
+// Every encoded value starts with the encoding format version and the kind of the encoded value,
+// followed by its fields. Integers are unsigned varints, and lists of byte slices are encoded as their
+// length, then the common size of their items plus one, or 0 if their sizes differ, in which case each
+// item is prefixed by its own size. Hashes of a same list sharing their size, they are stored back to back.
+//
+// The proofs are followed by their envelope since version 2, the encodings of version 1 remaining readable.
+const (
+	// binaryEncodingVersion is the current version of the binary encoding format.
+	binaryEncodingVersion byte = 2
+	// binaryEncodingVersionEnvelope is the first version of the binary encoding format with proof envelopes.
+	binaryEncodingVersionEnvelope byte = 2
+
+	// binaryKindProof is the kind of an encoded Proof.
+	binaryKindProof byte = 'p'
+	// binaryKindProofSet is the kind of an encoded ProofSet.
+	binaryKindProofSet byte = 's'
+	// binaryKindTree is the kind of an encoded MerkleTree.
+	binaryKindTree byte = 't'
+)
+
+// Flags of the tree configuration in its binary encoding.
+const (
+	binaryFlagSortSiblingPairs byte = 1 << iota
+	binaryFlagDisableLeafHashing
+	binaryFlagRFC6962
+)
+
+var (
+	// ErrBinaryUnsupportedVersion is the error for a binary encoding format version not supported.
+	ErrBinaryUnsupportedVersion = errors.New("unsupported binary encoding version")
+	// ErrBinaryInvalidKind is the error for binary encoded data not being of the expected kind.
+	ErrBinaryInvalidKind = errors.New("binary encoded data is not of the expected kind")
+	// ErrBinaryCorrupted is the error for truncated or malformed binary encoded data.
+	ErrBinaryCorrupted = errors.New("binary encoded data is corrupted")
+	// ErrLoadConfigMismatch is the error for a loaded tree not matching the provided configuration.
+	ErrLoadConfigMismatch = errors.New("loaded merkle tree does not match the configuration")
+)
+
+// ProofSet is the set of the proofs of all the leaves of a Merkle Tree, in the leaves order.
+type ProofSet []*Proof
+
+// MarshalBinary encodes the proof in the compact binary format.
+func (p *Proof) MarshalBinary() ([]byte, error) {
+	buf := newBinaryEncoder(binaryKindProof)
+	buf.writeProof(p)
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a proof encoded in the binary format.
+func (p *Proof) UnmarshalBinary(data []byte) error {
+	dec, err := newBinaryDecoder(data, binaryKindProof)
+	if err != nil {
+		return err
+	}
+	proof, err := dec.readProof()
+	if err != nil {
+		return err
+	}
+	*p = *proof
+	return dec.finish()
+}
+
+// MarshalBinary encodes the proof set in the compact binary format.
+func (s ProofSet) MarshalBinary() ([]byte, error) {
+	buf := newBinaryEncoder(binaryKindProofSet)
+	buf.writeUvarint(uint64(len(s)))
+	for _, proof := range s {
+		if proof == nil {
+			return nil, ErrProofIsNil
+		}
+		buf.writeProof(proof)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a proof set encoded in the binary format.
+func (s *ProofSet) UnmarshalBinary(data []byte) error {
+	dec, err := newBinaryDecoder(data, binaryKindProofSet)
+	if err != nil {
+		return err
+	}
+	numProofs, err := dec.readLength()
+	if err != nil {
+		return err
+	}
+	proofs := make(ProofSet, numProofs)
+	for i := range proofs {
+		if proofs[i], err = dec.readProof(); err != nil {
+			return err
+		}
+	}
+	*s = proofs
+	return dec.finish()
+}
+
+// MarshalBinary encodes the Merkle Tree in the compact binary format: its configuration, root and
+// all its node levels when built, or its leaves and proofs in ModeProofGen.
+// The hash function is identified by the configured HashAlgorithm, a custom HashFunc is not encoded.
+func (m *MerkleTree) MarshalBinary() ([]byte, error) {
+	buf := newBinaryEncoder(binaryKindTree)
+	buf.WriteByte(m.binaryFlags())
+	buf.writeUvarint(uint64(m.Mode))
+	buf.writeBytes([]byte(m.HashAlgorithm))
+	buf.writeUvarint(uint64(m.NumLeaves))
+	buf.writeBytes(m.Root)
+
+	// The leaves are the first level of the built tree nodes, the levels including their duplicated
+	// and promoted nodes
+	buf.writeUvarint(uint64(len(m.nodes)))
+	for i := range m.nodes {
+		buf.writeSlices(m.levelNodes(i))
+	}
+	if len(m.nodes) > 0 {
+		return buf.Bytes(), nil
+	}
+	buf.writeSlices(m.Leaves)
+	buf.writeUvarint(uint64(len(m.Proofs)))
+	for _, proof := range m.Proofs {
+		buf.writeProof(proof)
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary decodes a Merkle Tree encoded in the binary format, its proofs being generated again
+// out of the tree nodes in ModeProofGenAndTreeBuild.
+// The hash function is initialized from the decoded HashAlgorithm, unless a HashFunc is already set.
+// The parallelization settings of the tree are kept.
+func (m *MerkleTree) UnmarshalBinary(data []byte) (err error) {
+	dec, err := newBinaryDecoder(data, binaryKindTree)
+	if err != nil {
+		return err
+	}
+	flags, err := dec.ReadByte()
+	if err != nil {
+		return ErrBinaryCorrupted
+	}
+	mode, err := dec.readUvarint()
+	if err != nil {
+		return err
+	}
+	hashAlgorithm, err := dec.readBytes()
+	if err != nil {
+		return err
+	}
+	numLeaves, err := dec.readLength()
+	if err != nil {
+		return err
+	}
+	root, err := dec.readBytes()
+	if err != nil {
+		return err
+	}
+	numLevels, err := dec.readLength()
+	if err != nil {
+		return err
+	}
+	nodes := make([][][]byte, numLevels)
+	for i := range nodes {
+		if nodes[i], err = dec.readSlices(); err != nil {
+			return err
+		}
+	}
+	var (
+		leaves [][]byte
+		proofs []*Proof
+	)
+	if numLevels > 0 {
+		if len(nodes[0]) < numLeaves {
+			return ErrBinaryCorrupted
+		}
+		leaves = nodes[0][:numLeaves:numLeaves]
+	} else {
+		if leaves, err = dec.readSlices(); err != nil {
+			return err
+		}
+		numProofs, err := dec.readLength()
+		if err != nil {
+			return err
+		}
+		proofs = make([]*Proof, numProofs)
+		for i := range proofs {
+			if proofs[i], err = dec.readProof(); err != nil {
+				return err
+			}
+		}
+	}
+	if err = dec.finish(); err != nil {
+		return err
+	}
+	if len(leaves) != numLeaves || (numLevels > 0 && numLevels != treeDepth(numLeaves)) {
+		return ErrBinaryCorrupted
+	}
+	if TypeConfigMode(mode) > ModeProofGenAndTreeBuild {
+		return ErrInvalidConfigMode
+	}
+
+	m.Mode = TypeConfigMode(mode)
+	m.HashAlgorithm = string(hashAlgorithm)
+	m.setBinaryFlags(flags)
+	m.newDigest, m.nodeWidth = nil, 0
+	if m.HashFunc == nil {
+		if err = m.initNodeDigest(); err != nil {
+			return err
+		}
+	}
+	if err = m.initHashFunc(); err != nil {
+		return err
+	}
+	m.concatHashFunc = concatHashFuncFor(&m.Config)
+	m.NumLeaves = numLeaves
+	m.Depth = treeDepth(numLeaves)
+	m.Root = root
+	m.Leaves = leaves
+	m.nodes = nil
+	m.Proofs = proofs
+	m.leafMap = nil
+	if m.Depth == 0 {
+		// A tree of a single leaf, or of none, has no nodes above the leaves
+		if err = m.buildLeafTree(); err != nil {
+			return err
+		}
+		if !bytes.Equal(m.Root, root) {
+			return ErrBinaryCorrupted
+		}
+		return nil
+	}
+	if m.Mode != ModeTreeBuild && m.Mode != ModeProofGenAndTreeBuild {
+		return nil
+	}
+	if numLevels == 0 {
+		return ErrBinaryCorrupted
+	}
+
+	// The length of each level is set by the one of the level below, the last node of an odd-length level
+	// being duplicated, or promoted in RFC 6962 mode
+	levelLength := numLeaves
+	for _, level := range nodes {
+		if levelLength&1 == 1 && !m.RFC6962 {
+			levelLength++
+		}
+		if len(level) != levelLength {
+			return ErrBinaryCorrupted
+		}
+		levelLength = (levelLength + 1) >> 1
+	}
+
+	if err = m.setNodes(nodes); err != nil {
+		return err
+	}
+	m.leafMap = make(map[string]int, numLeaves)
+	for i, leaf := range leaves {
+		m.leafMap[string(leaf)] = i
+	}
+	if m.Mode == ModeProofGenAndTreeBuild {
+		if m.RunInParallel {
+			var releasePool func()
+			m.wp, releasePool = acquireWorkerPool(&m.Config)
+			defer releasePool()
+		}
+		return m.generateProofsFromNodes()
+	}
+	return nil
+}
+
+// setNodes stores the decoded levels of nodes above the leaves into flat buffers of fixed-width nodes,
+// without their duplicated and promoted nodes.
+func (m *MerkleTree) setNodes(levels [][][]byte) error {
+	if m.nodeWidth == 0 && len(levels) > 1 {
+		m.nodeWidth = len(levels[1][0])
+	}
+	m.nodes = make([][]byte, len(levels))
+	for level := 1; level < len(levels); level++ {
+		length := m.hashedLevelLength(level)
+		buffer := make([]byte, 0, length*m.nodeWidth)
+		for _, node := range levels[level][:length] {
+			if len(node) != m.nodeWidth || m.nodeWidth == 0 {
+				m.nodes = nil
+				return ErrBinaryCorrupted
+			}
+			buffer = append(buffer, node...)
+		}
+		m.nodes[level] = buffer
+	}
+	return nil
+}
+
+// Load reconstructs a Merkle Tree from its binary encoding, as produced by MarshalBinary, without
+// recomputing its nodes: a tree persisted in ModeTreeBuild takes O(n) nodes to store and serves
+// proofs for any of its leaves once loaded.
+//
+// The tree configuration is the encoded one, except for the hash function, set from the provided
+// configuration if any, and the parallelization settings.
+func Load(config *Config, data []byte) (*MerkleTree, error) {
+	m := new(MerkleTree)
+	if config != nil {
+		m.HashFunc, m.hashFuncByName = config.HashFunc, config.hashFuncByName
+		m.RunInParallel = config.RunInParallel
+		m.NumRoutines = config.NumRoutines
+		m.WorkerPool = config.WorkerPool
+	}
+	if err := m.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	if config != nil && config.HashAlgorithm != "" && config.HashAlgorithm != m.HashAlgorithm {
+		return nil, fmt.Errorf("%w: hash algorithm '%s' instead of '%s'", ErrLoadConfigMismatch, m.HashAlgorithm, config.HashAlgorithm)
+	}
+
+	// Check that the encoded root is the one of the top level nodes
+	if len(m.nodes) > 0 {
+		root, err := m.newNodeHasher().hashPair(nil, m.node(m.Depth-1, 0), m.node(m.Depth-1, 1))
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.Equal(root, m.Root) {
+			return nil, ErrBinaryCorrupted
+		}
+	}
+	return m, nil
+}
+
+// binaryFlags returns the flags of the configuration in its binary encoding.
+func (c *Config) binaryFlags() (flags byte) {
+	if c.SortSiblingPairs {
+		flags |= binaryFlagSortSiblingPairs
+	}
+	if c.DisableLeafHashing {
+		flags |= binaryFlagDisableLeafHashing
+	}
+	if c.RFC6962 {
+		flags |= binaryFlagRFC6962
+	}
+	return flags
+}
+
+// setBinaryFlags sets the configuration out of its flags in the binary encoding.
+func (c *Config) setBinaryFlags(flags byte) {
+	c.SortSiblingPairs = flags&binaryFlagSortSiblingPairs != 0
+	c.DisableLeafHashing = flags&binaryFlagDisableLeafHashing != 0
+	c.RFC6962 = flags&binaryFlagRFC6962 != 0
+}
+
+// binaryEncoder is the buffer of a value being encoded in the binary format.
+type binaryEncoder struct {
+	bytes.Buffer
+}
+
+// newBinaryEncoder creates an encoder whose buffer starts with the encoding version and the kind of value.
+func newBinaryEncoder(kind byte) *binaryEncoder {
+	buf := new(binaryEncoder)
+	buf.WriteByte(binaryEncodingVersion)
+	buf.WriteByte(kind)
+	return buf
+}
+
+// writeUvarint writes an unsigned varint.
+func (b *binaryEncoder) writeUvarint(v uint64) {
+	b.Write(binary.AppendUvarint(nil, v))
+}
+
+// writeBytes writes a byte slice prefixed by its size.
+func (b *binaryEncoder) writeBytes(data []byte) {
+	b.writeUvarint(uint64(len(data)))
+	b.Write(data)
+}
+
+// writeSlices writes a list of byte slices, back to back if they share the same size.
+func (b *binaryEncoder) writeSlices(slices [][]byte) {
+	b.writeUvarint(uint64(len(slices)))
+	if len(slices) == 0 {
+		return
+	}
+	size := len(slices[0])
+	for _, s := range slices[1:] {
+		if len(s) != size {
+			size = -1
+			break
+		}
+	}
+	b.writeUvarint(uint64(size + 1))
+	for _, s := range slices {
+		if size < 0 {
+			b.writeUvarint(uint64(len(s)))
+		}
+		b.Write(s)
+	}
+}
+
+// writeProof writes the path, the siblings and the envelope of a proof, whose version is 0 if it has none.
+func (b *binaryEncoder) writeProof(p *Proof) {
+	b.writeUvarint(uint64(p.Path))
+	b.writeSlices(p.Siblings)
+	if p.Envelope == nil {
+		b.writeUvarint(0)
+		return
+	}
+	b.writeUvarint(uint64(p.Envelope.Version))
+	b.writeUvarint(p.Envelope.LeafIndex)
+	b.writeUvarint(p.Envelope.TreeSize)
+	b.writeBytes([]byte(p.Envelope.HashAlgorithm))
+	b.writeUvarint(uint64(p.Envelope.Flags))
+}
+
+// binaryDecoder is the reader of a value encoded in the binary format.
+type binaryDecoder struct {
+	*bytes.Reader
+	// version is the encoding format version of the value.
+	version byte
+}
+
+// newBinaryDecoder creates a decoder after checking the encoding version and the kind of the encoded value.
+func newBinaryDecoder(data []byte, kind byte) (*binaryDecoder, error) {
+	if len(data) < 2 {
+		return nil, ErrBinaryCorrupted
+	}
+	if data[0] == 0 || data[0] > binaryEncodingVersion {
+		return nil, fmt.Errorf("%w: %d", ErrBinaryUnsupportedVersion, data[0])
+	}
+	if data[1] != kind {
+		return nil, fmt.Errorf("%w: '%c' instead of '%c'", ErrBinaryInvalidKind, data[1], kind)
+	}
+	return &binaryDecoder{Reader: bytes.NewReader(data[2:]), version: data[0]}, nil
+}
+
+// readUvarint reads an unsigned varint.
+func (d *binaryDecoder) readUvarint() (uint64, error) {
+	v, err := binary.ReadUvarint(d)
+	if err != nil {
+		return 0, ErrBinaryCorrupted
+	}
+	return v, nil
+}
+
+// readLength reads a number of items or bytes, which can not exceed the remaining encoded data.
+func (d *binaryDecoder) readLength() (int, error) {
+	v, err := d.readUvarint()
+	if err != nil {
+		return 0, err
+	}
+	if v > uint64(d.Len()) {
+		return 0, ErrBinaryCorrupted
+	}
+	return int(v), nil
+}
+
+// readRaw reads the specified number of bytes.
+func (d *binaryDecoder) readRaw(size int) ([]byte, error) {
+	if size > d.Len() {
+		return nil, ErrBinaryCorrupted
+	}
+	data := make([]byte, size)
+	if _, err := d.Read(data); err != nil && size > 0 {
+		return nil, ErrBinaryCorrupted
+	}
+	return data, nil
+}
+
+// readBytes reads a byte slice prefixed by its size.
+func (d *binaryDecoder) readBytes() ([]byte, error) {
+	size, err := d.readLength()
+	if err != nil {
+		return nil, err
+	}
+	return d.readRaw(size)
+}
+
+// readSlices reads a list of byte slices.
+func (d *binaryDecoder) readSlices() ([][]byte, error) {
+	count, err := d.readLength()
+	if err != nil || count == 0 {
+		return nil, err
+	}
+	size, err := d.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	slices := make([][]byte, count)
+	if size > 0 {
+		// Items of the same size are sliced out of a single allocation.
+		if size-1 > uint64(d.Len()) || (size-1)*uint64(count) > uint64(d.Len()) {
+			return nil, ErrBinaryCorrupted
+		}
+		data, err := d.readRaw(int(size-1) * count)
+		if err != nil {
+			return nil, err
+		}
+		for i := range slices {
+			slices[i] = data[i*int(size-1) : (i+1)*int(size-1) : (i+1)*int(size-1)]
+		}
+		return slices, nil
+	}
+	for i := range slices {
+		if slices[i], err = d.readBytes(); err != nil {
+			return nil, err
+		}
+	}
+	return slices, nil
+}
+
+// readProof reads the path, the siblings and the envelope of a proof.
+func (d *binaryDecoder) readProof() (*Proof, error) {
+	path, err := d.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if path > uint64(^uint32(0)) {
+		return nil, ErrBinaryCorrupted
+	}
+	siblings, err := d.readSlices()
+	if err != nil {
+		return nil, err
+	}
+	if siblings == nil {
+		siblings = [][]byte{}
+	}
+	proof := &Proof{Siblings: siblings, Path: uint32(path)}
+	if d.version >= binaryEncodingVersionEnvelope {
+		if proof.Envelope, err = d.readProofEnvelope(); err != nil {
+			return nil, err
+		}
+	}
+	return proof, nil
+}
+
+// readProofEnvelope reads the envelope of a proof, nil if its version is 0.
+func (d *binaryDecoder) readProofEnvelope() (*ProofEnvelope, error) {
+	version, err := d.readUvarint()
+	if err != nil || version == 0 {
+		return nil, err
+	}
+	var envelope ProofEnvelope
+	if version > uint64(^uint32(0)) {
+		return nil, ErrBinaryCorrupted
+	}
+	envelope.Version = uint32(version)
+	if envelope.LeafIndex, err = d.readUvarint(); err != nil {
+		return nil, err
+	}
+	if envelope.TreeSize, err = d.readUvarint(); err != nil {
+		return nil, err
+	}
+	hashAlgorithm, err := d.readBytes()
+	if err != nil {
+		return nil, err
+	}
+	envelope.HashAlgorithm = string(hashAlgorithm)
+	flags, err := d.readUvarint()
+	if err != nil {
+		return nil, err
+	}
+	if flags > uint64(^uint32(0)) {
+		return nil, ErrBinaryCorrupted
+	}
+	envelope.Flags = uint32(flags)
+	return &envelope, nil
+}
+
+// finish checks that all the encoded data has been read.
+func (d *binaryDecoder) finish() error {
+	if d.Len() != 0 {
+		return ErrBinaryCorrupted
+	}
+	return nil
+}
@@ -0,0 +1,281 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+func TestProof_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name  string
+		proof *Proof
+	}{
+		{
+			name:  "test_no_sibling",
+			proof: &Proof{Siblings: [][]byte{}, Path: 0},
+		},
+		{
+			name:  "test_siblings",
+			proof: &Proof{Siblings: [][]byte{[]byte("0123456789abcdef0123456789abcdef"), []byte("fedcba9876543210fedcba9876543210")}, Path: 2},
+		},
+		{
+			name:  "test_siblings_of_different_sizes",
+			proof: &Proof{Siblings: [][]byte{[]byte("short"), []byte("a longer sibling")}, Path: 1<<32 - 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.proof.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+			got := new(Proof)
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.proof) {
+				t.Errorf("UnmarshalBinary() = %v, want %v", got, tt.proof)
+			}
+		})
+	}
+}
+
+func TestProofSet_MarshalBinary(t *testing.T) {
+	m, err := New(&Config{Mode: ModeProofGen}, generatedTestDataBlocks(1000))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	data, err := ProofSet(m.Proofs).MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	var got ProofSet
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if !reflect.DeepEqual([]*Proof(got), m.Proofs) {
+		t.Errorf("UnmarshalBinary() proofs differ from the generated ones")
+	}
+
+	// The binary encoding is at least a third smaller than the JSON one
+	jsonData, err := json.Marshal(m.Proofs)
+	if err != nil {
+		t.Fatalf("json.Marshal() error = %v", err)
+	}
+	if 3*len(data) > 2*len(jsonData) {
+		t.Errorf("MarshalBinary() size = %d, JSON size = %d", len(data), len(jsonData))
+	}
+}
+
+func TestMerkleTree_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+		blocks int
+	}{
+		{
+			name:   "test_mode_proof_gen",
+			config: &Config{Mode: ModeProofGen},
+			blocks: 7,
+		},
+		{
+			name:   "test_mode_tree_build",
+			config: &Config{Mode: ModeTreeBuild},
+			blocks: 100,
+		},
+		{
+			name:   "test_mode_proof_gen_and_tree_build",
+			config: &Config{Mode: ModeProofGenAndTreeBuild},
+			blocks: 33,
+		},
+		{
+			name:   "test_mode_proof_gen_and_tree_build_parallel",
+			config: &Config{Mode: ModeProofGenAndTreeBuild, RunInParallel: true, NumRoutines: 4},
+			blocks: 129,
+		},
+		{
+			name:   "test_default_config",
+			config: MerkleTreeDefaultConfig(true),
+			blocks: 10,
+		},
+		{
+			name:   "test_rfc6962",
+			config: MerkleTreeRFC6962Config(true),
+			blocks: 11,
+		},
+		{
+			name:   "test_sort_sibling_pairs_blake3",
+			config: &Config{Mode: ModeProofGenAndTreeBuild, SortSiblingPairs: true, HashAlgorithm: hash.AlgoBLAKE3},
+			blocks: 5,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := generatedTestDataBlocks(tt.blocks)
+			m, err := New(tt.config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			data, err := m.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+			got := new(MerkleTree)
+			if err := got.UnmarshalBinary(data); err != nil {
+				t.Fatalf("UnmarshalBinary() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got.Root, m.Root) || !reflect.DeepEqual(got.Leaves, m.Leaves) ||
+				!reflect.DeepEqual(got.nodes, m.nodes) || !reflect.DeepEqual(got.Proofs, m.Proofs) {
+				t.Fatalf("UnmarshalBinary() tree differs from the encoded one")
+			}
+			if got.Depth != m.Depth || got.NumLeaves != m.NumLeaves || got.Mode != m.Mode || got.HashAlgorithm != m.HashAlgorithm ||
+				got.SortSiblingPairs != m.SortSiblingPairs || got.DisableLeafHashing != m.DisableLeafHashing || got.RFC6962 != m.RFC6962 {
+				t.Fatalf("UnmarshalBinary() config differs from the encoded one")
+			}
+
+			// The decoded tree is fully functional
+			for i, block := range blocks {
+				var proof *Proof
+				if got.Mode == ModeProofGen {
+					proof = got.Proofs[i]
+				} else if proof, err = got.Proof(block); err != nil {
+					t.Fatalf("Proof() error = %v", err)
+				}
+				if valid, err := got.Verify(block, proof); err != nil || !valid {
+					t.Errorf("Verify() block #%d = %v, error = %v", i, valid, err)
+				}
+			}
+			if got.Mode != ModeProofGen {
+				if err := got.Update(0, &DataBlock{Data: []byte("updated")}); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				if err := m.Update(0, &DataBlock{Data: []byte("updated")}); err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				if !reflect.DeepEqual(got.Root, m.Root) {
+					t.Errorf("Update() root = %x, want %x", got.Root, m.Root)
+				}
+			}
+		})
+	}
+}
+
+func TestMerkleTree_UnmarshalBinary_errors(t *testing.T) {
+	m, err := New(&Config{Mode: ModeTreeBuild}, generatedTestDataBlocks(5))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	data, _ := m.MarshalBinary()
+	proofData, _ := (&Proof{Siblings: [][]byte{[]byte("sibling")}}).MarshalBinary()
+
+	unsupportedVersion := append([]byte{}, data...)
+	unsupportedVersion[0] = binaryEncodingVersion + 1
+	unsupportedAlgo := new(MerkleTree)
+	unsupportedAlgo.HashAlgorithm = "unknown"
+	unsupportedAlgo.Mode = ModeTreeBuild
+	unsupportedAlgo.nodes, unsupportedAlgo.nodeWidth = m.nodes, m.nodeWidth
+	unsupportedAlgo.NumLeaves, unsupportedAlgo.Leaves = m.NumLeaves, m.Leaves
+	unsupportedAlgoData, _ := unsupportedAlgo.MarshalBinary()
+
+	tests := []struct {
+		name    string
+		data    []byte
+		wantErr error
+	}{
+		{
+			name:    "test_empty",
+			data:    nil,
+			wantErr: ErrBinaryCorrupted,
+		},
+		{
+			name:    "test_unsupported_version",
+			data:    unsupportedVersion,
+			wantErr: ErrBinaryUnsupportedVersion,
+		},
+		{
+			name:    "test_invalid_kind",
+			data:    proofData,
+			wantErr: ErrBinaryInvalidKind,
+		},
+		{
+			name:    "test_truncated",
+			data:    data[:len(data)-1],
+			wantErr: ErrBinaryCorrupted,
+		},
+		{
+			name:    "test_trailing_data",
+			data:    append(append([]byte{}, data...), 0),
+			wantErr: ErrBinaryCorrupted,
+		},
+		{
+			name:    "test_json",
+			data:    []byte(`[{"Siblings":null,"Path":0}]`),
+			wantErr: ErrBinaryUnsupportedVersion,
+		},
+		{
+			name:    "test_unsupported_hash_algorithm",
+			data:    unsupportedAlgoData,
+			wantErr: hash.ErrUnsupportedAlgo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := new(MerkleTree).UnmarshalBinary(tt.data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UnmarshalBinary() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	const numLeaves = 1000
+	blocks := generatedTestDataBlocks(numLeaves)
+	m, err := New(MerkleTreeDefaultConfig(false), blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+
+	// The tree nodes take O(n) storage, unlike the proofs of all its leaves
+	proofs, err := New(MerkleTreeDefaultConfig(true), blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	proofsData, _ := ProofSet(proofs.Proofs).MarshalBinary()
+	if maxSize := 2 * (numLeaves + 1) * 100; len(data) > maxSize || len(data) > len(proofsData) {
+		t.Errorf("MarshalBinary() size = %d, want at most %d and less than the proofs size %d", len(data), maxSize, len(proofsData))
+	}
+
+	loaded, err := Load(&Config{RunInParallel: true, HashAlgorithm: hash.DefaultAlgo}, data)
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	for i, block := range blocks {
+		proof, err := loaded.Proof(block)
+		if err != nil {
+			t.Fatalf("Proof() error = %v", err)
+		}
+		if !reflect.DeepEqual(proof, proofs.Proofs[i]) {
+			t.Errorf("Proof() block #%d = %v, want %v", i, proof, proofs.Proofs[i])
+		}
+	}
+
+	// Loading with another hash algorithm, or a root not matching the nodes, fails
+	if _, err := Load(&Config{HashAlgorithm: hash.AlgoBLAKE3}, data); !errors.Is(err, ErrLoadConfigMismatch) {
+		t.Errorf("Load() error = %v, wantErr %v", err, ErrLoadConfigMismatch)
+	}
+	m.Root = append([]byte{}, m.Root...)
+	m.Root[0] ^= 0xff
+	tampered, _ := m.MarshalBinary()
+	if _, err := Load(nil, tampered); !errors.Is(err, ErrBinaryCorrupted) {
+		t.Errorf("Load() error = %v, wantErr %v", err, ErrBinaryCorrupted)
+	}
+}
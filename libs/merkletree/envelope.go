@@ -0,0 +1,128 @@
+package merkletree
+
+import (
+	"errors"
+	"math"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// ProofEnvelopeVersion is the current version of the proof envelopes.
+const ProofEnvelopeVersion uint32 = 1
+
+// Flags of the tree configuration in the proof envelopes, the same as in the binary encoding of the trees.
+const (
+	// ProofFlagSortSiblingPairs is set for the trees whose sibling pairs are sorted before being hashed.
+	ProofFlagSortSiblingPairs = uint32(binaryFlagSortSiblingPairs)
+	// ProofFlagDisableLeafHashing is set for the trees whose leaves are the data blocks as is.
+	ProofFlagDisableLeafHashing = uint32(binaryFlagDisableLeafHashing)
+	// ProofFlagRFC6962 is set for the trees following the RFC 6962 Merkle Tree Hash definition.
+	ProofFlagRFC6962 = uint32(binaryFlagRFC6962)
+)
+
+var (
+	// ErrProofEnvelopeUnsupportedVersion is the error for a proof envelope version not supported.
+	ErrProofEnvelopeUnsupportedVersion = errors.New("unsupported proof envelope version")
+	// ErrProofEnvelopeMismatch is the error for a proof envelope not matching the verification parameters:
+	// the hash algorithm, the configuration flags or the tree size.
+	ErrProofEnvelopeMismatch = errors.New("proof envelope does not match the verification parameters")
+	// ErrProofEnvelopeInvalid is the error for a proof whose siblings do not match the leaf index and
+	// the tree size of its envelope.
+	ErrProofEnvelopeInvalid = errors.New("proof siblings do not match the leaf index and tree size of the envelope")
+)
+
+// ProofEnvelope describes the Merkle Tree a proof has been generated for, for verifiers to refuse
+// the proofs of trees of other parameters.
+//
+// The sides of the siblings are derived from the leaf index, the Path of the proof being ignored:
+// the envelope supports trees deeper than the 32 levels of the Path.
+type ProofEnvelope struct {
+	// Version is the version of the envelope, ProofEnvelopeVersion.
+	Version uint32
+	// LeafIndex is the index of the proven leaf.
+	LeafIndex uint64
+	// TreeSize is the number of leaves of the tree.
+	TreeSize uint64
+	// HashAlgorithm is the name of the hash algorithm of the tree, see the hash package registry.
+	HashAlgorithm string
+	// Flags are the flags of the tree configuration, e.g. ProofFlagRFC6962.
+	Flags uint32
+}
+
+// hashAlgorithmName returns the name of the hash algorithm of the configuration, the default one if none.
+// It is empty for a custom hash function provided without name, which can not be told apart from others.
+func (c *Config) hashAlgorithmName() string {
+	if c.HashAlgorithm != "" {
+		return c.HashAlgorithm
+	}
+	if c.HashFunc != nil && !c.hashFuncByName {
+		return ""
+	}
+	return hash.DefaultAlgo
+}
+
+// newProofEnvelopes creates the envelopes of the proofs of all the leaves of a tree, in a single allocation.
+// No envelopes are created for a custom hash function without name.
+func newProofEnvelopes(config *Config, treeSize int) []ProofEnvelope {
+	envelope := newProofEnvelope(config, 0, treeSize)
+	if envelope == nil {
+		return nil
+	}
+	envelopes := make([]ProofEnvelope, treeSize)
+	for i := range envelopes {
+		envelopes[i] = *envelope
+		envelopes[i].LeafIndex = uint64(i)
+	}
+	return envelopes
+}
+
+// newProofEnvelope creates the envelope of the proof of a leaf, nil for a custom hash function without name.
+func newProofEnvelope(config *Config, leafIndex, treeSize int) *ProofEnvelope {
+	hashAlgorithm := config.hashAlgorithmName()
+	if hashAlgorithm == "" {
+		return nil
+	}
+	return &ProofEnvelope{
+		Version:       ProofEnvelopeVersion,
+		LeafIndex:     uint64(leafIndex),
+		TreeSize:      uint64(treeSize),
+		HashAlgorithm: hashAlgorithm,
+		Flags:         uint32(config.binaryFlags()),
+	}
+}
+
+// path checks the envelope against the verification configuration and the number of siblings of the proof,
+// and returns the path of the proof derived from the leaf index: its bit i is set if the i-th sibling
+// is on the right. The hash algorithm of the envelope is not checked against a custom hash function without name.
+func (e *ProofEnvelope) path(config *Config, numSiblings int) (uint64, error) {
+	if e.Version != ProofEnvelopeVersion {
+		return 0, ErrProofEnvelopeUnsupportedVersion
+	}
+	if hashAlgorithm := config.hashAlgorithmName(); (hashAlgorithm != "" && e.HashAlgorithm != hashAlgorithm) || e.Flags != uint32(config.binaryFlags()) {
+		return 0, ErrProofEnvelopeMismatch
+	}
+	if e.LeafIndex >= e.TreeSize || e.TreeSize > math.MaxInt {
+		return 0, ErrProofEnvelopeInvalid
+	}
+
+	// Promoted nodes in RFC 6962 mode have no sibling.
+	var (
+		idx       = int(e.LeafIndex)
+		treeSize  = int(e.TreeSize)
+		path      uint64
+		nSiblings int
+	)
+	for level := 0; level < treeDepth(treeSize); level++ {
+		if idx&1 == 1 {
+			nSiblings++
+		} else if !config.RFC6962 || idx+1 < levelLength(treeSize, level) {
+			path |= 1 << nSiblings
+			nSiblings++
+		}
+		idx >>= 1
+	}
+	if nSiblings != numSiblings {
+		return 0, ErrProofEnvelopeInvalid
+	}
+	return path, nil
+}
@@ -0,0 +1,330 @@
+package merkletree
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+func TestProofEnvelope(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		wantAlgo  string
+		wantFlags uint32
+	}{
+		{
+			name:     "test_default",
+			config:   &Config{Mode: ModeProofGenAndTreeBuild},
+			wantAlgo: hash.DefaultAlgo,
+		},
+		{
+			name:      "test_rfc6962_sorted",
+			config:    &Config{Mode: ModeTreeBuild, RFC6962: true, SortSiblingPairs: true},
+			wantAlgo:  hash.DefaultAlgo,
+			wantFlags: ProofFlagRFC6962 | ProofFlagSortSiblingPairs,
+		},
+		{
+			name:      "test_disable_leaf_hashing_blake3",
+			config:    &Config{Mode: ModeProofGen, DisableLeafHashing: true, HashAlgorithm: hash.AlgoBLAKE3},
+			wantAlgo:  hash.AlgoBLAKE3,
+			wantFlags: ProofFlagDisableLeafHashing,
+		},
+		{
+			name:      "test_rfc6962_proof_gen",
+			config:    &Config{Mode: ModeProofGen, RFC6962: true},
+			wantAlgo:  hash.DefaultAlgo,
+			wantFlags: ProofFlagRFC6962,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := generatedTestDataBlocks(13)
+			m, err := New(tt.config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			for i, block := range blocks {
+				proof, err := m.ProofByIndex(i)
+				if err != nil {
+					t.Fatalf("ProofByIndex() error = %v", err)
+				}
+				want := &ProofEnvelope{
+					Version:       ProofEnvelopeVersion,
+					LeafIndex:     uint64(i),
+					TreeSize:      uint64(len(blocks)),
+					HashAlgorithm: tt.wantAlgo,
+					Flags:         tt.wantFlags,
+				}
+				if !reflect.DeepEqual(proof.Envelope, want) {
+					t.Fatalf("ProofByIndex() envelope = %+v, want %+v", proof.Envelope, want)
+				}
+				if ok, err := m.Verify(block, proof); err != nil || !ok {
+					t.Errorf("Verify() leaf %d got = %v, error = %v", i, ok, err)
+				}
+
+				// The path is derived from the leaf index of the envelope
+				proof.Path = ^proof.Path
+				if ok, err := m.Verify(block, proof); err != nil || !ok {
+					t.Errorf("Verify() leaf %d with a wrong path got = %v, error = %v", i, ok, err)
+				}
+
+				// A proof without envelope, as of former versions, is verified by its path
+				proof.Path = ^proof.Path
+				proof.Envelope = nil
+				if ok, err := m.Verify(block, proof); err != nil || !ok {
+					t.Errorf("Verify() leaf %d without envelope got = %v, error = %v", i, ok, err)
+				}
+			}
+		})
+	}
+}
+
+func TestProofEnvelope_customHashFunc(t *testing.T) {
+	blake3HashFunc, err := hash.HashFuncByName(hash.AlgoBLAKE3)
+	if err != nil {
+		t.Fatalf("HashFuncByName() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		config   *Config
+		wantAlgo string
+	}{
+		{
+			name:   "test_unnamed_proof_gen",
+			config: &Config{Mode: ModeProofGen, HashFunc: blake3HashFunc},
+		},
+		{
+			name:   "test_unnamed_tree_build",
+			config: &Config{Mode: ModeTreeBuild, HashFunc: blake3HashFunc},
+		},
+		{
+			name:     "test_named_proof_gen",
+			config:   &Config{Mode: ModeProofGen, HashFunc: blake3HashFunc, HashAlgorithm: hash.AlgoBLAKE3},
+			wantAlgo: hash.AlgoBLAKE3,
+		},
+		{
+			name:     "test_named_tree_build",
+			config:   &Config{Mode: ModeTreeBuild, HashFunc: blake3HashFunc, HashAlgorithm: hash.AlgoBLAKE3},
+			wantAlgo: hash.AlgoBLAKE3,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := generatedTestDataBlocks(5)
+			m, err := New(tt.config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			storeConfig := DiskStoreConfig{Path: filepath.Join(t.TempDir(), "tree.store")}
+			diskTree, err := NewDisk(context.Background(), tt.config, storeConfig, &sliceDataBlockIterator{blocks: blocks})
+			if err != nil {
+				t.Fatalf("NewDisk() error = %v", err)
+			}
+			defer diskTree.Close()
+
+			// The proofs of an unnamed custom hash function have no envelope, rather than one of another algorithm
+			trees := []interface {
+				ProofByIndex(idx int) (*Proof, error)
+				Verify(dataBlock IDataBlock, proof *Proof) (bool, error)
+			}{m, diskTree}
+			for _, tree := range trees {
+				for i, block := range blocks {
+					proof, err := tree.ProofByIndex(i)
+					if err != nil {
+						t.Fatalf("ProofByIndex() error = %v", err)
+					}
+					if tt.wantAlgo == "" && proof.Envelope != nil {
+						t.Errorf("ProofByIndex() leaf %d envelope = %+v, want none", i, proof.Envelope)
+					}
+					if tt.wantAlgo != "" && (proof.Envelope == nil || proof.Envelope.HashAlgorithm != tt.wantAlgo) {
+						t.Errorf("ProofByIndex() leaf %d envelope = %+v, want hash algorithm '%v'", i, proof.Envelope, tt.wantAlgo)
+					}
+					if ok, err := tree.Verify(block, proof); err != nil || !ok {
+						t.Errorf("Verify() leaf %d got = %v, error = %v", i, ok, err)
+					}
+				}
+			}
+			if tt.config.Mode == ModeProofGen && (m.Proofs[0].Envelope == nil) != (tt.wantAlgo == "") {
+				t.Errorf("New() proof envelope = %+v, want hash algorithm '%v'", m.Proofs[0].Envelope, tt.wantAlgo)
+			}
+		})
+	}
+
+	// The hash algorithm of an envelope is not checked against an unnamed custom hash function
+	blocks := generatedTestDataBlocks(5)
+	m, err := New(nil, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if ok, err := Verify(blocks[1], m.Proofs[1], m.Root, &Config{HashFunc: hash.DefaultHashFunc}); err != nil || !ok {
+		t.Errorf("Verify() got = %v, error = %v", ok, err)
+	}
+}
+
+func TestProofPathWithSibling(t *testing.T) {
+	tests := []struct {
+		name        string
+		numSiblings int
+		right       func(i int) bool
+		want        uint32
+	}{
+		{
+			name:        "test_32_right_siblings",
+			numSiblings: 32,
+			right:       func(int) bool { return true },
+			want:        0xffffffff,
+		},
+		{
+			name:        "test_32_alternate_siblings",
+			numSiblings: 32,
+			right:       func(i int) bool { return i&1 == 0 },
+			want:        0x55555555,
+		},
+		{
+			name:        "test_33_right_siblings",
+			numSiblings: 33,
+			right:       func(int) bool { return true },
+		},
+		{
+			name:        "test_40_siblings_left_beyond_32",
+			numSiblings: 40,
+			right:       func(i int) bool { return i < 32 },
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The sides of the siblings of deeper proofs do not fit in the Path, it is left unset
+			var path uint32
+			for i := 0; i < tt.numSiblings; i++ {
+				path = proofPathWithSibling(path, i, tt.right(i))
+			}
+			if path != tt.want {
+				t.Errorf("proofPathWithSibling() = %#x, want %#x", path, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify_proofEnvelopeMismatch(t *testing.T) {
+	blocks := generatedTestDataBlocks(13)
+	config := &Config{Mode: ModeTreeBuild, RFC6962: true}
+	m, err := New(config, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		config  *Config
+		tamper  func(*ProofEnvelope)
+		want    bool
+		wantErr error
+	}{
+		{
+			name:    "test_unsupported_version",
+			tamper:  func(e *ProofEnvelope) { e.Version++ },
+			wantErr: ErrProofEnvelopeUnsupportedVersion,
+		},
+		{
+			name:    "test_other_hash_algorithm",
+			tamper:  func(e *ProofEnvelope) { e.HashAlgorithm = hash.AlgoSHA3_256 },
+			wantErr: ErrProofEnvelopeMismatch,
+		},
+		{
+			name:    "test_other_flags",
+			tamper:  func(e *ProofEnvelope) { e.Flags |= ProofFlagSortSiblingPairs },
+			wantErr: ErrProofEnvelopeMismatch,
+		},
+		{
+			name:    "test_other_config",
+			config:  &Config{RFC6962: true, HashAlgorithm: hash.AlgoBLAKE3},
+			wantErr: ErrProofEnvelopeMismatch,
+		},
+		{
+			name:    "test_leaf_index_out_of_range",
+			tamper:  func(e *ProofEnvelope) { e.LeafIndex = e.TreeSize },
+			wantErr: ErrProofEnvelopeInvalid,
+		},
+		{
+			name:    "test_other_number_of_siblings",
+			tamper:  func(e *ProofEnvelope) { e.TreeSize = 1000 },
+			wantErr: ErrProofEnvelopeInvalid,
+		},
+		{
+			name:   "test_other_leaf_index",
+			tamper: func(e *ProofEnvelope) { e.LeafIndex = 6 },
+			want:   false,
+		},
+		{
+			name: "test_valid",
+			want: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proof, err := m.ProofByIndex(4)
+			if err != nil {
+				t.Fatalf("ProofByIndex() error = %v", err)
+			}
+			if tt.tamper != nil {
+				tt.tamper(proof.Envelope)
+			}
+			verifyConfig := tt.config
+			if verifyConfig == nil {
+				verifyConfig = &Config{RFC6962: true}
+			}
+			got, err := Verify(blocks[4], proof, m.Root, verifyConfig)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Verify() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("Verify() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// The tree size of the envelope must be the one of the tree
+	other, err := New(config, blocks[:12])
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	proof, err := other.ProofByIndex(4)
+	if err != nil {
+		t.Fatalf("ProofByIndex() error = %v", err)
+	}
+	if _, err := m.Verify(blocks[4], proof); !errors.Is(err, ErrProofEnvelopeMismatch) {
+		t.Errorf("Verify() error = %v, wantErr %v", err, ErrProofEnvelopeMismatch)
+	}
+}
+
+func TestProof_UnmarshalBinary_version1(t *testing.T) {
+	blocks := generatedTestDataBlocks(9)
+	m, err := New(nil, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	// A proof encoded in version 1 has no envelope, the last byte encoding the absence of envelope
+	legacy := &Proof{Siblings: m.Proofs[3].Siblings, Path: m.Proofs[3].Path}
+	data, err := legacy.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	data = data[:len(data)-1]
+	data[0] = 1
+
+	var got Proof
+	if err := got.UnmarshalBinary(data); err != nil {
+		t.Fatalf("UnmarshalBinary() error = %v", err)
+	}
+	if !reflect.DeepEqual(&got, legacy) {
+		t.Errorf("UnmarshalBinary() got = %v, want %v", got, legacy)
+	}
+	if ok, err := m.Verify(blocks[3], &got); err != nil || !ok {
+		t.Errorf("Verify() got = %v, error = %v", ok, err)
+	}
+}
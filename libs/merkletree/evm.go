@@ -0,0 +1,299 @@
+package merkletree
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+var (
+	// ErrEVMIncompatibleConfig is the error for a Merkle Tree whose configuration is not compatible with
+	// OpenZeppelin's MerkleProof library: Keccak-256 hashing of sorted sibling pairs, without RFC 6962.
+	ErrEVMIncompatibleConfig = errors.New("merkle tree config is not EVM compatible, Keccak-256 and sorted sibling pairs are required")
+	// ErrEVMInvalidNodeSize is the error for a tree node that is not a 32 bytes word.
+	ErrEVMInvalidNodeSize = errors.New("merkle tree node is not a 32 bytes word")
+	// ErrEVMInvalidBytes32 is the error for a bytes32 value that is not a 0x prefixed hex string of 32 bytes.
+	ErrEVMInvalidBytes32 = errors.New("invalid bytes32 hex string")
+	// ErrEVMInvalidMultiProof is the error for a multiproof OpenZeppelin's multiProofVerify would revert with.
+	ErrEVMInvalidMultiProof = errors.New("invalid EVM multiproof")
+)
+
+// Bytes32 is a 32 bytes word, as the Solidity bytes32 type.
+// It is encoded as a 0x prefixed hex string in JSON, as expected by the EVM tooling.
+type Bytes32 [32]byte
+
+// String returns the 0x prefixed hex string of the word.
+func (b Bytes32) String() string {
+	return "0x" + hex.EncodeToString(b[:])
+}
+
+// MarshalText encodes the word as a 0x prefixed hex string.
+func (b Bytes32) MarshalText() ([]byte, error) {
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText decodes the word from a 0x prefixed hex string.
+func (b *Bytes32) UnmarshalText(text []byte) error {
+	s, ok := strings.CutPrefix(string(text), "0x")
+	if !ok || hex.DecodedLen(len(s)) != len(b) {
+		return ErrEVMInvalidBytes32
+	}
+	if _, err := hex.Decode(b[:], []byte(s)); err != nil {
+		return ErrEVMInvalidBytes32
+	}
+	return nil
+}
+
+// toBytes32 converts a tree node to a 32 bytes word.
+func toBytes32(node []byte) (b Bytes32, err error) {
+	if len(node) != len(b) {
+		return b, ErrEVMInvalidNodeSize
+	}
+	copy(b[:], node)
+	return b, nil
+}
+
+// toBytes32List converts tree nodes to 32 bytes words.
+func toBytes32List(nodes [][]byte) ([]Bytes32, error) {
+	list := make([]Bytes32, len(nodes))
+	for i, node := range nodes {
+		b, err := toBytes32(node)
+		if err != nil {
+			return nil, err
+		}
+		list[i] = b
+	}
+	return list, nil
+}
+
+// EVMProof is a Merkle proof in the format of the arguments of OpenZeppelin's
+// `MerkleProof.verify(bytes32[] proof, bytes32 root, bytes32 leaf)`.
+type EVMProof struct {
+	Proof []Bytes32 `json:"proof"`
+	Root  Bytes32   `json:"root"`
+	Leaf  Bytes32   `json:"leaf"`
+}
+
+// EVMMultiProof is a Merkle multiproof in the format of the arguments of OpenZeppelin's
+// `MerkleProof.multiProofVerify(bytes32[] proof, bool[] proofFlags, bytes32 root, bytes32[] leaves)`.
+// The leaves are ordered by their index in the tree.
+type EVMMultiProof struct {
+	Proof      []Bytes32 `json:"proof"`
+	ProofFlags []bool    `json:"proofFlags"`
+	Root       Bytes32   `json:"root"`
+	Leaves     []Bytes32 `json:"leaves"`
+}
+
+// checkEVMCompatible checks that the tree proofs are verifiable by OpenZeppelin's MerkleProof library.
+func (m *MerkleTree) checkEVMCompatible() error {
+	if m.HashAlgorithm != hash.AlgoKeccak256 || !m.SortSiblingPairs || m.RFC6962 {
+		return ErrEVMIncompatibleConfig
+	}
+	return nil
+}
+
+// EVMProof exports the proof of the leaf at the specified index in the format of OpenZeppelin's MerkleProof.verify.
+// The tree must be generated with an EVM compatible configuration, e.g. MerkleTreeEVMConfig.
+func (m *MerkleTree) EVMProof(idx int) (*EVMProof, error) {
+	if err := m.checkEVMCompatible(); err != nil {
+		return nil, err
+	}
+	if idx < 0 || idx >= m.NumLeaves {
+		return nil, ErrLeafIndexOutOfRange
+	}
+
+	// Use the generated proof, or the siblings of the built tree.
+	var siblings [][]byte
+	if m.Proofs != nil {
+		siblings = m.Proofs[idx].Siblings
+	} else if m.nodes != nil {
+		siblings = make([][]byte, 0, m.Depth)
+		for i, nodeIdx := 0, idx; i < m.Depth; i, nodeIdx = i+1, nodeIdx>>1 {
+			siblings = append(siblings, m.node(i, nodeIdx^1))
+		}
+	} else {
+		return nil, ErrProofInvalidModeTreeNotBuilt
+	}
+
+	var (
+		proof = new(EVMProof)
+		err   error
+	)
+	if proof.Proof, err = toBytes32List(siblings); err != nil {
+		return nil, err
+	}
+	if proof.Root, err = toBytes32(m.Root); err != nil {
+		return nil, err
+	}
+	if proof.Leaf, err = toBytes32(m.Leaves[idx]); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// EVMMultiProof exports the multiproof of the leaves at the specified indices in the format of
+// OpenZeppelin's MerkleProof.multiProofVerify.
+// The tree must be generated with an EVM compatible configuration, e.g. MerkleTreeEVMConfig.
+// This method is only available when the configuration mode is ModeTreeBuild or ModeProofGenAndTreeBuild.
+func (m *MerkleTree) EVMMultiProof(indices []int) (*EVMMultiProof, error) {
+	if err := m.checkEVMCompatible(); err != nil {
+		return nil, err
+	}
+	if m.Mode != ModeTreeBuild && m.Mode != ModeProofGenAndTreeBuild {
+		return nil, ErrProofInvalidModeTreeNotBuilt
+	}
+	if len(indices) == 0 {
+		return nil, ErrMultiProofNoIndex
+	}
+	for _, idx := range indices {
+		if idx < 0 || idx >= m.NumLeaves {
+			return nil, ErrMultiProofIndexOutOfRange
+		}
+	}
+
+	// Sorted and deduplicated indices of the known nodes at the current level.
+	known := make([]int, len(indices))
+	copy(known, indices)
+	sort.Ints(known)
+	known = dedupSortedInts(known)
+
+	leaves := make([][]byte, len(known))
+	for i, idx := range known {
+		leaves[i] = m.Leaves[idx]
+	}
+
+	// The known nodes are hashed level by level, in the order of a FIFO queue as multiProofVerify does:
+	// a known node is hashed either with the next known node if it is its sibling, or with a proof node.
+	var (
+		siblings = make([][]byte, 0)
+		flags    = make([]bool, 0, 2*len(known))
+	)
+	for level := 0; level < m.Depth; level++ {
+		next := make([]int, 0, len(known))
+		for i := 0; i < len(known); i++ {
+			idx := known[i]
+			if i+1 < len(known) && known[i+1] == idx^1 {
+				flags = append(flags, true)
+				i++
+			} else {
+				flags = append(flags, false)
+				siblings = append(siblings, m.node(level, idx^1))
+			}
+			next = append(next, idx>>1)
+		}
+		known = next
+	}
+
+	var (
+		proof = &EVMMultiProof{ProofFlags: flags}
+		err   error
+	)
+	if proof.Proof, err = toBytes32List(siblings); err != nil {
+		return nil, err
+	}
+	if proof.Root, err = toBytes32(m.Root); err != nil {
+		return nil, err
+	}
+	if proof.Leaves, err = toBytes32List(leaves); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// evmHashPair hashes a pair of words with Keccak-256 once sorted, as OpenZeppelin's MerkleProof._hashPair.
+func evmHashPair(hashFunc TypeHashFunc, a, b Bytes32) (Bytes32, error) {
+	if bytes.Compare(a[:], b[:]) > 0 {
+		a, b = b, a
+	}
+	h, err := hashFunc(concatHash(nil, a[:], b[:]))
+	if err != nil {
+		return Bytes32{}, err
+	}
+	return toBytes32(h)
+}
+
+// VerifyEVM is the reference implementation of OpenZeppelin's `MerkleProof.verify`: it returns true if
+// the leaf is proven to be part of the tree of the specified root.
+func VerifyEVM(proof []Bytes32, root Bytes32, leaf Bytes32) (bool, error) {
+	hashFunc, err := hash.HashFuncByName(hash.AlgoKeccak256)
+	if err != nil {
+		return false, err
+	}
+	computed := leaf
+	for _, node := range proof {
+		if computed, err = evmHashPair(hashFunc, computed, node); err != nil {
+			return false, err
+		}
+	}
+	return computed == root, nil
+}
+
+// VerifyEVMMulti is the reference implementation of OpenZeppelin's `MerkleProof.multiProofVerify`: it returns true
+// if the leaves are proven to be part of the tree of the specified root.
+// The ErrEVMInvalidMultiProof error is returned where the contract reverts.
+func VerifyEVMMulti(proof []Bytes32, proofFlags []bool, root Bytes32, leaves []Bytes32) (bool, error) {
+	hashFunc, err := hash.HashFuncByName(hash.AlgoKeccak256)
+	if err != nil {
+		return false, err
+	}
+	var (
+		leavesLen   = len(leaves)
+		proofLen    = len(proof)
+		totalHashes = len(proofFlags)
+	)
+	if leavesLen+proofLen != totalHashes+1 {
+		return false, ErrEVMInvalidMultiProof
+	}
+
+	// The hashes are consumed as a queue, after the leaves. The hashes array is zero initialized,
+	// as a Solidity memory array.
+	var (
+		hashes                     = make([]Bytes32, totalHashes)
+		leafPos, hashPos, proofPos int
+	)
+	next := func() (Bytes32, bool) {
+		if leafPos < leavesLen {
+			leafPos++
+			return leaves[leafPos-1], true
+		}
+		if hashPos >= totalHashes {
+			return Bytes32{}, false
+		}
+		hashPos++
+		return hashes[hashPos-1], true
+	}
+	for i := 0; i < totalHashes; i++ {
+		a, ok := next()
+		b := a
+		if proofFlags[i] {
+			b, ok = next()
+		} else if ok = proofPos < proofLen; ok {
+			b = proof[proofPos]
+			proofPos++
+		}
+		if !ok {
+			return false, ErrEVMInvalidMultiProof
+		}
+		if hashes[i], err = evmHashPair(hashFunc, a, b); err != nil {
+			return false, err
+		}
+	}
+
+	var computed Bytes32
+	switch {
+	case totalHashes > 0:
+		if proofPos != proofLen {
+			return false, ErrEVMInvalidMultiProof
+		}
+		computed = hashes[totalHashes-1]
+	case leavesLen > 0:
+		computed = leaves[0]
+	default:
+		computed = proof[0]
+	}
+	return computed == root, nil
+}
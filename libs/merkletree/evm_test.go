@@ -0,0 +1,197 @@
+package merkletree
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// evmTestVector is a known EVM Merkle Tree vector, computed independently from this package
+// following the semantics of OpenZeppelin's MerkleProof library.
+type evmTestVector struct {
+	Name        string      `json:"name"`
+	FileHashes  []Bytes32   `json:"fileHashes"`
+	Leaves      []Bytes32   `json:"leaves"`
+	Root        Bytes32     `json:"root"`
+	Proofs      [][]Bytes32 `json:"proofs"`
+	MultiProofs []struct {
+		Indices []int `json:"indices"`
+		EVMMultiProof
+	} `json:"multiProofs"`
+}
+
+// loadEVMTestVectors loads the known EVM Merkle Tree vectors.
+func loadEVMTestVectors(t *testing.T) []evmTestVector {
+	t.Helper()
+	data, err := os.ReadFile("testdata/evm/vectors.json")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var vectors []evmTestVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	return vectors
+}
+
+func TestMerkleTree_EVMProof(t *testing.T) {
+	for _, tt := range loadEVMTestVectors(t) {
+		for _, proofsGen := range []bool{false, true} {
+			t.Run(tt.Name, func(t *testing.T) {
+				blocks := make([]IDataBlock, len(tt.FileHashes))
+				for i := range tt.FileHashes {
+					blocks[i] = &DataBlock{Data: tt.FileHashes[i][:]}
+				}
+				m, err := New(MerkleTreeEVMConfig(proofsGen), blocks)
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				if root, _ := toBytes32(m.Root); root != tt.Root {
+					t.Fatalf("New() root = %s, want %s", root, tt.Root)
+				}
+
+				for i := range blocks {
+					proof, err := m.EVMProof(i)
+					if err != nil {
+						t.Fatalf("EVMProof() error = %v", err)
+					}
+					want := &EVMProof{Proof: tt.Proofs[i], Root: tt.Root, Leaf: tt.Leaves[i]}
+					if !reflect.DeepEqual(proof, want) {
+						t.Errorf("EVMProof() leaf #%d = %v, want %v", i, proof, want)
+					}
+					if valid, err := VerifyEVM(proof.Proof, proof.Root, proof.Leaf); err != nil || !valid {
+						t.Errorf("VerifyEVM() leaf #%d = %v, error = %v", i, valid, err)
+					}
+					if valid, _ := VerifyEVM(proof.Proof, proof.Root, tt.Leaves[(i+1)%len(tt.Leaves)]); valid {
+						t.Errorf("VerifyEVM() leaf #%d verified with another leaf", i)
+					}
+				}
+
+				for _, mp := range tt.MultiProofs {
+					proof, err := m.EVMMultiProof(mp.Indices)
+					if err != nil {
+						t.Fatalf("EVMMultiProof() error = %v", err)
+					}
+					if !reflect.DeepEqual(*proof, mp.EVMMultiProof) {
+						t.Errorf("EVMMultiProof() indices %v = %v, want %v", mp.Indices, proof, mp.EVMMultiProof)
+					}
+					if valid, err := VerifyEVMMulti(proof.Proof, proof.ProofFlags, proof.Root, proof.Leaves); err != nil || !valid {
+						t.Errorf("VerifyEVMMulti() indices %v = %v, error = %v", mp.Indices, valid, err)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestVerifyEVMMulti(t *testing.T) {
+	blocks := generatedTestDataBlocks(11)
+	m, err := New(MerkleTreeEVMConfig(false), blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	proof, err := m.EVMMultiProof([]int{9, 1, 2, 10, 1})
+	if err != nil {
+		t.Fatalf("EVMMultiProof() error = %v", err)
+	}
+	if len(proof.Leaves) != 4 {
+		t.Fatalf("EVMMultiProof() leaves = %d, want 4 deduplicated leaves", len(proof.Leaves))
+	}
+
+	tampered := append([]Bytes32{}, proof.Leaves...)
+	tampered[0][0] ^= 0xff
+	tests := []struct {
+		name       string
+		proof      []Bytes32
+		proofFlags []bool
+		leaves     []Bytes32
+		want       bool
+		wantErr    error
+	}{
+		{
+			name:       "test_valid",
+			proof:      proof.Proof,
+			proofFlags: proof.ProofFlags,
+			leaves:     proof.Leaves,
+			want:       true,
+		},
+		{
+			name:       "test_tampered_leaf",
+			proof:      proof.Proof,
+			proofFlags: proof.ProofFlags,
+			leaves:     tampered,
+			want:       false,
+		},
+		{
+			name:       "test_missing_leaf",
+			proof:      proof.Proof,
+			proofFlags: proof.ProofFlags,
+			leaves:     proof.Leaves[1:],
+			wantErr:    ErrEVMInvalidMultiProof,
+		},
+		{
+			name:       "test_unused_proof_node",
+			proof:      append(append([]Bytes32{}, proof.Proof...), proof.Root),
+			proofFlags: append(append([]bool{}, proof.ProofFlags...), true),
+			leaves:     proof.Leaves,
+			wantErr:    ErrEVMInvalidMultiProof,
+		},
+		{
+			name:       "test_single_leaf_as_root",
+			proofFlags: []bool{},
+			leaves:     []Bytes32{proof.Root},
+			want:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyEVMMulti(tt.proof, tt.proofFlags, proof.Root, tt.leaves)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyEVMMulti() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyEVMMulti() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMerkleTree_EVMProof_errors(t *testing.T) {
+	blocks := generatedTestDataBlocks(5)
+	notEVM, err := New(&Config{Mode: ModeTreeBuild, HashAlgorithm: hash.AlgoKeccak256}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := notEVM.EVMProof(0); !errors.Is(err, ErrEVMIncompatibleConfig) {
+		t.Errorf("EVMProof() error = %v, wantErr %v", err, ErrEVMIncompatibleConfig)
+	}
+	rfc := MerkleTreeEVMConfig(false)
+	rfc.RFC6962 = true
+	if m, err := New(rfc, blocks); err != nil {
+		t.Fatalf("New() error = %v", err)
+	} else if _, err := m.EVMMultiProof([]int{0}); !errors.Is(err, ErrEVMIncompatibleConfig) {
+		t.Errorf("EVMMultiProof() error = %v, wantErr %v", err, ErrEVMIncompatibleConfig)
+	}
+
+	m, err := New(MerkleTreeEVMConfig(false), blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if _, err := m.EVMProof(5); !errors.Is(err, ErrLeafIndexOutOfRange) {
+		t.Errorf("EVMProof() error = %v, wantErr %v", err, ErrLeafIndexOutOfRange)
+	}
+	if _, err := m.EVMMultiProof(nil); !errors.Is(err, ErrMultiProofNoIndex) {
+		t.Errorf("EVMMultiProof() error = %v, wantErr %v", err, ErrMultiProofNoIndex)
+	}
+
+	var b Bytes32
+	for _, text := range []string{`"0x12"`, `"097d924cb5f5731f0d85f5677c19e8f7ea87e62eebdc9166ad48a3572e142b42"`, `"0xzz7d924cb5f5731f0d85f5677c19e8f7ea87e62eebdc9166ad48a3572e142b42"`} {
+		if err := json.Unmarshal([]byte(text), &b); !errors.Is(err, ErrEVMInvalidBytes32) {
+			t.Errorf("json.Unmarshal() %s error = %v, wantErr %v", text, err, ErrEVMInvalidBytes32)
+		}
+	}
+}
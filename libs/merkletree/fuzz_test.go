@@ -0,0 +1,185 @@
+package merkletree
+
+import (
+	"bytes"
+	"testing"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// fuzzHashAlgorithms are the hash algorithms picked by the fuzz targets.
+var fuzzHashAlgorithms = []string{hash.AlgoSHA256, hash.AlgoKeccak256, hash.AlgoBLAKE3, hash.AlgoSHA3_256}
+
+// fuzzMaxNumBlocks is the maximum number of data blocks of the fuzzed trees.
+const fuzzMaxNumBlocks = 70
+
+// fuzzTree derives a tree configuration out of the fuzzed flags: the 3 configuration flags, the hash algorithm,
+// the mode and the parallel run, and splits the fuzzed data into data blocks, possibly empty or duplicated.
+func fuzzTree(data []byte, numBlocks, flags uint8) (goldenTestConfig, *Config, [][]byte) {
+	golden := goldenTestConfig{
+		HashAlgorithm:      fuzzHashAlgorithms[flags>>3&3],
+		SortSiblingPairs:   flags&1 != 0,
+		DisableLeafHashing: flags&2 != 0,
+		RFC6962:            flags&4 != 0,
+	}
+	mode := []TypeConfigMode{ModeProofGen, ModeTreeBuild, ModeProofGenAndTreeBuild, ModeTreeBuild}[flags>>5&3]
+	config := golden.config(mode, flags&0x80 != 0)
+
+	n := int(numBlocks) % (fuzzMaxNumBlocks + 1)
+	blocks := make([][]byte, n)
+	for i := range blocks {
+		blocks[i] = data[i*len(data)/n : (i+1)*len(data)/n]
+	}
+	return golden, config, blocks
+}
+
+func fuzzDataBlocks(blocks [][]byte) []IDataBlock {
+	dataBlocks := make([]IDataBlock, len(blocks))
+	for i, block := range blocks {
+		dataBlocks[i] = &DataBlock{Data: block}
+	}
+	return dataBlocks
+}
+
+func addFuzzSeeds(f *testing.F) {
+	for flags := 0; flags < 256; flags += 7 {
+		f.Add([]byte("vrfs-fuzz-seed-data-blocks-of-several-sizes-0123456789"), uint8(flags%(fuzzMaxNumBlocks+1)), uint8(flags), uint16(flags))
+	}
+	f.Add([]byte{}, uint8(0), uint8(0), uint16(0))
+	f.Add([]byte{0x01}, uint8(1), uint8(0x07), uint16(0))
+	f.Add(bytes.Repeat([]byte{0xab}, 64), uint8(13), uint8(0xff), uint16(12))
+}
+
+func FuzzNew(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte, numBlocks, flags uint8, _ uint16) {
+		golden, config, blocks := fuzzTree(data, numBlocks, flags)
+		ref, err := newReferenceTree(golden)
+		if err != nil {
+			t.Fatalf("newReferenceTree() error = %v", err)
+		}
+		want, _ := ref.rootAndProofs(blocks)
+
+		m, err := New(config, fuzzDataBlocks(blocks))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if !bytes.Equal(m.Root, want) {
+			t.Fatalf("New() root = %x, want %x", m.Root, want)
+		}
+		if m.NumLeaves != len(blocks) || m.Depth != treeDepth(len(blocks)) {
+			t.Fatalf("New() leaves = %d, depth = %d, want %d, %d", m.NumLeaves, m.Depth, len(blocks), treeDepth(len(blocks)))
+		}
+
+		// The streaming builder computes the same root
+		b, err := NewBuilder(config)
+		if err != nil {
+			t.Fatalf("NewBuilder() error = %v", err)
+		}
+		if err = b.Add(fuzzDataBlocks(blocks)...); err != nil {
+			t.Fatalf("Add() error = %v", err)
+		}
+		if root, err := b.Finish(); err != nil || !bytes.Equal(root, want) {
+			t.Fatalf("Finish() root = %x, error = %v, want %x", root, err, want)
+		}
+	})
+}
+
+func FuzzProof(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte, numBlocks, flags uint8, index uint16) {
+		golden, config, blocks := fuzzTree(data, numBlocks, flags)
+		if len(blocks) == 0 {
+			return
+		}
+		ref, err := newReferenceTree(golden)
+		if err != nil {
+			t.Fatalf("newReferenceTree() error = %v", err)
+		}
+		root, proofs := ref.rootAndProofs(blocks)
+
+		m, err := New(config, fuzzDataBlocks(blocks))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		idx := int(index) % len(blocks)
+		proof, err := m.ProofByIndex(idx)
+		if err != nil {
+			t.Fatalf("ProofByIndex() error = %v", err)
+		}
+		if !equalGoldenTestProofs(toGoldenTestProof(proof), proofs[idx]) {
+			t.Fatalf("ProofByIndex() #%d = %v, want %v", idx, proof, proofs[idx])
+		}
+		if proof.Envelope == nil || proof.Envelope.LeafIndex != uint64(idx) || proof.Envelope.TreeSize != uint64(len(blocks)) {
+			t.Fatalf("ProofByIndex() #%d envelope = %+v", idx, proof.Envelope)
+		}
+		if ok, err := Verify(&DataBlock{Data: blocks[idx]}, proof, root, config); err != nil || !ok {
+			t.Fatalf("Verify() #%d got = %v, error = %v", idx, ok, err)
+		}
+	})
+}
+
+func FuzzVerify(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte, numBlocks, flags uint8, tamper uint16) {
+		_, config, blocks := fuzzTree(data, numBlocks, flags)
+		if len(blocks) == 0 {
+			return
+		}
+		m, err := New(config, fuzzDataBlocks(blocks))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		idx := int(tamper) % len(blocks)
+		proof, err := m.ProofByIndex(idx)
+		if err != nil {
+			t.Fatalf("ProofByIndex() error = %v", err)
+		}
+		block := &DataBlock{Data: blocks[idx]}
+		legacy := &Proof{Siblings: proof.Siblings, Path: proof.Path}
+		for _, p := range []*Proof{proof, legacy} {
+			if ok, err := Verify(block, p, m.Root, config); err != nil || !ok {
+				t.Fatalf("Verify() #%d got = %v, error = %v", idx, ok, err)
+			}
+
+			// A tampered sibling
+			if len(p.Siblings) > 0 {
+				tampered := &Proof{Siblings: cloneNodes(p.Siblings), Path: p.Path, Envelope: p.Envelope}
+				i := int(tamper>>8) % len(tampered.Siblings)
+				if sibling := tampered.Siblings[i]; len(sibling) > 0 {
+					sibling[int(tamper)%len(sibling)] ^= byte(tamper>>8) | 0x01
+				} else {
+					tampered.Siblings[i] = []byte{byte(tamper)}
+				}
+				if ok, _ := Verify(block, tampered, m.Root, config); ok {
+					t.Fatalf("Verify() #%d tampered sibling got = %v", idx, ok)
+				}
+
+				// A missing sibling
+				truncated := &Proof{Siblings: p.Siblings[:len(p.Siblings)-1], Path: p.Path, Envelope: p.Envelope}
+				if ok, _ := Verify(block, truncated, m.Root, config); ok {
+					t.Fatalf("Verify() #%d missing sibling got = %v", idx, ok)
+				}
+			}
+
+			// An additional sibling
+			extended := &Proof{Siblings: append(cloneNodes(p.Siblings), m.Root), Path: p.Path, Envelope: p.Envelope}
+			if ok, _ := Verify(block, extended, m.Root, config); ok {
+				t.Fatalf("Verify() #%d additional sibling got = %v", idx, ok)
+			}
+
+			// A tampered data block
+			tamperedBlock := &DataBlock{Data: append(append([]byte{}, block.Data...), byte(tamper))}
+			if ok, _ := Verify(tamperedBlock, p, m.Root, config); ok {
+				t.Fatalf("Verify() #%d tampered data block got = %v", idx, ok)
+			}
+		}
+
+		// A proof of another tree configuration
+		other := *config
+		other.SortSiblingPairs = !other.SortSiblingPairs
+		if ok, _ := Verify(block, proof, m.Root, &other); ok {
+			t.Fatalf("Verify() #%d other configuration got = %v", idx, ok)
+		}
+	})
+}
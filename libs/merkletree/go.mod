@@ -2,4 +2,13 @@ module github.com/ja88a/vrfs-go-merkletree/libs/merkletree
 
 go 1.21.4
 
-require github.com/agiledragon/gomonkey/v2 v2.11.0
+require (
+	github.com/agiledragon/gomonkey/v2 v2.11.0
+	golang.org/x/crypto v0.14.0
+	lukechampine.com/blake3 v1.1.7
+)
+
+require (
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+)
@@ -0,0 +1,140 @@
+package hash
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	gohash "hash"
+	"sort"
+	"sync"
+
+	"golang.org/x/crypto/blake2b"
+	"golang.org/x/crypto/sha3"
+	"lukechampine.com/blake3"
+)
+
+// Names of the hash algorithms available in the registry.
+// A hash algorithm name is the identifier shared by the services and clients to compute and verify filesets.
+const (
+	// AlgoSHA256 is the SHA-256 hash algorithm, used by default.
+	AlgoSHA256 = "sha256"
+	// AlgoSHA512_256 is the SHA-512/256 hash algorithm.
+	AlgoSHA512_256 = "sha512_256"
+	// AlgoSHA3_256 is the SHA3-256 hash algorithm.
+	AlgoSHA3_256 = "sha3_256"
+	// AlgoBLAKE2b256 is the BLAKE2b-256 hash algorithm.
+	AlgoBLAKE2b256 = "blake2b_256"
+	// AlgoBLAKE3 is the BLAKE3 hash algorithm, with a 256-bit output.
+	AlgoBLAKE3 = "blake3"
+	// AlgoKeccak256 is the legacy Keccak-256 hash algorithm, as used by Ethereum.
+	AlgoKeccak256 = "keccak256"
+
+	// DefaultAlgo is the hash algorithm used when none is specified.
+	DefaultAlgo = AlgoSHA256
+)
+
+var (
+	// ErrUnsupportedAlgo is the error for a hash algorithm name not found in the registry.
+	ErrUnsupportedAlgo = errors.New("unsupported hash algorithm")
+	// ErrInvalidAlgo is the error for the registration of a hash algorithm without a name or digest constructor.
+	ErrInvalidAlgo = errors.New("a hash algorithm requires a name and a digest constructor")
+)
+
+// NewDigestFunc is the constructor of a new hash digest, to be used for streaming data.
+type NewDigestFunc func() gohash.Hash
+
+var (
+	// registry maps the hash algorithm names to their digest constructors.
+	registry = map[string]NewDigestFunc{
+		AlgoSHA256:     sha256.New,
+		AlgoSHA512_256: sha512.New512_256,
+		AlgoSHA3_256:   sha3.New256,
+		AlgoBLAKE2b256: newBlake2b256,
+		AlgoBLAKE3:     newBlake3,
+		AlgoKeccak256:  sha3.NewLegacyKeccak256,
+	}
+	// registryMu protects concurrent access to the registry.
+	registryMu sync.RWMutex
+)
+
+// newBlake2b256 creates a new unkeyed BLAKE2b-256 hash digest.
+func newBlake2b256() gohash.Hash {
+	// The error is only returned for keys longer than 64 bytes.
+	digest, _ := blake2b.New256(nil)
+	return digest
+}
+
+// newBlake3 creates a new unkeyed BLAKE3 hash digest with a 256-bit output.
+func newBlake3() gohash.Hash {
+	return blake3.New(32, nil)
+}
+
+// Register adds a hash algorithm to the registry, or replaces the existing one of the same name.
+func Register(name string, newDigest NewDigestFunc) error {
+	if name == "" || newDigest == nil {
+		return ErrInvalidAlgo
+	}
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = newDigest
+	return nil
+}
+
+// NewDigest creates a new hash digest of the named hash algorithm, e.g. for hashing streamed data.
+// The default hash algorithm is used if the name is empty.
+func NewDigest(name string) (gohash.Hash, error) {
+	newDigest, err := digestFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	return newDigest(), nil
+}
+
+// NewDigestFuncByName returns the digest constructor of the named hash algorithm, e.g. for a caller
+// reusing its own digests rather than creating one for each hash.
+// The default hash algorithm is used if the name is empty.
+func NewDigestFuncByName(name string) (NewDigestFunc, error) {
+	return digestFunc(name)
+}
+
+// HashFuncByName returns the hash function of the named hash algorithm.
+// The returned function creates a new hash digest for each call, ensuring that it is safe for concurrent use.
+// The default hash algorithm is used if the name is empty.
+func HashFuncByName(name string) (func([]byte) ([]byte, error), error) {
+	newDigest, err := digestFunc(name)
+	if err != nil {
+		return nil, err
+	}
+	return func(data []byte) ([]byte, error) {
+		digest := newDigest()
+		digest.Write(data)
+		return digest.Sum(make([]byte, 0, digest.Size())), nil
+	}, nil
+}
+
+// SupportedAlgos returns the sorted names of the hash algorithms available in the registry.
+func SupportedAlgos() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// digestFunc retrieves the digest constructor of the named hash algorithm from the registry.
+func digestFunc(name string) (NewDigestFunc, error) {
+	if name == "" {
+		name = DefaultAlgo
+	}
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	newDigest, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: '%s'", ErrUnsupportedAlgo, name)
+	}
+	return newDigest, nil
+}
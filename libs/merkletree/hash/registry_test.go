@@ -0,0 +1,128 @@
+package hash
+
+import (
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestHashFuncByName(t *testing.T) {
+	tests := []struct {
+		name string
+		algo string
+		want string
+	}{
+		{
+			name: "test_default",
+			algo: "",
+			want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		{
+			name: "test_sha256",
+			algo: AlgoSHA256,
+			want: "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad",
+		},
+		{
+			name: "test_sha512_256",
+			algo: AlgoSHA512_256,
+			want: "53048e2681941ef99b2e29b76b4c7dabe4c2d0c634fc6d46e0e2f13107e7af23",
+		},
+		{
+			name: "test_sha3_256",
+			algo: AlgoSHA3_256,
+			want: "3a985da74fe225b2045c172d6bd390bd855f086e3e9d525b46bfe24511431532",
+		},
+		{
+			name: "test_blake2b_256",
+			algo: AlgoBLAKE2b256,
+			want: "bddd813c634239723171ef3fee98579b94964e3bb1cb3e427262c8c068d52319",
+		},
+		{
+			name: "test_blake3",
+			algo: AlgoBLAKE3,
+			want: "6437b3ac38465133ffb63b75273a8db548c558465d79db03fd359c6cd5bd9d85",
+		},
+		{
+			name: "test_keccak256",
+			algo: AlgoKeccak256,
+			want: "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hashFunc, err := HashFuncByName(tt.algo)
+			if err != nil {
+				t.Fatalf("HashFuncByName() error = %v", err)
+			}
+			got, err := hashFunc([]byte("abc"))
+			if err != nil {
+				t.Fatalf("hashFunc() error = %v", err)
+			}
+			if hex.EncodeToString(got) != tt.want {
+				t.Errorf("hashFunc() = %x, want %s", got, tt.want)
+			}
+
+			// The streamed digest must match the hash function.
+			digest, err := NewDigest(tt.algo)
+			if err != nil {
+				t.Fatalf("NewDigest() error = %v", err)
+			}
+			digest.Write([]byte("a"))
+			digest.Write([]byte("bc"))
+			if streamed := hex.EncodeToString(digest.Sum(nil)); streamed != tt.want {
+				t.Errorf("NewDigest() sum = %s, want %s", streamed, tt.want)
+			}
+		})
+	}
+}
+
+func TestHashFuncByName_parallel(t *testing.T) {
+	for _, algo := range SupportedAlgos() {
+		hashFunc, err := HashFuncByName(algo)
+		if err != nil {
+			t.Fatalf("HashFuncByName() error = %v", err)
+		}
+		want, _ := hashFunc([]byte(algo))
+		var wg sync.WaitGroup
+		for i := 0; i < 16; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				if got, _ := hashFunc([]byte(algo)); !reflect.DeepEqual(got, want) {
+					t.Errorf("hashFunc() %s = %x, want %x", algo, got, want)
+				}
+			}()
+		}
+		wg.Wait()
+	}
+}
+
+func TestRegister(t *testing.T) {
+	if _, err := HashFuncByName("unknown"); !errors.Is(err, ErrUnsupportedAlgo) {
+		t.Errorf("HashFuncByName() error = %v, wantErr %v", err, ErrUnsupportedAlgo)
+	}
+	if _, err := NewDigest("unknown"); !errors.Is(err, ErrUnsupportedAlgo) {
+		t.Errorf("NewDigest() error = %v, wantErr %v", err, ErrUnsupportedAlgo)
+	}
+	if _, err := NewDigestFuncByName("unknown"); !errors.Is(err, ErrUnsupportedAlgo) {
+		t.Errorf("NewDigestFuncByName() error = %v, wantErr %v", err, ErrUnsupportedAlgo)
+	}
+	if err := Register("", nil); !errors.Is(err, ErrInvalidAlgo) {
+		t.Errorf("Register() error = %v, wantErr %v", err, ErrInvalidAlgo)
+	}
+	newDigest, _ := digestFunc(AlgoSHA256)
+	if err := Register("test_custom", newDigest); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	hashFunc, err := HashFuncByName("test_custom")
+	if err != nil {
+		t.Fatalf("HashFuncByName() error = %v", err)
+	}
+	got, _ := hashFunc([]byte("abc"))
+	want, _ := DefaultHashFunc([]byte("abc"))
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("hashFunc() = %x, want %x", got, want)
+	}
+}
@@ -24,11 +24,14 @@ package merkletree
 
 import (
 	"bytes"
+	"context"
 	"crypto/rand"
 	"crypto/sha256"
 	"errors"
 	"fmt"
 	"reflect"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/agiledragon/gomonkey/v2"
@@ -99,6 +102,10 @@ func TestMerkleTreeNew_modeProofGen(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	emptyRoot, err := hash.DefaultHashFunc(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
 	type args struct {
 		blocks []IDataBlock
 		config *Config
@@ -114,14 +121,16 @@ func TestMerkleTreeNew_modeProofGen(t *testing.T) {
 			args: args{
 				blocks: generatedTestDataBlocks(0),
 			},
-			wantErr: true,
+			wantErr:  false,
+			wantRoot: emptyRoot,
 		},
 		{
 			name: "test_1",
 			args: args{
-				blocks: generatedTestDataBlocks(1),
+				blocks: []IDataBlock{dummyDataBlocks[0]},
 			},
-			wantErr: true,
+			wantErr:  false,
+			wantRoot: dummyHashList[0],
 		},
 		{
 			name: "test_2",
@@ -729,6 +738,1015 @@ func TestMerkleTreeNew_modeProofGenAndTreeBuildRunInParallel(t *testing.T) {
 	}
 }
 
+func TestMerkleTreeNew_rfc6962(t *testing.T) {
+	// Test vectors of the certificate-transparency-go project, for trees made of the first N leaves.
+	leaves := [][]byte{
+		{},
+		{0x00},
+		{0x10},
+		{0x20, 0x21},
+		{0x30, 0x31},
+		{0x40, 0x41, 0x42, 0x43},
+		{0x50, 0x51, 0x52, 0x53, 0x54, 0x55, 0x56, 0x57},
+		{0x60, 0x61, 0x62, 0x63, 0x64, 0x65, 0x66, 0x67, 0x68, 0x69, 0x6a, 0x6b, 0x6c, 0x6d, 0x6e, 0x6f},
+	}
+	wantRoots := []string{
+		"fac54203e7cc696cf0dfcb42c92a1d9dbaf70ad9e621f4bd8d98662f00e3c125",
+		"aeb6bcfe274b70a14fb067a5e5578264db0fa9b51af5e0ba159158f329e06e77",
+		"d37ee418976dd95753c1c73862b9398fa2a2cf9b4ff0fdfe8b30cd95209614b7",
+		"4e3bbb1f7b478dcfe71fb631631519a3bca12c9aefca1612bfce4c13a86264d4",
+		"76e67dadbcdf1e10e1b74ddc608abd2f98dfb16fbce75277b5232a127f2087ef",
+		"ddb89be403809e325750d3d263cd78929c2942b7942a34b77e122c9594a74c8c",
+		"5dc9da79a70659a9ad559cb701ded9a2ab9d823aad2f4960cfe370eff4604328",
+	}
+	configs := []*Config{
+		{Mode: ModeProofGen, RFC6962: true},
+		{Mode: ModeTreeBuild, RFC6962: true},
+		{Mode: ModeProofGenAndTreeBuild, RFC6962: true},
+		{Mode: ModeProofGen, RFC6962: true, RunInParallel: true, NumRoutines: 3},
+		{Mode: ModeProofGenAndTreeBuild, RFC6962: true, RunInParallel: true, NumRoutines: 3},
+	}
+	for i, wantRoot := range wantRoots {
+		numLeaves := i + 2
+		blocks := make([]IDataBlock, numLeaves)
+		for j := range blocks {
+			blocks[j] = &DataBlock{Data: leaves[j]}
+		}
+		for _, config := range configs {
+			t.Run(fmt.Sprintf("test_vector_%d_mode_%d_parallel_%v", numLeaves, config.Mode, config.RunInParallel), func(t *testing.T) {
+				m, err := New(config, blocks)
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				if got := fmt.Sprintf("%x", m.Root); got != wantRoot {
+					t.Errorf("New() Root = %s, want %s", got, wantRoot)
+				}
+			})
+		}
+	}
+
+	// Cross-check larger trees with the recursive RFC 6962 definition of the Merkle Tree Hash.
+	for _, numLeaves := range []int{5, 11, 100, 1001} {
+		blocks := generatedTestDataBlocks(numLeaves)
+		wantRoot := rfc6962MTH(blocks)
+		for _, config := range configs {
+			t.Run(fmt.Sprintf("test_mth_%d_mode_%d_parallel_%v", numLeaves, config.Mode, config.RunInParallel), func(t *testing.T) {
+				m, err := New(config, blocks)
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				if !bytes.Equal(m.Root, wantRoot) {
+					t.Errorf("New() Root = %x, want %x", m.Root, wantRoot)
+				}
+				for j, block := range blocks {
+					var proof *Proof
+					if config.Mode == ModeProofGen {
+						proof = m.Proofs[j]
+					} else if proof, err = m.Proof(block); err != nil {
+						t.Fatalf("Proof() error = %v", err)
+					}
+					if ok, err := Verify(block, proof, wantRoot, &Config{RFC6962: true}); err != nil || !ok {
+						t.Errorf("Verify() leaf %d got = %v, error = %v", j, ok, err)
+					}
+					if ok, _ := Verify(block, proof, wantRoot, nil); ok {
+						t.Errorf("Verify() leaf %d without RFC 6962 mode got = %v, want false", j, ok)
+					}
+				}
+			})
+		}
+	}
+
+	// The last node is promoted, hence [a, b, c] and [a, b, c, c] have different roots.
+	blocks := generatedTestDataBlocks(3)
+	m3, err := New(&Config{RFC6962: true}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	m4, err := New(&Config{RFC6962: true}, append(blocks, blocks[2]))
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if bytes.Equal(m3.Root, m4.Root) {
+		t.Errorf("New() [a, b, c] and [a, b, c, c] roots are both %x", m3.Root)
+	}
+}
+
+// rfc6962MTH is the recursive Merkle Tree Hash definition of the RFC 6962, section 2.1.
+func rfc6962MTH(blocks []IDataBlock) []byte {
+	if len(blocks) == 1 {
+		data, _ := blocks[0].Serialize()
+		h := sha256.Sum256(append([]byte{0x00}, data...))
+		return h[:]
+	}
+	k := 1
+	for k<<1 < len(blocks) {
+		k <<= 1
+	}
+	node := append([]byte{0x01}, rfc6962MTH(blocks[:k])...)
+	h := sha256.Sum256(append(node, rfc6962MTH(blocks[k:])...))
+	return h[:]
+}
+
+func TestMerkleTreeNew_hashAlgorithm(t *testing.T) {
+	blocks := generatedTestDataBlocks(11)
+	for _, algo := range hash.SupportedAlgos() {
+		hashFunc, err := hash.HashFuncByName(algo)
+		if err != nil {
+			t.Fatalf("HashFuncByName() error = %v", err)
+		}
+		want, err := New(&Config{HashFunc: hashFunc, Mode: ModeTreeBuild}, blocks)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		for _, parallel := range []bool{false, true} {
+			t.Run(fmt.Sprintf("test_%s_parallel_%v", algo, parallel), func(t *testing.T) {
+				config := &Config{HashAlgorithm: algo, Mode: ModeProofGenAndTreeBuild, RunInParallel: parallel}
+				m, err := New(config, blocks)
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				if !bytes.Equal(m.Root, want.Root) {
+					t.Errorf("New() Root = %x, want %x", m.Root, want.Root)
+				}
+				if ok, err := Verify(blocks[3], m.Proofs[3], m.Root, &Config{HashAlgorithm: algo}); err != nil || !ok {
+					t.Errorf("Verify() got = %v, error = %v, want true", ok, err)
+				}
+			})
+		}
+	}
+	if _, err := New(&Config{HashAlgorithm: "unknown"}, blocks); !errors.Is(err, hash.ErrUnsupportedAlgo) {
+		t.Errorf("New() error = %v, wantErr %v", err, hash.ErrUnsupportedAlgo)
+	}
+	if _, err := Verify(blocks[0], &Proof{}, nil, &Config{HashAlgorithm: "unknown"}); !errors.Is(err, hash.ErrUnsupportedAlgo) {
+		t.Errorf("Verify() error = %v, wantErr %v", err, hash.ErrUnsupportedAlgo)
+	}
+}
+
+func TestNew_sharedWorkerPool(t *testing.T) {
+	const numTrees = 8
+	wp := NewWorkerPool(4)
+	defer wp.Close()
+	modes := []TypeConfigMode{ModeProofGen, ModeTreeBuild, ModeProofGenAndTreeBuild}
+
+	// Trees generated concurrently with a shared pool are the ones generated with their own pool
+	var wg sync.WaitGroup
+	wg.Add(numTrees)
+	for i := 0; i < numTrees; i++ {
+		go func(i int) {
+			defer wg.Done()
+			blocks := generatedTestDataBlocks(100 + 37*i)
+			mode := modes[i%len(modes)]
+			want, err := New(&Config{Mode: mode, RunInParallel: true, NumRoutines: 3}, blocks)
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+			config := &Config{Mode: mode, RunInParallel: true, WorkerPool: wp}
+			m, err := New(config, blocks)
+			if err != nil {
+				t.Errorf("New() error = %v", err)
+				return
+			}
+			if !bytes.Equal(m.Root, want.Root) {
+				t.Errorf("New() tree #%d root = %x, want %x", i, m.Root, want.Root)
+			}
+			if m.NumRoutines != wp.NumWorkers() {
+				t.Errorf("New() NumRoutines = %d, want the %d pool workers", m.NumRoutines, wp.NumWorkers())
+			}
+			if err := m.Append(generatedTestDataBlocks(5)...); err != nil {
+				t.Errorf("Append() error = %v", err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// The shared pool is left open by the trees
+	stats := wp.Stats()
+	if stats.CompletedTasks == 0 || stats.BusyWorkers != 0 || stats.QueueDepth != 0 {
+		t.Errorf("Stats() = %+v, want completed tasks and idle workers", stats)
+	}
+	if _, err := New(&Config{RunInParallel: true, WorkerPool: wp}, generatedTestDataBlocks(10)); err != nil {
+		t.Errorf("New() error = %v", err)
+	}
+}
+
+func TestNew_closedWorkerPool(t *testing.T) {
+	blocks := generatedTestDataBlocks(100)
+	wp := NewWorkerPool(4)
+	config := &Config{Mode: ModeProofGenAndTreeBuild, RunInParallel: true, WorkerPool: wp}
+	m, err := New(config, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	root := m.Root
+	wp.Close()
+
+	// Trees computed on a closed shared pool fail rather than crash
+	for _, mode := range []TypeConfigMode{ModeProofGen, ModeTreeBuild, ModeProofGenAndTreeBuild} {
+		if _, err := New(&Config{Mode: mode, RunInParallel: true, WorkerPool: wp}, blocks); !errors.Is(err, ErrWorkerPoolClosed) {
+			t.Errorf("New() mode %d error = %v, wantErr %v", mode, err, ErrWorkerPoolClosed)
+		}
+	}
+	if err := m.Append(generatedTestDataBlocks(5)...); !errors.Is(err, ErrWorkerPoolClosed) {
+		t.Errorf("Append() error = %v, wantErr %v", err, ErrWorkerPoolClosed)
+	}
+	if m.NumLeaves != len(blocks) || !bytes.Equal(m.Root, root) {
+		t.Errorf("Append() failure left %d leaves, root %x, want %d, %x", m.NumLeaves, m.Root, len(blocks), root)
+	}
+	if _, err := Load(config, data); !errors.Is(err, ErrWorkerPoolClosed) {
+		t.Errorf("Load() error = %v, wantErr %v", err, ErrWorkerPoolClosed)
+	}
+	keys, values := generatedSparseTestData(t, 100, nil)
+	if _, err := NewSparse(&Config{RunInParallel: true, WorkerPool: wp}, keys, values); !errors.Is(err, ErrWorkerPoolClosed) {
+		t.Errorf("NewSparse() error = %v, wantErr %v", err, ErrWorkerPoolClosed)
+	}
+}
+
+func TestNewWithContext(t *testing.T) {
+	blocks := generatedTestDataBlocks(1000)
+	errHash := errors.New("test_hash_func_err")
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name:   "test_mode_proof_gen",
+			config: &Config{Mode: ModeProofGen},
+		},
+		{
+			name:   "test_mode_tree_build",
+			config: &Config{Mode: ModeTreeBuild},
+		},
+		{
+			name:   "test_mode_proof_gen_parallel",
+			config: &Config{Mode: ModeProofGen, RunInParallel: true, NumRoutines: 4},
+		},
+		{
+			name:   "test_mode_proof_gen_and_tree_build_parallel",
+			config: &Config{Mode: ModeProofGenAndTreeBuild, RunInParallel: true, NumRoutines: 4},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A live context has no effect on the generated tree
+			want, err := New(tt.config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			m, err := NewWithContext(context.Background(), tt.config, blocks)
+			if err != nil || !bytes.Equal(m.Root, want.Root) {
+				t.Fatalf("NewWithContext() root = %x, error = %v, want %x", m.Root, err, want.Root)
+			}
+
+			// The generation is aborted once the context is cancelled
+			ctx, cancel := context.WithCancel(context.Background())
+			config := *tt.config
+			var numHashes atomic.Int32
+			config.HashFunc = func(data []byte) ([]byte, error) {
+				if numHashes.Add(1) == 100 {
+					cancel()
+				}
+				return hash.DefaultHashFuncParallel(data)
+			}
+			if _, err := NewWithContext(ctx, &config, blocks); !errors.Is(err, context.Canceled) {
+				t.Errorf("NewWithContext() error = %v, wantErr %v", err, context.Canceled)
+			}
+			if n := numHashes.Load(); n >= int32(len(blocks)) {
+				t.Errorf("NewWithContext() hashes = %d after cancellation, want less than %d", n, len(blocks))
+			}
+
+			// The first hash error stops the generation
+			numHashes.Store(0)
+			config.HashFunc = func(data []byte) ([]byte, error) {
+				if numHashes.Add(1) >= 10 {
+					return nil, errHash
+				}
+				return hash.DefaultHashFuncParallel(data)
+			}
+			if _, err := NewWithContext(context.Background(), &config, blocks); !errors.Is(err, errHash) {
+				t.Errorf("NewWithContext() error = %v, wantErr %v", err, errHash)
+			}
+			if n := numHashes.Load(); n >= int32(len(blocks)) {
+				t.Errorf("NewWithContext() hashes = %d after an error, want less than %d", n, len(blocks))
+			}
+		})
+	}
+}
+
+func TestMerkleTree_Append(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		numBlocks int
+		appends   []int
+	}{
+		{
+			name:      "test_proof_gen_0_1_2",
+			config:    &Config{Mode: ModeProofGen},
+			numBlocks: 0,
+			appends:   []int{1, 2},
+		},
+		{
+			name:      "test_build_tree_1_1_3",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 1,
+			appends:   []int{1, 3},
+		},
+		{
+			name:      "test_build_tree_proof_rfc6962_0_1_4",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild, RFC6962: true},
+			numBlocks: 0,
+			appends:   []int{1, 4},
+		},
+		{
+			name:      "test_proof_gen_2_1",
+			config:    &Config{Mode: ModeProofGen},
+			numBlocks: 2,
+			appends:   []int{1},
+		},
+		{
+			name:      "test_proof_gen_5_3_8",
+			config:    &Config{Mode: ModeProofGen},
+			numBlocks: 5,
+			appends:   []int{3, 8},
+		},
+		{
+			name:      "test_proof_gen_parallel_100_27",
+			config:    &Config{Mode: ModeProofGen, RunInParallel: true, NumRoutines: 4},
+			numBlocks: 100,
+			appends:   []int{27},
+		},
+		{
+			name:      "test_build_tree_2_1_1_1",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 2,
+			appends:   []int{1, 1, 1},
+		},
+		{
+			name:      "test_build_tree_4_1",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 4,
+			appends:   []int{1},
+		},
+		{
+			name:      "test_build_tree_7_10",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 7,
+			appends:   []int{10},
+		},
+		{
+			name:      "test_build_tree_sorted_1000_1_24",
+			config:    &Config{Mode: ModeTreeBuild, SortSiblingPairs: true},
+			numBlocks: 1000,
+			appends:   []int{1, 24},
+		},
+		{
+			name:      "test_build_tree_disable_leaf_hashing_9_3",
+			config:    &Config{Mode: ModeTreeBuild, DisableLeafHashing: true},
+			numBlocks: 9,
+			appends:   []int{3},
+		},
+		{
+			name:      "test_build_tree_parallel_64_1",
+			config:    &Config{Mode: ModeTreeBuild, RunInParallel: true, NumRoutines: 4},
+			numBlocks: 64,
+			appends:   []int{1},
+		},
+		{
+			name:      "test_build_tree_proof_3_1_4",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild},
+			numBlocks: 3,
+			appends:   []int{1, 4},
+		},
+		{
+			name:      "test_build_tree_proof_parallel_33_31",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild, RunInParallel: true, NumRoutines: 8},
+			numBlocks: 33,
+			appends:   []int{31},
+		},
+		{
+			name:      "test_proof_gen_rfc6962_5_3_8",
+			config:    &Config{Mode: ModeProofGen, RFC6962: true},
+			numBlocks: 5,
+			appends:   []int{3, 8},
+		},
+		{
+			name:      "test_build_tree_rfc6962_7_1_10",
+			config:    &Config{Mode: ModeTreeBuild, RFC6962: true},
+			numBlocks: 7,
+			appends:   []int{1, 10},
+		},
+		{
+			name:      "test_build_tree_proof_rfc6962_parallel_3_1_4_29",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild, RFC6962: true, RunInParallel: true, NumRoutines: 4},
+			numBlocks: 3,
+			appends:   []int{1, 4, 29},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total := tt.numBlocks
+			for _, numAppended := range tt.appends {
+				total += numAppended
+			}
+			blocks := generatedTestDataBlocks(total)
+			m, err := New(tt.config, blocks[:tt.numBlocks])
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			numLeaves := tt.numBlocks
+			for _, numAppended := range tt.appends {
+				if err := m.Append(blocks[numLeaves : numLeaves+numAppended]...); err != nil {
+					t.Fatalf("Append() error = %v", err)
+				}
+				numLeaves += numAppended
+
+				want, err := New(tt.config, blocks[:numLeaves])
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				if !bytes.Equal(m.Root, want.Root) {
+					t.Errorf("Append() %d leaves Root = %x, want %x", numLeaves, m.Root, want.Root)
+				}
+				if m.Depth != want.Depth || m.NumLeaves != want.NumLeaves {
+					t.Errorf("Append() %d leaves Depth = %d NumLeaves = %d, want %d %d",
+						numLeaves, m.Depth, m.NumLeaves, want.Depth, want.NumLeaves)
+				}
+				if !reflect.DeepEqual(m.Leaves, want.Leaves) {
+					t.Errorf("Append() %d leaves Leaves differ", numLeaves)
+				}
+				if !reflect.DeepEqual(m.Proofs, want.Proofs) {
+					t.Errorf("Append() %d leaves Proofs differ", numLeaves)
+				}
+				if !reflect.DeepEqual(m.nodes, want.nodes) {
+					t.Errorf("Append() %d leaves nodes differ", numLeaves)
+				}
+			}
+			if tt.config.Mode == ModeProofGen {
+				return
+			}
+			for i := 0; i < numLeaves; i++ {
+				proof, err := m.Proof(blocks[i])
+				if err != nil {
+					t.Fatalf("Proof() error = %v", err)
+				}
+				if ok, err := m.Verify(blocks[i], proof); err != nil || !ok {
+					t.Errorf("Verify() leaf %d got = %v, error = %v", i, ok, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMerkleTree_Append_errors(t *testing.T) {
+	blocks := generatedTestDataBlocks(5)
+	m, err := New(&Config{Mode: ModeTreeBuild}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	root := m.Root
+	if err := m.Append(); err != nil {
+		t.Errorf("Append() no data block error = %v", err)
+	}
+	if err := m.Append(generatedTestDataBlocks(1)[0], nil); !errors.Is(err, ErrDataBlockIsNil) {
+		t.Errorf("Append() error = %v, wantErr %v", err, ErrDataBlockIsNil)
+	}
+	if !bytes.Equal(m.Root, root) || m.NumLeaves != 5 {
+		t.Errorf("Append() failure modified the tree")
+	}
+}
+
+func TestMerkleTree_Append_hashFailure(t *testing.T) {
+	configs := map[string]*Config{
+		"proof_gen":                {Mode: ModeProofGen},
+		"tree_build":               {Mode: ModeTreeBuild},
+		"proof_gen_and_tree_build": {Mode: ModeProofGenAndTreeBuild},
+		"parallel_tree_build":      {Mode: ModeProofGenAndTreeBuild, RunInParallel: true, NumRoutines: 4},
+	}
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			// The hash function fails on the third node hashed after the tree is built
+			var failAfter atomic.Int32
+			failAfter.Store(-1)
+			config.HashFunc = func(data []byte) ([]byte, error) {
+				if len(data) == 64 && failAfter.Load() >= 0 && failAfter.Add(-1) < 0 {
+					return nil, errors.New("hash failure")
+				}
+				sum := sha256.Sum256(data)
+				return sum[:], nil
+			}
+			blocks := generatedTestDataBlocks(13)
+			m, err := New(config, blocks[:7])
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			root, numLeaves, depth := m.Root, m.NumLeaves, m.Depth
+
+			failAfter.Store(2)
+			if err := m.Append(blocks[7:]...); err == nil {
+				t.Fatalf("Append() error = nil, want the hash failure")
+			}
+			failAfter.Store(-1)
+			if !bytes.Equal(m.Root, root) || m.NumLeaves != numLeaves || m.Depth != depth || len(m.Leaves) != numLeaves {
+				t.Fatalf("Append() failure modified the tree: %d leaves, depth %d", m.NumLeaves, m.Depth)
+			}
+			for i, block := range blocks[:7] {
+				var proof *Proof
+				if config.Mode == ModeProofGen {
+					proof = m.Proofs[i]
+				} else if proof, err = m.Proof(block); err != nil {
+					t.Fatalf("Proof() #%d error = %v", i, err)
+				}
+				if ok, err := Verify(block, proof, root, config); err != nil || !ok {
+					t.Errorf("Verify() #%d got = %v, error = %v", i, ok, err)
+				}
+			}
+			if config.Mode != ModeProofGen {
+				if _, err := m.Proof(blocks[7]); !errors.Is(err, ErrProofInvalidDataBlock) {
+					t.Errorf("Proof() of a block not appended error = %v, wantErr %v", err, ErrProofInvalidDataBlock)
+				}
+			}
+
+			// The tree is appended again once the hash function no longer fails
+			if err := m.Append(blocks[7:]...); err != nil {
+				t.Fatalf("Append() error = %v", err)
+			}
+			want, err := New(config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !bytes.Equal(m.Root, want.Root) {
+				t.Errorf("Append() root = %x, want %x", m.Root, want.Root)
+			}
+		})
+	}
+}
+
+func TestMerkleTree_Update(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		numBlocks int
+		updates   [][]int
+	}{
+		{
+			name:      "test_build_tree_2",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 2,
+			updates:   [][]int{{0}, {1}, {0, 1}},
+		},
+		{
+			name:      "test_build_tree_5_last",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 5,
+			updates:   [][]int{{4}, {3, 4}},
+		},
+		{
+			name:      "test_build_tree_sorted_9",
+			config:    &Config{Mode: ModeTreeBuild, SortSiblingPairs: true},
+			numBlocks: 9,
+			updates:   [][]int{{8}, {0, 8}, {5, 2, 5}},
+		},
+		{
+			name:      "test_build_tree_disable_leaf_hashing_1001",
+			config:    &Config{Mode: ModeTreeBuild, DisableLeafHashing: true},
+			numBlocks: 1001,
+			updates:   [][]int{{1000}, {0, 500, 999}},
+		},
+		{
+			name:      "test_build_tree_parallel_64",
+			config:    &Config{Mode: ModeTreeBuild, RunInParallel: true, NumRoutines: 4},
+			numBlocks: 64,
+			updates:   [][]int{{31, 32}},
+		},
+		{
+			name:      "test_build_tree_proof_3",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild},
+			numBlocks: 3,
+			updates:   [][]int{{2}, {0}, {1, 2}},
+		},
+		{
+			name:      "test_build_tree_proof_13",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild},
+			numBlocks: 13,
+			updates:   [][]int{{12}, {11}, {3, 7, 12}},
+		},
+		{
+			name:      "test_build_tree_proof_parallel_100",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild, RunInParallel: true, NumRoutines: 8},
+			numBlocks: 100,
+			updates:   [][]int{{99}, {0, 1, 2, 3, 50, 98}},
+		},
+		{
+			name:      "test_build_tree_rfc6962_13",
+			config:    &Config{Mode: ModeTreeBuild, RFC6962: true},
+			numBlocks: 13,
+			updates:   [][]int{{12}, {11}, {3, 7, 12}},
+		},
+		{
+			name:      "test_build_tree_proof_rfc6962_21",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild, RFC6962: true},
+			numBlocks: 21,
+			updates:   [][]int{{20}, {19}, {0, 16, 17, 20}},
+		},
+		{
+			name:      "test_build_tree_proof_rfc6962_sorted_parallel_100",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild, RFC6962: true, SortSiblingPairs: true, RunInParallel: true, NumRoutines: 8},
+			numBlocks: 100,
+			updates:   [][]int{{99}, {0, 1, 2, 3, 50, 96, 98}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := generatedTestDataBlocks(tt.numBlocks)
+			m, err := New(tt.config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			for _, indices := range tt.updates {
+				newBlocks := generatedTestDataBlocks(len(indices))
+				oldBlocks := make([]IDataBlock, len(indices))
+				if len(indices) == 1 {
+					oldBlocks[0] = blocks[indices[0]]
+					err = m.Update(indices[0], newBlocks[0])
+				} else {
+					for i, idx := range indices {
+						oldBlocks[i] = blocks[idx]
+					}
+					err = m.UpdateBatch(indices, newBlocks)
+				}
+				if err != nil {
+					t.Fatalf("Update() error = %v", err)
+				}
+				for i, idx := range indices {
+					blocks[idx] = newBlocks[i]
+				}
+
+				// Cross-check with a fresh tree of the updated data blocks.
+				want, err := New(tt.config, blocks)
+				if err != nil {
+					t.Fatalf("New() error = %v", err)
+				}
+				if !bytes.Equal(m.Root, want.Root) {
+					t.Errorf("Update() %v Root = %x, want %x", indices, m.Root, want.Root)
+				}
+				if !reflect.DeepEqual(m.Leaves, want.Leaves) {
+					t.Errorf("Update() %v Leaves differ", indices)
+				}
+				if !reflect.DeepEqual(m.nodes, want.nodes) {
+					t.Errorf("Update() %v nodes differ", indices)
+				}
+				if !reflect.DeepEqual(m.Proofs, want.Proofs) {
+					t.Errorf("Update() %v Proofs differ", indices)
+				}
+				if !reflect.DeepEqual(m.leafMap, want.leafMap) {
+					t.Errorf("Update() %v leafMap differs", indices)
+				}
+
+				// Proofs of the replaced data blocks are no longer available.
+				for _, oldBlock := range oldBlocks {
+					if _, err := m.Proof(oldBlock); !errors.Is(err, ErrProofInvalidDataBlock) {
+						t.Errorf("Proof() replaced data block error = %v, wantErr %v", err, ErrProofInvalidDataBlock)
+					}
+				}
+			}
+			for i := 0; i < tt.numBlocks; i++ {
+				proof, err := m.Proof(blocks[i])
+				if err != nil {
+					t.Fatalf("Proof() error = %v", err)
+				}
+				if ok, err := m.Verify(blocks[i], proof); err != nil || !ok {
+					t.Errorf("Verify() leaf %d got = %v, error = %v", i, ok, err)
+				}
+			}
+		})
+	}
+}
+
+func TestMerkleTree_Update_errors(t *testing.T) {
+	blocks := generatedTestDataBlocks(5)
+	tests := []struct {
+		name    string
+		config  *Config
+		indices []int
+		blocks  []IDataBlock
+		wantErr error
+	}{
+		{
+			name:    "test_wrong_mode",
+			config:  &Config{Mode: ModeProofGen},
+			indices: []int{0},
+			blocks:  generatedTestDataBlocks(1),
+			wantErr: ErrUpdateInvalidModeTreeNotBuilt,
+		},
+		{
+			name:    "test_index_out_of_range",
+			config:  &Config{Mode: ModeTreeBuild},
+			indices: []int{1, 5},
+			blocks:  generatedTestDataBlocks(2),
+			wantErr: ErrLeafIndexOutOfRange,
+		},
+		{
+			name:    "test_negative_index",
+			config:  &Config{Mode: ModeTreeBuild},
+			indices: []int{-1},
+			blocks:  generatedTestDataBlocks(1),
+			wantErr: ErrLeafIndexOutOfRange,
+		},
+		{
+			name:    "test_mismatch",
+			config:  &Config{Mode: ModeTreeBuild},
+			indices: []int{0, 1},
+			blocks:  generatedTestDataBlocks(1),
+			wantErr: ErrUpdateMismatch,
+		},
+		{
+			name:    "test_data_block_nil",
+			config:  &Config{Mode: ModeProofGenAndTreeBuild},
+			indices: []int{0},
+			blocks:  []IDataBlock{nil},
+			wantErr: ErrDataBlockIsNil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(tt.config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			root := m.Root
+			if err := m.UpdateBatch(tt.indices, tt.blocks); !errors.Is(err, tt.wantErr) {
+				t.Errorf("UpdateBatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !bytes.Equal(m.Root, root) {
+				t.Errorf("UpdateBatch() failure modified the tree")
+			}
+		})
+	}
+}
+
+func TestMerkleTree_Update_duplicateLeaves(t *testing.T) {
+	for _, mode := range []TypeConfigMode{ModeTreeBuild, ModeProofGenAndTreeBuild} {
+		blocks := generatedTestDataBlocks(3)
+		blocks[1] = blocks[0]
+		m, err := New(&Config{Mode: mode}, blocks)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+
+		// Leaf 0 still holds the replaced leaf of index 1, its proof is still available
+		newBlocks := generatedTestDataBlocks(1)
+		if err := m.Update(1, newBlocks[0]); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		proof, err := m.Proof(blocks[0])
+		if err != nil {
+			t.Fatalf("Proof() of the duplicated leaf error = %v", err)
+		}
+		if ok, err := Verify(blocks[0], proof, m.Root, nil); err != nil || !ok {
+			t.Errorf("Verify() of the duplicated leaf got = %v, error = %v", ok, err)
+		}
+
+		// No index holds the leaf anymore once both are replaced
+		if err := m.UpdateBatch([]int{0, 2}, []IDataBlock{newBlocks[0], blocks[0]}); err != nil {
+			t.Fatalf("UpdateBatch() error = %v", err)
+		}
+		if proof, err = m.Proof(blocks[0]); err != nil {
+			t.Fatalf("Proof() of the moved leaf error = %v", err)
+		}
+		if ok, err := Verify(blocks[0], proof, m.Root, nil); err != nil || !ok {
+			t.Errorf("Verify() of the moved leaf got = %v, error = %v", ok, err)
+		}
+		if err := m.Update(2, blocks[2]); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		if _, err := m.Proof(blocks[0]); !errors.Is(err, ErrProofInvalidDataBlock) {
+			t.Errorf("Proof() of the replaced leaf error = %v, wantErr %v", err, ErrProofInvalidDataBlock)
+		}
+		for _, block := range []IDataBlock{newBlocks[0], blocks[2]} {
+			proof, err := m.Proof(block)
+			if err != nil {
+				t.Fatalf("Proof() error = %v", err)
+			}
+			if ok, err := Verify(block, proof, m.Root, nil); err != nil || !ok {
+				t.Errorf("Verify() got = %v, error = %v", ok, err)
+			}
+		}
+	}
+}
+
+func TestMerkleTree_Update_hashFailure(t *testing.T) {
+	// The hash function fails on the second node hashed after the tree is built
+	failAfter := -1
+	config := &Config{
+		Mode: ModeProofGenAndTreeBuild,
+		HashFunc: func(data []byte) ([]byte, error) {
+			if len(data) == 64 && failAfter >= 0 {
+				if failAfter == 0 {
+					return nil, errors.New("hash failure")
+				}
+				failAfter--
+			}
+			sum := sha256.Sum256(data)
+			return sum[:], nil
+		},
+	}
+	blocks := generatedTestDataBlocks(9)
+	m, err := New(config, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	root := m.Root
+	leaves := append([][]byte{}, m.Leaves...)
+	data, err := m.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+
+	failAfter = 1
+	if err := m.UpdateBatch([]int{0, 5}, generatedTestDataBlocks(2)); err == nil {
+		t.Fatalf("UpdateBatch() error = nil, want the hash failure")
+	}
+	failAfter = -1
+	if !bytes.Equal(m.Root, root) {
+		t.Errorf("UpdateBatch() failure modified the root")
+	}
+	for i := range leaves {
+		if !bytes.Equal(m.Leaves[i], leaves[i]) {
+			t.Errorf("UpdateBatch() failure modified leaf #%d", i)
+		}
+	}
+	if got, err := m.MarshalBinary(); err != nil || !bytes.Equal(got, data) {
+		t.Errorf("UpdateBatch() failure modified the tree nodes, error = %v", err)
+	}
+	for i, block := range blocks {
+		proof, err := m.Proof(block)
+		if err != nil {
+			t.Fatalf("Proof() #%d error = %v", i, err)
+		}
+		if ok, err := Verify(block, proof, root, config); err != nil || !ok {
+			t.Errorf("Verify() #%d got = %v, error = %v", i, ok, err)
+		}
+	}
+}
+
+func TestMerkleTree_Update_detachedProofs(t *testing.T) {
+	blocks := generatedTestDataBlocks(9)
+	m, err := New(&Config{Mode: ModeTreeBuild}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	root := m.Root
+	proof, err := m.Proof(blocks[0])
+	if err != nil {
+		t.Fatalf("Proof() error = %v", err)
+	}
+	multiProof, err := m.MultiProofByIndices([]int{0, 8})
+	if err != nil {
+		t.Fatalf("MultiProofByIndices() error = %v", err)
+	}
+
+	// The nodes are updated in place, the proofs already generated are not modified
+	if err := m.UpdateBatch([]int{1, 7}, generatedTestDataBlocks(2)); err != nil {
+		t.Fatalf("UpdateBatch() error = %v", err)
+	}
+	if ok, err := Verify(blocks[0], proof, root, nil); err != nil || !ok {
+		t.Errorf("Verify() got = %v, error = %v", ok, err)
+	}
+	if ok, err := VerifyMulti([]IDataBlock{blocks[0], blocks[8]}, multiProof, root, nil); err != nil || !ok {
+		t.Errorf("VerifyMulti() got = %v, error = %v", ok, err)
+	}
+}
+
+func TestMerkleTreeNew_singleLeaf(t *testing.T) {
+	configs := map[string]*Config{
+		"proof_gen":                 {Mode: ModeProofGen},
+		"tree_build":                {Mode: ModeTreeBuild},
+		"proof_gen_and_tree_build":  {Mode: ModeProofGenAndTreeBuild},
+		"parallel_tree_build":       {Mode: ModeTreeBuild, RunInParallel: true, NumRoutines: 4},
+		"rfc6962_sorted_proof_gen":  {Mode: ModeProofGen, RFC6962: true, SortSiblingPairs: true},
+		"disable_leaf_hashing_tree": {Mode: ModeProofGenAndTreeBuild, DisableLeafHashing: true},
+	}
+	for name, config := range configs {
+		t.Run(name, func(t *testing.T) {
+			blocks := generatedTestDataBlocks(1)
+			m, err := New(config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if m.Depth != 0 || !bytes.Equal(m.Root, m.Leaves[0]) {
+				t.Fatalf("New() depth = %d, root = %x, want 0, %x", m.Depth, m.Root, m.Leaves[0])
+			}
+			var proof *Proof
+			if config.Mode == ModeProofGen {
+				proof = m.Proofs[0]
+			} else if proof, err = m.Proof(blocks[0]); err != nil {
+				t.Fatalf("Proof() error = %v", err)
+			}
+			if len(proof.Siblings) != 0 || proof.Path != 0 {
+				t.Errorf("Proof() = %v, want no sibling", proof)
+			}
+			if ok, err := Verify(blocks[0], proof, m.Root, config); err != nil || !ok {
+				t.Errorf("Verify() got = %v, error = %v", ok, err)
+			}
+			if ok, _ := Verify(generatedTestDataBlocks(2)[1], proof, m.Root, config); ok {
+				t.Errorf("Verify() other block got = %v, want false", ok)
+			}
+
+			data, err := m.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+			loaded, err := Load(config, data)
+			if err != nil {
+				t.Fatalf("Load() error = %v", err)
+			}
+			if !bytes.Equal(loaded.Root, m.Root) || !reflect.DeepEqual(loaded.Proofs, m.Proofs) {
+				t.Errorf("Load() root = %x, proofs = %v, want %x, %v", loaded.Root, loaded.Proofs, m.Root, m.Proofs)
+			}
+
+			if config.Mode == ModeProofGen {
+				return
+			}
+			multiProof, err := m.MultiProofByIndices([]int{0})
+			if err != nil {
+				t.Fatalf("MultiProofByIndices() error = %v", err)
+			}
+			if ok, err := VerifyMulti(blocks, multiProof, m.Root, config); err != nil || !ok {
+				t.Errorf("VerifyMulti() got = %v, error = %v", ok, err)
+			}
+
+			// Updating the single leaf updates the root
+			updated := &DataBlock{Data: []byte("updated")}
+			if err := m.Update(0, updated); err != nil {
+				t.Fatalf("Update() error = %v", err)
+			}
+			if ok, err := Verify(updated, proof, m.Root, config); err != nil || !ok {
+				t.Errorf("Verify() updated got = %v, error = %v", ok, err)
+			}
+		})
+	}
+}
+
+func TestMerkleTreeNew_empty(t *testing.T) {
+	for _, config := range []*Config{nil, {Mode: ModeTreeBuild}, {Mode: ModeProofGenAndTreeBuild, HashAlgorithm: hash.AlgoKeccak256}} {
+		m, err := New(config, nil)
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		want, err := EmptyRoot(config)
+		if err != nil {
+			t.Fatalf("EmptyRoot() error = %v", err)
+		}
+		if m.NumLeaves != 0 || !bytes.Equal(m.Root, want) {
+			t.Errorf("New() leaves = %d, root = %x, want 0, %x", m.NumLeaves, m.Root, want)
+		}
+		data, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error = %v", err)
+		}
+		if loaded, err := Load(config, data); err != nil || !bytes.Equal(loaded.Root, want) {
+			t.Errorf("Load() error = %v", err)
+		}
+	}
+	if _, err := EmptyRoot(&Config{HashAlgorithm: "unknown"}); !errors.Is(err, hash.ErrUnsupportedAlgo) {
+		t.Errorf("EmptyRoot() error = %v, wantErr %v", err, hash.ErrUnsupportedAlgo)
+	}
+}
+
+func TestNew_hashSizeMismatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		hashFunc TypeHashFunc
+	}{
+		{
+			name: "test_variable_size",
+			hashFunc: func(data []byte) ([]byte, error) {
+				sum := sha256.Sum256(data)
+				return sum[:1+sum[0]&1], nil
+			},
+		},
+		{
+			name: "test_empty",
+			hashFunc: func([]byte) ([]byte, error) {
+				return []byte{}, nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			config := &Config{HashFunc: tt.hashFunc, Mode: ModeTreeBuild}
+			if _, err := New(config, generatedTestDataBlocks(100)); !errors.Is(err, ErrHashSizeMismatch) {
+				t.Errorf("New() error = %v, wantErr %v", err, ErrHashSizeMismatch)
+			}
+		})
+	}
+}
+
 func setupTestVerify(size int) (*MerkleTree, []IDataBlock) {
 	blocks := generatedTestDataBlocks(size)
 	m, err := New(nil, blocks)
@@ -953,6 +1971,89 @@ func TestMerkleTree_Proof(t *testing.T) {
 	}
 }
 
+func TestMerkleTree_ProofByIndex(t *testing.T) {
+	tests := []struct {
+		name   string
+		config *Config
+	}{
+		{
+			name:   "test_proof_gen",
+			config: &Config{Mode: ModeProofGen},
+		},
+		{
+			name:   "test_tree_build",
+			config: &Config{Mode: ModeTreeBuild},
+		},
+		{
+			name:   "test_proof_gen_and_tree_build_parallel",
+			config: &Config{Mode: ModeProofGenAndTreeBuild, RunInParallel: true, NumRoutines: 4},
+		},
+		{
+			name:   "test_rfc6962_tree_build",
+			config: &Config{Mode: ModeTreeBuild, RFC6962: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The third and sixth blocks are duplicates, e.g. files of the same content
+			blocks := generatedTestDataBlocks(7)
+			blocks[5] = &DataBlock{Data: blocks[2].(*DataBlock).Data}
+			want, err := New(&Config{Mode: ModeProofGen, RFC6962: tt.config.RFC6962}, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			m, err := New(tt.config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			for i, block := range blocks {
+				proof, err := m.ProofByIndex(i)
+				if err != nil {
+					t.Fatalf("ProofByIndex() error = %v", err)
+				}
+				if !reflect.DeepEqual(proof, want.Proofs[i]) {
+					t.Errorf("ProofByIndex() %d got = %v, want %v", i, proof, want.Proofs[i])
+				}
+				if ok, err := m.Verify(block, proof); err != nil || !ok {
+					t.Errorf("Verify() %d got = %v, error = %v", i, ok, err)
+				}
+				if leaf, err := m.Leaf(i); err != nil || !bytes.Equal(leaf, want.Leaves[i]) {
+					t.Errorf("Leaf() %d got = %x, error = %v, want %x", i, leaf, err, want.Leaves[i])
+				}
+			}
+			if indices, err := m.LeafIndex(blocks[2]); err != nil || !reflect.DeepEqual(indices, []int{2, 5}) {
+				t.Errorf("LeafIndex() got = %v, error = %v, want [2 5]", indices, err)
+			}
+			if indices, err := m.LeafIndex(blocks[6]); err != nil || !reflect.DeepEqual(indices, []int{6}) {
+				t.Errorf("LeafIndex() got = %v, error = %v, want [6]", indices, err)
+			}
+
+			// The proofs are copies, modifying them does not alter the tree
+			proof, _ := m.ProofByIndex(0)
+			proof.Siblings[0][0] ^= 0xff
+			proof, _ = m.ProofByIndex(0)
+			if ok, err := m.Verify(blocks[0], proof); err != nil || !ok {
+				t.Errorf("Verify() after proof modification got = %v, error = %v", ok, err)
+			}
+
+			for _, idx := range []int{-1, len(blocks)} {
+				if _, err := m.ProofByIndex(idx); !errors.Is(err, ErrLeafIndexOutOfRange) {
+					t.Errorf("ProofByIndex(%d) error = %v, wantErr %v", idx, err, ErrLeafIndexOutOfRange)
+				}
+				if _, err := m.Leaf(idx); !errors.Is(err, ErrLeafIndexOutOfRange) {
+					t.Errorf("Leaf(%d) error = %v, wantErr %v", idx, err, ErrLeafIndexOutOfRange)
+				}
+			}
+			if _, err := m.LeafIndex(&DataBlock{Data: []byte("not a member")}); !errors.Is(err, ErrProofInvalidDataBlock) {
+				t.Errorf("LeafIndex() error = %v, wantErr %v", err, ErrProofInvalidDataBlock)
+			}
+			if _, err := m.LeafIndex(nil); !errors.Is(err, ErrDataBlockIsNil) {
+				t.Errorf("LeafIndex() error = %v, wantErr %v", err, ErrDataBlockIsNil)
+			}
+		})
+	}
+}
+
 func mockHashFunc(data []byte) ([]byte, error) {
 	sha256Func := sha256.New()
 	sha256Func.Write(data)
@@ -24,9 +24,12 @@ package merkletree
 
 import (
 	"bytes"
+	"context"
 	"errors"
+	gohash "hash"
 	"math/bits"
 	"runtime"
+	"sort"
 	"sync"
 
 	"github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
@@ -42,6 +45,13 @@ const (
 	ModeProofGenAndTreeBuild
 )
 
+const (
+	// rfc6962LeafPrefix is the domain separation prefix of the leaf hashes in RFC 6962 mode.
+	rfc6962LeafPrefix byte = 0x00
+	// rfc6962NodePrefix is the domain separation prefix of the internal node hashes in RFC 6962 mode.
+	rfc6962NodePrefix byte = 0x01
+)
+
 var (
 	// ErrInvalidNumOfDataBlocks is the error for an invalid number of data blocks.
 	ErrInvalidNumOfDataBlocks = errors.New("the number of data blocks must be greater than 1")
@@ -56,16 +66,62 @@ var (
 	ErrProofInvalidModeTreeNotBuilt = errors.New("merkle tree is not in built, could not generate proof by this method")
 	// ErrProofInvalidDataBlock is the error for an invalid data block in Proof() function.
 	ErrProofInvalidDataBlock = errors.New("data block is not a member of the merkle tree")
+	// ErrUpdateInvalidModeTreeNotBuilt is the error for an invalid mode in Update() functions.
+	// Update() functions require a built tree to recompute the path of the updated leaves.
+	ErrUpdateInvalidModeTreeNotBuilt = errors.New("merkle tree is not built, could not update leaves by this method")
+	// ErrUpdateMismatch is the error for a number of data blocks not matching the number of leaf indices to update.
+	ErrUpdateMismatch = errors.New("the number of data blocks does not match the number of leaf indices")
+	// ErrLeafIndexOutOfRange is the error for a leaf index not part of the merkle tree.
+	ErrLeafIndexOutOfRange = errors.New("leaf index is out of the merkle tree range")
+	// ErrHashSizeMismatch is the error for a hash function returning hashes of different sizes, or empty ones:
+	// the tree nodes are stored in buffers of fixed-width nodes.
+	ErrHashSizeMismatch = errors.New("the hashes of the merkle tree nodes must all be of the same non-zero size")
+	// ErrWorkerPoolClosed is the error for a tree computed in parallel with a shared worker pool already closed,
+	// see Config.WorkerPool.
+	ErrWorkerPoolClosed = pool.ErrPoolClosed
 )
 
 // workerArgs is used as the arguments for the worker functions when performing parallel computations.
 // Each worker function has its own dedicated argument struct embedded within workerArgs,
 // which eliminates the need for interface conversion overhead and provides clear separation of concerns.
 type workerArgs struct {
-	generateProofs   *workerArgsGenerateProofs
-	updateProofs     *workerArgsUpdateProofs
-	generateLeaves   *workerArgsGenerateLeaves
-	computeTreeNodes *workerArgsComputeTreeNodes
+	// ctx is the context of the parallel computation, it may be nil.
+	ctx                context.Context
+	generateProofs     *workerArgsGenerateProofs
+	updateProofs       *workerArgsUpdateProofs
+	generateLeaves     *workerArgsGenerateLeaves
+	computeTreeNodes   *workerArgsComputeTreeNodes
+	buildSparseSubtree *workerArgsBuildSparseSubtree
+	hashDiskPairs      *workerArgsHashDiskPairs
+}
+
+// WorkerPool is the goroutine pool running the parallel computations of the Merkle Trees.
+// It is safe for concurrent use by several trees, see Config.WorkerPool.
+type WorkerPool = pool.Pool[workerArgs, error]
+
+// NewWorkerPool creates a worker pool to be shared by Merkle Trees, with the given number of workers.
+// If numWorkers is less than 1, it will be set to the number of CPUs.
+func NewWorkerPool(numWorkers int) *WorkerPool {
+	return pool.NewPool[workerArgs, error](numWorkers, 0)
+}
+
+// acquireWorkerPool returns the worker pool for the parallel computations of the configuration,
+// defaulting its NumRoutines, and the function releasing the pool once the computations are done:
+// the shared pool of the configuration is left open, a pool created for the computations is closed.
+func acquireWorkerPool(config *Config) (*WorkerPool, func()) {
+	if config.NumRoutines <= 0 {
+		if config.WorkerPool != nil {
+			config.NumRoutines = config.WorkerPool.NumWorkers()
+		} else {
+			config.NumRoutines = runtime.NumCPU()
+		}
+	}
+	if config.WorkerPool != nil {
+		return config.WorkerPool, func() {}
+	}
+	// Task channel capacity is passed as 0, so use the default value: 2 * numWorkers.
+	wp := pool.NewPool[workerArgs, error](config.NumRoutines, 0)
+	return wp, wp.Close
 }
 
 // TypeConfigMode is the type in the Merkle Tree configuration indicating what operations are performed.
@@ -74,15 +130,27 @@ type TypeConfigMode int
 // TypeHashFunc is the signature of the hash functions used for Merkle Tree generation.
 type TypeHashFunc func([]byte) ([]byte, error)
 
-type typeConcatHashFunc func([]byte, []byte) []byte
+type typeConcatHashFunc func(dst []byte, b1 []byte, b2 []byte) []byte
 
 // Config is the configuration of Merkle Tree.
 type Config struct {
 	// Customizable hash function used for tree generation.
 	HashFunc TypeHashFunc
+	// Name of the hash algorithm, as registered in the hash package, used if no HashFunc is provided.
+	// The default hash function is used if both are unset.
+	// A provided HashFunc is only named by HashAlgorithm in the proof envelopes: the proofs of a tree whose
+	// custom HashFunc is not named have no envelope, and the hash algorithm of the envelopes verified with it
+	// is not checked.
+	HashAlgorithm string
 	// Number of goroutines run in parallel.
-	// If RunInParallel is true and NumRoutine is set to 0, use number of CPU as the number of goroutines.
+	// If RunInParallel is true and NumRoutine is set to 0, use number of CPU as the number of goroutines,
+	// or the number of workers of the WorkerPool if set.
 	NumRoutines int
+	// Worker pool shared by several trees for their parallel computations, if RunInParallel is true.
+	// If not set, a pool of NumRoutines workers is created and closed by each tree generation.
+	// The shared pool is never closed by the trees, it is up to the caller once no longer used:
+	// the parallel computations on a closed pool fail with ErrWorkerPoolClosed.
+	WorkerPool *WorkerPool
 	// Mode of the Merkle Tree generation.
 	Mode TypeConfigMode
 	// If RunInParallel is true, the generation runs in parallel, otherwise runs without parallelization.
@@ -93,6 +161,14 @@ type Config struct {
 	SortSiblingPairs bool
 	// If true, the leaf nodes are NOT hashed before being added to the Merkle Tree.
 	DisableLeafHashing bool
+	// If true, the tree follows the RFC 6962 (Certificate Transparency) Merkle Tree Hash definition:
+	// leaves are hashed with a 0x00 prefix, internal nodes with a 0x01 prefix, and the last node
+	// of an odd-length level is promoted to the upper level instead of being duplicated.
+	// When DisableLeafHashing is also set, the data blocks are expected to be RFC 6962 leaf hashes already.
+	RFC6962 bool
+	// hashFuncByName is set once HashFunc is initialized out of HashAlgorithm, or of the default hash algorithm,
+	// rather than provided.
+	hashFuncByName bool
 }
 
 // MerkleTree implements the Merkle Tree data structure.
@@ -104,15 +180,25 @@ type MerkleTree struct {
 	// leafMapMu is a mutex that protects concurrent access to the leafMap.
 	leafMapMu sync.Mutex
 	// wp is the worker pool used for parallel computation in the tree building process.
-	wp *pool.Pool[workerArgs, error]
+	wp *WorkerPool
+	// ctx is the context of the tree building process, only set while the tree is being generated.
+	ctx context.Context
 	// concatHashFunc is the function for concatenating two hashes.
 	// If SortSiblingPairs in Config is true, then the sibling pairs are first sorted and then concatenated,
 	// supporting the OpenZeppelin Merkle Tree protocol.
 	// Otherwise, the sibling pairs are concatenated directly.
 	concatHashFunc typeConcatHashFunc
-	// nodes contains the Merkle Tree's internal node structure.
+	// nodes contains the Merkle Tree's internal node structure, each level being stored in a flat buffer
+	// of nodeWidth bytes nodes. The first level is the one of the Leaves, it is not stored.
+	// The nodes duplicated to fix the odd length of a level, or promoted in RFC 6962 mode, are not stored either,
+	// see node().
 	// It is only available when the configuration mode is set to ModeTreeBuild or ModeProofGenAndTreeBuild.
-	nodes [][][]byte
+	nodes [][]byte
+	// nodeWidth is the size of the hashes of the tree nodes, 0 until known for a custom hash function.
+	nodeWidth int
+	// newDigest creates the digests hashing the tree nodes in place of the hash function of the configured
+	// hash algorithm, so that they are reused. It is nil if a custom hash function is configured.
+	newDigest hash.NewDigestFunc
 	// Root is the hash of the Merkle root node.
 	Root []byte
 	// Leaves are the hashes of the data blocks that form the Merkle Tree's leaves.
@@ -131,16 +217,26 @@ type MerkleTree struct {
 // Proof represents a Merkle Tree proof.
 type Proof struct {
 	Siblings [][]byte // Sibling nodes to the Merkle Tree path of the data block.
-	Path     uint32   // Path variable indicating whether the neighbor is on the left or right.
+	// Path variable indicating whether the neighbor is on the left or right.
+	// It is 0 for the proofs of more than 32 siblings, whose sides are then only derived from their Envelope.
+	Path uint32
+	// Envelope describes the tree of the proof, it is nil for the proofs of former versions
+	// and of the trees of a custom hash function without HashAlgorithm name.
+	Envelope *ProofEnvelope `json:",omitempty"`
 }
 
 // New generates a new Merkle Tree with the specified configuration and data blocks.
 func New(config *Config, blocks []IDataBlock) (m *MerkleTree, err error) {
-	// Check if there are enough data blocks to build the tree.
-	if len(blocks) <= 1 {
-		return nil, ErrInvalidNumOfDataBlocks
-	}
+	return NewWithContext(context.Background(), config, blocks)
+}
 
+// NewWithContext generates a new Merkle Tree with the specified configuration and data blocks,
+// the generation being aborted with the context error once the context is done.
+// The context only applies to the generation, not to the later operations on the tree.
+//
+// The root of a tree of a single data block is its leaf, the proof of the leaf having no sibling,
+// and the root of a tree of no data block is the EmptyRoot sentinel.
+func NewWithContext(ctx context.Context, config *Config, blocks []IDataBlock) (m *MerkleTree, err error) {
 	// Initialize the configuration if it is not provided.
 	if config == nil {
 		config = new(Config)
@@ -150,38 +246,37 @@ func New(config *Config, blocks []IDataBlock) (m *MerkleTree, err error) {
 	m = &MerkleTree{
 		Config:    *config,
 		NumLeaves: len(blocks),
-		Depth:     bits.Len(uint(len(blocks) - 1)),
+		Depth:     treeDepth(len(blocks)),
+		ctx:       ctx,
 	}
+	defer func(tree *MerkleTree) {
+		tree.ctx = nil
+	}(m)
 
 	// Initialize the hash function.
 	if m.HashFunc == nil {
-		if m.RunInParallel {
+		if err = m.initNodeDigest(); err != nil {
+			return nil, err
+		}
+		if m.RunInParallel && m.HashAlgorithm == "" {
 			// Use a concurrent safe hash function for parallel execution.
-			m.HashFunc = hash.DefaultHashFuncParallel
-		} else {
-			m.HashFunc = hash.DefaultHashFunc
+			m.HashFunc, m.hashFuncByName = hash.DefaultHashFuncParallel, true
+		} else if err = m.initHashFunc(); err != nil {
+			return nil, err
 		}
 	}
 
 	// Hash concatenation function initialization.
 	if m.concatHashFunc == nil {
-		if m.SortSiblingPairs {
-			m.concatHashFunc = concatSortHash
-		} else {
-			m.concatHashFunc = concatHash
-		}
+		m.concatHashFunc = concatHashFuncFor(&m.Config)
 	}
 
 	// Configure parallelization settings.
 	if m.RunInParallel {
-		// Set NumRoutines to the number of CPU cores if not specified or invalid.
-		if m.NumRoutines <= 0 {
-			m.NumRoutines = runtime.NumCPU()
-		}
-		// Initialize a wait group for parallel computation and generate leaves.
-		// Task channel capacity is passed as 0, so use the default value: 2 * numWorkers.
-		m.wp = pool.NewPool[workerArgs, error](m.NumRoutines, 0)
-		defer m.wp.Close()
+		// Acquire the worker pool, NumRoutines defaulting to the number of CPU cores or to the shared pool workers.
+		var releasePool func()
+		m.wp, releasePool = acquireWorkerPool(&m.Config)
+		defer releasePool()
 		if m.Leaves, err = m.generateLeavesInParallel(blocks); err != nil {
 			return nil, err
 		}
@@ -198,6 +293,14 @@ func New(config *Config, blocks []IDataBlock) (m *MerkleTree, err error) {
 		m.Mode = ModeProofGen
 	}
 
+	// Trees of a single leaf, or of none, have no nodes above the leaves.
+	if m.NumLeaves <= 1 {
+		if err = m.buildLeafTree(); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+
 	// Generate proofs in ModeProofGen.
 	if m.Mode == ModeProofGen {
 		err = m.generateProofs()
@@ -217,16 +320,10 @@ func New(config *Config, blocks []IDataBlock) (m *MerkleTree, err error) {
 		if err = m.buildTree(); err != nil {
 			return
 		}
-		m.initProofs()
-		if m.RunInParallel {
-			for i := 0; i < len(m.nodes); i++ {
-				m.updateProofsInParallel(m.nodes[i], len(m.nodes[i]), i)
-			}
+		if err = m.generateProofsFromNodes(); err != nil {
 			return
 		}
-		for i := 0; i < len(m.nodes); i++ {
-			m.updateProofs(m.nodes[i], len(m.nodes[i]), i)
-		}
+		err = ctxErr(m.ctx)
 		return
 	}
 
@@ -234,31 +331,301 @@ func New(config *Config, blocks []IDataBlock) (m *MerkleTree, err error) {
 	return nil, ErrInvalidConfigMode
 }
 
-// concatSortHash concatenates two byte slices, b1 and b2, in a sorted order.
-func concatHash(b1 []byte, b2 []byte) []byte {
-	result := make([]byte, len(b1)+len(b2))
-	copy(result, b1)
-	copy(result[len(b1):], b2)
-	return result
+// buildLeafTree builds a tree of a single leaf, or of none, in any configuration mode: the root of a single
+// leaf tree is the leaf itself, its proof having no sibling, and the root of the empty tree is EmptyRoot.
+func (m *MerkleTree) buildLeafTree() (err error) {
+	if m.Mode != ModeProofGen && m.Mode != ModeTreeBuild && m.Mode != ModeProofGenAndTreeBuild {
+		return ErrInvalidConfigMode
+	}
+	if m.NumLeaves == 0 {
+		if m.Root, err = m.HashFunc(nil); err != nil {
+			return err
+		}
+	} else {
+		m.Root = append([]byte{}, m.Leaves[0]...)
+	}
+	m.Proofs = nil
+	if m.Mode != ModeTreeBuild {
+		m.initProofs()
+	}
+	if m.Mode != ModeProofGen {
+		m.nodes = make([][]byte, 0)
+		m.leafMapMu.Lock()
+		m.leafMap = make(map[string]int, m.NumLeaves)
+		for i, leaf := range m.Leaves {
+			m.leafMap[string(leaf)] = i
+		}
+		m.leafMapMu.Unlock()
+	}
+	return nil
+}
+
+// treeDepth returns the depth of a tree of the specified number of leaves, 0 for a single leaf or none.
+func treeDepth(numLeaves int) int {
+	if numLeaves <= 1 {
+		return 0
+	}
+	return bits.Len(uint(numLeaves - 1))
+}
+
+// EmptyRoot returns the root of the Merkle Tree of no data block, the hash of empty data as defined
+// by RFC 6962, for the hash function of the configuration.
+func EmptyRoot(config *Config) ([]byte, error) {
+	if config == nil {
+		config = new(Config)
+	}
+	if err := config.initHashFunc(); err != nil {
+		return nil, err
+	}
+	return config.HashFunc(nil)
+}
+
+// initHashFunc initializes the hash function of the configuration if not provided,
+// out of the configured hash algorithm name or the default hash function.
+// The hash functions of the registered hash algorithms are safe for concurrent use.
+func (c *Config) initHashFunc() (err error) {
+	if c.HashFunc != nil {
+		return nil
+	}
+	if c.HashAlgorithm == "" {
+		c.HashFunc, c.hashFuncByName = hash.DefaultHashFunc, true
+		return nil
+	}
+	if c.HashFunc, err = hash.HashFuncByName(c.HashAlgorithm); err != nil {
+		return err
+	}
+	c.hashFuncByName = true
+	return nil
+}
+
+// initNodeDigest initializes the constructor of the digests hashing the tree nodes, of the configured
+// hash algorithm, along with the size of the nodes.
+func (m *MerkleTree) initNodeDigest() (err error) {
+	if m.newDigest, err = hash.NewDigestFuncByName(m.HashAlgorithm); err != nil {
+		return err
+	}
+	m.nodeWidth = m.newDigest().Size()
+	return nil
+}
+
+// nodeHasher hashes the tree nodes, reusing its concatenation buffer and hash digest across the calls.
+// It is not safe for concurrent use, each goroutine requires its own.
+type nodeHasher struct {
+	hashFunc       TypeHashFunc
+	concatHashFunc typeConcatHashFunc
+	// digest is used in place of hashFunc if set.
+	digest  gohash.Hash
+	scratch []byte
+}
+
+// newNodeHasher creates a hasher of the tree nodes, using a digest created by newDigest if provided.
+func newNodeHasher(hashFunc TypeHashFunc, concatHashFunc typeConcatHashFunc, newDigest hash.NewDigestFunc) *nodeHasher {
+	h := &nodeHasher{
+		hashFunc:       hashFunc,
+		concatHashFunc: concatHashFunc,
+	}
+	if newDigest != nil {
+		h.digest = newDigest()
+	}
+	return h
+}
+
+// newNodeHasher creates a hasher of the tree nodes.
+func (m *MerkleTree) newNodeHasher() *nodeHasher {
+	return newNodeHasher(m.HashFunc, m.concatHashFunc, m.newDigest)
+}
+
+// hash appends the hash of the data to dst. A nil dst results in a new slice.
+func (h *nodeHasher) hash(dst []byte, data []byte) ([]byte, error) {
+	if h.digest == nil {
+		sum, err := h.hashFunc(data)
+		if err != nil || dst == nil {
+			return sum, err
+		}
+		return append(dst, sum...), nil
+	}
+	h.digest.Reset()
+	h.digest.Write(data)
+	return h.digest.Sum(dst), nil
+}
+
+// hashPair appends the hash of the concatenation of two nodes to dst. A nil dst results in a new slice.
+func (h *nodeHasher) hashPair(dst []byte, b1 []byte, b2 []byte) ([]byte, error) {
+	h.scratch = h.concatHashFunc(h.scratch[:0], b1, b2)
+	return h.hash(dst, h.scratch)
+}
+
+// dataBlockToLeaf generates the leaf from the data block, as dataBlockToLeaf does, the leaf hash
+// being appended to dst when hashed with the digest of the hasher.
+func (h *nodeHasher) dataBlockToLeaf(dst []byte, block IDataBlock, config *Config) ([]byte, error) {
+	if h.digest == nil || config.DisableLeafHashing {
+		return dataBlockToLeaf(block, config)
+	}
+	blockBytes, err := block.Serialize()
+	if err != nil {
+		return nil, err
+	}
+	if config.RFC6962 {
+		h.scratch = append(append(h.scratch[:0], rfc6962LeafPrefix), blockBytes...)
+		blockBytes = h.scratch
+	}
+	return h.hash(dst, blockBytes)
+}
+
+// nodeSlot returns the empty slice, of a node capacity, at the specified index of a level buffer,
+// for a node to be appended in place. It is nil for an empty buffer, e.g. if the size of the nodes is not known.
+func nodeSlot(buffer []byte, idx, width int) []byte {
+	if len(buffer) == 0 {
+		return nil
+	}
+	offset := idx * width
+	return buffer[offset : offset : offset+width]
+}
+
+// concatHash appends the concatenation of two byte slices, b1 and b2, to dst.
+// A nil dst results in a new slice, a scratch buffer can be provided to be reused.
+func concatHash(dst []byte, b1 []byte, b2 []byte) []byte {
+	if dst == nil {
+		dst = make([]byte, 0, len(b1)+len(b2))
+	}
+	dst = append(dst, b1...)
+	return append(dst, b2...)
 }
 
-// concatSortHash concatenates two byte slices, b1 and b2, in a sorted order.
+// concatSortHash appends the concatenation of two byte slices, b1 and b2, in a sorted order to dst.
 // The function ensures that the smaller byte slice (in terms of lexicographic order)
 // is placed before the larger one. This is used for compatibility with OpenZeppelin's
 // Merkle Proof verification implementation.
-func concatSortHash(b1 []byte, b2 []byte) []byte {
+func concatSortHash(dst []byte, b1 []byte, b2 []byte) []byte {
 	if bytes.Compare(b1, b2) < 0 {
-		return concatHash(b1, b2)
+		return concatHash(dst, b1, b2)
 	}
-	return concatHash(b2, b1)
+	return concatHash(dst, b2, b1)
+}
+
+// concatHashRFC6962 appends the concatenation of two byte slices, b1 and b2, after the RFC 6962
+// internal node prefix to dst.
+func concatHashRFC6962(dst []byte, b1 []byte, b2 []byte) []byte {
+	if dst == nil {
+		dst = make([]byte, 0, 1+len(b1)+len(b2))
+	}
+	dst = append(dst, rfc6962NodePrefix)
+	dst = append(dst, b1...)
+	return append(dst, b2...)
+}
+
+// concatSortHashRFC6962 appends the concatenation of two byte slices, b1 and b2, in a sorted order
+// after the RFC 6962 internal node prefix to dst.
+func concatSortHashRFC6962(dst []byte, b1 []byte, b2 []byte) []byte {
+	if bytes.Compare(b1, b2) < 0 {
+		return concatHashRFC6962(dst, b1, b2)
+	}
+	return concatHashRFC6962(dst, b2, b1)
+}
+
+// concatHashFuncFor returns the function for concatenating two hashes matching the configuration.
+func concatHashFuncFor(config *Config) typeConcatHashFunc {
+	switch {
+	case config.RFC6962 && config.SortSiblingPairs:
+		return concatSortHashRFC6962
+	case config.RFC6962:
+		return concatHashRFC6962
+	case config.SortSiblingPairs:
+		return concatSortHash
+	default:
+		return concatHash
+	}
+}
+
+// generateProofsFromNodes generates the proofs of all the leaves out of the built tree nodes.
+func (m *MerkleTree) generateProofsFromNodes() error {
+	m.initProofs()
+	for i := 0; i < m.Depth; i++ {
+		level := m.levelNodes(i)
+		if !m.RunInParallel {
+			m.updateProofs(level, len(level), i)
+		} else if err := m.updateProofsInParallel(level, len(level), i); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// node returns the node at the specified index of a level, sliced out of the level buffer.
+// The nodes duplicated to fix the odd length of a level, or promoted in RFC 6962 mode, are the ones
+// they are a copy of.
+func (m *MerkleTree) node(level, idx int) []byte {
+	level, idx = m.nodePosition(level, idx)
+	if level == 0 {
+		return m.Leaves[idx]
+	}
+	offset := idx * m.nodeWidth
+	return m.nodes[level][offset : offset+m.nodeWidth : offset+m.nodeWidth]
+}
+
+// nodePosition returns the position of the node stored for the node at the specified level and index,
+// the promoted and duplicated nodes being stored as the node they are a copy of.
+func (m *MerkleTree) nodePosition(level, idx int) (int, int) {
+	if m.RFC6962 {
+		// A promoted node is the last node of the odd-length level below.
+		for level > 0 && idx<<1+1 == levelLength(m.NumLeaves, level-1) {
+			level--
+			idx <<= 1
+		}
+	} else if last := levelLength(m.NumLeaves, level) - 1; idx > last {
+		idx = last
+	}
+	return level, idx
+}
+
+// levelNodes returns the nodes of a level, including the node duplicated to fix its odd length.
+func (m *MerkleTree) levelNodes(level int) [][]byte {
+	length := levelLength(m.NumLeaves, level)
+	if length&1 == 1 && !m.RFC6962 {
+		length++
+	}
+	nodes := make([][]byte, length)
+	for i := range nodes {
+		nodes[i] = m.node(level, i)
+	}
+	return nodes
+}
+
+// hashedLevelLength returns the number of stored nodes of a level above the leaves, i.e. the hashed ones.
+func (m *MerkleTree) hashedLevelLength(level int) int {
+	if m.RFC6962 {
+		return levelLength(m.NumLeaves, level-1) >> 1
+	}
+	return levelLength(m.NumLeaves, level)
+}
+
+// cloneNodes copies the nodes into a single buffer, detaching them from the tree levels updated in place.
+func cloneNodes(nodes [][]byte) [][]byte {
+	size := 0
+	for _, node := range nodes {
+		size += len(node)
+	}
+	var (
+		buffer = make([]byte, 0, size)
+		clones = make([][]byte, len(nodes))
+	)
+	for i, node := range nodes {
+		buffer = append(buffer, node...)
+		clones[i] = buffer[len(buffer)-len(node) : len(buffer) : len(buffer)]
+	}
+	return clones
 }
 
 // initProofs initializes the MerkleTree's Proofs with the appropriate size and depth.
 func (m *MerkleTree) initProofs() {
 	m.Proofs = make([]*Proof, m.NumLeaves)
+	envelopes := newProofEnvelopes(&m.Config, m.NumLeaves)
 	for i := 0; i < m.NumLeaves; i++ {
 		m.Proofs[i] = new(Proof)
 		m.Proofs[i].Siblings = make([][]byte, 0, m.Depth)
+		if envelopes != nil {
+			m.Proofs[i].Envelope = &envelopes[i]
+		}
 	}
 }
 
@@ -275,21 +642,34 @@ func (m *MerkleTree) generateProofs() error {
 		return m.generateProofsInParallel(buffer, bufferLength)
 	}
 
-	m.updateProofs(buffer, m.NumLeaves, 0)
-	var err error
+	m.updateProofs(buffer, bufferLength, 0)
+	var (
+		hasher = m.newNodeHasher()
+		err    error
+	)
 	for step := 1; step < m.Depth; step++ {
+		if err = ctxErr(m.ctx); err != nil {
+			return err
+		}
+		// The nodes of the level are hashed into a single buffer, referenced by the proofs.
+		nodes := make([]byte, (bufferLength>>1)*m.nodeWidth)
 		for idx := 0; idx < bufferLength; idx += 2 {
-			buffer[idx>>1], err = m.HashFunc(m.concatHashFunc(buffer[idx], buffer[idx+1]))
+			// The last node of an odd-length level is promoted in RFC 6962 mode.
+			if idx+1 == bufferLength {
+				buffer[idx>>1] = buffer[idx]
+				continue
+			}
+			buffer[idx>>1], err = hasher.hashPair(nodeSlot(nodes, idx>>1, m.nodeWidth), buffer[idx], buffer[idx+1])
 			if err != nil {
 				return err
 			}
 		}
-		bufferLength >>= 1
+		bufferLength = (bufferLength + 1) >> 1
 		buffer, bufferLength = m.fixOddLength(buffer, bufferLength)
 		m.updateProofs(buffer, bufferLength, step)
 	}
 
-	m.Root, err = m.HashFunc(m.concatHashFunc(buffer[0], buffer[1]))
+	m.Root, err = hasher.hashPair(nil, buffer[0], buffer[1])
 	return err
 }
 
@@ -297,8 +677,11 @@ func (m *MerkleTree) generateProofs() error {
 type workerArgsGenerateProofs struct {
 	hashFunc       TypeHashFunc
 	concatHashFunc typeConcatHashFunc
+	newDigest      hash.NewDigestFunc
 	buffer         [][]byte
 	tempBuffer     [][]byte
+	nodes          []byte
+	nodeWidth      int
 	startIdx       int
 	bufferLength   int
 	numRoutines    int
@@ -309,16 +692,24 @@ type workerArgsGenerateProofs struct {
 func workerGenerateProofs(args workerArgs) error {
 	chosenArgs := args.generateProofs
 	var (
-		hashFunc     = chosenArgs.hashFunc
-		concatFunc   = chosenArgs.concatHashFunc
+		hasher       = newNodeHasher(chosenArgs.hashFunc, chosenArgs.concatHashFunc, chosenArgs.newDigest)
 		buffer       = chosenArgs.buffer
 		tempBuffer   = chosenArgs.tempBuffer
+		nodes        = chosenArgs.nodes
+		nodeWidth    = chosenArgs.nodeWidth
 		startIdx     = chosenArgs.startIdx
 		bufferLength = chosenArgs.bufferLength
 		numRoutines  = chosenArgs.numRoutines
 	)
 	for i := startIdx; i < bufferLength; i += numRoutines << 1 {
-		newHash, err := hashFunc(concatFunc(buffer[i], buffer[i+1]))
+		if err := ctxErr(args.ctx); err != nil {
+			return err
+		}
+		if i+1 == bufferLength {
+			tempBuffer[i>>1] = buffer[i]
+			continue
+		}
+		newHash, err := hasher.hashPair(nodeSlot(nodes, i>>1, nodeWidth), buffer[i], buffer[i+1])
 		if err != nil {
 			return err
 		}
@@ -329,8 +720,10 @@ func workerGenerateProofs(args workerArgs) error {
 
 // generateProofsInParallel generates proofs concurrently for the MerkleTree.
 func (m *MerkleTree) generateProofsInParallel(buffer [][]byte, bufferLength int) (err error) {
-	tempBuffer := make([][]byte, bufferLength>>1)
-	m.updateProofsInParallel(buffer, m.NumLeaves, 0)
+	tempBuffer := make([][]byte, (bufferLength+1)>>1)
+	if err = m.updateProofsInParallel(buffer, bufferLength, 0); err != nil {
+		return
+	}
 	numRoutines := m.NumRoutines
 	for step := 1; step < m.Depth; step++ {
 		// Limit the number of workers to the previous level length.
@@ -339,14 +732,19 @@ func (m *MerkleTree) generateProofsInParallel(buffer [][]byte, bufferLength int)
 		}
 
 		// Create the list of arguments for the worker pool.
+		// The nodes of the level are hashed into a single buffer, referenced by the proofs.
+		nodes := make([]byte, (bufferLength>>1)*m.nodeWidth)
 		argList := make([]workerArgs, numRoutines)
 		for i := 0; i < numRoutines; i++ {
 			argList[i] = workerArgs{
 				generateProofs: &workerArgsGenerateProofs{
 					hashFunc:       m.HashFunc,
 					concatHashFunc: m.concatHashFunc,
+					newDigest:      m.newDigest,
 					buffer:         buffer,
 					tempBuffer:     tempBuffer,
+					nodes:          nodes,
+					nodeWidth:      m.nodeWidth,
 					startIdx:       i << 1,
 					bufferLength:   bufferLength,
 					numRoutines:    numRoutines,
@@ -355,33 +753,33 @@ func (m *MerkleTree) generateProofsInParallel(buffer [][]byte, bufferLength int)
 		}
 
 		// Execute proof generation concurrently using the worker pool.
-		errList := m.wp.Map(workerGenerateProofs, argList)
-		for _, err = range errList {
-			if err != nil {
-				return
-			}
+		if err = m.mapInParallel(workerGenerateProofs, argList); err != nil {
+			return
 		}
 
 		// Swap the buffers for the next iteration.
 		buffer, tempBuffer = tempBuffer, buffer
-		bufferLength >>= 1
+		bufferLength = (bufferLength + 1) >> 1
 
 		// Fix the buffer if it has an odd number of elements.
 		buffer, bufferLength = m.fixOddLength(buffer, bufferLength)
 
 		// Update the proofs with the new buffer.
-		m.updateProofsInParallel(buffer, bufferLength, step)
+		if err = m.updateProofsInParallel(buffer, bufferLength, step); err != nil {
+			return
+		}
 	}
 
 	// Compute the root hash of the Merkle tree.
-	m.Root, err = m.HashFunc(m.concatHashFunc(buffer[0], buffer[1]))
+	m.Root, err = m.newNodeHasher().hashPair(nil, buffer[0], buffer[1])
 	return
 }
 
 // fixOddLength adjusts the buffer for odd-length slices by appending a node.
+// In RFC 6962 mode, the last node is promoted instead and the buffer is left unchanged.
 func (m *MerkleTree) fixOddLength(buffer [][]byte, bufferLength int) ([][]byte, int) {
 	// If the buffer length is even, no adjustment is needed.
-	if bufferLength&1 == 0 {
+	if bufferLength&1 == 0 || m.RFC6962 {
 		return buffer, bufferLength
 	}
 
@@ -402,7 +800,7 @@ func (m *MerkleTree) fixOddLength(buffer [][]byte, bufferLength int) ([][]byte,
 func (m *MerkleTree) updateProofs(buffer [][]byte, bufferLength, step int) {
 	batch := 1 << step
 	for i := 0; i < bufferLength; i += 2 {
-		m.updateProofPairs(buffer, i, batch, step)
+		m.updateProofPairs(buffer, bufferLength, i, batch)
 	}
 }
 
@@ -412,7 +810,6 @@ type workerArgsUpdateProofs struct {
 	buffer       [][]byte
 	startIdx     int
 	batch        int
-	step         int
 	bufferLength int
 	numRoutines  int
 }
@@ -425,19 +822,18 @@ func workerUpdateProofs(args workerArgs) error {
 		buffer       = chosenArgs.buffer
 		startIdx     = chosenArgs.startIdx
 		batch        = chosenArgs.batch
-		step         = chosenArgs.step
 		bufferLength = chosenArgs.bufferLength
 		numRoutines  = chosenArgs.numRoutines
 	)
 	for i := startIdx; i < bufferLength; i += numRoutines << 1 {
-		tree.updateProofPairs(buffer, i, batch, step)
+		tree.updateProofPairs(buffer, bufferLength, i, batch)
 	}
 	// return the nil error to be compatible with the worker type
 	return nil
 }
 
 // updateProofsInParallel updates proofs concurrently for the Merkle Tree.
-func (m *MerkleTree) updateProofsInParallel(buffer [][]byte, bufferLength, step int) {
+func (m *MerkleTree) updateProofsInParallel(buffer [][]byte, bufferLength, step int) error {
 	batch := 1 << step
 	numRoutines := m.NumRoutines
 	if numRoutines > bufferLength {
@@ -451,30 +847,51 @@ func (m *MerkleTree) updateProofsInParallel(buffer [][]byte, bufferLength, step
 				buffer:       buffer,
 				startIdx:     i << 1,
 				batch:        batch,
-				step:         step,
 				bufferLength: bufferLength,
 				numRoutines:  numRoutines,
 			},
 		}
 	}
-	m.wp.Map(workerUpdateProofs, argList)
+	return m.mapInParallel(workerUpdateProofs, argList)
 }
 
 // updateProofPairs updates the proofs in the Merkle Tree in pairs.
-func (m *MerkleTree) updateProofPairs(buffer [][]byte, idx, batch, step int) {
+// The path bit of a sibling is set at its position in the proof, since levels where the node
+// is promoted in RFC 6962 mode do not provide any sibling.
+func (m *MerkleTree) updateProofPairs(buffer [][]byte, bufferLength, idx, batch int) {
+	// The last node of an odd-length level has no sibling in RFC 6962 mode.
+	if idx+1 >= bufferLength {
+		return
+	}
 	start := idx * batch
 	end := min(start+batch, len(m.Proofs))
 	for i := start; i < end; i++ {
-		m.Proofs[i].Path += 1 << step
+		m.Proofs[i].Path = proofPathWithSibling(m.Proofs[i].Path, len(m.Proofs[i].Siblings), true)
 		m.Proofs[i].Siblings = append(m.Proofs[i].Siblings, buffer[idx+1])
 	}
 	start += batch
 	end = min(start+batch, len(m.Proofs))
 	for i := start; i < end; i++ {
+		m.Proofs[i].Path = proofPathWithSibling(m.Proofs[i].Path, len(m.Proofs[i].Siblings), false)
 		m.Proofs[i].Siblings = append(m.Proofs[i].Siblings, buffer[idx])
 	}
 }
 
+// maxProofPathSiblings is the max number of siblings of a proof whose sides are encoded in its Path.
+const maxProofPathSiblings = 32
+
+// proofPathWithSibling returns the Path of a proof once a sibling is appended to its numSiblings siblings,
+// on the right side if set. The Path is 0 once the proof has more than maxProofPathSiblings siblings.
+func proofPathWithSibling(path uint32, numSiblings int, right bool) uint32 {
+	if numSiblings >= maxProofPathSiblings {
+		return 0
+	}
+	if right {
+		path |= 1 << numSiblings
+	}
+	return path
+}
+
 func min(a, b int) int {
 	if a < b {
 		return a
@@ -482,22 +899,58 @@ func min(a, b int) int {
 	return b
 }
 
+// ctxErr returns the error of the context if it is done, nil for a nil context.
+func ctxErr(ctx context.Context) error {
+	if ctx == nil {
+		return nil
+	}
+	return ctx.Err()
+}
+
+// mapInParallel executes the worker function for each of the arguments using the worker pool,
+// and returns the first error. The remaining tasks are skipped once an error occurs or the tree context is done.
+func (m *MerkleTree) mapInParallel(handler func(workerArgs) error, argList []workerArgs) error {
+	ctx := m.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	_, err := m.wp.MapCtx(ctx, func(ctx context.Context, args workerArgs) error {
+		args.ctx = ctx
+		return handler(args)
+	}, argList)
+	return err
+}
+
 // generateLeaves generates the leaves slice from the data blocks.
 func (m *MerkleTree) generateLeaves(blocks []IDataBlock) ([][]byte, error) {
 	var (
 		leaves = make([][]byte, m.NumLeaves)
+		buffer = m.newLeavesBuffer()
+		hasher = m.newNodeHasher()
 		err    error
 	)
 	for i := 0; i < m.NumLeaves; i++ {
-		if leaves[i], err = dataBlockToLeaf(blocks[i], &m.Config); err != nil {
+		if err = ctxErr(m.ctx); err != nil {
+			return nil, err
+		}
+		if leaves[i], err = hasher.dataBlockToLeaf(nodeSlot(buffer, i, m.nodeWidth), blocks[i], &m.Config); err != nil {
 			return nil, err
 		}
 	}
 	return leaves, nil
 }
 
+// newLeavesBuffer returns the buffer the leaves are hashed into, nil if they are not hashed with a digest.
+func (m *MerkleTree) newLeavesBuffer() []byte {
+	if m.newDigest == nil || m.DisableLeafHashing {
+		return nil
+	}
+	return make([]byte, m.NumLeaves*m.nodeWidth)
+}
+
 // dataBlockToLeaf generates the leaf from the data block.
 // If the leaf hashing is disabled, the data block is returned as the leaf.
+// In RFC 6962 mode, the data block is hashed with the leaf prefix.
 func dataBlockToLeaf(block IDataBlock, config *Config) ([]byte, error) {
 	blockBytes, err := block.Serialize()
 	if err != nil {
@@ -509,14 +962,23 @@ func dataBlockToLeaf(block IDataBlock, config *Config) ([]byte, error) {
 		copy(leaf, blockBytes)
 		return leaf, nil
 	}
+	if config.RFC6962 {
+		prefixed := make([]byte, 1+len(blockBytes))
+		prefixed[0] = rfc6962LeafPrefix
+		copy(prefixed[1:], blockBytes)
+		return config.HashFunc(prefixed)
+	}
 	return config.HashFunc(blockBytes)
 }
 
 // workerArgsGenerateLeaves contains arguments for the workerGenerateLeaves function.
 type workerArgsGenerateLeaves struct {
 	config      *Config
+	newDigest   hash.NewDigestFunc
 	dataBlocks  []IDataBlock
 	leaves      [][]byte
+	buffer      []byte
+	nodeWidth   int
 	startIdx    int
 	lenLeaves   int
 	numRoutines int
@@ -527,15 +989,21 @@ func workerGenerateLeaves(args workerArgs) error {
 	chosenArgs := args.generateLeaves
 	var (
 		config      = chosenArgs.config
+		hasher      = newNodeHasher(config.HashFunc, nil, chosenArgs.newDigest)
 		blocks      = chosenArgs.dataBlocks
 		leaves      = chosenArgs.leaves
+		buffer      = chosenArgs.buffer
+		nodeWidth   = chosenArgs.nodeWidth
 		start       = chosenArgs.startIdx
 		lenLeaves   = chosenArgs.lenLeaves
 		numRoutines = chosenArgs.numRoutines
 	)
 	var err error
 	for i := start; i < lenLeaves; i += numRoutines {
-		if leaves[i], err = dataBlockToLeaf(blocks[i], config); err != nil {
+		if err = ctxErr(args.ctx); err != nil {
+			return err
+		}
+		if leaves[i], err = hasher.dataBlockToLeaf(nodeSlot(buffer, i, nodeWidth), blocks[i], config); err != nil {
 			return err
 		}
 	}
@@ -547,6 +1015,7 @@ func (m *MerkleTree) generateLeavesInParallel(blocks []IDataBlock) ([][]byte, er
 	var (
 		lenLeaves   = len(blocks)
 		leaves      = make([][]byte, lenLeaves)
+		buffer      = m.newLeavesBuffer()
 		numRoutines = m.NumRoutines
 	)
 	if numRoutines > lenLeaves {
@@ -557,26 +1026,26 @@ func (m *MerkleTree) generateLeavesInParallel(blocks []IDataBlock) ([][]byte, er
 		argList[i] = workerArgs{
 			generateLeaves: &workerArgsGenerateLeaves{
 				config:      &m.Config,
+				newDigest:   m.newDigest,
 				dataBlocks:  blocks,
 				leaves:      leaves,
+				buffer:      buffer,
+				nodeWidth:   m.nodeWidth,
 				startIdx:    i,
 				lenLeaves:   lenLeaves,
 				numRoutines: numRoutines,
 			},
 		}
 	}
-	errList := m.wp.Map(workerGenerateLeaves, argList)
-	for _, err := range errList {
-		if err != nil {
-			return nil, err
-		}
+	if err := m.mapInParallel(workerGenerateLeaves, argList); err != nil {
+		return nil, err
 	}
 	return leaves, nil
 }
 
 // buildTree builds the Merkle Tree.
 func (m *MerkleTree) buildTree() (err error) {
-	finishMap := make(chan struct{})
+	finishMap := make(chan struct{}, 1)
 	go func() {
 		m.leafMapMu.Lock()
 		defer m.leafMapMu.Unlock()
@@ -585,106 +1054,434 @@ func (m *MerkleTree) buildTree() (err error) {
 		}
 		finishMap <- struct{}{} // empty channel to serve as a wait group for map generation
 	}()
-	m.nodes = make([][][]byte, m.Depth)
-	m.nodes[0] = make([][]byte, m.NumLeaves)
-	copy(m.nodes[0], m.Leaves)
-	var bufferLength int
-	m.nodes[0], bufferLength = m.fixOddLength(m.nodes[0], m.NumLeaves)
-	if m.RunInParallel {
-		if err := m.computeTreeNodesInParallel(bufferLength); err != nil {
-			return err
-		}
+	m.nodes = make([][]byte, m.Depth)
+	if err = m.computeTreeNodes(0, 0); err != nil {
+		return
 	}
-	for i := 0; i < m.Depth-1; i++ {
-		m.nodes[i+1] = make([][]byte, bufferLength>>1)
-		for j := 0; j < bufferLength; j += 2 {
-			if m.nodes[i+1][j>>1], err = m.HashFunc(
-				m.concatHashFunc(m.nodes[i][j], m.nodes[i][j+1]),
-			); err != nil {
+	<-finishMap
+	return
+}
+
+// computeTreeNodes computes the nodes of the levels above the leaves, then the Merkle root.
+// The nodes whose subtree only has leaves before firstLeaf are kept, unless their level is not part
+// of a tree of depth oldDepth: the right edge of the tree is computed when leaves are appended.
+func (m *MerkleTree) computeTreeNodes(firstLeaf, oldDepth int) (err error) {
+	hasher := m.newNodeHasher()
+	start := firstLeaf
+	for level := 1; level < m.Depth; level++ {
+		if err = ctxErr(m.ctx); err != nil {
+			return
+		}
+		// Index of the first changed node of the level.
+		// Levels which did not exist before are fully computed.
+		start >>= 1
+		if level >= oldDepth {
+			start = 0
+		}
+		if m.nodeWidth == 0 {
+			// The size of the nodes is the one of the first hash of a custom hash function.
+			var node []byte
+			if node, err = hasher.hashPair(nil, m.node(level-1, 0), m.node(level-1, 1)); err != nil {
+				return
+			}
+			if len(node) == 0 {
+				return ErrHashSizeMismatch
+			}
+			m.nodes[level], m.nodeWidth, start = node, len(node), 1
+		}
+		length := m.hashedLevelLength(level)
+		m.nodes[level] = append(m.nodes[level][:start*m.nodeWidth], make([]byte, (length-start)*m.nodeWidth)...)
+		if m.RunInParallel {
+			if err = m.computeTreeNodesInParallel(level, start, length); err != nil {
+				return
+			}
+			continue
+		}
+		for idx := start; idx < length; idx++ {
+			if err = m.hashNode(hasher, level, idx); err != nil {
 				return
 			}
 		}
-		m.nodes[i+1], bufferLength = m.fixOddLength(m.nodes[i+1], len(m.nodes[i+1]))
-	}
-	if m.Root, err = m.HashFunc(m.concatHashFunc(
-		m.nodes[m.Depth-1][0], m.nodes[m.Depth-1][1],
-	)); err != nil {
-		return
 	}
-	<-finishMap
+	m.Root, err = hasher.hashPair(nil, m.node(m.Depth-1, 0), m.node(m.Depth-1, 1))
 	return
 }
 
+// hashNode computes the node at the specified index of a level, out of its children, in place in the level buffer.
+func (m *MerkleTree) hashNode(hasher *nodeHasher, level, idx int) error {
+	node, err := hasher.hashPair(nodeSlot(m.nodes[level], idx, m.nodeWidth),
+		m.node(level-1, idx<<1), m.node(level-1, idx<<1+1))
+	if err != nil {
+		return err
+	}
+	if len(node) != m.nodeWidth {
+		return ErrHashSizeMismatch
+	}
+	return nil
+}
+
 // workerArgsComputeTreeNodes contains arguments for the workerComputeTreeNodes function.
 type workerArgsComputeTreeNodes struct {
-	tree         *MerkleTree
-	startIdx     int
-	bufferLength int
-	numRoutines  int
-	depth        int
+	tree        *MerkleTree
+	startIdx    int
+	endIdx      int
+	numRoutines int
+	level       int
 }
 
 // workerBuildTree is the worker function that builds the Merkle tree in parallel.
 func workerBuildTree(args workerArgs) error {
 	chosenArgs := args.computeTreeNodes
 	var (
-		tree         = chosenArgs.tree
-		start        = chosenArgs.startIdx
-		bufferLength = chosenArgs.bufferLength
-		numRoutines  = chosenArgs.numRoutines
-		depth        = chosenArgs.depth
+		tree        = chosenArgs.tree
+		hasher      = tree.newNodeHasher()
+		start       = chosenArgs.startIdx
+		end         = chosenArgs.endIdx
+		numRoutines = chosenArgs.numRoutines
+		level       = chosenArgs.level
 	)
-	for i := start; i < bufferLength; i += numRoutines << 1 {
-		newHash, err := tree.HashFunc(tree.concatHashFunc(
-			tree.nodes[depth][i], tree.nodes[depth][i+1],
-		))
-		if err != nil {
+	for i := start; i < end; i += numRoutines {
+		if err := ctxErr(args.ctx); err != nil {
+			return err
+		}
+		if err := tree.hashNode(hasher, level, i); err != nil {
 			return err
 		}
-		tree.nodes[depth+1][i>>1] = newHash
 	}
 	return nil
 }
 
-// computeTreeNodesInParallel computes the tree nodes in parallel.
-func (m *MerkleTree) computeTreeNodesInParallel(bufferLength int) error {
-	for i := 0; i < m.Depth-1; i++ {
-		m.nodes[i+1] = make([][]byte, bufferLength>>1)
-		numRoutines := m.NumRoutines
-		if numRoutines > bufferLength {
-			numRoutines = bufferLength
+// computeTreeNodesInParallel computes the nodes of a level from index start to end in parallel.
+func (m *MerkleTree) computeTreeNodesInParallel(level, start, end int) error {
+	numRoutines := m.NumRoutines
+	if numRoutines > end-start {
+		numRoutines = end - start
+	}
+	argList := make([]workerArgs, numRoutines)
+	for j := 0; j < numRoutines; j++ {
+		argList[j] = workerArgs{
+			computeTreeNodes: &workerArgsComputeTreeNodes{
+				tree:        m,
+				startIdx:    start + j,
+				endIdx:      end,
+				numRoutines: numRoutines,
+				level:       level,
+			},
 		}
-		argList := make([]workerArgs, numRoutines)
-		for j := 0; j < numRoutines; j++ {
-			argList[j] = workerArgs{
-				computeTreeNodes: &workerArgsComputeTreeNodes{
-					tree:         m,
-					startIdx:     j << 1,
-					bufferLength: bufferLength,
-					numRoutines:  m.NumRoutines,
-					depth:        i,
-				},
+	}
+	return m.mapInParallel(workerBuildTree, argList)
+}
+
+// Append adds the data blocks as new leaves at the end of the Merkle Tree and updates its Root, Depth,
+// NumLeaves and cached Proofs accordingly. The resulting tree is identical to the one generated by New
+// with all the data blocks. The tree is left untouched in case of failure.
+//
+// When the tree is built, i.e. in ModeTreeBuild or ModeProofGenAndTreeBuild, only the nodes of the right edge
+// affected by the new leaves are computed. In ModeProofGen, the proofs are generated again from the cached leaves.
+func (m *MerkleTree) Append(blocks ...IDataBlock) (err error) {
+	if len(blocks) == 0 {
+		return nil
+	}
+	for _, block := range blocks {
+		if block == nil {
+			return ErrDataBlockIsNil
+		}
+	}
+
+	// Generate the new leaves, leaving the tree untouched in case of failure.
+	newLeaves := make([][]byte, len(blocks))
+	for i, block := range blocks {
+		if newLeaves[i], err = dataBlockToLeaf(block, &m.Config); err != nil {
+			return err
+		}
+	}
+
+	// The worker pool used to build the tree is released once built, it is acquired again for parallel computations.
+	if m.RunInParallel {
+		var releasePool func()
+		m.wp, releasePool = acquireWorkerPool(&m.Config)
+		defer releasePool()
+	}
+
+	// The tree is restored in case of failure.
+	oldNumLeaves, oldDepth := m.NumLeaves, m.Depth
+	defer m.restoreOnAppendFailure(newLeaves, &err)()
+
+	m.Leaves = append(m.Leaves, newLeaves...)
+	m.NumLeaves = len(m.Leaves)
+	m.Depth = treeDepth(m.NumLeaves)
+
+	if m.NumLeaves == 1 {
+		return m.buildLeafTree()
+	}
+	if m.Mode == ModeProofGen {
+		return m.generateProofs()
+	}
+
+	m.leafMapMu.Lock()
+	for i := oldNumLeaves; i < m.NumLeaves; i++ {
+		m.leafMap[string(m.Leaves[i])] = i
+	}
+	m.leafMapMu.Unlock()
+
+	if err = m.appendTreeNodes(oldNumLeaves, oldDepth); err != nil {
+		return err
+	}
+	if m.Mode == ModeProofGenAndTreeBuild {
+		return m.generateProofsFromNodes()
+	}
+	return nil
+}
+
+// restoreOnAppendFailure saves the state of the tree before the new leaves get appended, returning the function
+// restoring it if the append fails, i.e. if the error pointed by errp is set once the append is done.
+// Only the nodes of the right edge, updated in place, and the leaf map entries of the new leaves are saved.
+func (m *MerkleTree) restoreOnAppendFailure(newLeaves [][]byte, errp *error) func() {
+	var (
+		numLeaves, depth, nodeWidth = m.NumLeaves, m.Depth, m.nodeWidth
+		leaves, root, proofs, nodes = m.Leaves, m.Root, m.Proofs, append([][]byte{}, m.nodes...)
+		leafMap                     = m.leafMap
+		// Index mapped to each new leaf before the append, -1 for the leaves not mapped
+		mappedLeaves = make(map[string]int, len(newLeaves))
+		edgeNodes    = make([][]byte, len(m.nodes))
+	)
+	if leafMap != nil {
+		m.leafMapMu.Lock()
+		for _, leaf := range newLeaves {
+			if idx, ok := leafMap[string(leaf)]; ok {
+				mappedLeaves[string(leaf)] = idx
+			} else {
+				mappedLeaves[string(leaf)] = -1
 			}
 		}
-		errList := m.wp.Map(workerBuildTree, argList)
-		for _, err := range errList {
-			if err != nil {
-				return err
+		m.leafMapMu.Unlock()
+	}
+	for level := 1; level < len(m.nodes); level++ {
+		if start := (numLeaves >> level) * nodeWidth; start < len(m.nodes[level]) {
+			edgeNodes[level] = append([]byte{}, m.nodes[level][start:]...)
+		}
+	}
+
+	return func() {
+		if *errp == nil {
+			return
+		}
+		for level, edge := range edgeNodes {
+			if edge != nil {
+				copy(nodes[level][(numLeaves>>level)*nodeWidth:], edge)
+			}
+		}
+		m.NumLeaves, m.Depth, m.nodeWidth = numLeaves, depth, nodeWidth
+		m.Leaves, m.Root, m.Proofs, m.nodes = leaves, root, proofs, nodes
+		m.leafMapMu.Lock()
+		m.leafMap = leafMap
+		for key, idx := range mappedLeaves {
+			if idx < 0 {
+				delete(m.leafMap, key)
+			} else {
+				m.leafMap[key] = idx
 			}
 		}
-		m.nodes[i+1], bufferLength = m.fixOddLength(m.nodes[i+1], len(m.nodes[i+1]))
+		m.leafMapMu.Unlock()
 	}
+}
+
+// appendTreeNodes computes the tree nodes on the right edge of the tree that are affected by the leaves
+// appended after the first oldNumLeaves ones, then the new Merkle root.
+func (m *MerkleTree) appendTreeNodes(oldNumLeaves, oldDepth int) error {
+	for len(m.nodes) < m.Depth {
+		m.nodes = append(m.nodes, nil)
+	}
+	return m.computeTreeNodes(oldNumLeaves, oldDepth)
+}
+
+// Update replaces the leaf at the specified index with the data block and recomputes the path
+// from that leaf to the root. See UpdateBatch.
+func (m *MerkleTree) Update(index int, block IDataBlock) error {
+	return m.UpdateBatch([]int{index}, []IDataBlock{block})
+}
+
+// UpdateBatch replaces the leaves at the specified indices with the corresponding data blocks.
+// Only the nodes on the paths from the updated leaves to the root are recomputed, the leaf map
+// and the cached proofs are refreshed accordingly. The tree is left untouched in case of failure.
+// This method is only available when the configuration mode is ModeTreeBuild or ModeProofGenAndTreeBuild.
+func (m *MerkleTree) UpdateBatch(indices []int, blocks []IDataBlock) (err error) {
+	if m.Mode != ModeTreeBuild && m.Mode != ModeProofGenAndTreeBuild {
+		return ErrUpdateInvalidModeTreeNotBuilt
+	}
+	if len(indices) != len(blocks) {
+		return ErrUpdateMismatch
+	}
+
+	// Generate the new leaves, leaving the tree untouched in case of failure.
+	newLeaves := make([][]byte, len(blocks))
+	for i, block := range blocks {
+		if indices[i] < 0 || indices[i] >= m.NumLeaves {
+			return ErrLeafIndexOutOfRange
+		}
+		if block == nil {
+			return ErrDataBlockIsNil
+		}
+		if newLeaves[i], err = dataBlockToLeaf(block, &m.Config); err != nil {
+			return err
+		}
+	}
+
+	// Indices of the updated nodes at the current level.
+	dirty := make([]int, len(indices))
+	copy(dirty, indices)
+	sort.Ints(dirty)
+	dirty = dedupSortedInts(dirty)
+
+	// Compute the updated nodes and the new root first, leaving the tree untouched in case of failure.
+	// The staged nodes are the updated ones per level, the last block of an index being its new leaf.
+	staged := make([]map[int][]byte, max(m.Depth, 1))
+	staged[0] = make(map[int][]byte, len(dirty))
+	for i, idx := range indices {
+		staged[0][idx] = newLeaves[i]
+	}
+	stagedNode := func(level, idx int) []byte {
+		level, idx = m.nodePosition(level, idx)
+		if node, ok := staged[level][idx]; ok {
+			return node
+		}
+		return m.node(level, idx)
+	}
+	var root []byte
+	dirtyLevels := make([][]int, m.Depth)
+	if m.NumLeaves == 1 {
+		// The root of a single leaf tree is the leaf itself, its proof has no sibling.
+		root = append([]byte{}, staged[0][0]...)
+	} else {
+		hasher := m.newNodeHasher()
+		for level := 0; level < m.Depth; level++ {
+			dirtyLevels[level] = dirty
+			if level == m.Depth-1 {
+				break
+			}
+
+			// Recompute the parent nodes of the updated ones.
+			levelLen := levelLength(m.NumLeaves, level)
+			parents := make([]int, 0, len(dirty))
+			staged[level+1] = make(map[int][]byte, len(dirty))
+			for _, idx := range dirty {
+				parent := idx >> 1
+				if len(parents) > 0 && parents[len(parents)-1] == parent {
+					continue
+				}
+				parents = append(parents, parent)
+				// The last node of an odd-length level is promoted in RFC 6962 mode.
+				if m.RFC6962 && levelLen&1 == 1 && idx == levelLen-1 {
+					continue
+				}
+				node, err := hasher.hashPair(nil, stagedNode(level, parent<<1), stagedNode(level, parent<<1+1))
+				if err != nil {
+					return err
+				}
+				if len(node) != m.nodeWidth {
+					return ErrHashSizeMismatch
+				}
+				staged[level+1][parent] = node
+			}
+			dirty = parents
+		}
+		if root, err = hasher.hashPair(nil, stagedNode(m.Depth-1, 0), stagedNode(m.Depth-1, 1)); err != nil {
+			return err
+		}
+	}
+
+	// Replace the leaves and refresh the leaf map. The key of a replaced leaf is removed if it was mapped
+	// to its index, then mapped again to another index still holding the same leaf, if any.
+	m.leafMapMu.Lock()
+	replacedKeys := make(map[string]struct{})
+	for i, idx := range indices {
+		oldKey := string(m.Leaves[idx])
+		if mapIdx, ok := m.leafMap[oldKey]; ok && mapIdx == idx {
+			delete(m.leafMap, oldKey)
+			replacedKeys[oldKey] = struct{}{}
+		}
+		m.Leaves[idx] = newLeaves[i]
+	}
+	for idx, leaf := range staged[0] {
+		m.leafMap[string(leaf)] = idx
+	}
+	for key := range replacedKeys {
+		if _, ok := m.leafMap[key]; ok {
+			continue
+		}
+		for i := m.NumLeaves - 1; i >= 0; i-- {
+			if string(m.Leaves[i]) == key {
+				m.leafMap[key] = i
+				break
+			}
+		}
+	}
+	m.leafMapMu.Unlock()
+
+	// Store the updated nodes in place, then refresh the siblings of the cached proofs.
+	for level := 1; level < len(staged); level++ {
+		for idx, node := range staged[level] {
+			copy(m.nodes[level][idx*m.nodeWidth:(idx+1)*m.nodeWidth], node)
+		}
+	}
+	if m.Mode == ModeProofGenAndTreeBuild {
+		for level, dirty := range dirtyLevels {
+			levelLen := levelLength(m.NumLeaves, level)
+			for _, idx := range dirty {
+				node := m.node(level, idx)
+				m.updateProofSiblings(level, idx^1, node)
+				if idx == levelLen-1 && levelLen&1 == 1 && !m.RFC6962 {
+					m.updateProofSiblings(level, idx, node)
+				}
+			}
+		}
+	}
+	m.Root = root
 	return nil
 }
 
+// updateProofSiblings sets the sibling at the specified level of the proofs of all the leaves
+// under the node at index idx of that level.
+func (m *MerkleTree) updateProofSiblings(level, idx int, sibling []byte) {
+	start := idx << level
+	end := min((idx+1)<<level, m.NumLeaves)
+	for i := start; i < end; i++ {
+		m.Proofs[i].Siblings[m.siblingPosition(i, level)] = sibling
+	}
+}
+
+// siblingPosition returns the position, in the proof of the leaf, of its sibling at the specified level.
+// In RFC 6962 mode, the levels where the path of the leaf goes through a promoted node have no sibling.
+func (m *MerkleTree) siblingPosition(leaf, level int) int {
+	if !m.RFC6962 {
+		return level
+	}
+	pos := level
+	for l := 0; l < level; l++ {
+		if idx := leaf >> l; idx&1 == 0 && idx+1 >= levelLength(m.NumLeaves, l) {
+			pos--
+		}
+	}
+	return pos
+}
+
 // Verify checks if the data block is valid using the Merkle Tree proof and the cached Merkle root hash.
+// The envelope of the proof, if any, must also match the size of the tree.
 func (m *MerkleTree) Verify(dataBlock IDataBlock, proof *Proof) (bool, error) {
+	if proof != nil && proof.Envelope != nil && proof.Envelope.TreeSize != uint64(m.NumLeaves) {
+		return false, ErrProofEnvelopeMismatch
+	}
 	return Verify(dataBlock, proof, m.Root, &m.Config)
 }
 
 // Verify checks if the data block is valid using the Merkle Tree proof and the provided Merkle root hash.
 // It returns true if the data block is valid, false otherwise. An error is returned in case of any issues
 // during the verification process.
+//
+// The envelope of the proof, if any, must match the hash algorithm and the flags of the configuration,
+// the sides of the siblings being derived from its leaf index and tree size rather than from the Path.
+// The hash algorithm is not checked for a custom HashFunc without HashAlgorithm name.
+// Proofs without envelope, as generated by former versions, are verified by their Path.
 func Verify(dataBlock IDataBlock, proof *Proof, root []byte, config *Config) (bool, error) {
 	// Validate input parameters.
 	if dataBlock == nil {
@@ -696,16 +1493,22 @@ func Verify(dataBlock IDataBlock, proof *Proof, root []byte, config *Config) (bo
 	if config == nil {
 		config = new(Config)
 	}
-	if config.HashFunc == nil {
-		config.HashFunc = hash.DefaultHashFunc
+	if err := config.initHashFunc(); err != nil {
+		return false, err
 	}
 
-	// Determine the concatenation function based on the configuration.
-	concatFunc := concatHash
-	if config.SortSiblingPairs {
-		concatFunc = concatSortHash
+	// Determine the path of the proof, out of its envelope if any.
+	path := uint64(proof.Path)
+	if proof.Envelope != nil {
+		var err error
+		if path, err = proof.Envelope.path(config, len(proof.Siblings)); err != nil {
+			return false, err
+		}
 	}
 
+	// Determine the concatenation function based on the configuration.
+	concatFunc := concatHashFuncFor(config)
+
 	// Convert the data block to a leaf.
 	leaf, err := dataBlockToLeaf(dataBlock, config)
 	if err != nil {
@@ -716,12 +1519,12 @@ func Verify(dataBlock IDataBlock, proof *Proof, root []byte, config *Config) (bo
 	// Copy the slice so that the original leaf won't be modified.
 	result := make([]byte, len(leaf))
 	copy(result, leaf)
-	path := proof.Path
+	hasher := newNodeHasher(config.HashFunc, concatFunc, nil)
 	for _, sib := range proof.Siblings {
 		if path&1 == 1 {
-			result, err = config.HashFunc(concatFunc(result, sib))
+			result, err = hasher.hashPair(nil, result, sib)
 		} else {
-			result, err = config.HashFunc(concatFunc(sib, result))
+			result, err = hasher.hashPair(nil, sib, result)
 		}
 		if err != nil {
 			return false, err
@@ -735,6 +1538,7 @@ func Verify(dataBlock IDataBlock, proof *Proof, root []byte, config *Config) (bo
 // This method is only available when the configuration mode is ModeTreeBuild or ModeProofGenAndTreeBuild.
 // In ModeProofGen, proofs for all the data blocks are already generated, and the Merkle Tree structure
 // is not cached.
+// The proof of a duplicated data block is the one of its last leaf, ProofByIndex proves any of them.
 func (m *MerkleTree) Proof(dataBlock IDataBlock) (*Proof, error) {
 	if m.Mode != ModeTreeBuild && m.Mode != ModeProofGenAndTreeBuild {
 		return nil, ErrProofInvalidModeTreeNotBuilt
@@ -753,23 +1557,87 @@ func (m *MerkleTree) Proof(dataBlock IDataBlock) (*Proof, error) {
 	if !ok {
 		return nil, ErrProofInvalidDataBlock
 	}
+	return m.proofFromNodes(idx), nil
+}
+
+// ProofByIndex returns the Merkle proof of the leaf at the specified index, in any configuration mode:
+// the proof is generated out of the tree nodes when built, or copied from the generated proofs otherwise.
+// Unlike Proof, it identifies the leaf of duplicated data blocks, see LeafIndex.
+func (m *MerkleTree) ProofByIndex(idx int) (*Proof, error) {
+	if idx < 0 || idx >= m.NumLeaves {
+		return nil, ErrLeafIndexOutOfRange
+	}
+	if m.Mode == ModeTreeBuild || m.Mode == ModeProofGenAndTreeBuild {
+		return m.proofFromNodes(idx), nil
+	}
+	if idx >= len(m.Proofs) || m.Proofs[idx] == nil {
+		return nil, ErrProofInvalidModeTreeNotBuilt
+	}
+	proof := &Proof{
+		Path:     m.Proofs[idx].Path,
+		Siblings: cloneNodes(m.Proofs[idx].Siblings),
+	}
+	if m.Proofs[idx].Envelope != nil {
+		envelope := *m.Proofs[idx].Envelope
+		proof.Envelope = &envelope
+	}
+	return proof, nil
+}
+
+// Leaf returns a copy of the leaf at the specified index.
+func (m *MerkleTree) Leaf(idx int) ([]byte, error) {
+	if idx < 0 || idx >= m.NumLeaves {
+		return nil, ErrLeafIndexOutOfRange
+	}
+	return append([]byte{}, m.Leaves[idx]...), nil
+}
+
+// LeafIndex returns the indices of all the leaves of the data block, in increasing order, in any
+// configuration mode. The same data block may be part of the tree several times, e.g. files of the
+// same content, Proof then only proves one of them: the leaves are searched in O(n).
+func (m *MerkleTree) LeafIndex(dataBlock IDataBlock) ([]int, error) {
+	if dataBlock == nil {
+		return nil, ErrDataBlockIsNil
+	}
+	leaf, err := dataBlockToLeaf(dataBlock, &m.Config)
+	if err != nil {
+		return nil, err
+	}
+	var indices []int
+	for i, l := range m.Leaves {
+		if bytes.Equal(l, leaf) {
+			indices = append(indices, i)
+		}
+	}
+	if len(indices) == 0 {
+		return nil, ErrProofInvalidDataBlock
+	}
+	return indices, nil
+}
+
+// proofFromNodes generates the proof of the leaf at the specified index out of the tree nodes.
+func (m *MerkleTree) proofFromNodes(idx int) *Proof {
+	envelope := newProofEnvelope(&m.Config, idx, m.NumLeaves)
 
 	// Compute the path and siblings for the proof.
+	// Promoted nodes in RFC 6962 mode have no sibling.
 	var (
 		path     uint32
-		siblings = make([][]byte, m.Depth)
+		siblings = make([][]byte, 0, m.Depth)
 	)
 	for i := 0; i < m.Depth; i++ {
 		if idx&1 == 1 {
-			siblings[i] = m.nodes[i][idx-1]
-		} else {
-			path += 1 << i
-			siblings[i] = m.nodes[i][idx+1]
+			path = proofPathWithSibling(path, len(siblings), false)
+			siblings = append(siblings, m.node(i, idx-1))
+		} else if !m.RFC6962 || idx+1 < levelLength(m.NumLeaves, i) {
+			path = proofPathWithSibling(path, len(siblings), true)
+			siblings = append(siblings, m.node(i, idx+1))
 		}
 		idx >>= 1
 	}
 	return &Proof{
 		Path:     path,
-		Siblings: siblings,
-	}, nil
+		Siblings: cloneNodes(siblings),
+		Envelope: envelope,
+	}
 }
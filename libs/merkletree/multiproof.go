@@ -0,0 +1,249 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+	"sort"
+)
+
+var (
+	// ErrMultiProofNoIndex is the error for a multiproof request without any leaf index.
+	ErrMultiProofNoIndex = errors.New("at least one leaf index is required to generate a multiproof")
+	// ErrMultiProofIndexOutOfRange is the error for a leaf index not part of the merkle tree.
+	ErrMultiProofIndexOutOfRange = errors.New("leaf index is out of the merkle tree range")
+	// ErrMultiProofMismatch is the error for a number of data blocks not matching the multiproof indices.
+	ErrMultiProofMismatch = errors.New("the number of data blocks does not match the multiproof indices")
+)
+
+// MultiProof represents a compact Merkle Tree proof for several data blocks of a same tree.
+//
+// Sibling nodes shared by the paths of the proven leaves are only provided once, and nodes
+// that can be computed from the proven leaves themselves are omitted.
+type MultiProof struct {
+	// Indices are the leaf indices of the proven data blocks, in the order the data blocks are expected for verification.
+	Indices []int
+	// NumLeaves is the number of leaves of the Merkle Tree the proof has been generated from.
+	NumLeaves int
+	// Siblings are the deduplicated nodes required to compute the root, ordered bottom-up then left to right.
+	Siblings [][]byte
+}
+
+// indexedNode is a tree node identified by its index in its tree level.
+type indexedNode struct {
+	idx  int
+	hash []byte
+}
+
+// levelLength returns the number of nodes of a tree level, before any odd length fix.
+func levelLength(numLeaves, level int) int {
+	return (numLeaves + (1 << level) - 1) >> level
+}
+
+// MultiProof generates a single proof for several data blocks using the previously generated Merkle Tree structure.
+// This method is only available when the configuration mode is ModeTreeBuild or ModeProofGenAndTreeBuild.
+func (m *MerkleTree) MultiProof(dataBlocks []IDataBlock) (*MultiProof, error) {
+	if m.Mode != ModeTreeBuild && m.Mode != ModeProofGenAndTreeBuild {
+		return nil, ErrProofInvalidModeTreeNotBuilt
+	}
+
+	// Retrieve the index of each data block in the Merkle Tree.
+	indices := make([]int, len(dataBlocks))
+	for i, dataBlock := range dataBlocks {
+		if dataBlock == nil {
+			return nil, ErrDataBlockIsNil
+		}
+		leaf, err := dataBlockToLeaf(dataBlock, &m.Config)
+		if err != nil {
+			return nil, err
+		}
+		m.leafMapMu.Lock()
+		idx, ok := m.leafMap[string(leaf)]
+		m.leafMapMu.Unlock()
+		if !ok {
+			return nil, ErrProofInvalidDataBlock
+		}
+		indices[i] = idx
+	}
+	return m.MultiProofByIndices(indices)
+}
+
+// MultiProofByIndices generates a single proof for the leaves at the specified indices.
+// This method is only available when the configuration mode is ModeTreeBuild or ModeProofGenAndTreeBuild.
+func (m *MerkleTree) MultiProofByIndices(indices []int) (*MultiProof, error) {
+	if m.Mode != ModeTreeBuild && m.Mode != ModeProofGenAndTreeBuild {
+		return nil, ErrProofInvalidModeTreeNotBuilt
+	}
+	if len(indices) == 0 {
+		return nil, ErrMultiProofNoIndex
+	}
+	for _, idx := range indices {
+		if idx < 0 || idx >= m.NumLeaves {
+			return nil, ErrMultiProofIndexOutOfRange
+		}
+	}
+
+	// Sorted and deduplicated indices of the known nodes at the current level.
+	known := make([]int, len(indices))
+	copy(known, indices)
+	sort.Ints(known)
+	known = dedupSortedInts(known)
+
+	siblings := make([][]byte, 0)
+	for level := 0; level < m.Depth; level++ {
+		levelLen := levelLength(m.NumLeaves, level)
+		next := make([]int, 0, len(known))
+		for i := 0; i < len(known); i++ {
+			idx := known[i]
+			sibIdx := idx ^ 1
+			switch {
+			case i+1 < len(known) && known[i+1] == sibIdx:
+				// Both nodes of the pair are known, skip the next one.
+				i++
+			case sibIdx >= levelLen:
+				// The node is duplicated to fix the odd length of the level, or promoted in RFC 6962 mode.
+			default:
+				siblings = append(siblings, m.node(level, sibIdx))
+			}
+			next = append(next, idx>>1)
+		}
+		known = next
+	}
+
+	proofIndices := make([]int, len(indices))
+	copy(proofIndices, indices)
+	return &MultiProof{
+		Indices:   proofIndices,
+		NumLeaves: m.NumLeaves,
+		Siblings:  cloneNodes(siblings),
+	}, nil
+}
+
+// dedupSortedInts removes the duplicated values of a sorted slice, in place.
+func dedupSortedInts(s []int) []int {
+	if len(s) == 0 {
+		return s
+	}
+	j := 1
+	for i := 1; i < len(s); i++ {
+		if s[i] != s[j-1] {
+			s[j] = s[i]
+			j++
+		}
+	}
+	return s[:j]
+}
+
+// VerifyMulti checks if the data blocks are valid using the Merkle Tree multiproof and the cached Merkle root hash.
+func (m *MerkleTree) VerifyMulti(dataBlocks []IDataBlock, proof *MultiProof) (bool, error) {
+	return VerifyMulti(dataBlocks, proof, m.Root, &m.Config)
+}
+
+// VerifyMulti checks if the data blocks are valid using the Merkle Tree multiproof and the provided Merkle root hash.
+// The data blocks must be provided in the order of the multiproof indices.
+// It returns true if all the data blocks are valid, false otherwise. An error is returned in case of any issues
+// during the verification process.
+func VerifyMulti(dataBlocks []IDataBlock, proof *MultiProof, root []byte, config *Config) (bool, error) {
+	// Validate input parameters.
+	if proof == nil {
+		return false, ErrProofIsNil
+	}
+	if len(proof.Indices) == 0 {
+		return false, ErrMultiProofNoIndex
+	}
+	if len(dataBlocks) != len(proof.Indices) {
+		return false, ErrMultiProofMismatch
+	}
+	if config == nil {
+		config = new(Config)
+	}
+	if err := config.initHashFunc(); err != nil {
+		return false, err
+	}
+
+	// Determine the concatenation function based on the configuration.
+	concatFunc := concatHashFuncFor(config)
+
+	// Convert the data blocks to leaves, indexed by their position in the tree.
+	known := make([]indexedNode, len(dataBlocks))
+	for i, dataBlock := range dataBlocks {
+		if dataBlock == nil {
+			return false, ErrDataBlockIsNil
+		}
+		idx := proof.Indices[i]
+		if idx < 0 || idx >= proof.NumLeaves {
+			return false, ErrMultiProofIndexOutOfRange
+		}
+		leaf, err := dataBlockToLeaf(dataBlock, config)
+		if err != nil {
+			return false, err
+		}
+		known[i] = indexedNode{idx: idx, hash: leaf}
+	}
+	sort.SliceStable(known, func(i, j int) bool {
+		return known[i].idx < known[j].idx
+	})
+
+	// Remove the duplicated leaves, which must be identical.
+	j := 1
+	for i := 1; i < len(known); i++ {
+		if known[i].idx != known[j-1].idx {
+			known[j] = known[i]
+			j++
+			continue
+		}
+		if !bytes.Equal(known[i].hash, known[j-1].hash) {
+			return false, nil
+		}
+	}
+	known = known[:j]
+
+	// Compute the nodes level by level, consuming the proof siblings when required.
+	var (
+		depth  = bits.Len(uint(proof.NumLeaves - 1))
+		sibPos int
+		err    error
+	)
+	for level := 0; level < depth; level++ {
+		levelLen := levelLength(proof.NumLeaves, level)
+		next := make([]indexedNode, 0, len(known))
+		for i := 0; i < len(known); i++ {
+			node := known[i]
+			sibIdx := node.idx ^ 1
+			var sib []byte
+			switch {
+			case i+1 < len(known) && known[i+1].idx == sibIdx:
+				sib = known[i+1].hash
+				i++
+			case sibIdx >= levelLen && config.RFC6962:
+				next = append(next, indexedNode{idx: node.idx >> 1, hash: node.hash})
+				continue
+			case sibIdx >= levelLen:
+				sib = node.hash
+			default:
+				if sibPos >= len(proof.Siblings) {
+					return false, nil
+				}
+				sib = proof.Siblings[sibPos]
+				sibPos++
+			}
+			var parent []byte
+			if node.idx&1 == 0 {
+				parent, err = config.HashFunc(concatFunc(nil, node.hash, sib))
+			} else {
+				parent, err = config.HashFunc(concatFunc(nil, sib, node.hash))
+			}
+			if err != nil {
+				return false, err
+			}
+			next = append(next, indexedNode{idx: node.idx >> 1, hash: parent})
+		}
+		known = next
+	}
+
+	// All the provided siblings must have been consumed to compute the root.
+	if sibPos != len(proof.Siblings) {
+		return false, nil
+	}
+	return bytes.Equal(known[0].hash, root), nil
+}
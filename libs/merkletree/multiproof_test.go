@@ -0,0 +1,246 @@
+package merkletree
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestMerkleTree_MultiProof(t *testing.T) {
+	tests := []struct {
+		name      string
+		config    *Config
+		numBlocks int
+		indices   []int
+		wantErr   error
+	}{
+		{
+			name:      "test_2_all",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 2,
+			indices:   []int{0, 1},
+		},
+		{
+			name:      "test_5_last",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 5,
+			indices:   []int{4},
+		},
+		{
+			name:      "test_9_unsorted",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 9,
+			indices:   []int{8, 0, 3},
+		},
+		{
+			name:      "test_40_duplicated_indices",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 40,
+			indices:   []int{7, 7, 12, 39, 13},
+		},
+		{
+			name:      "test_1001_sorted_sibling_pairs",
+			config:    &Config{Mode: ModeTreeBuild, SortSiblingPairs: true},
+			numBlocks: 1001,
+			indices:   []int{0, 1, 2, 500, 999, 1000},
+		},
+		{
+			name:      "test_100_parallel_proof_gen_and_tree_build",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild, RunInParallel: true, NumRoutines: 4},
+			numBlocks: 100,
+			indices:   []int{1, 33, 64, 98, 99},
+		},
+		{
+			name:      "test_rfc6962_13",
+			config:    &Config{Mode: ModeTreeBuild, RFC6962: true},
+			numBlocks: 13,
+			indices:   []int{0, 8, 12},
+		},
+		{
+			name:      "test_rfc6962_sorted_parallel_1001",
+			config:    &Config{Mode: ModeProofGenAndTreeBuild, RFC6962: true, SortSiblingPairs: true, RunInParallel: true, NumRoutines: 4},
+			numBlocks: 1001,
+			indices:   []int{3, 999, 1000},
+		},
+		{
+			name:      "test_wrong_mode",
+			config:    &Config{Mode: ModeProofGen},
+			numBlocks: 5,
+			indices:   []int{1},
+			wantErr:   ErrProofInvalidModeTreeNotBuilt,
+		},
+		{
+			name:      "test_no_index",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 5,
+			indices:   []int{},
+			wantErr:   ErrMultiProofNoIndex,
+		},
+		{
+			name:      "test_index_out_of_range",
+			config:    &Config{Mode: ModeTreeBuild},
+			numBlocks: 5,
+			indices:   []int{5},
+			wantErr:   ErrMultiProofIndexOutOfRange,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := generatedTestDataBlocks(tt.numBlocks)
+			m, err := New(tt.config, blocks)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			proof, err := m.MultiProofByIndices(tt.indices)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MultiProofByIndices() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+
+			proofBlocks := make([]IDataBlock, len(tt.indices))
+			for i, idx := range tt.indices {
+				proofBlocks[i] = blocks[idx]
+			}
+			got, err := m.VerifyMulti(proofBlocks, proof)
+			if err != nil {
+				t.Fatalf("VerifyMulti() error = %v", err)
+			}
+			if !got {
+				t.Fatalf("VerifyMulti() got = %v, want true", got)
+			}
+
+			// The multiproof must not be larger than the sum of the single leaf proofs.
+			singleProofsSize := 0
+			for _, idx := range dedupSortedInts(append([]int{}, tt.indices...)) {
+				singleProof, err := m.Proof(blocks[idx])
+				if err != nil {
+					t.Fatalf("Proof() error = %v", err)
+				}
+				singleProofsSize += len(singleProof.Siblings)
+			}
+			if len(proof.Siblings) > singleProofsSize {
+				t.Errorf("MultiProofByIndices() got %d siblings, more than the %d of single proofs", len(proof.Siblings), singleProofsSize)
+			}
+
+			// Tampered data blocks must not verify.
+			tampered := make([]IDataBlock, len(proofBlocks))
+			copy(tampered, proofBlocks)
+			tampered[len(tampered)-1] = &DataBlock{Data: []byte("tampered")}
+			if got, _ := m.VerifyMulti(tampered, proof); got {
+				t.Errorf("VerifyMulti() tampered data block got = %v, want false", got)
+			}
+
+			// Tampered siblings must not verify.
+			if len(proof.Siblings) > 0 {
+				tamperedProof := &MultiProof{
+					Indices:   proof.Indices,
+					NumLeaves: proof.NumLeaves,
+					Siblings:  append([][]byte{}, proof.Siblings...),
+				}
+				tamperedProof.Siblings[0] = []byte("tampered")
+				if got, _ := m.VerifyMulti(proofBlocks, tamperedProof); got {
+					t.Errorf("VerifyMulti() tampered sibling got = %v, want false", got)
+				}
+			}
+		})
+	}
+}
+
+func TestMerkleTree_MultiProof_dataBlocks(t *testing.T) {
+	blocks := generatedTestDataBlocks(40)
+	m, err := New(&Config{Mode: ModeTreeBuild}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	proofBlocks := []IDataBlock{blocks[3], blocks[17], blocks[18], blocks[36]}
+	proof, err := m.MultiProof(proofBlocks)
+	if err != nil {
+		t.Fatalf("MultiProof() error = %v", err)
+	}
+	got, err := VerifyMulti(proofBlocks, proof, m.Root, &Config{})
+	if err != nil || !got {
+		t.Errorf("VerifyMulti() got = %v, error = %v, want true", got, err)
+	}
+	if _, err := m.MultiProof([]IDataBlock{&DataBlock{Data: []byte("unknown")}}); !errors.Is(err, ErrProofInvalidDataBlock) {
+		t.Errorf("MultiProof() error = %v, wantErr %v", err, ErrProofInvalidDataBlock)
+	}
+}
+
+func TestVerifyMulti(t *testing.T) {
+	blocks := generatedTestDataBlocks(8)
+	m, err := New(&Config{Mode: ModeTreeBuild}, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	proof, err := m.MultiProofByIndices([]int{1, 6})
+	if err != nil {
+		t.Fatalf("MultiProofByIndices() error = %v", err)
+	}
+	tests := []struct {
+		name    string
+		blocks  []IDataBlock
+		proof   *MultiProof
+		want    bool
+		wantErr error
+	}{
+		{
+			name:   "test_ok",
+			blocks: []IDataBlock{blocks[1], blocks[6]},
+			proof:  proof,
+			want:   true,
+		},
+		{
+			name:   "test_swapped_blocks",
+			blocks: []IDataBlock{blocks[6], blocks[1]},
+			proof:  proof,
+			want:   false,
+		},
+		{
+			name:   "test_missing_sibling",
+			blocks: []IDataBlock{blocks[1], blocks[6]},
+			proof:  &MultiProof{Indices: proof.Indices, NumLeaves: proof.NumLeaves, Siblings: proof.Siblings[1:]},
+			want:   false,
+		},
+		{
+			name:   "test_extra_sibling",
+			blocks: []IDataBlock{blocks[1], blocks[6]},
+			proof:  &MultiProof{Indices: proof.Indices, NumLeaves: proof.NumLeaves, Siblings: append(append([][]byte{}, proof.Siblings...), m.Root)},
+			want:   false,
+		},
+		{
+			name:   "test_wrong_num_leaves",
+			blocks: []IDataBlock{blocks[1], blocks[6]},
+			proof:  &MultiProof{Indices: proof.Indices, NumLeaves: 7, Siblings: proof.Siblings},
+			want:   false,
+		},
+		{
+			name:    "test_proof_nil",
+			blocks:  []IDataBlock{blocks[1]},
+			wantErr: ErrProofIsNil,
+		},
+		{
+			name:    "test_blocks_mismatch",
+			blocks:  []IDataBlock{blocks[1]},
+			proof:   proof,
+			wantErr: ErrMultiProofMismatch,
+		},
+		{
+			name:    "test_data_block_nil",
+			blocks:  []IDataBlock{blocks[1], nil},
+			proof:   proof,
+			wantErr: ErrDataBlockIsNil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := VerifyMulti(tt.blocks, tt.proof, m.Root, nil)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyMulti() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("VerifyMulti() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,607 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"math/bits"
+	"sort"
+
+	"github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+const (
+	// sparseLeafPrefix is the domain separation prefix of the sparse Merkle Tree leaf hashes.
+	sparseLeafPrefix byte = 0x00
+	// sparseNodePrefix is the domain separation prefix of the sparse Merkle Tree internal node hashes.
+	sparseNodePrefix byte = 0x01
+)
+
+var (
+	// ErrSparseKeyValueMismatch is the error for a number of keys not matching the number of values.
+	ErrSparseKeyValueMismatch = errors.New("the number of keys does not match the number of values")
+	// ErrSparseInvalidKey is the error for a key whose size is not the size of the tree hashes.
+	ErrSparseInvalidKey = errors.New("the key size must be the size of the sparse merkle tree hashes")
+	// ErrSparseDuplicateKey is the error for a key set more than once.
+	ErrSparseDuplicateKey = errors.New("duplicate key in the sparse merkle tree")
+	// ErrSparseKeyNotFound is the error for a key that is not a member of the sparse Merkle Tree.
+	ErrSparseKeyNotFound = errors.New("key is not a member of the sparse merkle tree")
+	// ErrSparseKeyFound is the error for a key that is a member of the sparse Merkle Tree,
+	// which non-membership could not be proven.
+	ErrSparseKeyFound = errors.New("key is a member of the sparse merkle tree")
+)
+
+// SparseMerkleTree implements a sparse Merkle Tree, i.e. a Merkle Tree having a leaf for every possible key,
+// most of them being empty. A key is a hash, typically of a file path, and its bits are the path
+// from the root to its leaf: 0 for the left child, 1 for the right one.
+//
+// The tree is kept compact: an empty subtree hashes to a zero placeholder, and a subtree holding a single
+// key/value pair hashes to its leaf, whatever its depth. Its proofs are then made of O(log n) siblings.
+// Unlike MerkleTree, it allows proving that a key is not a member of the tree, e.g. that a file was deleted.
+//
+// The configuration HashFunc, HashAlgorithm, NumRoutines, WorkerPool and RunInParallel are used, the other settings
+// are ignored. A SparseMerkleTree is not safe for concurrent modifications.
+type SparseMerkleTree struct {
+	Config
+	// root is the root node of the tree, nil if the tree is empty.
+	root *sparseNode
+	// placeholder is the hash of the empty subtrees.
+	placeholder []byte
+	// Root is the hash of the sparse Merkle Tree root node.
+	Root []byte
+	// NumLeaves is the number of key/value pairs in the sparse Merkle Tree.
+	NumLeaves int
+}
+
+// sparseNode is a node of a sparse Merkle Tree.
+// Leaf nodes hold a key/value pair, internal nodes hold at least 2 leaves in their subtrees.
+type sparseNode struct {
+	hash []byte
+	// left and right are the children of an internal node, nil for empty subtrees.
+	left, right *sparseNode
+	// key and value are set for leaf nodes only.
+	key, value []byte
+}
+
+// isLeaf returns true if the node is a leaf node.
+func (n *sparseNode) isLeaf() bool {
+	return n.key != nil
+}
+
+// SparseProof represents a sparse Merkle Tree proof, of membership or non-membership of a key.
+type SparseProof struct {
+	// Siblings are the sibling nodes along the path of the key, from the root down to the leaf or
+	// empty subtree ending the path. The siblings which are empty subtrees are nil.
+	Siblings [][]byte
+	// LeafKey and LeafValue are the key/value pair of the leaf ending the path of a non-member key,
+	// if any. They are nil if the path ends with an empty subtree, and for membership proofs.
+	LeafKey   []byte
+	LeafValue []byte
+}
+
+// workerArgsBuildSparseSubtree contains the parameters required for workerBuildSparseSubtree.
+type workerArgsBuildSparseSubtree struct {
+	tree     *SparseMerkleTree
+	keys     [][]byte
+	values   [][]byte
+	subtrees []*sparseNode
+	bounds   []int
+	startIdx int
+	stride   int
+	depth    int
+}
+
+// NewSparse generates a new sparse Merkle Tree with the specified configuration and key/value pairs.
+// The keys must have the size of the configured hash function output, e.g. as returned by SparseKey.
+func NewSparse(config *Config, keys [][]byte, values [][]byte) (t *SparseMerkleTree, err error) {
+	if len(keys) != len(values) {
+		return nil, ErrSparseKeyValueMismatch
+	}
+	if config == nil {
+		config = new(Config)
+	}
+	t = &SparseMerkleTree{
+		Config:    *config,
+		NumLeaves: len(keys),
+	}
+
+	// Initialize the hash function, a concurrent safe one for parallel execution.
+	if t.HashFunc == nil {
+		if t.RunInParallel && t.HashAlgorithm == "" {
+			t.HashFunc, t.hashFuncByName = hash.DefaultHashFuncParallel, true
+		} else if err = t.initHashFunc(); err != nil {
+			return nil, err
+		}
+	}
+	if t.placeholder, err = sparsePlaceholder(&t.Config); err != nil {
+		return nil, err
+	}
+
+	// Sort the key/value pairs by key, so that every subtree is a range of them.
+	sortedKeys := make([][]byte, len(keys))
+	sortedValues := make([][]byte, len(values))
+	order := make([]int, len(keys))
+	for i := range order {
+		if len(keys[i]) != len(t.placeholder) {
+			return nil, ErrSparseInvalidKey
+		}
+		order[i] = i
+	}
+	sort.Slice(order, func(i, j int) bool {
+		return bytes.Compare(keys[order[i]], keys[order[j]]) < 0
+	})
+	for i, idx := range order {
+		sortedKeys[i], sortedValues[i] = keys[idx], values[idx]
+		if i > 0 && bytes.Equal(sortedKeys[i-1], sortedKeys[i]) {
+			return nil, ErrSparseDuplicateKey
+		}
+	}
+
+	if t.RunInParallel {
+		t.root, err = t.buildInParallel(sortedKeys, sortedValues)
+	} else {
+		t.root, err = t.build(sortedKeys, sortedValues, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	t.Root = t.hashOf(t.root)
+	return t, nil
+}
+
+// SparseKey returns the sparse Merkle Tree key of a file path, i.e. the hash of the path.
+// The configuration is left untouched, for it to be reused by NewSparse.
+func SparseKey(path string, config *Config) ([]byte, error) {
+	hashFunc, err := sparseHashFunc(config)
+	if err != nil {
+		return nil, err
+	}
+	return hashFunc([]byte(path))
+}
+
+// sparseHashFunc returns the hash function of a configuration, initialized on a copy of the configuration
+// so that the default one, not safe for concurrent use, is never set on the caller's configuration.
+func sparseHashFunc(config *Config) (TypeHashFunc, error) {
+	var c Config
+	if config != nil {
+		c = *config
+	}
+	if err := c.initHashFunc(); err != nil {
+		return nil, err
+	}
+	return c.HashFunc, nil
+}
+
+// sparsePlaceholder returns the hash of the empty subtrees: zero bytes of the hash function output size.
+func sparsePlaceholder(config *Config) ([]byte, error) {
+	h, err := config.HashFunc(nil)
+	if err != nil {
+		return nil, err
+	}
+	return make([]byte, len(h)), nil
+}
+
+// keyBit returns the bit of the key at the specified depth, i.e. the direction of its path: 0 for left, 1 for right.
+func keyBit(key []byte, depth int) int {
+	return int(key[depth>>3]>>(7-depth&7)) & 1
+}
+
+// commonPrefixLen returns the number of leading bits shared by two keys of the same size.
+func commonPrefixLen(key1, key2 []byte) int {
+	for i := range key1 {
+		if x := key1[i] ^ key2[i]; x != 0 {
+			return i<<3 + bits.LeadingZeros8(x)
+		}
+	}
+	return len(key1) << 3
+}
+
+// hashOf returns the hash of a node, the placeholder for empty subtrees.
+func (t *SparseMerkleTree) hashOf(n *sparseNode) []byte {
+	if n == nil {
+		return t.placeholder
+	}
+	return n.hash
+}
+
+// sparseLeafHash computes the hash of a key/value pair leaf.
+func sparseLeafHash(hashFunc TypeHashFunc, key, value []byte) ([]byte, error) {
+	data := make([]byte, 0, 1+len(key)+len(value))
+	data = append(data, sparseLeafPrefix)
+	data = append(data, key...)
+	return hashFunc(append(data, value...))
+}
+
+// sparseNodeHash computes the hash of an internal node out of its children hashes.
+func sparseNodeHash(hashFunc TypeHashFunc, left, right []byte) ([]byte, error) {
+	data := make([]byte, 0, 1+len(left)+len(right))
+	data = append(data, sparseNodePrefix)
+	data = append(data, left...)
+	return hashFunc(append(data, right...))
+}
+
+// newLeaf creates a leaf node for a key/value pair.
+func (t *SparseMerkleTree) newLeaf(key, value []byte) (*sparseNode, error) {
+	h, err := sparseLeafHash(t.HashFunc, key, value)
+	if err != nil {
+		return nil, err
+	}
+	return &sparseNode{hash: h, key: key, value: value}, nil
+}
+
+// newInternal creates an internal node out of its children, at least one of them being non nil.
+func (t *SparseMerkleTree) newInternal(left, right *sparseNode) (*sparseNode, error) {
+	h, err := sparseNodeHash(t.HashFunc, t.hashOf(left), t.hashOf(right))
+	if err != nil {
+		return nil, err
+	}
+	return &sparseNode{hash: h, left: left, right: right}, nil
+}
+
+// build builds the subtree of the specified depth holding the sorted key/value pairs.
+func (t *SparseMerkleTree) build(keys, values [][]byte, depth int) (*sparseNode, error) {
+	switch len(keys) {
+	case 0:
+		return nil, nil
+	case 1:
+		return t.newLeaf(keys[0], values[0])
+	}
+	mid := sort.Search(len(keys), func(i int) bool {
+		return keyBit(keys[i], depth) == 1
+	})
+	left, err := t.build(keys[:mid], values[:mid], depth+1)
+	if err != nil {
+		return nil, err
+	}
+	right, err := t.build(keys[mid:], values[mid:], depth+1)
+	if err != nil {
+		return nil, err
+	}
+	return t.newInternal(left, right)
+}
+
+// workerBuildSparseSubtree is the worker function that builds sparse Merkle Tree subtrees in parallel.
+func workerBuildSparseSubtree(args workerArgs) error {
+	chosenArgs := args.buildSparseSubtree
+	var (
+		tree   = chosenArgs.tree
+		bounds = chosenArgs.bounds
+		err    error
+	)
+	for i := chosenArgs.startIdx; i < len(chosenArgs.subtrees); i += chosenArgs.stride {
+		lo, hi := bounds[i], bounds[i+1]
+		chosenArgs.subtrees[i], err = tree.build(chosenArgs.keys[lo:hi], chosenArgs.values[lo:hi], chosenArgs.depth)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// buildInParallel builds the tree holding the sorted key/value pairs, the subtrees of the first levels
+// being built in parallel.
+func (t *SparseMerkleTree) buildInParallel(keys, values [][]byte) (*sparseNode, error) {
+	wp, releasePool := acquireWorkerPool(&t.Config)
+	defer releasePool()
+
+	// Split the keys in up to NumRoutines subtrees, by their prefix of splitDepth bits out of the first key byte.
+	splitDepth := min(bits.Len(uint(t.NumRoutines))-1, 8)
+	if splitDepth == 0 {
+		return t.build(keys, values, 0)
+	}
+	numSubtrees := 1 << splitDepth
+	bounds := make([]int, numSubtrees+1)
+	for i := 1; i <= numSubtrees; i++ {
+		bounds[i] = sort.Search(len(keys), func(j int) bool {
+			return int(keys[j][0]>>(8-splitDepth)) >= i
+		})
+	}
+
+	// Tasks are spread over a number of workers not exceeding the pool capacity.
+	numRoutines := min(t.NumRoutines, numSubtrees)
+	subtrees := make([]*sparseNode, numSubtrees)
+	argList := make([]workerArgs, numRoutines)
+	for i := 0; i < numRoutines; i++ {
+		argList[i] = workerArgs{
+			buildSparseSubtree: &workerArgsBuildSparseSubtree{
+				tree:     t,
+				keys:     keys,
+				values:   values,
+				subtrees: subtrees,
+				bounds:   bounds,
+				startIdx: i,
+				stride:   numRoutines,
+				depth:    splitDepth,
+			},
+		}
+	}
+	errs, err := wp.Map(workerBuildSparseSubtree, argList)
+	if err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// Build the first levels out of the subtrees, a subtree holding a single leaf being the leaf itself.
+	var buildTop func(lo, hi, depth int) (*sparseNode, error)
+	buildTop = func(lo, hi, depth int) (*sparseNode, error) {
+		if n := bounds[hi] - bounds[lo]; n <= 1 {
+			return t.build(keys[bounds[lo]:bounds[hi]], values[bounds[lo]:bounds[hi]], depth)
+		}
+		if depth == splitDepth {
+			return subtrees[lo], nil
+		}
+		mid := (lo + hi) >> 1
+		left, err := buildTop(lo, mid, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		right, err := buildTop(mid, hi, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		return t.newInternal(left, right)
+	}
+	return buildTop(0, numSubtrees, 0)
+}
+
+// Key returns the key of a file path in the sparse Merkle Tree, i.e. the hash of the path.
+func (t *SparseMerkleTree) Key(path string) ([]byte, error) {
+	return t.HashFunc([]byte(path))
+}
+
+// Get returns the value of a key, and whether the key is a member of the sparse Merkle Tree.
+func (t *SparseMerkleTree) Get(key []byte) ([]byte, bool) {
+	if len(key) != len(t.placeholder) {
+		return nil, false
+	}
+	n := t.root
+	for depth := 0; n != nil && !n.isLeaf(); depth++ {
+		if keyBit(key, depth) == 0 {
+			n = n.left
+		} else {
+			n = n.right
+		}
+	}
+	if n == nil || !bytes.Equal(n.key, key) {
+		return nil, false
+	}
+	return n.value, true
+}
+
+// Put sets the value of a key, adding it to the sparse Merkle Tree if it is not a member yet,
+// and updates the tree Root.
+func (t *SparseMerkleTree) Put(key, value []byte) error {
+	if len(key) != len(t.placeholder) {
+		return ErrSparseInvalidKey
+	}
+	root, added, err := t.put(t.root, key, value, 0)
+	if err != nil {
+		return err
+	}
+	t.root, t.Root = root, root.hash
+	if added {
+		t.NumLeaves++
+	}
+	return nil
+}
+
+// put sets the value of a key in the subtree of a node, and returns the updated node.
+func (t *SparseMerkleTree) put(n *sparseNode, key, value []byte, depth int) (*sparseNode, bool, error) {
+	if n == nil {
+		leaf, err := t.newLeaf(key, value)
+		return leaf, true, err
+	}
+	if n.isLeaf() {
+		if bytes.Equal(n.key, key) {
+			leaf, err := t.newLeaf(key, value)
+			return leaf, false, err
+		}
+		// Split the leaf subtree into the subtree holding both leaves.
+		keys, values := [][]byte{n.key, key}, [][]byte{n.value, value}
+		if keyBit(key, commonPrefixLen(n.key, key)) == 0 {
+			keys[0], keys[1], values[0], values[1] = keys[1], keys[0], values[1], values[0]
+		}
+		subtree, err := t.build(keys, values, depth)
+		return subtree, true, err
+	}
+	left, right := n.left, n.right
+	var (
+		added bool
+		err   error
+	)
+	if keyBit(key, depth) == 0 {
+		left, added, err = t.put(left, key, value, depth+1)
+	} else {
+		right, added, err = t.put(right, key, value, depth+1)
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	internal, err := t.newInternal(left, right)
+	return internal, added, err
+}
+
+// Delete removes a key from the sparse Merkle Tree, and updates the tree Root.
+func (t *SparseMerkleTree) Delete(key []byte) error {
+	if len(key) != len(t.placeholder) {
+		return ErrSparseInvalidKey
+	}
+	root, err := t.delete(t.root, key, 0)
+	if err != nil {
+		return err
+	}
+	t.root, t.Root = root, t.hashOf(root)
+	t.NumLeaves--
+	return nil
+}
+
+// delete removes a key from the subtree of a node, and returns the updated node.
+func (t *SparseMerkleTree) delete(n *sparseNode, key []byte, depth int) (*sparseNode, error) {
+	if n == nil {
+		return nil, ErrSparseKeyNotFound
+	}
+	if n.isLeaf() {
+		if !bytes.Equal(n.key, key) {
+			return nil, ErrSparseKeyNotFound
+		}
+		return nil, nil
+	}
+	left, right := n.left, n.right
+	var err error
+	if keyBit(key, depth) == 0 {
+		left, err = t.delete(left, key, depth+1)
+	} else {
+		right, err = t.delete(right, key, depth+1)
+	}
+	if err != nil {
+		return nil, err
+	}
+	// A subtree left with a single leaf collapses into the leaf.
+	if left == nil && right.isLeaf() {
+		return right, nil
+	}
+	if right == nil && left.isLeaf() {
+		return left, nil
+	}
+	return t.newInternal(left, right)
+}
+
+// prove returns the siblings along the path of a key, and the node ending it.
+func (t *SparseMerkleTree) prove(key []byte) ([][]byte, *sparseNode, error) {
+	if len(key) != len(t.placeholder) {
+		return nil, nil, ErrSparseInvalidKey
+	}
+	siblings := make([][]byte, 0)
+	n := t.root
+	for depth := 0; n != nil && !n.isLeaf(); depth++ {
+		sibling := n.right
+		if keyBit(key, depth) == 1 {
+			sibling, n = n.left, n.right
+		} else {
+			n = n.left
+		}
+		if sibling == nil {
+			siblings = append(siblings, nil)
+		} else {
+			siblings = append(siblings, sibling.hash)
+		}
+	}
+	return siblings, n, nil
+}
+
+// ProveInclusion generates the proof that a key is a member of the sparse Merkle Tree.
+func (t *SparseMerkleTree) ProveInclusion(key []byte) (*SparseProof, error) {
+	siblings, n, err := t.prove(key)
+	if err != nil {
+		return nil, err
+	}
+	if n == nil || !bytes.Equal(n.key, key) {
+		return nil, ErrSparseKeyNotFound
+	}
+	return &SparseProof{Siblings: siblings}, nil
+}
+
+// ProveExclusion generates the proof that a key is not a member of the sparse Merkle Tree.
+func (t *SparseMerkleTree) ProveExclusion(key []byte) (*SparseProof, error) {
+	siblings, n, err := t.prove(key)
+	if err != nil {
+		return nil, err
+	}
+	proof := &SparseProof{Siblings: siblings}
+	if n != nil {
+		if bytes.Equal(n.key, key) {
+			return nil, ErrSparseKeyFound
+		}
+		proof.LeafKey, proof.LeafValue = n.key, n.value
+	}
+	return proof, nil
+}
+
+// VerifyInclusion verifies that a key/value pair is a member of the sparse Merkle Tree.
+func (t *SparseMerkleTree) VerifyInclusion(key, value []byte, proof *SparseProof) (bool, error) {
+	return VerifySparseInclusion(key, value, proof, t.Root, &t.Config)
+}
+
+// VerifyExclusion verifies that a key is not a member of the sparse Merkle Tree.
+func (t *SparseMerkleTree) VerifyExclusion(key []byte, proof *SparseProof) (bool, error) {
+	return VerifySparseExclusion(key, proof, t.Root, &t.Config)
+}
+
+// VerifySparseInclusion checks that a key/value pair is a member of the sparse Merkle Tree of the specified root,
+// using its inclusion proof.
+// It returns true if the pair is a member of the tree, false otherwise. An error is returned in case of
+// any issues during the verification process.
+func VerifySparseInclusion(key, value []byte, proof *SparseProof, root []byte, config *Config) (bool, error) {
+	if proof == nil {
+		return false, ErrProofIsNil
+	}
+	if proof.LeafKey != nil || len(key) != len(root) || len(proof.Siblings) > len(key)<<3 {
+		return false, nil
+	}
+	hashFunc, err := sparseHashFunc(config)
+	if err != nil {
+		return false, err
+	}
+	leaf, err := sparseLeafHash(hashFunc, key, value)
+	if err != nil {
+		return false, err
+	}
+	return verifySparsePath(key, leaf, proof.Siblings, root, hashFunc)
+}
+
+// VerifySparseExclusion checks that a key is not a member of the sparse Merkle Tree of the specified root,
+// using its exclusion proof.
+// It returns true if the key is not a member of the tree, false otherwise. An error is returned in case of
+// any issues during the verification process.
+func VerifySparseExclusion(key []byte, proof *SparseProof, root []byte, config *Config) (bool, error) {
+	if proof == nil {
+		return false, ErrProofIsNil
+	}
+	if len(key) != len(root) || len(proof.Siblings) > len(key)<<3 {
+		return false, nil
+	}
+	hashFunc, err := sparseHashFunc(config)
+	if err != nil {
+		return false, err
+	}
+
+	// The path of the key ends with either an empty subtree, or the leaf of another key sharing its path.
+	node := make([]byte, len(root))
+	if proof.LeafKey != nil {
+		if len(proof.LeafKey) != len(key) || commonPrefixLen(proof.LeafKey, key) < len(proof.Siblings) ||
+			bytes.Equal(proof.LeafKey, key) {
+			return false, nil
+		}
+		if node, err = sparseLeafHash(hashFunc, proof.LeafKey, proof.LeafValue); err != nil {
+			return false, err
+		}
+	}
+	return verifySparsePath(key, node, proof.Siblings, root, hashFunc)
+}
+
+// verifySparsePath computes the root out of the node ending the path of a key and its siblings,
+// and compares it with the expected root.
+func verifySparsePath(key, node []byte, siblings [][]byte, root []byte, hashFunc TypeHashFunc) (bool, error) {
+	var (
+		placeholder = make([]byte, len(root))
+		err         error
+	)
+	for depth := len(siblings) - 1; depth >= 0; depth-- {
+		sibling := siblings[depth]
+		if sibling == nil {
+			sibling = placeholder
+		}
+		if keyBit(key, depth) == 0 {
+			node, err = sparseNodeHash(hashFunc, node, sibling)
+		} else {
+			node, err = sparseNodeHash(hashFunc, sibling, node)
+		}
+		if err != nil {
+			return false, err
+		}
+	}
+	return bytes.Equal(node, root), nil
+}
@@ -0,0 +1,290 @@
+package merkletree
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"testing"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// generatedSparseTestData generates the keys of file paths and their file hash values.
+func generatedSparseTestData(t *testing.T, num int, config *Config) ([][]byte, [][]byte) {
+	keys := make([][]byte, num)
+	values := make([][]byte, num)
+	for i := 0; i < num; i++ {
+		key, err := SparseKey(fmt.Sprintf("dir/file_%d.txt", i), config)
+		if err != nil {
+			t.Fatalf("SparseKey() error = %v", err)
+		}
+		keys[i] = key
+		values[i] = generatedTestDataBlocks(1)[0].(*DataBlock).Data
+	}
+	return keys, values
+}
+
+func TestNewSparse(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   *Config
+		numPairs int
+	}{
+		{
+			name:     "test_empty",
+			config:   nil,
+			numPairs: 0,
+		},
+		{
+			name:     "test_single_pair",
+			config:   nil,
+			numPairs: 1,
+		},
+		{
+			name:     "test_2_pairs",
+			config:   &Config{},
+			numPairs: 2,
+		},
+		{
+			name:     "test_1000_pairs",
+			config:   &Config{},
+			numPairs: 1000,
+		},
+		{
+			name:     "test_1000_pairs_parallel",
+			config:   &Config{RunInParallel: true, NumRoutines: 4},
+			numPairs: 1000,
+		},
+		{
+			name:     "test_100_pairs_parallel_many_routines",
+			config:   &Config{RunInParallel: true, NumRoutines: 1000},
+			numPairs: 100,
+		},
+		{
+			name:     "test_blake3_parallel",
+			config:   &Config{RunInParallel: true, HashAlgorithm: hash.AlgoBLAKE3},
+			numPairs: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keys, values := generatedSparseTestData(t, tt.numPairs, tt.config)
+			tree, err := NewSparse(tt.config, keys, values)
+			if err != nil {
+				t.Fatalf("NewSparse() error = %v", err)
+			}
+			if tree.NumLeaves != tt.numPairs {
+				t.Errorf("NewSparse() NumLeaves = %d, want %d", tree.NumLeaves, tt.numPairs)
+			}
+
+			// The root does not depend on the parallelization nor on the order of the pairs
+			reversedKeys := make([][]byte, len(keys))
+			reversedValues := make([][]byte, len(values))
+			for i := range keys {
+				reversedKeys[len(keys)-1-i], reversedValues[len(keys)-1-i] = keys[i], values[i]
+			}
+			sequentialConfig := new(Config)
+			if tt.config != nil {
+				sequentialConfig.HashAlgorithm = tt.config.HashAlgorithm
+			}
+			sequential, err := NewSparse(sequentialConfig, reversedKeys, reversedValues)
+			if err != nil {
+				t.Fatalf("NewSparse() error = %v", err)
+			}
+			if !bytes.Equal(tree.Root, sequential.Root) {
+				t.Errorf("NewSparse() root = %x, want %x", tree.Root, sequential.Root)
+			}
+
+			for i, key := range keys {
+				if value, ok := tree.Get(key); !ok || !bytes.Equal(value, values[i]) {
+					t.Errorf("Get() pair #%d = %x, %v", i, value, ok)
+				}
+				proof, err := tree.ProveInclusion(key)
+				if err != nil {
+					t.Fatalf("ProveInclusion() error = %v", err)
+				}
+				if valid, err := VerifySparseInclusion(key, values[i], proof, tree.Root, tt.config); err != nil || !valid {
+					t.Errorf("VerifySparseInclusion() pair #%d = %v, error = %v", i, valid, err)
+				}
+				if valid, _ := tree.VerifyInclusion(key, []byte("another value"), proof); valid {
+					t.Errorf("VerifyInclusion() pair #%d verified with another value", i)
+				}
+				if valid, _ := tree.VerifyExclusion(key, proof); valid {
+					t.Errorf("VerifyExclusion() member pair #%d verified", i)
+				}
+				if _, err := tree.ProveExclusion(key); !errors.Is(err, ErrSparseKeyFound) {
+					t.Errorf("ProveExclusion() error = %v, wantErr %v", err, ErrSparseKeyFound)
+				}
+			}
+
+			// Non-members
+			absentKeys, _ := generatedSparseTestData(t, tt.numPairs+50, tt.config)
+			for _, key := range absentKeys[tt.numPairs:] {
+				if _, ok := tree.Get(key); ok {
+					t.Errorf("Get() non-member key %x found", key)
+				}
+				proof, err := tree.ProveExclusion(key)
+				if err != nil {
+					t.Fatalf("ProveExclusion() error = %v", err)
+				}
+				if valid, err := VerifySparseExclusion(key, proof, tree.Root, tt.config); err != nil || !valid {
+					t.Errorf("VerifySparseExclusion() key %x = %v, error = %v", key, valid, err)
+				}
+				if _, err := tree.ProveInclusion(key); !errors.Is(err, ErrSparseKeyNotFound) {
+					t.Errorf("ProveInclusion() error = %v, wantErr %v", err, ErrSparseKeyNotFound)
+				}
+				if len(keys) > 0 {
+					if valid, _ := tree.VerifyExclusion(keys[0], proof); valid {
+						t.Errorf("VerifyExclusion() proof of key %x verified for member key %x", key, keys[0])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestSparseKey_parallelConfig(t *testing.T) {
+	// A config used for computing the keys is then still built in parallel with a concurrent safe hash function
+	config := &Config{RunInParallel: true, NumRoutines: 8}
+	keys, values := generatedSparseTestData(t, 1000, config)
+	if config.HashFunc != nil {
+		t.Fatalf("SparseKey() set the hash function of the config")
+	}
+	for run := 0; run < 5; run++ {
+		tree, err := NewSparse(config, keys, values)
+		if err != nil {
+			t.Fatalf("NewSparse() error = %v", err)
+		}
+		sequential, err := NewSparse(nil, keys, values)
+		if err != nil {
+			t.Fatalf("NewSparse() error = %v", err)
+		}
+		if !bytes.Equal(tree.Root, sequential.Root) {
+			t.Fatalf("NewSparse() run #%d root = %x, want %x", run, tree.Root, sequential.Root)
+		}
+		for i, key := range keys {
+			proof, err := tree.ProveInclusion(key)
+			if err != nil {
+				t.Fatalf("ProveInclusion() error = %v", err)
+			}
+			if valid, err := VerifySparseInclusion(key, values[i], proof, tree.Root, config); err != nil || !valid {
+				t.Errorf("VerifySparseInclusion() pair #%d = %v, error = %v", i, valid, err)
+			}
+		}
+		if config.HashFunc != nil {
+			t.Fatalf("VerifySparseInclusion() set the hash function of the config")
+		}
+	}
+}
+
+func TestSparseMerkleTree_PutDelete(t *testing.T) {
+	const numPairs = 200
+	keys, values := generatedSparseTestData(t, numPairs, nil)
+	full, err := NewSparse(nil, keys, values)
+	if err != nil {
+		t.Fatalf("NewSparse() error = %v", err)
+	}
+
+	// Adding the pairs one by one results in the same tree
+	tree, err := NewSparse(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewSparse() error = %v", err)
+	}
+	emptyRoot := tree.Root
+	for i := range keys {
+		if err := tree.Put(keys[i], values[i]); err != nil {
+			t.Fatalf("Put() error = %v", err)
+		}
+	}
+	if !bytes.Equal(tree.Root, full.Root) || tree.NumLeaves != numPairs {
+		t.Fatalf("Put() root = %x, NumLeaves = %d, want %x, %d", tree.Root, tree.NumLeaves, full.Root, numPairs)
+	}
+
+	// Updating a value changes the root, and restoring it restores the root
+	if err := tree.Put(keys[7], []byte("updated")); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+	if bytes.Equal(tree.Root, full.Root) || tree.NumLeaves != numPairs {
+		t.Errorf("Put() update did not change the root, NumLeaves = %d", tree.NumLeaves)
+	}
+	if err := tree.Put(keys[7], values[7]); err != nil {
+		t.Fatalf("Put() error = %v", err)
+	}
+
+	// Deleting a key results in the tree without it, where its non-membership is provable
+	deleted := keys[numPairs/2]
+	if err := tree.Delete(deleted); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	without, err := NewSparse(nil, append(append([][]byte{}, keys[:numPairs/2]...), keys[numPairs/2+1:]...),
+		append(append([][]byte{}, values[:numPairs/2]...), values[numPairs/2+1:]...))
+	if err != nil {
+		t.Fatalf("NewSparse() error = %v", err)
+	}
+	if !bytes.Equal(tree.Root, without.Root) || tree.NumLeaves != numPairs-1 {
+		t.Fatalf("Delete() root = %x, want %x", tree.Root, without.Root)
+	}
+	proof, err := tree.ProveExclusion(deleted)
+	if err != nil {
+		t.Fatalf("ProveExclusion() error = %v", err)
+	}
+	if valid, err := VerifySparseExclusion(deleted, proof, tree.Root, nil); err != nil || !valid {
+		t.Errorf("VerifySparseExclusion() deleted key = %v, error = %v", valid, err)
+	}
+	if err := tree.Delete(deleted); !errors.Is(err, ErrSparseKeyNotFound) {
+		t.Errorf("Delete() error = %v, wantErr %v", err, ErrSparseKeyNotFound)
+	}
+
+	// Deleting all the keys results in the empty tree
+	for _, key := range keys {
+		if bytes.Equal(key, deleted) {
+			continue
+		}
+		if err := tree.Delete(key); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+	}
+	if !bytes.Equal(tree.Root, emptyRoot) || tree.NumLeaves != 0 {
+		t.Errorf("Delete() root = %x, NumLeaves = %d, want the empty tree", tree.Root, tree.NumLeaves)
+	}
+}
+
+func TestSparseMerkleTree_errors(t *testing.T) {
+	keys, values := generatedSparseTestData(t, 3, nil)
+	if _, err := NewSparse(nil, keys, values[:2]); !errors.Is(err, ErrSparseKeyValueMismatch) {
+		t.Errorf("NewSparse() error = %v, wantErr %v", err, ErrSparseKeyValueMismatch)
+	}
+	if _, err := NewSparse(nil, [][]byte{keys[0], keys[1], keys[0]}, values); !errors.Is(err, ErrSparseDuplicateKey) {
+		t.Errorf("NewSparse() error = %v, wantErr %v", err, ErrSparseDuplicateKey)
+	}
+	if _, err := NewSparse(nil, [][]byte{keys[0], []byte("short")}, values[:2]); !errors.Is(err, ErrSparseInvalidKey) {
+		t.Errorf("NewSparse() error = %v, wantErr %v", err, ErrSparseInvalidKey)
+	}
+	if _, err := NewSparse(&Config{HashAlgorithm: "unknown"}, keys, values); !errors.Is(err, hash.ErrUnsupportedAlgo) {
+		t.Errorf("NewSparse() error = %v, wantErr %v", err, hash.ErrUnsupportedAlgo)
+	}
+
+	tree, err := NewSparse(nil, keys, values)
+	if err != nil {
+		t.Fatalf("NewSparse() error = %v", err)
+	}
+	if err := tree.Put([]byte("short"), values[0]); !errors.Is(err, ErrSparseInvalidKey) {
+		t.Errorf("Put() error = %v, wantErr %v", err, ErrSparseInvalidKey)
+	}
+	if _, err := tree.ProveExclusion([]byte("short")); !errors.Is(err, ErrSparseInvalidKey) {
+		t.Errorf("ProveExclusion() error = %v, wantErr %v", err, ErrSparseInvalidKey)
+	}
+	if _, err := VerifySparseExclusion(keys[0], nil, tree.Root, nil); !errors.Is(err, ErrProofIsNil) {
+		t.Errorf("VerifySparseExclusion() error = %v, wantErr %v", err, ErrProofIsNil)
+	}
+
+	// An inclusion proof of a key is not an exclusion proof of another key sharing its path
+	proof, _ := tree.ProveInclusion(keys[0])
+	forged := &SparseProof{Siblings: proof.Siblings, LeafKey: keys[0], LeafValue: values[0]}
+	if valid, _ := tree.VerifyExclusion(keys[0], forged); valid {
+		t.Errorf("VerifyExclusion() forged proof verified for a member key")
+	}
+	if valid, _ := tree.VerifyInclusion(keys[0], values[0], forged); valid {
+		t.Errorf("VerifyInclusion() verified a proof ending with another leaf")
+	}
+}
@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: MIT
+pragma solidity ^0.8.20;
+
+import {MerkleProof} from "@openzeppelin/contracts/utils/cryptography/MerkleProof.sol";
+
+/// @title Registry of the fileset Merkle roots anchored on-chain
+/// @notice Fixture of the EVM compatible trees of the VRFS merkletree lib, generated with `MerkleTreeEVMConfig`:
+/// the leaves are the Keccak-256 hashes of the file hashes, the internal nodes the Keccak-256 hashes of sorted pairs.
+/// The proofs exported via `EVMProof` and `EVMMultiProof` are the arguments of `verifyFile` and `verifyFiles`.
+contract FilesetRootRegistry {
+    /// @notice Merkle root of each anchored fileset
+    mapping(bytes32 filesetId => bytes32 root) public roots;
+
+    /// @notice Emitted when the Merkle root of a fileset is anchored
+    event FilesetAnchored(bytes32 indexed filesetId, bytes32 root);
+
+    error FilesetAlreadyAnchored(bytes32 filesetId);
+    error FilesetNotAnchored(bytes32 filesetId);
+
+    /// @notice Anchors the Merkle root of a fileset, once
+    function anchor(bytes32 filesetId, bytes32 root) external {
+        if (roots[filesetId] != bytes32(0)) {
+            revert FilesetAlreadyAnchored(filesetId);
+        }
+        roots[filesetId] = root;
+        emit FilesetAnchored(filesetId, root);
+    }
+
+    /// @notice Verifies that a file, identified by its hash, is part of an anchored fileset
+    function verifyFile(bytes32 filesetId, bytes32 fileHash, bytes32[] calldata proof) external view returns (bool) {
+        return MerkleProof.verifyCalldata(proof, _root(filesetId), _leaf(fileHash));
+    }
+
+    /// @notice Verifies that several files are part of an anchored fileset, with a single multiproof.
+    /// The file hashes are expected in the order of the file indices in the fileset
+    function verifyFiles(
+        bytes32 filesetId,
+        bytes32[] calldata fileHashes,
+        bytes32[] calldata proof,
+        bool[] calldata proofFlags
+    ) external view returns (bool) {
+        bytes32[] memory leaves = new bytes32[](fileHashes.length);
+        for (uint256 i = 0; i < fileHashes.length; i++) {
+            leaves[i] = _leaf(fileHashes[i]);
+        }
+        return MerkleProof.multiProofVerify(proof, proofFlags, _root(filesetId), leaves);
+    }
+
+    function _root(bytes32 filesetId) private view returns (bytes32 root) {
+        root = roots[filesetId];
+        if (root == bytes32(0)) {
+            revert FilesetNotAnchored(filesetId);
+        }
+    }
+
+    function _leaf(bytes32 fileHash) private pure returns (bytes32) {
+        return keccak256(abi.encodePacked(fileHash));
+    }
+}
@@ -23,13 +23,49 @@
 package threadpoolexec
 
 import (
+	"context"
+	"errors"
 	"runtime"
+	"sync"
+	"sync/atomic"
 )
 
+// ErrPoolClosed is the error for a task submitted to a closed pool.
+var ErrPoolClosed = errors.New("the pool is closed")
+
 // Pool implements a simple goroutine pool.
 type Pool[A, R any] struct {
 	numWorkers int
 	taskChan   chan task[A, R]
+	// mu protects closed, the context aware submissions hold it while dispatching tasks
+	// so that no task is queued after the workers stop tasks.
+	mu        sync.RWMutex
+	closed    bool
+	closeOnce sync.Once
+	// wg waits for the workers to exit.
+	wg sync.WaitGroup
+	// counters of the tasks run by the workers.
+	counters counters
+}
+
+// counters are the task counters shared by the workers of a pool.
+type counters struct {
+	busy      atomic.Int64
+	completed atomic.Uint64
+}
+
+// Stats is a snapshot of the pool activity.
+type Stats struct {
+	// NumWorkers is the number of workers of the pool.
+	NumWorkers int
+	// QueueDepth is the number of tasks queued, waiting for a worker.
+	QueueDepth int
+	// QueueCapacity is the capacity of the task queue, the submissions block once reached.
+	QueueCapacity int
+	// BusyWorkers is the number of workers running a task.
+	BusyWorkers int
+	// CompletedTasks is the number of tasks run since the pool creation.
+	CompletedTasks uint64
 }
 
 // NewPool creates a new goroutine pool with the given number of workers and job queue capacity.
@@ -46,58 +82,181 @@ func NewPool[A, R any](numWorkers, cap int) *Pool[A, R] {
 		numWorkers: numWorkers,
 		taskChan:   make(chan task[A, R], cap),
 	}
+	p.wg.Add(numWorkers)
 	for i := 0; i < numWorkers; i++ {
-		newWorker(p.taskChan)
+		newWorker(p.taskChan, &p.wg, &p.counters)
 	}
 	return p
 }
 
+// NumWorkers returns the number of workers of the pool.
+func (p *Pool[A, R]) NumWorkers() int {
+	return p.numWorkers
+}
+
+// Stats returns a snapshot of the pool activity, e.g. for monitoring the contention of a pool
+// shared by concurrent callers.
+func (p *Pool[A, R]) Stats() Stats {
+	return Stats{
+		NumWorkers:     p.numWorkers,
+		QueueDepth:     len(p.taskChan),
+		QueueCapacity:  cap(p.taskChan),
+		BusyWorkers:    int(p.counters.busy.Load()),
+		CompletedTasks: p.counters.completed.Load(),
+	}
+}
+
 // Submit submits a task and waits for the result.
-func (p *Pool[A, R]) Submit(handler func(A) R, args A) R {
-	result := p.AsyncSubmit(handler, args)
-	return <-result
+// ErrPoolClosed is returned if the pool is closed.
+func (p *Pool[A, R]) Submit(handler func(A) R, args A) (R, error) {
+	result, err := p.AsyncSubmit(handler, args)
+	if err != nil {
+		var zero R
+		return zero, err
+	}
+	return <-result, nil
 }
 
 // AsyncSubmit submits a task and returns the channel to wait for the result.
-func (p *Pool[A, R]) AsyncSubmit(handler func(A) R, args A) chan R {
-	resChan := make(chan R)
-	p.taskChan <- task[A, R]{
+// The result channel is buffered, so that the worker never waits for the result to be received.
+// ErrPoolClosed is returned if the pool is closed.
+func (p *Pool[A, R]) AsyncSubmit(handler func(A) R, args A) (chan R, error) {
+	resChan := make(chan R, 1)
+	if err := p.dispatch(context.Background(), task[A, R]{
 		handler: handler,
 		args:    args,
 		result:  resChan,
+	}); err != nil {
+		return nil, err
 	}
-	return resChan
+	return resChan, nil
 }
 
 // Map submits a batch of tasks and waits for the results.
-func (p *Pool[A, R]) Map(handler func(A) R, args []A) []R {
-	resultChanList := p.AsyncMap(handler, args)
+// It is safe for concurrent use, the tasks of concurrent calls share the workers.
+// ErrPoolClosed is returned if the pool is closed.
+func (p *Pool[A, R]) Map(handler func(A) R, args []A) ([]R, error) {
+	resultChanList, err := p.AsyncMap(handler, args)
+	if err != nil {
+		return nil, err
+	}
 	results := make([]R, len(args))
 	for i := 0; i < len(args); i++ {
 		results[i] = <-resultChanList[i]
 	}
-	return results
+	return results, nil
 }
 
 // AsyncMap submits a batch of tasks and returns the channel to wait for the results.
-func (p *Pool[A, R]) AsyncMap(handler func(A) R, args []A) []chan R {
+// ErrPoolClosed is returned if the pool is closed, the tasks already dispatched still being executed.
+func (p *Pool[A, R]) AsyncMap(handler func(A) R, args []A) ([]chan R, error) {
 	resultChanList := make([]chan R, len(args))
 	for i := 0; i < len(args); i++ {
-		resultChanList[i] = make(chan R)
-		p.taskChan <- task[A, R]{
+		resultChanList[i] = make(chan R, 1)
+		if err := p.dispatch(context.Background(), task[A, R]{
 			handler: handler,
 			args:    args[i],
 			result:  resultChanList[i],
+		}); err != nil {
+			return nil, err
 		}
 	}
-	return resultChanList
+	return resultChanList, nil
 }
 
-// Close closes the pool and waits for all the workers to stop.
-func (p *Pool[A, R]) Close() {
-	for i := 0; i < p.numWorkers; i++ {
-		p.taskChan <- task[A, R]{
-			stop: true,
+// SubmitCtx submits a task and waits for the result, unless the context is done before.
+// The handler is provided the context. The returned error is the context error if it is done before the task
+// is dispatched or completed, or ErrPoolClosed if the pool is closed.
+func (p *Pool[A, R]) SubmitCtx(ctx context.Context, handler func(context.Context, A) R, args A) (R, error) {
+	var zero R
+	resChan := make(chan R, 1)
+	if err := p.dispatch(ctx, task[A, R]{
+		handler: func(a A) R { return handler(ctx, a) },
+		args:    args,
+		result:  resChan,
+	}); err != nil {
+		return zero, err
+	}
+	select {
+	case res := <-resChan:
+		return res, nil
+	case <-ctx.Done():
+		return zero, ctx.Err()
+	}
+}
+
+// MapCtx submits a batch of tasks and waits for the results of the dispatched ones.
+// The tasks dispatching stops on the first task error, i.e. a non nil result if R is an error type,
+// or once the context is done. The tasks already dispatched but not started yet are then skipped,
+// their result being the zero value, and the handlers are provided a cancelled context.
+// The returned error is the first task error, the context error, or ErrPoolClosed if the pool is closed.
+func (p *Pool[A, R]) MapCtx(ctx context.Context, handler func(context.Context, A) R, args []A) ([]R, error) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	taskHandler := func(a A) R {
+		if ctx.Err() != nil {
+			var zero R
+			return zero
 		}
+		res := handler(ctx, a)
+		if err, ok := any(res).(error); ok && err != nil {
+			cancel(err)
+		}
+		return res
+	}
+
+	resultChanList := make([]chan R, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		resChan := make(chan R, 1)
+		if err := p.dispatch(ctx, task[A, R]{
+			handler: taskHandler,
+			args:    args[i],
+			result:  resChan,
+		}); errors.Is(err, ErrPoolClosed) {
+			return nil, err
+		} else if err != nil {
+			break
+		}
+		resultChanList = append(resultChanList, resChan)
+	}
+
+	results := make([]R, len(args))
+	for i := 0; i < len(resultChanList); i++ {
+		results[i] = <-resultChanList[i]
 	}
+	return results, context.Cause(ctx)
+}
+
+// dispatch queues a task, unless the context is done or the pool is closed before.
+func (p *Pool[A, R]) dispatch(ctx context.Context, t task[A, R]) error {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if p.closed {
+		return ErrPoolClosed
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case p.taskChan <- t:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close closes the pool and waits for all the workers to stop, once the queued tasks are executed.
+// It can be called several times.
+func (p *Pool[A, R]) Close() {
+	p.closeOnce.Do(func() {
+		p.mu.Lock()
+		p.closed = true
+		p.mu.Unlock()
+		for i := 0; i < p.numWorkers; i++ {
+			p.taskChan <- task[A, R]{
+				stop: true,
+			}
+		}
+	})
+	p.wg.Wait()
 }
\ No newline at end of file
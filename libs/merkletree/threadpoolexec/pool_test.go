@@ -23,8 +23,14 @@
 package threadpoolexec
 
 import (
+	"context"
+	"errors"
+	"fmt"
 	"runtime"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func BenchmarkNormalGoroutine(b *testing.B) {
@@ -55,6 +61,61 @@ func BenchmarkPool(b *testing.B) {
 	}
 }
 
+// busyHandler is a CPU bound task, of about the cost of hashing a pair of tree nodes.
+func busyHandler(n int) int {
+	for k := 0; k < 1000; k++ {
+		n ^= k
+	}
+	return n
+}
+
+// BenchmarkPool_MapContention benchmarks concurrent callers mapping batches of tasks, either on a single
+// shared pool or on a pool created per batch, as done when each tree building process owns its pool.
+func BenchmarkPool_MapContention(b *testing.B) {
+	args := make([]int, 64)
+	for _, numCallers := range []int{1, 4, 16, 64} {
+		b.Run(fmt.Sprintf("shared_%d_callers", numCallers), func(b *testing.B) {
+			p := NewPool[int, int](0, 0)
+			defer p.Close()
+			b.SetParallelism(numCallers)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					p.Map(busyHandler, args)
+				}
+			})
+		})
+		b.Run(fmt.Sprintf("per_call_%d_callers", numCallers), func(b *testing.B) {
+			b.SetParallelism(numCallers)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					p := NewPool[int, int](0, 0)
+					p.Map(busyHandler, args)
+					p.Close()
+				}
+			})
+		})
+	}
+}
+
+// BenchmarkPool_SubmitContention benchmarks concurrent callers submitting single tasks to a shared pool.
+func BenchmarkPool_SubmitContention(b *testing.B) {
+	for _, numCallers := range []int{1, 16, 256} {
+		b.Run(fmt.Sprintf("%d_callers", numCallers), func(b *testing.B) {
+			p := NewPool[int, int](4, 0)
+			defer p.Close()
+			b.SetParallelism(numCallers)
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					p.Submit(busyHandler, 0)
+				}
+			})
+		})
+	}
+}
+
 func TestPool_Submit(t *testing.T) {
 	type args struct {
 		handler func(any) any
@@ -186,4 +247,210 @@ func TestNewPool(t *testing.T) {
 			}
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestPool_MapCtx(t *testing.T) {
+	errTask := errors.New("test_task_err")
+	tests := []struct {
+		name       string
+		numTasks   int
+		failingIdx int
+		cancelled  bool
+		wantErr    error
+	}{
+		{
+			name:       "test_all_tasks",
+			numTasks:   100,
+			failingIdx: -1,
+		},
+		{
+			name:       "test_first_error",
+			numTasks:   1000,
+			failingIdx: 10,
+			wantErr:    errTask,
+		},
+		{
+			name:       "test_cancelled",
+			numTasks:   100,
+			failingIdx: -1,
+			cancelled:  true,
+			wantErr:    context.Canceled,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewPool[int, error](4, 0)
+			defer p.Close()
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			if tt.cancelled {
+				cancel()
+			}
+			args := make([]int, tt.numTasks)
+			for i := range args {
+				args[i] = i
+			}
+			var numRun atomic.Int32
+			results, err := p.MapCtx(ctx, func(_ context.Context, i int) error {
+				numRun.Add(1)
+				if i == tt.failingIdx {
+					return errTask
+				}
+				return nil
+			}, args)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("MapCtx() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(results) != tt.numTasks {
+				t.Errorf("MapCtx() results = %d, want %d", len(results), tt.numTasks)
+			}
+			// No task is dispatched once failed or cancelled, out of the queued ones
+			if n := int(numRun.Load()); tt.wantErr == nil && n != tt.numTasks || tt.wantErr != nil && n >= tt.numTasks {
+				t.Errorf("MapCtx() run tasks = %d, out of %d", n, tt.numTasks)
+			}
+		})
+	}
+
+	// More tasks than workers and queue capacity do not block
+	p := NewPool[int, int](2, 1)
+	defer p.Close()
+	results, err := p.Map(func(i int) int { return i * 2 }, make([]int, 50))
+	if err != nil || len(results) != 50 {
+		t.Errorf("Map() results = %d, error = %v, want 50", len(results), err)
+	}
+}
+
+func TestPool_SubmitCtx(t *testing.T) {
+	p := NewPool[int, int](1, 0)
+	res, err := p.SubmitCtx(context.Background(), func(_ context.Context, i int) int { return i + 1 }, 41)
+	if err != nil || res != 42 {
+		t.Errorf("SubmitCtx() = %d, error = %v", res, err)
+	}
+
+	// The context gets done while the task is running
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := p.SubmitCtx(ctx, func(ctx context.Context, i int) int {
+		<-ctx.Done()
+		return i
+	}, 0); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("SubmitCtx() error = %v, wantErr %v", err, context.DeadlineExceeded)
+	}
+
+	p.Close()
+	if _, err := p.SubmitCtx(context.Background(), func(_ context.Context, i int) int { return i }, 0); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("SubmitCtx() error = %v, wantErr %v", err, ErrPoolClosed)
+	}
+	if _, err := p.MapCtx(context.Background(), func(_ context.Context, i int) int { return i }, []int{0}); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("MapCtx() error = %v, wantErr %v", err, ErrPoolClosed)
+	}
+}
+
+func TestPool_Close(t *testing.T) {
+	p := NewPool[any, any](4, 0)
+	var numDone atomic.Int32
+	for i := 0; i < 8; i++ {
+		p.AsyncSubmit(func(any) any {
+			time.Sleep(time.Millisecond)
+			numDone.Add(1)
+			return nil
+		}, nil)
+	}
+
+	// The queued tasks are executed and the workers exited once closed, closing again is a no-op
+	p.Close()
+	if n := numDone.Load(); n != 8 {
+		t.Errorf("Close() returned with %d tasks done, want 8", n)
+	}
+	p.Close()
+}
+
+func TestPool_MapConcurrent(t *testing.T) {
+	const numCallers, numTasks = 16, 100
+	p := NewPool[int, int](4, 0)
+	defer p.Close()
+	var wg sync.WaitGroup
+	wg.Add(numCallers)
+	for c := 0; c < numCallers; c++ {
+		go func(c int) {
+			defer wg.Done()
+			args := make([]int, numTasks)
+			for i := range args {
+				args[i] = c*numTasks + i
+			}
+			results, err := p.Map(func(n int) int { return 2 * n }, args)
+			if err != nil {
+				t.Errorf("Map() caller #%d error = %v", c, err)
+			}
+			for i, res := range results {
+				if res != 2*args[i] {
+					t.Errorf("Map() caller #%d result #%d = %d, want %d", c, i, res, 2*args[i])
+				}
+			}
+		}(c)
+	}
+	wg.Wait()
+	if stats := p.Stats(); stats.CompletedTasks != numCallers*numTasks {
+		t.Errorf("Stats() CompletedTasks = %d, want %d", stats.CompletedTasks, numCallers*numTasks)
+	}
+}
+
+func TestPool_Stats(t *testing.T) {
+	p := NewPool[any, any](2, 4)
+	defer p.Close()
+	if got, want := p.Stats(), (Stats{NumWorkers: 2, QueueCapacity: 4}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	// Both workers are blocked on a task, the other tasks are queued
+	started, release := make(chan struct{}), make(chan struct{})
+	blocking := func(any) any {
+		started <- struct{}{}
+		<-release
+		return nil
+	}
+	var results []chan any
+	for i := 0; i < 5; i++ {
+		res, err := p.AsyncSubmit(blocking, nil)
+		if err != nil {
+			t.Fatalf("AsyncSubmit() error = %v", err)
+		}
+		results = append(results, res)
+	}
+	<-started
+	<-started
+	if got, want := p.Stats(), (Stats{NumWorkers: 2, QueueDepth: 3, QueueCapacity: 4, BusyWorkers: 2}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+
+	close(release)
+	go func() {
+		for range started {
+		}
+	}()
+	for _, res := range results {
+		<-res
+	}
+	if got, want := p.Stats(), (Stats{NumWorkers: 2, QueueCapacity: 4, CompletedTasks: 5}); got != want {
+		t.Errorf("Stats() = %+v, want %+v", got, want)
+	}
+	close(started)
+}
+
+func TestPool_closed(t *testing.T) {
+	p := NewPool[any, any](1, 0)
+	p.Close()
+	handler := func(any) any { return nil }
+	if _, err := p.Submit(handler, nil); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Submit() error = %v, wantErr %v", err, ErrPoolClosed)
+	}
+	if _, err := p.AsyncSubmit(handler, nil); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("AsyncSubmit() error = %v, wantErr %v", err, ErrPoolClosed)
+	}
+	if _, err := p.Map(handler, []any{nil}); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("Map() error = %v, wantErr %v", err, ErrPoolClosed)
+	}
+	if _, err := p.AsyncMap(handler, []any{nil}); !errors.Is(err, ErrPoolClosed) {
+		t.Errorf("AsyncMap() error = %v, wantErr %v", err, ErrPoolClosed)
+	}
+}
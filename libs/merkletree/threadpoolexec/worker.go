@@ -22,6 +22,8 @@
 
 package threadpoolexec
 
+import "sync"
+
 // task is a job to be executed by a worker.
 type task[A, R any] struct {
 	handler func(A) R
@@ -31,22 +33,31 @@ type task[A, R any] struct {
 }
 
 type worker[A, R any] struct {
-	jobChan chan task[A, R]
+	jobChan  chan task[A, R]
+	wg       *sync.WaitGroup
+	counters *counters
 }
 
-func newWorker[A, R any](jobChan chan task[A, R]) *worker[A, R] {
+func newWorker[A, R any](jobChan chan task[A, R], wg *sync.WaitGroup, counters *counters) *worker[A, R] {
 	w := &worker[A, R]{
-		jobChan: jobChan,
+		jobChan:  jobChan,
+		wg:       wg,
+		counters: counters,
 	}
 	go w.run()
 	return w
 }
 
 func (w *worker[A, R]) run() {
+	defer w.wg.Done()
 	for job := range w.jobChan {
 		if job.stop {
 			return
 		}
-		job.result <- job.handler(job.args)
+		w.counters.busy.Add(1)
+		res := job.handler(job.args)
+		w.counters.busy.Add(-1)
+		w.counters.completed.Add(1)
+		job.result <- res
 	}
 }
\ No newline at end of file
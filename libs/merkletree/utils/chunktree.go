@@ -0,0 +1,379 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+
+	mt "github.com/ja88a/vrfs-go-merkletree/libs/merkletree"
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// Default size of the file chunks forming the leaves of a file chunk tree
+const DefaultFileChunkSize = 256 * 1024
+
+var (
+	// ErrInvalidChunkSize is the error for a file chunk size lower than 1.
+	ErrInvalidChunkSize = errors.New("the file chunk size must be a positive number of bytes")
+	// ErrChunkIndexOutOfRange is the error for a file chunk index not part of the file.
+	ErrChunkIndexOutOfRange = errors.New("file chunk index out of range")
+	// ErrInvalidByteRange is the error for a byte range not contained in the file.
+	ErrInvalidByteRange = errors.New("invalid file byte range")
+	// ErrChunkVerificationFailed is the error for a file chunk not matching its file chunk root.
+	ErrChunkVerificationFailed = errors.New("file chunk verification failed")
+)
+
+// FileChunkTree is the Merkle Tree built over the fixed-size chunks of a file.
+//
+// Its root is used as the file leaf of a fileset Merkle Tree, in place of the whole file hash,
+// so that any chunk, hence any byte range, of the file can be verified against the fileset root.
+// The root commits to the file size, i.e. to its number of chunks: it is the hash of the chunk
+// hashes tree root followed by the file size, as a big endian uint64.
+// The tree of a single chunk file, including the empty file, is reduced to its chunk hash
+type FileChunkTree struct {
+	// Size of the file chunks, the last chunk of the file may be shorter
+	ChunkSize int
+	// Size of the file, in bytes
+	FileSize int64
+	// Hash of each file chunk, they are the leaves of the tree
+	ChunkHashes [][]byte
+	// Root of the file chunk tree, committing to the file size
+	Root []byte
+	// Merkle Tree of the chunk hashes, nil for a single chunk file
+	tree *mt.MerkleTree
+}
+
+// Config for generating the Merkle Tree of file chunk hashes, with the specified hash algorithm
+//
+// The chunk hashes are computed while streaming the file, they are used as is for the leaves
+func FileChunkTreeConfig(hashAlgo string) *mt.Config {
+	mtConfig := mt.MerkleTreeDefaultConfig(false)
+	mtConfig.Mode = mt.ModeProofGen
+	if hashAlgo != "" {
+		mtConfig.HashAlgorithm = hashAlgo
+	}
+	return mtConfig
+}
+
+// Compute the file chunk tree of a file, its content being streamed through the hash function chunk per chunk
+//
+// The name of the hash algorithm is the one registered in the hash package, the default one is used if empty
+func ComputeFileChunkTree(filePath string, hashAlgo string, chunkSize int) (*FileChunkTree, error) {
+	return computeFileChunkTree(openLocalFile, filePath, hashAlgo, chunkSize, make([]byte, fileHashBufferSize))
+}
+
+// Compute the file chunk tree of a file, its content being opened with the specified function and streamed
+// with the provided buffer
+func computeFileChunkTree(open FileOpenFunc, filePath string, hashAlgo string, chunkSize int, buffer []byte) (*FileChunkTree, error) {
+	file, err := open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("file chunks hashing process failed on reading content of file '%v'\nError:\n%v", filePath, err)
+	}
+	defer file.Close()
+
+	chunkTree, err := newFileChunkTree(file, hashAlgo, chunkSize, buffer)
+	if err != nil {
+		return nil, fmt.Errorf("file chunks hashing process failed for file '%v'\n%w", filePath, err)
+	}
+	return chunkTree, nil
+}
+
+// Compute the file chunk tree roots of all provided file paths, to be used as the fileset leaves
+//
+// The file chunk trees are computed concurrently, with as many workers as CPUs if numWorkers is lower than 1.
+// The roots are returned in the order of the provided file paths
+func ComputeFileChunkRoots(filePaths []string, hashAlgo string, chunkSize int, numWorkers int) ([][]byte, error) {
+	return ComputeFileChunkRootsWith(openLocalFile, filePaths, hashAlgo, chunkSize, numWorkers)
+}
+
+// Compute the file chunk tree roots of all provided file paths, their contents being opened with the specified function
+//
+// The roots are the ones of `ComputeFileChunkRoots`, for files not necessarily stored locally
+func ComputeFileChunkRootsWith(open FileOpenFunc, filePaths []string, hashAlgo string, chunkSize int, numWorkers int) ([][]byte, error) {
+	if chunkSize <= 0 {
+		return nil, ErrInvalidChunkSize
+	}
+	if _, err := hash.NewDigest(hashAlgo); err != nil {
+		return nil, fmt.Errorf("file chunks hashing process failed on selecting the hash algorithm\n%w", err)
+	}
+
+	return computeFilesConcurrently(len(filePaths), numWorkers, func(fileIndex int, buffer []byte) ([]byte, error) {
+		chunkTree, err := computeFileChunkTree(open, filePaths[fileIndex], hashAlgo, chunkSize, buffer)
+		if err != nil {
+			return nil, err
+		}
+		return chunkTree.Root, nil
+	})
+}
+
+// Build the file chunk tree of a file content read from the provided reader
+func NewFileChunkTree(r io.Reader, hashAlgo string, chunkSize int) (*FileChunkTree, error) {
+	return newFileChunkTree(r, hashAlgo, chunkSize, make([]byte, fileHashBufferSize))
+}
+
+// Build the file chunk tree of a content read with the provided buffer
+func newFileChunkTree(r io.Reader, hashAlgo string, chunkSize int, buffer []byte) (*FileChunkTree, error) {
+	if chunkSize <= 0 {
+		return nil, ErrInvalidChunkSize
+	}
+	chunkHashes, fileSize, err := computeChunkHashes(r, hashAlgo, chunkSize, buffer)
+	if err != nil {
+		return nil, err
+	}
+
+	chunkTree := &FileChunkTree{
+		ChunkSize:   chunkSize,
+		FileSize:    fileSize,
+		ChunkHashes: chunkHashes,
+	}
+	// The root of a single chunk tree is the chunk hash
+	if chunkTree.tree, err = GenerateMerkleTreeWithConfig(chunkHashes, FileChunkTreeConfig(hashAlgo)); err != nil {
+		return nil, err
+	}
+	if chunkTree.Root, err = fileChunkRoot(chunkTree.tree.Root, fileSize, hashAlgo); err != nil {
+		return nil, err
+	}
+	return chunkTree, nil
+}
+
+// Stream a content through the hash function, chunk per chunk, and return the chunk hashes
+// with the total content size. An empty content is made of a single empty chunk
+func computeChunkHashes(r io.Reader, hashAlgo string, chunkSize int, buffer []byte) ([][]byte, int64, error) {
+	digest, err := hash.NewDigest(hashAlgo)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var (
+		chunkHashes [][]byte
+		fileSize    int64
+		chunkLen    int
+	)
+	for {
+		n, err := r.Read(buffer[:min(len(buffer), chunkSize-chunkLen)])
+		digest.Write(buffer[:n])
+		chunkLen += n
+		fileSize += int64(n)
+		if chunkLen == chunkSize {
+			chunkHashes = append(chunkHashes, digest.Sum(nil))
+			digest.Reset()
+			chunkLen = 0
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	if chunkLen > 0 || len(chunkHashes) == 0 {
+		chunkHashes = append(chunkHashes, digest.Sum(nil))
+	}
+	return chunkHashes, fileSize, nil
+}
+
+// Compute the file chunk root out of the chunk hashes tree root and the file size
+func fileChunkRoot(treeRoot []byte, fileSize int64, hashAlgo string) ([]byte, error) {
+	digest, err := hash.NewDigest(hashAlgo)
+	if err != nil {
+		return nil, err
+	}
+	digest.Write(treeRoot)
+	digest.Write(binary.BigEndian.AppendUint64(nil, uint64(fileSize)))
+	return digest.Sum(nil), nil
+}
+
+// NumChunks returns the number of chunks of the file
+func (c *FileChunkTree) NumChunks() int {
+	return len(c.ChunkHashes)
+}
+
+// ChunkProof returns the Merkle proof of a file chunk towards the chunk hashes tree root
+//
+// The proof of a single chunk file has no sibling
+func (c *FileChunkTree) ChunkProof(chunkIndex int) (*mt.Proof, error) {
+	if chunkIndex < 0 || chunkIndex >= c.NumChunks() {
+		return nil, fmt.Errorf("%w: %d not in [0, %d)", ErrChunkIndexOutOfRange, chunkIndex, c.NumChunks())
+	}
+	return c.tree.Proofs[chunkIndex], nil
+}
+
+// Compute the number of chunks of a file, an empty file being made of a single empty chunk
+func NumFileChunks(fileSize int64, chunkSize int) int {
+	if fileSize <= 0 || chunkSize <= 0 {
+		return 1
+	}
+	return int((fileSize + int64(chunkSize) - 1) / int64(chunkSize))
+}
+
+// Compute the range of chunk indexes, bounds included, covering the specified byte range of a file
+func ChunkRange(offset int64, length int64, fileSize int64, chunkSize int) (first int, last int, err error) {
+	if chunkSize <= 0 {
+		return 0, 0, ErrInvalidChunkSize
+	}
+	if offset < 0 || length <= 0 || offset+length > fileSize {
+		return 0, 0, fmt.Errorf("%w: offset %d length %d for a file size of %d", ErrInvalidByteRange, offset, length, fileSize)
+	}
+	return int(offset / int64(chunkSize)), int((offset + length - 1) / int64(chunkSize)), nil
+}
+
+// FileChunkVerifier verifies the chunks of a file against its file chunk root,
+// e.g. a file leaf verified against its fileset Merkle Tree root
+type FileChunkVerifier struct {
+	// Root of the file chunk tree, committing to the file size
+	Root []byte
+	// Size of the file, in bytes
+	FileSize int64
+	// Size of the file chunks
+	ChunkSize int
+	// Name of the hash algorithm, the default one is used if empty
+	HashAlgo string
+}
+
+// VerifyChunk checks that a file chunk content is the one at the specified index, using its chunk proof
+//
+// The chunk length and the proof path must match the chunk index, so that chunks can neither be
+// truncated nor swapped. It returns true if the chunk is valid, false otherwise
+func (v *FileChunkVerifier) VerifyChunk(chunkIndex int, chunk []byte, proof *mt.Proof) (bool, error) {
+	if v.ChunkSize <= 0 {
+		return false, ErrInvalidChunkSize
+	}
+	if proof == nil {
+		return false, mt.ErrProofIsNil
+	}
+	numChunks := NumFileChunks(v.FileSize, v.ChunkSize)
+	if chunkIndex < 0 || chunkIndex >= numChunks {
+		return false, fmt.Errorf("%w: %d not in [0, %d)", ErrChunkIndexOutOfRange, chunkIndex, numChunks)
+	}
+	if int64(len(chunk)) != v.chunkLength(chunkIndex) {
+		return false, nil
+	}
+
+	// The chunk index is set by the proof path, since the chunk hashes tree is a full binary tree
+	// whose odd nodes are duplicated: a sibling on the right is set for even node indexes
+	depth := bits.Len(uint(numChunks - 1))
+	if len(proof.Siblings) != depth {
+		return false, nil
+	}
+	if pathIndex := ^int(proof.Path) & (1<<depth - 1); pathIndex != chunkIndex {
+		return false, nil
+	}
+
+	digest, err := hash.NewDigest(v.HashAlgo)
+	if err != nil {
+		return false, err
+	}
+	digest.Write(chunk)
+	result := digest.Sum(nil)
+	path := proof.Path
+	for _, sib := range proof.Siblings {
+		digest.Reset()
+		if path&1 == 1 {
+			digest.Write(result)
+			digest.Write(sib)
+		} else {
+			digest.Write(sib)
+			digest.Write(result)
+		}
+		result = digest.Sum(result[:0])
+		path >>= 1
+	}
+
+	root, err := fileChunkRoot(result, v.FileSize, v.HashAlgo)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(root, v.Root), nil
+}
+
+// chunkLength returns the expected length of a file chunk, the last one may be shorter
+func (v *FileChunkVerifier) chunkLength(chunkIndex int) int64 {
+	return min(int64(v.ChunkSize), v.FileSize-int64(chunkIndex)*int64(v.ChunkSize))
+}
+
+// ChunkProofFunc provides the proof of a file chunk from its index, e.g. retrieved from the file storage
+type ChunkProofFunc func(chunkIndex int) (*mt.Proof, error)
+
+// NewReader returns a reader verifying the chunks `first` to `last`, bounds included, of the file content
+// streamed from r, the stream being expected to start at the beginning of the first chunk
+//
+// A chunk is only made available once fully received and verified, so that no unverified data is ever
+// returned. An error wrapping ErrChunkVerificationFailed is returned as soon as a chunk is invalid, and
+// io.ErrUnexpectedEOF if the stream ends before the last chunk
+func (v *FileChunkVerifier) NewReader(r io.Reader, first int, last int, proofs ChunkProofFunc) (io.Reader, error) {
+	if v.ChunkSize <= 0 {
+		return nil, ErrInvalidChunkSize
+	}
+	if numChunks := NumFileChunks(v.FileSize, v.ChunkSize); first < 0 || first > last || last >= numChunks {
+		return nil, fmt.Errorf("%w: [%d, %d] not in [0, %d)", ErrChunkIndexOutOfRange, first, last, numChunks)
+	}
+	if _, err := hash.NewDigest(v.HashAlgo); err != nil {
+		return nil, err
+	}
+	return &chunkVerifyingReader{
+		r:          r,
+		verifier:   v,
+		proofs:     proofs,
+		chunkIndex: first,
+		lastChunk:  last,
+		chunk:      make([]byte, v.ChunkSize),
+	}, nil
+}
+
+// chunkVerifyingReader is the reader of verified file chunks
+type chunkVerifyingReader struct {
+	r          io.Reader
+	verifier   *FileChunkVerifier
+	proofs     ChunkProofFunc
+	chunkIndex int
+	lastChunk  int
+	chunk      []byte
+	pending    []byte
+	err        error
+}
+
+// Read returns the content of the verified file chunks
+func (c *chunkVerifyingReader) Read(p []byte) (int, error) {
+	for len(c.pending) == 0 {
+		if c.err != nil {
+			return 0, c.err
+		}
+		c.err = c.nextChunk()
+	}
+	n := copy(p, c.pending)
+	c.pending = c.pending[n:]
+	return n, nil
+}
+
+// nextChunk reads and verifies the next chunk of the stream, io.EOF is returned once the last chunk is read
+func (c *chunkVerifyingReader) nextChunk() error {
+	if c.chunkIndex > c.lastChunk {
+		return io.EOF
+	}
+	chunk := c.chunk[:c.verifier.chunkLength(c.chunkIndex)]
+	if _, err := io.ReadFull(c.r, chunk); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return fmt.Errorf("failed to read file chunk %d\n%w", c.chunkIndex, err)
+	}
+
+	proof, err := c.proofs(c.chunkIndex)
+	if err != nil {
+		return fmt.Errorf("failed to retrieve the proof of file chunk %d\n%w", c.chunkIndex, err)
+	}
+	valid, err := c.verifier.VerifyChunk(c.chunkIndex, chunk, proof)
+	if err != nil {
+		return fmt.Errorf("failed to verify file chunk %d\n%w", c.chunkIndex, err)
+	}
+	if !valid {
+		return fmt.Errorf("%w: chunk %d does not match root %x", ErrChunkVerificationFailed, c.chunkIndex, c.verifier.Root)
+	}
+
+	c.pending = chunk
+	c.chunkIndex++
+	return nil
+}
@@ -0,0 +1,286 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	mt "github.com/ja88a/vrfs-go-merkletree/libs/merkletree"
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// testChunkContent returns a deterministic content of the specified size.
+func testChunkContent(size int) []byte {
+	content := make([]byte, size)
+	for i := range content {
+		content[i] = byte(i*31 + i>>8)
+	}
+	return content
+}
+
+// chunkProofs returns the chunk proof function of a file chunk tree.
+func chunkProofs(chunkTree *FileChunkTree) ChunkProofFunc {
+	return func(chunkIndex int) (*mt.Proof, error) {
+		return chunkTree.ChunkProof(chunkIndex)
+	}
+}
+
+func TestNewFileChunkTree(t *testing.T) {
+	tests := []struct {
+		name          string
+		size          int
+		chunkSize     int
+		hashAlgo      string
+		wantNumChunks int
+	}{
+		{
+			name:          "test_empty_file",
+			size:          0,
+			chunkSize:     16,
+			wantNumChunks: 1,
+		},
+		{
+			name:          "test_single_partial_chunk",
+			size:          10,
+			chunkSize:     16,
+			wantNumChunks: 1,
+		},
+		{
+			name:          "test_single_full_chunk",
+			size:          16,
+			chunkSize:     16,
+			wantNumChunks: 1,
+		},
+		{
+			name:          "test_2_chunks",
+			size:          17,
+			chunkSize:     16,
+			wantNumChunks: 2,
+		},
+		{
+			name:          "test_odd_chunks",
+			size:          5*16 + 3,
+			chunkSize:     16,
+			wantNumChunks: 6,
+		},
+		{
+			name:          "test_chunks_larger_than_buffer",
+			size:          3*fileHashBufferSize + 2,
+			chunkSize:     fileHashBufferSize + 1,
+			wantNumChunks: 3,
+		},
+		{
+			name:          "test_blake3",
+			size:          1000,
+			chunkSize:     64,
+			hashAlgo:      hash.AlgoBLAKE3,
+			wantNumChunks: 16,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			content := testChunkContent(tt.size)
+			chunkTree, err := NewFileChunkTree(bytes.NewReader(content), tt.hashAlgo, tt.chunkSize)
+			if err != nil {
+				t.Fatalf("NewFileChunkTree() error = %v", err)
+			}
+			if chunkTree.NumChunks() != tt.wantNumChunks || NumFileChunks(int64(tt.size), tt.chunkSize) != tt.wantNumChunks {
+				t.Fatalf("NewFileChunkTree() chunks = %d, want %d", chunkTree.NumChunks(), tt.wantNumChunks)
+			}
+			if chunkTree.FileSize != int64(tt.size) {
+				t.Errorf("NewFileChunkTree() file size = %d, want %d", chunkTree.FileSize, tt.size)
+			}
+
+			// Every chunk is verifiable against the root, and only at its own index
+			verifier := &FileChunkVerifier{Root: chunkTree.Root, FileSize: int64(tt.size), ChunkSize: tt.chunkSize, HashAlgo: tt.hashAlgo}
+			for i := 0; i < chunkTree.NumChunks(); i++ {
+				chunk := content[i*tt.chunkSize : min((i+1)*tt.chunkSize, tt.size)]
+				proof, err := chunkTree.ChunkProof(i)
+				if err != nil {
+					t.Fatalf("ChunkProof() error = %v", err)
+				}
+				if valid, err := verifier.VerifyChunk(i, chunk, proof); err != nil || !valid {
+					t.Errorf("VerifyChunk() chunk %d = %v, error = %v", i, valid, err)
+				}
+				if i > 0 {
+					if valid, _ := verifier.VerifyChunk(i-1, chunk, proof); valid {
+						t.Errorf("VerifyChunk() chunk %d verified at index %d", i, i-1)
+					}
+				}
+				if len(chunk) > 0 {
+					tampered := bytes.Clone(chunk)
+					tampered[0] ^= 0xff
+					if valid, _ := verifier.VerifyChunk(i, tampered, proof); valid {
+						t.Errorf("VerifyChunk() tampered chunk %d verified", i)
+					}
+				}
+			}
+
+			// The root commits to the file size
+			truncated := &FileChunkVerifier{Root: chunkTree.Root, FileSize: int64(tt.size) - 1, ChunkSize: tt.chunkSize, HashAlgo: tt.hashAlgo}
+			if tt.size > 0 {
+				proof, _ := chunkTree.ChunkProof(0)
+				if valid, _ := truncated.VerifyChunk(0, content[:min(tt.chunkSize, tt.size-1)], proof); valid {
+					t.Errorf("VerifyChunk() verified for a truncated file size")
+				}
+			}
+		})
+	}
+}
+
+func TestComputeFileChunkRoots(t *testing.T) {
+	sizes := []int{0, 1, 1000, 3*fileHashBufferSize + 17}
+	filePaths := writeTestFiles(t, sizes)
+	got, err := ComputeFileChunkRoots(filePaths, hash.AlgoSHA256, 4096, 3)
+	if err != nil {
+		t.Fatalf("ComputeFileChunkRoots() error = %v", err)
+	}
+	for i, filePath := range filePaths {
+		chunkTree, err := ComputeFileChunkTree(filePath, hash.AlgoSHA256, 4096)
+		if err != nil {
+			t.Fatalf("ComputeFileChunkTree() error = %v", err)
+		}
+		if !bytes.Equal(got[i], chunkTree.Root) {
+			t.Errorf("ComputeFileChunkRoots() file #%d root = %x, want %x", i, got[i], chunkTree.Root)
+		}
+	}
+
+	// The file chunk roots are the leaves of the fileset tree
+	tree, err := GenerateMerkleTree(got, true, hash.AlgoSHA256)
+	if err != nil {
+		t.Fatalf("GenerateMerkleTree() error = %v", err)
+	}
+	for i, root := range got {
+		if valid, err := tree.Verify(&mt.DataBlock{Data: root}, tree.Proofs[i]); err != nil || !valid {
+			t.Errorf("Verify() file #%d = %v, error = %v", i, valid, err)
+		}
+	}
+
+	if _, err := ComputeFileChunkRoots(filePaths, "", 0, 0); !errors.Is(err, ErrInvalidChunkSize) {
+		t.Errorf("ComputeFileChunkRoots() error = %v, wantErr %v", err, ErrInvalidChunkSize)
+	}
+	if _, err := ComputeFileChunkRoots(filePaths, "unknown", 16, 0); !errors.Is(err, hash.ErrUnsupportedAlgo) {
+		t.Errorf("ComputeFileChunkRoots() error = %v, wantErr %v", err, hash.ErrUnsupportedAlgo)
+	}
+}
+
+func TestChunkRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		offset    int64
+		length    int64
+		wantFirst int
+		wantLast  int
+		wantErr   error
+	}{
+		{
+			name:      "test_first_byte",
+			offset:    0,
+			length:    1,
+			wantFirst: 0,
+			wantLast:  0,
+		},
+		{
+			name:      "test_chunk_boundaries",
+			offset:    16,
+			length:    32,
+			wantFirst: 1,
+			wantLast:  2,
+		},
+		{
+			name:      "test_overlapping_chunks",
+			offset:    15,
+			length:    2,
+			wantFirst: 0,
+			wantLast:  1,
+		},
+		{
+			name:      "test_whole_file",
+			offset:    0,
+			length:    100,
+			wantFirst: 0,
+			wantLast:  6,
+		},
+		{
+			name:    "test_out_of_file",
+			offset:  90,
+			length:  11,
+			wantErr: ErrInvalidByteRange,
+		},
+		{
+			name:    "test_empty_range",
+			offset:  10,
+			length:  0,
+			wantErr: ErrInvalidByteRange,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last, err := ChunkRange(tt.offset, tt.length, 100, 16)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ChunkRange() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if first != tt.wantFirst || last != tt.wantLast {
+				t.Errorf("ChunkRange() = [%d, %d], want [%d, %d]", first, last, tt.wantFirst, tt.wantLast)
+			}
+		})
+	}
+}
+
+func TestFileChunkVerifier_NewReader(t *testing.T) {
+	const chunkSize = 100
+	content := testChunkContent(10*chunkSize + 42)
+	chunkTree, err := NewFileChunkTree(bytes.NewReader(content), "", chunkSize)
+	if err != nil {
+		t.Fatalf("NewFileChunkTree() error = %v", err)
+	}
+	verifier := &FileChunkVerifier{Root: chunkTree.Root, FileSize: int64(len(content)), ChunkSize: chunkSize}
+
+	// Verify a byte range while it is being streamed, with small reads not aligned to the chunks
+	first, last, err := ChunkRange(250, 500, int64(len(content)), chunkSize)
+	if err != nil {
+		t.Fatalf("ChunkRange() error = %v", err)
+	}
+	stream := io.MultiReader(bytes.NewReader(content[first*chunkSize:400]), bytes.NewReader(content[400:]))
+	r, err := verifier.NewReader(stream, first, last, chunkProofs(chunkTree))
+	if err != nil {
+		t.Fatalf("NewReader() error = %v", err)
+	}
+	got, err := io.ReadAll(io.LimitReader(r, 1<<20))
+	if err != nil {
+		t.Fatalf("ReadAll() error = %v", err)
+	}
+	if !bytes.Equal(got, content[first*chunkSize:(last+1)*chunkSize]) {
+		t.Errorf("NewReader() read %d bytes, want chunks [%d, %d]", len(got), first, last)
+	}
+
+	// Whole file, up to its shorter last chunk
+	r, _ = verifier.NewReader(bytes.NewReader(content), 0, chunkTree.NumChunks()-1, chunkProofs(chunkTree))
+	if got, err = io.ReadAll(r); err != nil || !bytes.Equal(got, content) {
+		t.Errorf("NewReader() whole file read %d bytes, error = %v", len(got), err)
+	}
+
+	// Tampered content: the chunks preceding the tampered one only are returned
+	tampered := bytes.Clone(content)
+	tampered[3*chunkSize+7] ^= 0x01
+	r, _ = verifier.NewReader(bytes.NewReader(tampered), 0, chunkTree.NumChunks()-1, chunkProofs(chunkTree))
+	got, err = io.ReadAll(r)
+	if !errors.Is(err, ErrChunkVerificationFailed) {
+		t.Errorf("NewReader() tampered error = %v, wantErr %v", err, ErrChunkVerificationFailed)
+	}
+	if !bytes.Equal(got, content[:3*chunkSize]) {
+		t.Errorf("NewReader() tampered read %d bytes, want %d", len(got), 3*chunkSize)
+	}
+
+	// Truncated stream, on a chunk boundary
+	r, _ = verifier.NewReader(bytes.NewReader(content[:5*chunkSize]), 0, chunkTree.NumChunks()-1, chunkProofs(chunkTree))
+	if _, err = io.ReadAll(r); !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("NewReader() truncated error = %v, wantErr %v", err, io.ErrUnexpectedEOF)
+	}
+
+	// Chunk range out of the file
+	if _, err := verifier.NewReader(bytes.NewReader(content), 0, chunkTree.NumChunks(), chunkProofs(chunkTree)); !errors.Is(err, ErrChunkIndexOutOfRange) {
+		t.Errorf("NewReader() error = %v, wantErr %v", err, ErrChunkIndexOutOfRange)
+	}
+}
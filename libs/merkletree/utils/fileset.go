@@ -2,45 +2,195 @@ package utils
 
 import (
 	"fmt"
+	"io"
 	"io/fs"
 	"os"
 	"path/filepath"
+	"runtime"
+	"sort"
 
-	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
 	mt "github.com/ja88a/vrfs-go-merkletree/libs/merkletree"
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+	pool "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/threadpoolexec"
 )
 
-// Compute the file content hash of all provided file paths
-func ComputeFileHashes(filePaths []string) ([][]byte, error) {
-	var fileHashes [][]byte
+// Size of the buffers used for streaming the file contents through the hash function
+const fileHashBufferSize = 64 * 1024
 
-	// Loop over the dir files
-	for _, filePath := range filePaths {
-		fileContent, err := os.ReadFile(filePath)
-		if err != nil {
-			return nil, fmt.Errorf("files hashing process failed on reading content of file '%v'\nError:\n%v", filePath, err)
+// FileOpenFunc opens the content of a file out of its path, e.g. for hashing files held by a remote or in-memory store
+type FileOpenFunc func(filePath string) (io.ReadCloser, error)
+
+// Open the content of a local file, the FileOpenFunc of the local file paths
+func openLocalFile(filePath string) (io.ReadCloser, error) {
+	return os.Open(filePath)
+}
+
+// Compute the file content hash of all provided file paths, concurrently with as many workers as CPUs
+//
+// The name of the hash algorithm is the one registered in the hash package, the default one is used if empty
+func ComputeFileHashes(filePaths []string, hashAlgo string) ([][]byte, error) {
+	return ComputeFileHashesConcurrently(filePaths, hashAlgo, 0)
+}
+
+// Compute the file content hash of all provided file paths, using the specified number of concurrent workers
+//
+// The number of workers is set to the number of CPUs if lower than 1. File contents are streamed through
+// the hash function with a bounded buffer per worker, they are never fully loaded in memory.
+// The file hashes are returned in the order of the provided file paths
+func ComputeFileHashesConcurrently(filePaths []string, hashAlgo string, numWorkers int) ([][]byte, error) {
+	return ComputeFileHashesWith(openLocalFile, filePaths, hashAlgo, numWorkers)
+}
+
+// Compute the file hashes of all provided file paths, their contents being opened with the specified function,
+// using the specified number of concurrent workers
+//
+// The file hashes are the ones of `ComputeFileHashesConcurrently`, for files not necessarily stored locally
+func ComputeFileHashesWith(open FileOpenFunc, filePaths []string, hashAlgo string, numWorkers int) ([][]byte, error) {
+	if len(filePaths) == 0 {
+		return nil, nil
+	}
+	if _, err := hash.NewDigest(hashAlgo); err != nil {
+		return nil, fmt.Errorf("files hashing process failed on selecting the hash algorithm\n%w", err)
+	}
+
+	return computeFilesConcurrently(len(filePaths), numWorkers, func(fileIndex int, buffer []byte) ([]byte, error) {
+		return computeFileHash(open, filePaths[fileIndex], hashAlgo, buffer)
+	})
+}
+
+// typeHashFileFunc is the function computing the hash of a file out of its index, streaming its content with the provided buffer
+type typeHashFileFunc func(fileIndex int, buffer []byte) ([]byte, error)
+
+// Compute the hash of the specified number of files with the specified number of concurrent workers,
+// as many as CPUs if lower than 1. The file hashes are returned in the order of the file indexes
+func computeFilesConcurrently(numFiles int, numWorkers int, hashFile typeHashFileFunc) ([][]byte, error) {
+	if numFiles == 0 {
+		return nil, nil
+	}
+
+	// Hash the files concurrently, there is no need for more workers than files.
+	// Each worker handles the files at its start index, strided by the number of workers
+	if numWorkers <= 0 {
+		numWorkers = runtime.NumCPU()
+	}
+	numWorkers = min(numWorkers, numFiles)
+	fileHashes := make([][]byte, numFiles)
+	argList := make([]workerArgsComputeFileHashes, numWorkers)
+	for i := 0; i < numWorkers; i++ {
+		argList[i] = workerArgsComputeFileHashes{
+			fileHashes: fileHashes,
+			hashFile:   hashFile,
+			startIdx:   i,
+			numWorkers: numWorkers,
 		}
-		
-		// Append the unique file name in the fileset to enforce the computed hash unicity
-		// e.g. prevent from the conflict between same file content being part of the parent and/or subdirs
-		fileName := filepath.Base(filePath)
-		fileContentWName := append(fileContent, fileName...)
-
-		// Hash computation
-		fileHash, err := hash.DefaultHashFuncParallel(fileContentWName)
+	}
+	wp := pool.NewPool[workerArgsComputeFileHashes, error](numWorkers, 0)
+	defer wp.Close()
+	errs, err := wp.Map(workerComputeFileHashes, argList)
+	if err != nil {
+		return nil, err
+	}
+	for _, err := range errs {
 		if err != nil {
-			return nil, fmt.Errorf("upload process failed on computing hash for file '%v'\n%w", filePath, err)
+			return nil, err
 		}
-		fileHashes = append(fileHashes, fileHash)
 	}
 
 	return fileHashes, nil
 }
 
+// workerArgsComputeFileHashes contains the arguments of a workerComputeFileHashes call
+type workerArgsComputeFileHashes struct {
+	fileHashes [][]byte
+	hashFile   typeHashFileFunc
+	startIdx   int
+	numWorkers int
+}
+
+// workerComputeFileHashes is the worker function computing the hashes of a portion of the files,
+// reusing a single buffer for streaming their contents
+func workerComputeFileHashes(args workerArgsComputeFileHashes) (err error) {
+	buffer := make([]byte, fileHashBufferSize)
+	for i := args.startIdx; i < len(args.fileHashes); i += args.numWorkers {
+		if args.fileHashes[i], err = args.hashFile(i, buffer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Compute the hash of a file: its content, streamed through the hash function, followed by its base name
+//
+// The name of the hash algorithm is the one registered in the hash package, the default one is used if empty
+func ComputeFileHash(filePath string, hashAlgo string) ([]byte, error) {
+	return computeFileHash(openLocalFile, filePath, hashAlgo, make([]byte, fileHashBufferSize))
+}
+
+// Compute the hash of a file content only, as recorded in the fileset manifest entries
+//
+// The name of the hash algorithm is the one registered in the hash package, the default one is used if empty
+func ComputeFileContentHash(filePath string, hashAlgo string) ([]byte, error) {
+	fileHash, _, err := hashFile(openLocalFile, filePath, hashAlgo, make([]byte, fileHashBufferSize), nil)
+	return fileHash, err
+}
+
+// Compute the hash of a file, streaming its content through the hash function with the provided buffer
+func computeFileHash(open FileOpenFunc, filePath string, hashAlgo string, buffer []byte) ([]byte, error) {
+	// Append the unique file name in the fileset to enforce the computed hash unicity
+	// e.g. prevent from the conflict between same file content being part of the parent and/or subdirs
+	fileHash, _, err := hashFile(open, filePath, hashAlgo, buffer, []byte(filepath.Base(filePath)))
+	return fileHash, err
+}
+
+// Compute the hash of a file content followed by the provided suffix, streaming the content with the provided buffer.
+// The size of the file content is returned along with its hash
+func hashFile(open FileOpenFunc, filePath string, hashAlgo string, buffer []byte, suffix []byte) ([]byte, int64, error) {
+	digest, err := hash.NewDigest(hashAlgo)
+	if err != nil {
+		return nil, 0, fmt.Errorf("files hashing process failed on selecting the hash algorithm\n%w", err)
+	}
+
+	file, err := open(filePath)
+	if err != nil {
+		return nil, 0, fmt.Errorf("files hashing process failed on reading content of file '%v'\nError:\n%v", filePath, err)
+	}
+	defer file.Close()
+
+	// Stream the file content through the hash
+	var fileSize int64
+	for {
+		n, err := file.Read(buffer)
+		digest.Write(buffer[:n])
+		fileSize += int64(n)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, 0, fmt.Errorf("files hashing process failed on reading content of file '%v'\nError:\n%v", filePath, err)
+		}
+	}
+
+	digest.Write(suffix)
+
+	return digest.Sum(nil), fileSize, nil
+}
+
 // Build the Merkle Tree with file hashes as leaf values
 //
-// Specify if MerkleTree proofs are also to be generated via `generateProofs`
-func GenerateMerkleTree(fileHashes [][]byte, generateProofs bool) (*mt.MerkleTree, error) {
+// Specify if MerkleTree proofs are also to be generated via `generateProofs`, and the name
+// of the hash algorithm used for the file hashes, the default one being used if empty
+func GenerateMerkleTree(fileHashes [][]byte, generateProofs bool, hashAlgo string) (*mt.MerkleTree, error) {
+	mtConfig := mt.MerkleTreeDefaultConfig(generateProofs)
+	if hashAlgo != "" {
+		mtConfig.HashAlgorithm = hashAlgo
+	}
+	return GenerateMerkleTreeWithConfig(fileHashes, mtConfig)
+}
+
+// Build the Merkle Tree with file hashes as leaf values, using the specified tree config
+//
+// e.g. `mt.MerkleTreeRFC6962Config` for an RFC 6962 compliant tree
+func GenerateMerkleTreeWithConfig(fileHashes [][]byte, mtConfig *mt.Config) (*mt.MerkleTree, error) {
 	// Convert to leaf blocks
 	var fileHashBlocks []mt.IDataBlock
 	for _, fileHash := range fileHashes {
@@ -51,7 +201,6 @@ func GenerateMerkleTree(fileHashes [][]byte, generateProofs bool) (*mt.MerkleTre
 	}
 
 	// Generate Merkle Tree
-	mtConfig := mt.MerkleTreeDefaultConfig(generateProofs)
 	tree, err := mt.New(mtConfig, fileHashBlocks)
 	if err != nil {
 		return nil, fmt.Errorf("failure while computing merkletree from file hashes (%d) \n%w", len(fileHashes), err)
@@ -60,10 +209,95 @@ func GenerateMerkleTree(fileHashes [][]byte, generateProofs bool) (*mt.MerkleTre
 	return tree, nil
 }
 
+// Compute the Merkle Tree root of the file hashes, without building the tree nodes
+//
+// The root is the one of the tree generated by `GenerateMerkleTree`, the default hash algorithm being used if empty
+func GenerateMerkleRoot(fileHashes [][]byte, hashAlgo string) ([]byte, error) {
+	builder, err := newFilesetRootBuilder(hashAlgo)
+	if err != nil {
+		return nil, err
+	}
+	for _, fileHash := range fileHashes {
+		if err = builder.Add(&mt.DataBlock{Data: fileHash}); err != nil {
+			return nil, fmt.Errorf("failure while computing merkletree root from file hashes (%d) \n%w", len(fileHashes), err)
+		}
+	}
+	return builder.Finish()
+}
+
+// Compute the Merkle Tree root of the files found in the specified directory and its subdirs, while walking it
+//
+// The files are hashed one after the other, in the order of `ListDirFilePaths`, and folded into the root without holding their hashes.
+// The root is the one of the tree generated by `GenerateMerkleTree` out of the hashes of the `ListDirFilePaths` files
+func ComputeFilesetRoot(rootDir string, hashAlgo string) ([]byte, error) {
+	if _, err := os.Stat(rootDir); err != nil {
+		return nil, fmt.Errorf("unsupported local directory: '%v'\n%w", rootDir, err)
+	}
+	builder, err := newFilesetRootBuilder(hashAlgo)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := make([]byte, fileHashBufferSize)
+	err = walkDirFiles(rootDir, func(path string) error {
+		fileHash, err := computeFileHash(openLocalFile, path, hashAlgo, buffer)
+		if err != nil {
+			return err
+		}
+		return builder.Add(&mt.DataBlock{Data: fileHash})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failure while computing merkletree root of files in '%v'\n%w", rootDir, err)
+	}
+	return builder.Finish()
+}
+
+// Create the streaming builder of the Merkle Tree root of a fileset, of the default tree config
+func newFilesetRootBuilder(hashAlgo string) (*mt.Builder, error) {
+	mtConfig := mt.MerkleTreeDefaultConfig(false)
+	if hashAlgo != "" {
+		mtConfig.HashAlgorithm = hashAlgo
+	}
+	builder, err := mt.NewBuilder(mtConfig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize the merkletree root builder\n%w", err)
+	}
+	return builder, nil
+}
+
+// Build the sparse Merkle Tree of file hashes keyed by the hash of their file path
+//
+// It allows proving that a file path is not part of a fileset, e.g. once deleted.
+// The same hash algorithm is used for the file path keys and the tree nodes, the default one if empty
+func GenerateSparseMerkleTree(filePaths []string, fileHashes [][]byte, hashAlgo string) (*mt.SparseMerkleTree, error) {
+	mtConfig := &mt.Config{
+		HashAlgorithm: hash.DefaultAlgo,
+		RunInParallel: true,
+	}
+	if hashAlgo != "" {
+		mtConfig.HashAlgorithm = hashAlgo
+	}
+	keys := make([][]byte, len(filePaths))
+	for i, filePath := range filePaths {
+		key, err := mt.SparseKey(filePath, mtConfig)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute the sparse merkletree key of file '%s' \n%w", filePath, err)
+		}
+		keys[i] = key
+	}
+
+	tree, err := mt.NewSparse(mtConfig, keys, fileHashes)
+	if err != nil {
+		return nil, fmt.Errorf("failure while computing sparse merkletree from file hashes (%d) \n%w", len(fileHashes), err)
+	}
+	return tree, nil
+}
+
 // List all files (their path) found in the specified directory and its subdirs.
 //
-// Walks the file tree rooted at root, calling fn for each file or directory in the tree, including root.
-// The files are walked in lexical order, which makes the output deterministic
+// The files are listed in the lexical order of their slash separated paths relative to the directory,
+// the order of the fileset manifest entries, which makes the output deterministic and independent
+// of the local path separator
 func ListDirFilePaths(rootDir string) ([]string, error) {
 	var filePaths []string
 
@@ -71,47 +305,42 @@ func ListDirFilePaths(rootDir string) ([]string, error) {
 		return nil, fmt.Errorf("unsupported local directory: '%v'\n%w", rootDir, err)
 	}
 
-	err := filepath.WalkDir(rootDir, func(path string, info fs.DirEntry, err error) error {
-		if !info.IsDir() {
-			filePaths = append(filePaths, path)
-		}
+	err := walkDirFiles(rootDir, func(path string) error {
+		filePaths = append(filePaths, path)
 		return nil
 	})
 
 	return filePaths, err
 }
 
-// Utility method for extracting the longest common prefix among a set of provided strings
-// Use that technique instead of filepath.Base(filePath) to enable the support for subdirs
-// Once: filesPrefix := extractCommonPrefix(filePaths)
-// Then: strings.TrimPrefix(filePath, filesPrefix)
-// func ExtractCommonPrefix(strs []string) string {
-// 	lenStrs := len(strs)
-// 	if lenStrs == 0 {
-// 		return ""
-// 	}
-// 	firstString := strs[0]
-// 	lenFirstString := len(firstString)
-// 	commonPrefix := ""
-// 	for i := 0; i < lenFirstString; i++ {
-// 		firstStringChar := string(firstString[i])
-// 		match := true
-// 		for j := 1; j < lenStrs; j++ {
-// 			if (len(strs[j]) - 1) < i {
-// 				match = false
-// 				break
-// 			}
-// 			if string(strs[j][i]) != firstStringChar {
-// 				match = false
-// 				break
-// 			}
-// 		}
-// 		if match {
-// 			commonPrefix += firstStringChar
-// 		} else {
-// 			break
-// 		}
-// 	}
-
-// 	return commonPrefix
-// }
+// Walk the files of the specified directory and its subdirs, calling fn for each file in the lexical order
+// of their slash separated paths relative to the directory
+//
+// The entries of every dir are walked in the order of their names, subdir names being suffixed with `/`:
+// e.g. `a.txt` comes before the files of the `a` subdir, as `a.txt` < `a/b.txt`
+func walkDirFiles(dir string, fn func(path string) error) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	sortName := func(entry fs.DirEntry) string {
+		if entry.IsDir() {
+			return entry.Name() + "/"
+		}
+		return entry.Name()
+	}
+	sort.Slice(entries, func(i, j int) bool { return sortName(entries[i]) < sortName(entries[j]) })
+
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if entry.IsDir() {
+			err = walkDirFiles(path, fn)
+		} else {
+			err = fn(path)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,376 @@
+package utils
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"testing"
+
+	mt "github.com/ja88a/vrfs-go-merkletree/libs/merkletree"
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// legacyFileHash computes a file hash the historical way, out of the whole file content loaded in memory.
+func legacyFileHash(t *testing.T, filePath string) []byte {
+	t.Helper()
+	fileContent, err := os.ReadFile(filePath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	fileHash := sha256.Sum256(append(fileContent, filepath.Base(filePath)...))
+	return fileHash[:]
+}
+
+// writeTestFiles creates files of the specified sizes in a temporary directory and returns their paths.
+func writeTestFiles(t *testing.T, sizes []int) []string {
+	t.Helper()
+	dir := t.TempDir()
+	filePaths := make([]string, len(sizes))
+	for i, size := range sizes {
+		content := make([]byte, size)
+		for j := range content {
+			content[j] = byte(i + j*7)
+		}
+		filePaths[i] = filepath.Join(dir, fmt.Sprintf("file_%03d.txt", i))
+		if err := os.WriteFile(filePaths[i], content, 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return filePaths
+}
+
+func TestComputeFileHashes(t *testing.T) {
+	sizes := []int{0, 1, 100, fileHashBufferSize - 1, fileHashBufferSize, fileHashBufferSize + 1, 3*fileHashBufferSize + 17, 1 << 20}
+	filePaths := writeTestFiles(t, sizes)
+	want := make([][]byte, len(filePaths))
+	for i, filePath := range filePaths {
+		want[i] = legacyFileHash(t, filePath)
+	}
+
+	tests := []struct {
+		name       string
+		numWorkers int
+	}{
+		{
+			name:       "test_default_workers",
+			numWorkers: 0,
+		},
+		{
+			name:       "test_1_worker",
+			numWorkers: 1,
+		},
+		{
+			name:       "test_3_workers",
+			numWorkers: 3,
+		},
+		{
+			name:       "test_more_workers_than_files",
+			numWorkers: 64,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ComputeFileHashesConcurrently(filePaths, hash.AlgoSHA256, tt.numWorkers)
+			if err != nil {
+				t.Fatalf("ComputeFileHashesConcurrently() error = %v", err)
+			}
+			if len(got) != len(want) {
+				t.Fatalf("ComputeFileHashesConcurrently() got %d hashes, want %d", len(got), len(want))
+			}
+			for i := range want {
+				if !bytes.Equal(got[i], want[i]) {
+					t.Errorf("ComputeFileHashesConcurrently() file #%d hash = %x, want %x", i, got[i], want[i])
+				}
+			}
+		})
+	}
+
+	// The default hash algorithm is used when none is specified.
+	got, err := ComputeFileHashes(filePaths, "")
+	if err != nil {
+		t.Fatalf("ComputeFileHashes() error = %v", err)
+	}
+	for i := range want {
+		if !bytes.Equal(got[i], want[i]) {
+			t.Errorf("ComputeFileHashes() file #%d hash = %x, want %x", i, got[i], want[i])
+		}
+	}
+}
+
+func TestComputeFileHashes_errors(t *testing.T) {
+	filePaths := writeTestFiles(t, []int{10, 20})
+	if _, err := ComputeFileHashes(filePaths, "unknown"); !errors.Is(err, hash.ErrUnsupportedAlgo) {
+		t.Errorf("ComputeFileHashes() error = %v, wantErr %v", err, hash.ErrUnsupportedAlgo)
+	}
+	missing := append(filePaths, filepath.Join(filepath.Dir(filePaths[0]), "missing.txt"))
+	if _, err := ComputeFileHashes(missing, ""); err == nil {
+		t.Errorf("ComputeFileHashes() missing file error = %v, want an error", err)
+	}
+	if got, err := ComputeFileHashes(nil, ""); err != nil || got != nil {
+		t.Errorf("ComputeFileHashes() no file got = %v, error = %v", got, err)
+	}
+}
+
+func TestComputeFileHash_largeSparseFile(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping large sparse file hashing in short mode")
+	}
+
+	// Sparse file of 256 MiB, with a few bytes written at its start, middle and end.
+	const size = 256 << 20
+	filePath := filepath.Join(t.TempDir(), "sparse.bin")
+	file, err := os.Create(filePath)
+	if err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	if err := file.Truncate(size); err != nil {
+		t.Fatalf("Truncate() error = %v", err)
+	}
+	marks := map[int64][]byte{0: []byte("start"), size / 2: []byte("middle"), size - 3: []byte("end")}
+	for offset, mark := range marks {
+		if _, err := file.WriteAt(mark, offset); err != nil {
+			t.Fatalf("WriteAt() error = %v", err)
+		}
+	}
+	if err := file.Close(); err != nil {
+		t.Fatalf("Close() error = %v", err)
+	}
+
+	// Expected hash, out of the same content streamed from memory.
+	digest := sha256.New()
+	content := io.MultiReader(
+		bytes.NewReader([]byte("start")),
+		io.LimitReader(zeroReader{}, size/2-5),
+		bytes.NewReader([]byte("middle")),
+		io.LimitReader(zeroReader{}, size/2-3-6),
+		bytes.NewReader([]byte("end")),
+		bytes.NewReader([]byte("sparse.bin")),
+	)
+	if _, err := io.Copy(digest, content); err != nil {
+		t.Fatalf("Copy() error = %v", err)
+	}
+	want := digest.Sum(nil)
+
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	got, err := ComputeFileHashesConcurrently([]string{filePath, filePath}, hash.AlgoSHA256, 2)
+	if err != nil {
+		t.Fatalf("ComputeFileHashesConcurrently() error = %v", err)
+	}
+	runtime.ReadMemStats(&after)
+
+	for i := range got {
+		if !bytes.Equal(got[i], want) {
+			t.Errorf("ComputeFileHashesConcurrently() file #%d hash = %x, want %x", i, got[i], want)
+		}
+	}
+
+	// The file content must never be fully loaded in memory.
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > size/16 {
+		t.Errorf("ComputeFileHashesConcurrently() allocated %d bytes for hashing a %d bytes file", allocated, size)
+	}
+}
+
+// zeroReader is an endless reader of zero bytes.
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	for i := range p {
+		p[i] = 0
+	}
+	return len(p), nil
+}
+
+func TestGenerateMerkleTree_singleFile(t *testing.T) {
+	filePaths := writeTestFiles(t, []int{42})
+	fileHashes, err := ComputeFileHashes(filePaths, hash.AlgoSHA256)
+	if err != nil {
+		t.Fatalf("ComputeFileHashes() error = %v", err)
+	}
+	tree, err := GenerateMerkleTree(fileHashes, true, hash.AlgoSHA256)
+	if err != nil {
+		t.Fatalf("GenerateMerkleTree() error = %v", err)
+	}
+
+	// The fileset root is the file leaf, its proof has no sibling
+	if !bytes.Equal(tree.Root, fileHashes[0]) {
+		t.Errorf("GenerateMerkleTree() root = %x, want %x", tree.Root, fileHashes[0])
+	}
+	proof := tree.Proofs[0]
+	if len(proof.Siblings) != 0 {
+		t.Errorf("GenerateMerkleTree() proof siblings = %d, want 0", len(proof.Siblings))
+	}
+	if valid, err := mt.Verify(&mt.DataBlock{Data: fileHashes[0]}, proof, tree.Root, mt.MerkleTreeDefaultConfig(false)); err != nil || !valid {
+		t.Errorf("Verify() = %v, error = %v", valid, err)
+	}
+}
+
+func TestGenerateSparseMerkleTree(t *testing.T) {
+	filePaths := writeTestFiles(t, []int{10, 20, 30, 40})
+	fileHashes, err := ComputeFileHashes(filePaths, hash.AlgoSHA256)
+	if err != nil {
+		t.Fatalf("ComputeFileHashes() error = %v", err)
+	}
+	fileNames := make([]string, len(filePaths))
+	for i, filePath := range filePaths {
+		fileNames[i] = filepath.Base(filePath)
+	}
+	tree, err := GenerateSparseMerkleTree(fileNames[:3], fileHashes[:3], hash.AlgoSHA256)
+	if err != nil {
+		t.Fatalf("GenerateSparseMerkleTree() error = %v", err)
+	}
+
+	for i, fileName := range fileNames[:3] {
+		key, _ := tree.Key(fileName)
+		proof, err := tree.ProveInclusion(key)
+		if err != nil {
+			t.Fatalf("ProveInclusion() error = %v", err)
+		}
+		if valid, err := tree.VerifyInclusion(key, fileHashes[i], proof); err != nil || !valid {
+			t.Errorf("VerifyInclusion() file %s = %v, error = %v", fileName, valid, err)
+		}
+	}
+
+	// The last file is provably not part of the fileset
+	key, _ := tree.Key(fileNames[3])
+	proof, err := tree.ProveExclusion(key)
+	if err != nil {
+		t.Fatalf("ProveExclusion() error = %v", err)
+	}
+	if valid, err := tree.VerifyExclusion(key, proof); err != nil || !valid {
+		t.Errorf("VerifyExclusion() file %s = %v, error = %v", fileNames[3], valid, err)
+	}
+
+	if _, err := GenerateSparseMerkleTree(fileNames, fileHashes[:3], ""); err == nil {
+		t.Errorf("GenerateSparseMerkleTree() mismatching file hashes error = %v, want an error", err)
+	}
+}
+
+func TestComputeFilesetRoot(t *testing.T) {
+	for _, numFiles := range []int{1, 2, 5, 16, 33} {
+		sizes := make([]int, numFiles)
+		for i := range sizes {
+			sizes[i] = i * 100
+		}
+		filePaths := writeTestFiles(t, sizes)
+		fileHashes, err := ComputeFileHashes(filePaths, hash.AlgoBLAKE3)
+		if err != nil {
+			t.Fatalf("ComputeFileHashes() error = %v", err)
+		}
+		tree, err := GenerateMerkleTree(fileHashes, false, hash.AlgoBLAKE3)
+		if err != nil {
+			t.Fatalf("GenerateMerkleTree() error = %v", err)
+		}
+
+		root, err := GenerateMerkleRoot(fileHashes, hash.AlgoBLAKE3)
+		if err != nil || !bytes.Equal(root, tree.Root) {
+			t.Errorf("GenerateMerkleRoot() %d files root = %x, error = %v, want %x", numFiles, root, err, tree.Root)
+		}
+		root, err = ComputeFilesetRoot(filepath.Dir(filePaths[0]), hash.AlgoBLAKE3)
+		if err != nil || !bytes.Equal(root, tree.Root) {
+			t.Errorf("ComputeFilesetRoot() %d files root = %x, error = %v, want %x", numFiles, root, err, tree.Root)
+		}
+	}
+
+	if _, err := ComputeFilesetRoot(filepath.Join(t.TempDir(), "missing"), ""); err == nil {
+		t.Errorf("ComputeFilesetRoot() missing dir error = %v, want an error", err)
+	}
+	if _, err := GenerateMerkleRoot(nil, "unknown"); !errors.Is(err, hash.ErrUnsupportedAlgo) {
+		t.Errorf("GenerateMerkleRoot() error = %v, wantErr %v", err, hash.ErrUnsupportedAlgo)
+	}
+}
+
+func TestListDirFilePaths(t *testing.T) {
+	dir := writeTestFileset(t, map[string]string{
+		"a.txt":     "",
+		"a/b.txt":   "",
+		"a-b/c.txt": "",
+		"a/b/c.txt": "",
+		"b.txt":     "",
+		"B.txt":     "",
+	})
+	// Paths are sorted as slash separated relative paths, not per dir entry names: `a-b/...` < `a.txt` < `a/...`
+	want := []string{"B.txt", "a-b/c.txt", "a.txt", "a/b.txt", "a/b/c.txt", "b.txt"}
+
+	filePaths, err := ListDirFilePaths(dir)
+	if err != nil {
+		t.Fatalf("ListDirFilePaths() error = %v", err)
+	}
+	relPaths := make([]string, len(filePaths))
+	for i, filePath := range filePaths {
+		relPath, err := filepath.Rel(dir, filePath)
+		if err != nil {
+			t.Fatalf("Rel() error = %v", err)
+		}
+		relPaths[i] = filepath.ToSlash(relPath)
+	}
+	if fmt.Sprint(relPaths) != fmt.Sprint(want) {
+		t.Errorf("ListDirFilePaths() = %v, want %v", relPaths, want)
+	}
+
+	if _, err := ListDirFilePaths(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("ListDirFilePaths() missing dir error = %v, want an error", err)
+	}
+}
+
+// testMemoryOpenFunc opens the files of the specified contents, keyed by path.
+func testMemoryOpenFunc(files map[string]string) FileOpenFunc {
+	return func(filePath string) (io.ReadCloser, error) {
+		content, ok := files[filePath]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return io.NopCloser(strings.NewReader(content)), nil
+	}
+}
+
+func TestComputeFileHashesWith(t *testing.T) {
+	files := map[string]string{
+		"a.txt":     "root file",
+		"a/b.txt":   "sub file",
+		"empty.bin": "",
+		"large.bin": strings.Repeat("large file ", fileHashBufferSize/4),
+	}
+	dir := writeTestFileset(t, files)
+	var relPaths, filePaths []string
+	for relPath := range files {
+		relPaths = append(relPaths, relPath)
+	}
+	sort.Strings(relPaths)
+	for _, relPath := range relPaths {
+		filePaths = append(filePaths, filepath.Join(dir, filepath.FromSlash(relPath)))
+	}
+	open := testMemoryOpenFunc(files)
+
+	// The hashes of files opened from another store are the ones of the local files
+	want, err := ComputeFileHashes(filePaths, hash.AlgoBLAKE3)
+	if err != nil {
+		t.Fatalf("ComputeFileHashes() error = %v", err)
+	}
+	if got, err := ComputeFileHashesWith(open, relPaths, hash.AlgoBLAKE3, 2); err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ComputeFileHashesWith() = %x, error = %v, want %x", got, err, want)
+	}
+	want, err = ComputeFileChunkRoots(filePaths, hash.AlgoBLAKE3, 1024, 0)
+	if err != nil {
+		t.Fatalf("ComputeFileChunkRoots() error = %v", err)
+	}
+	if got, err := ComputeFileChunkRootsWith(open, relPaths, hash.AlgoBLAKE3, 1024, 2); err != nil || fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("ComputeFileChunkRootsWith() = %x, error = %v, want %x", got, err, want)
+	}
+
+	// Opening errors are returned
+	if _, err := ComputeFileHashesWith(open, []string{"missing.txt"}, "", 0); err == nil {
+		t.Errorf("ComputeFileHashesWith() missing file error = %v, want an error", err)
+	}
+	if _, err := ComputeFileChunkRootsWith(open, []string{"missing.txt"}, "", 1024, 0); err == nil {
+		t.Errorf("ComputeFileChunkRootsWith() missing file error = %v, want an error", err)
+	}
+}
@@ -0,0 +1,288 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// Version of the fileset manifests, encoded in the manifests and in the leaves of their entries
+const FilesetManifestVersion uint32 = 1
+
+// Reserved name of the fileset manifest uploaded alongside the fileset files
+const FilesetManifestName = ".vrfs-manifest.json"
+
+var (
+	// ErrManifestUnsupportedVersion is the error for a fileset manifest version not supported.
+	ErrManifestUnsupportedVersion = errors.New("unsupported fileset manifest version")
+	// ErrManifestInvalid is the error for a fileset manifest whose entries are not valid, e.g. paths
+	// not relative to the fileset root dir or not sorted, or content hashes not of the hash algorithm size.
+	ErrManifestInvalid = errors.New("invalid fileset manifest")
+	// ErrManifestNotCanonical is the error for a fileset manifest not in its canonical encoding.
+	ErrManifestNotCanonical = errors.New("fileset manifest is not canonically encoded")
+)
+
+// FilesetManifest describes the files of a fileset, its entries forming the leaves of the fileset Merkle Tree
+//
+// The canonical encoding of a manifest is its compact JSON encoding, the entries being sorted by path,
+// so that the same fileset always results in the same manifest bytes
+type FilesetManifest struct {
+	// Version of the manifest, FilesetManifestVersion
+	Version uint32 `json:"version"`
+	// Name of the hash algorithm of the content hashes and of the entry leaves
+	HashAlgo string `json:"hashAlgo"`
+	// Size of the file chunks if the content hashes are file chunk tree roots, 0 for whole file content hashes
+	ChunkSize uint32 `json:"chunkSize"`
+	// Entries of the files, sorted by path
+	Entries []ManifestEntry `json:"entries"`
+}
+
+// ManifestEntry describes a file of a fileset
+type ManifestEntry struct {
+	// Path of the file relative to the fileset root dir, slash separated
+	Path string `json:"path"`
+	// Size of the file in bytes
+	Size uint64 `json:"size"`
+	// Permission bits of the file
+	Mode uint32 `json:"mode"`
+	// Modification time of the file in Unix nanoseconds, 0 if not recorded
+	ModTime int64 `json:"mtime,omitempty"`
+	// Hash of the file content, or its file chunk tree root
+	ContentHash []byte `json:"contentHash"`
+}
+
+// Compute the manifest of the files found in the specified directory and its subdirs
+//
+// The content hashes are file chunk tree roots if a chunk size is specified, whole file content hashes otherwise.
+// The modification times of the files are only recorded if `withModTime` is set, the default hash algorithm
+// is used if empty. A file named as the fileset manifest at the root of the directory is refused
+func ComputeFilesetManifest(rootDir string, hashAlgo string, chunkSize int, withModTime bool) (*FilesetManifest, error) {
+	if hashAlgo == "" {
+		hashAlgo = hash.DefaultAlgo
+	}
+	if _, err := hash.NewDigest(hashAlgo); err != nil {
+		return nil, fmt.Errorf("fileset manifest computation failed on selecting the hash algorithm\n%w", err)
+	}
+	if chunkSize < 0 {
+		return nil, ErrInvalidChunkSize
+	}
+	filePaths, err := ListDirFilePaths(rootDir)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := &FilesetManifest{
+		Version:   FilesetManifestVersion,
+		HashAlgo:  hashAlgo,
+		ChunkSize: uint32(chunkSize),
+		Entries:   make([]ManifestEntry, len(filePaths)),
+	}
+	for i, filePath := range filePaths {
+		relPath, err := filepath.Rel(rootDir, filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute the fileset path of file '%v'\n%w", filePath, err)
+		}
+		if filepath.ToSlash(relPath) == FilesetManifestName {
+			return nil, fmt.Errorf("%w: file '%v' has the reserved name of the fileset manifest", ErrManifestInvalid, filePath)
+		}
+		info, err := os.Stat(filePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the info of file '%v'\n%w", filePath, err)
+		}
+		manifest.Entries[i] = ManifestEntry{
+			Path: filepath.ToSlash(relPath),
+			Size: uint64(info.Size()),
+			Mode: uint32(info.Mode().Perm()),
+		}
+		if withModTime {
+			manifest.Entries[i].ModTime = info.ModTime().UnixNano()
+		}
+	}
+	sort.Slice(manifest.Entries, func(i, j int) bool { return manifest.Entries[i].Path < manifest.Entries[j].Path })
+
+	filePaths = manifest.FilePaths(rootDir)
+	contentHashes, err := computeFilesConcurrently(len(filePaths), 0, func(fileIndex int, buffer []byte) ([]byte, error) {
+		_, contentHash, err := manifest.computeContentHash(openLocalFile, filePaths[fileIndex], buffer)
+		return contentHash, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i := range manifest.Entries {
+		manifest.Entries[i].ContentHash = contentHashes[i]
+	}
+	return manifest, nil
+}
+
+// Compute the content hash of a file of the manifest entries, or its file chunk tree root, along with its size,
+// its content being opened with the specified function and streamed with the provided buffer
+func (m *FilesetManifest) computeContentHash(open FileOpenFunc, filePath string, buffer []byte) (uint64, []byte, error) {
+	if m.ChunkSize > 0 {
+		chunkTree, err := computeFileChunkTree(open, filePath, m.HashAlgo, int(m.ChunkSize), buffer)
+		if err != nil {
+			return 0, nil, err
+		}
+		return uint64(chunkTree.FileSize), chunkTree.Root, nil
+	}
+	contentHash, fileSize, err := hashFile(open, filePath, m.HashAlgo, buffer, nil)
+	return uint64(fileSize), contentHash, err
+}
+
+// FilePaths returns the local paths of the files of the manifest entries, relative to the specified root dir
+func (m *FilesetManifest) FilePaths(rootDir string) []string {
+	filePaths := make([]string, len(m.Entries))
+	for i := range m.Entries {
+		filePaths[i] = filepath.Join(rootDir, filepath.FromSlash(m.Entries[i].Path))
+	}
+	return filePaths
+}
+
+// Validate checks the manifest version, that its entry paths are relative to the fileset root dir, sorted
+// and unique, and that its content hashes are of the size of the hash algorithm
+func (m *FilesetManifest) Validate() error {
+	if m.Version != FilesetManifestVersion {
+		return ErrManifestUnsupportedVersion
+	}
+	digest, err := hash.NewDigest(m.HashAlgo)
+	if err != nil || m.HashAlgo == "" {
+		return fmt.Errorf("%w: unsupported hash algorithm '%v'", ErrManifestInvalid, m.HashAlgo)
+	}
+	for i := range m.Entries {
+		entry := &m.Entries[i]
+		if !ValidManifestPath(entry.Path) {
+			return fmt.Errorf("%w: unsupported path '%v' of entry %d", ErrManifestInvalid, entry.Path, i)
+		}
+		if entry.Path == FilesetManifestName {
+			return fmt.Errorf("%w: entry %d has the reserved name '%v' of the fileset manifest", ErrManifestInvalid, i, entry.Path)
+		}
+		if i > 0 && entry.Path <= m.Entries[i-1].Path {
+			return fmt.Errorf("%w: entry %d '%v' is not sorted by path", ErrManifestInvalid, i, entry.Path)
+		}
+		if entry.Mode&^uint32(fs.ModePerm) != 0 {
+			return fmt.Errorf("%w: unsupported mode %o of entry '%v'", ErrManifestInvalid, entry.Mode, entry.Path)
+		}
+		if len(entry.ContentHash) != digest.Size() {
+			return fmt.Errorf("%w: content hash of entry '%v' is not a '%v' hash", ErrManifestInvalid, entry.Path, m.HashAlgo)
+		}
+	}
+	return nil
+}
+
+// ValidManifestPath reports whether a path is a valid manifest entry path: a slash separated path relative
+// to the fileset root dir, without any `.` or `..` element nor empty element
+func ValidManifestPath(path string) bool {
+	return path != "." && fs.ValidPath(path)
+}
+
+// Marshal returns the canonical encoding of the manifest, once validated
+func (m *FilesetManifest) Marshal() ([]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	canonical := *m
+	if canonical.Entries == nil {
+		canonical.Entries = []ManifestEntry{}
+	}
+	return json.Marshal(&canonical)
+}
+
+// Parse a fileset manifest out of its canonical encoding, the manifest being validated
+func ParseFilesetManifest(data []byte) (*FilesetManifest, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	var manifest FilesetManifest
+	if err := decoder.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%w\n%v", ErrManifestInvalid, err)
+	}
+	canonical, err := manifest.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(canonical, data) {
+		return nil, ErrManifestNotCanonical
+	}
+	return &manifest, nil
+}
+
+// Leaves returns the leaves of the fileset Merkle Tree, the leaves of the manifest entries in their order
+func (m *FilesetManifest) Leaves() ([][]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	leaves := make([][]byte, len(m.Entries))
+	for i := range m.Entries {
+		leaf, err := ManifestEntryLeaf(m.Version, m.HashAlgo, &m.Entries[i])
+		if err != nil {
+			return nil, err
+		}
+		leaves[i] = leaf
+	}
+	return leaves, nil
+}
+
+// Root returns the root of the fileset Merkle Tree of the manifest entries, the one of `GenerateMerkleTree`
+func (m *FilesetManifest) Root() ([]byte, error) {
+	leaves, err := m.Leaves()
+	if err != nil {
+		return nil, err
+	}
+	return GenerateMerkleRoot(leaves, m.HashAlgo)
+}
+
+// Compute the leaves of the fileset Merkle Tree out of the stored files of the manifest entries: their sizes
+// and content hashes are the ones of the stored files, their paths, modes and modification times the ones
+// of the manifest. The file paths are specified in the order of the manifest entries
+func (m *FilesetManifest) ComputeStoredLeaves(filePaths []string, numWorkers int) ([][]byte, error) {
+	return m.ComputeStoredLeavesWith(openLocalFile, filePaths, numWorkers)
+}
+
+// Compute the leaves of the fileset Merkle Tree out of the stored files of the manifest entries, as `ComputeStoredLeaves`
+// does, the file contents being opened with the specified function
+func (m *FilesetManifest) ComputeStoredLeavesWith(open FileOpenFunc, filePaths []string, numWorkers int) ([][]byte, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if len(filePaths) != len(m.Entries) {
+		return nil, fmt.Errorf("%w: %d files for %d entries", ErrManifestInvalid, len(filePaths), len(m.Entries))
+	}
+	return computeFilesConcurrently(len(filePaths), numWorkers, func(fileIndex int, buffer []byte) ([]byte, error) {
+		var err error
+		entry := m.Entries[fileIndex]
+		if entry.Size, entry.ContentHash, err = m.computeContentHash(open, filePaths[fileIndex], buffer); err != nil {
+			return nil, err
+		}
+		return ManifestEntryLeaf(m.Version, m.HashAlgo, &entry)
+	})
+}
+
+// Compute the leaf of a manifest entry, for the specified manifest version and hash algorithm
+//
+// The leaf is the hash of the binary encoding of the entry: the manifest version, the length prefixed path,
+// the size, the mode and the modification time as varints, then the length prefixed content hash
+func ManifestEntryLeaf(version uint32, hashAlgo string, entry *ManifestEntry) ([]byte, error) {
+	if version != FilesetManifestVersion {
+		return nil, ErrManifestUnsupportedVersion
+	}
+	digest, err := hash.NewDigest(hashAlgo)
+	if err != nil {
+		return nil, fmt.Errorf("fileset manifest entry hashing failed on selecting the hash algorithm\n%w", err)
+	}
+	buf := binary.AppendUvarint(nil, uint64(version))
+	buf = binary.AppendUvarint(buf, uint64(len(entry.Path)))
+	buf = append(buf, entry.Path...)
+	buf = binary.AppendUvarint(buf, entry.Size)
+	buf = binary.AppendUvarint(buf, uint64(entry.Mode))
+	buf = binary.AppendVarint(buf, entry.ModTime)
+	buf = binary.AppendUvarint(buf, uint64(len(entry.ContentHash)))
+	buf = append(buf, entry.ContentHash...)
+	digest.Write(buf)
+	return digest.Sum(nil), nil
+}
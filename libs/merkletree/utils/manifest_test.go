@@ -0,0 +1,268 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// writeTestFileset creates files of the specified relative paths and contents in a temporary directory.
+func writeTestFileset(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for relPath, content := range files {
+		filePath := filepath.Join(dir, filepath.FromSlash(relPath))
+		if err := os.MkdirAll(filepath.Dir(filePath), 0o700); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(filePath, []byte(content), 0o640); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	return dir
+}
+
+func TestComputeFilesetManifest(t *testing.T) {
+	// Files of the same base name in different subdirs are distinct entries
+	files := map[string]string{
+		"a.txt":       "root file",
+		"a/a.txt":     "sub file",
+		"a/b/a.txt":   "sub sub file",
+		"b.txt":       "",
+		"z/empty.bin": "",
+	}
+	dir := writeTestFileset(t, files)
+	tests := []struct {
+		name        string
+		hashAlgo    string
+		chunkSize   int
+		withModTime bool
+	}{
+		{
+			name: "test_default",
+		},
+		{
+			name:        "test_blake3_mod_time",
+			hashAlgo:    hash.AlgoBLAKE3,
+			withModTime: true,
+		},
+		{
+			name:      "test_file_chunks",
+			hashAlgo:  hash.AlgoSHA256,
+			chunkSize: 4,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest, err := ComputeFilesetManifest(dir, tt.hashAlgo, tt.chunkSize, tt.withModTime)
+			if err != nil {
+				t.Fatalf("ComputeFilesetManifest() error = %v", err)
+			}
+			wantPaths := []string{"a.txt", "a/a.txt", "a/b/a.txt", "b.txt", "z/empty.bin"}
+			if len(manifest.Entries) != len(wantPaths) {
+				t.Fatalf("ComputeFilesetManifest() entries = %d, want %d", len(manifest.Entries), len(wantPaths))
+			}
+			for i, entry := range manifest.Entries {
+				if entry.Path != wantPaths[i] || entry.Size != uint64(len(files[entry.Path])) || entry.Mode != 0o640 {
+					t.Errorf("ComputeFilesetManifest() entry %d = %+v, want path %v", i, entry, wantPaths[i])
+				}
+				if (entry.ModTime != 0) != tt.withModTime {
+					t.Errorf("ComputeFilesetManifest() entry %d mtime = %d", i, entry.ModTime)
+				}
+			}
+
+			// The manifest root is the one of the fileset Merkle Tree of its leaves
+			leaves, err := manifest.Leaves()
+			if err != nil {
+				t.Fatalf("Leaves() error = %v", err)
+			}
+			tree, err := GenerateMerkleTree(leaves, false, manifest.HashAlgo)
+			if err != nil {
+				t.Fatalf("GenerateMerkleTree() error = %v", err)
+			}
+			if root, err := manifest.Root(); err != nil || !bytes.Equal(root, tree.Root) {
+				t.Errorf("Root() = %x, error = %v, want %x", root, err, tree.Root)
+			}
+
+			// The leaves are rebuilt out of the stored files
+			stored, err := manifest.ComputeStoredLeaves(manifest.FilePaths(dir), 2)
+			if err != nil {
+				t.Fatalf("ComputeStoredLeaves() error = %v", err)
+			}
+			if !reflect.DeepEqual(stored, leaves) {
+				t.Errorf("ComputeStoredLeaves() = %x, want %x", stored, leaves)
+			}
+
+			// Or out of files opened from another store
+			entryPaths := make([]string, len(manifest.Entries))
+			for i := range manifest.Entries {
+				entryPaths[i] = manifest.Entries[i].Path
+			}
+			stored, err = manifest.ComputeStoredLeavesWith(testMemoryOpenFunc(files), entryPaths, 0)
+			if err != nil {
+				t.Fatalf("ComputeStoredLeavesWith() error = %v", err)
+			}
+			if !reflect.DeepEqual(stored, leaves) {
+				t.Errorf("ComputeStoredLeavesWith() = %x, want %x", stored, leaves)
+			}
+
+			// The canonical encoding is parsed back
+			data, err := manifest.Marshal()
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			parsed, err := ParseFilesetManifest(data)
+			if err != nil {
+				t.Fatalf("ParseFilesetManifest() error = %v", err)
+			}
+			if !reflect.DeepEqual(parsed, manifest) {
+				t.Errorf("ParseFilesetManifest() = %+v, want %+v", parsed, manifest)
+			}
+		})
+	}
+
+	// A file modified once stored changes its leaf
+	manifest, err := ComputeFilesetManifest(dir, "", 0, false)
+	if err != nil {
+		t.Fatalf("ComputeFilesetManifest() error = %v", err)
+	}
+	leaves, _ := manifest.Leaves()
+	if err := os.WriteFile(filepath.Join(dir, "a", "a.txt"), []byte("sub file!"), 0o640); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	stored, err := manifest.ComputeStoredLeaves(manifest.FilePaths(dir), 0)
+	if err != nil {
+		t.Fatalf("ComputeStoredLeaves() error = %v", err)
+	}
+	for i := range leaves {
+		if changed := !bytes.Equal(stored[i], leaves[i]); changed != (i == 1) {
+			t.Errorf("ComputeStoredLeaves() leaf %d changed = %v", i, changed)
+		}
+	}
+}
+
+func TestComputeFilesetManifest_reservedName(t *testing.T) {
+	// Only the root dir file colliding with the uploaded manifest is refused
+	dir := writeTestFileset(t, map[string]string{"a/" + FilesetManifestName: "sub file"})
+	if _, err := ComputeFilesetManifest(dir, "", 0, false); err != nil {
+		t.Errorf("ComputeFilesetManifest() sub file error = %v", err)
+	}
+	dir = writeTestFileset(t, map[string]string{"a.txt": "root file", FilesetManifestName: "root file"})
+	if _, err := ComputeFilesetManifest(dir, "", 0, false); !errors.Is(err, ErrManifestInvalid) {
+		t.Errorf("ComputeFilesetManifest() error = %v, wantErr %v", err, ErrManifestInvalid)
+	}
+}
+
+func TestParseFilesetManifest_errors(t *testing.T) {
+	contentHash := make([]byte, 32)
+	valid := func() *FilesetManifest {
+		return &FilesetManifest{
+			Version:  FilesetManifestVersion,
+			HashAlgo: hash.AlgoSHA256,
+			Entries: []ManifestEntry{
+				{Path: "a.txt", Size: 1, Mode: 0o644, ContentHash: contentHash},
+				{Path: "b/c.txt", Size: 2, Mode: 0o600, ContentHash: contentHash},
+			},
+		}
+	}
+	tests := []struct {
+		name    string
+		tamper  func(m *FilesetManifest)
+		wantErr error
+	}{
+		{
+			name:    "test_unsupported_version",
+			tamper:  func(m *FilesetManifest) { m.Version++ },
+			wantErr: ErrManifestUnsupportedVersion,
+		},
+		{
+			name:    "test_unsupported_hash_algorithm",
+			tamper:  func(m *FilesetManifest) { m.HashAlgo = "unknown" },
+			wantErr: ErrManifestInvalid,
+		},
+		{
+			name:    "test_parent_path",
+			tamper:  func(m *FilesetManifest) { m.Entries[1].Path = "../c.txt" },
+			wantErr: ErrManifestInvalid,
+		},
+		{
+			name:    "test_absolute_path",
+			tamper:  func(m *FilesetManifest) { m.Entries[1].Path = "/b/c.txt" },
+			wantErr: ErrManifestInvalid,
+		},
+		{
+			name:    "test_empty_path_element",
+			tamper:  func(m *FilesetManifest) { m.Entries[1].Path = "b//c.txt" },
+			wantErr: ErrManifestInvalid,
+		},
+		{
+			name:    "test_dot_path",
+			tamper:  func(m *FilesetManifest) { m.Entries[1].Path = "." },
+			wantErr: ErrManifestInvalid,
+		},
+		{
+			name:    "test_reserved_manifest_path",
+			tamper:  func(m *FilesetManifest) { m.Entries[0].Path = FilesetManifestName },
+			wantErr: ErrManifestInvalid,
+		},
+		{
+			name:    "test_unsorted_entries",
+			tamper:  func(m *FilesetManifest) { m.Entries[0], m.Entries[1] = m.Entries[1], m.Entries[0] },
+			wantErr: ErrManifestInvalid,
+		},
+		{
+			name:    "test_duplicated_entries",
+			tamper:  func(m *FilesetManifest) { m.Entries[1].Path = m.Entries[0].Path },
+			wantErr: ErrManifestInvalid,
+		},
+		{
+			name:    "test_unsupported_mode",
+			tamper:  func(m *FilesetManifest) { m.Entries[0].Mode |= uint32(os.ModeDir) },
+			wantErr: ErrManifestInvalid,
+		},
+		{
+			name:    "test_content_hash_size",
+			tamper:  func(m *FilesetManifest) { m.Entries[0].ContentHash = contentHash[1:] },
+			wantErr: ErrManifestInvalid,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			manifest := valid()
+			tt.tamper(manifest)
+			if _, err := manifest.Marshal(); !errors.Is(err, tt.wantErr) {
+				t.Errorf("Marshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			data, err := json.Marshal(manifest)
+			if err != nil {
+				t.Fatalf("json.Marshal() error = %v", err)
+			}
+			if _, err := ParseFilesetManifest(data); !errors.Is(err, tt.wantErr) {
+				t.Errorf("ParseFilesetManifest() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+
+	// Only the canonical encoding is supported
+	data, err := valid().Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, data, "", "  "); err != nil {
+		t.Fatalf("json.Indent() error = %v", err)
+	}
+	if _, err := ParseFilesetManifest(indented.Bytes()); !errors.Is(err, ErrManifestNotCanonical) {
+		t.Errorf("ParseFilesetManifest() error = %v, wantErr %v", err, ErrManifestNotCanonical)
+	}
+	unknownField := bytes.Replace(data, []byte(`{"version"`), []byte(`{"other":1,"version"`), 1)
+	if _, err := ParseFilesetManifest(unknownField); !errors.Is(err, ErrManifestInvalid) {
+		t.Errorf("ParseFilesetManifest() error = %v, wantErr %v", err, ErrManifestInvalid)
+	}
+}
@@ -0,0 +1,442 @@
+package merkletree
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
+)
+
+// goldenTestVectorsPath is the path of the golden test vectors, generated by the reference implementation below.
+const goldenTestVectorsPath = "testdata/vectors/vectors.json"
+
+// catyclopsOutputsPath is the path of the outputs of a fileset of 201 files uploaded and downloaded
+// by a former version, its tree having the file hashes hex strings as leaves.
+const catyclopsOutputsPath = "../../doc/outputs/2023-11-20_outputs_mt-hashes-siblings_catyclops.jsonc"
+
+var updateGoldenTestVectors = flag.Bool("update-vectors", false, "rewrite the golden test vectors out of the reference implementation")
+
+// hexBytes is a byte slice encoded in JSON as a hex string.
+type hexBytes []byte
+
+func (h hexBytes) MarshalJSON() ([]byte, error) {
+	return json.Marshal(hex.EncodeToString(h))
+}
+
+func (h *hexBytes) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	decoded, err := hex.DecodeString(s)
+	*h = decoded
+	return err
+}
+
+// goldenTestVector is a tree of known data blocks, root and proofs.
+type goldenTestVector struct {
+	Name   string            `json:"name"`
+	Config goldenTestConfig  `json:"config"`
+	Blocks []hexBytes        `json:"blocks"`
+	Root   hexBytes          `json:"root"`
+	Proofs []goldenTestProof `json:"proofs"`
+}
+
+// goldenTestConfig is the tree configuration of a golden test vector.
+type goldenTestConfig struct {
+	HashAlgorithm      string `json:"hashAlgorithm"`
+	SortSiblingPairs   bool   `json:"sortSiblingPairs"`
+	DisableLeafHashing bool   `json:"disableLeafHashing"`
+	RFC6962            bool   `json:"rfc6962"`
+}
+
+// goldenTestProof is the proof of a data block of a golden test vector.
+type goldenTestProof struct {
+	Siblings []hexBytes `json:"siblings"`
+	Path     uint32     `json:"path"`
+}
+
+func (c goldenTestConfig) config(mode TypeConfigMode, runInParallel bool) *Config {
+	config := &Config{
+		HashAlgorithm:      c.HashAlgorithm,
+		SortSiblingPairs:   c.SortSiblingPairs,
+		DisableLeafHashing: c.DisableLeafHashing,
+		RFC6962:            c.RFC6962,
+		Mode:               mode,
+		RunInParallel:      runInParallel,
+	}
+	if runInParallel {
+		config.NumRoutines = 3
+	}
+	return config
+}
+
+// referenceTree is a straightforward implementation of the trees, independent of MerkleTree: levels of
+// duplicated last nodes, or the recursive Merkle Tree Hash and audit paths of RFC 6962 section 2.1.
+type referenceTree struct {
+	config   goldenTestConfig
+	hashFunc func([]byte) ([]byte, error)
+}
+
+func newReferenceTree(config goldenTestConfig) (*referenceTree, error) {
+	hashFunc, err := hash.HashFuncByName(config.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	return &referenceTree{config: config, hashFunc: hashFunc}, nil
+}
+
+func (r *referenceTree) hash(data ...[]byte) []byte {
+	digest, err := r.hashFunc(bytes.Join(data, nil))
+	if err != nil {
+		panic(err)
+	}
+	return digest
+}
+
+func (r *referenceTree) leaf(block []byte) []byte {
+	switch {
+	case r.config.DisableLeafHashing:
+		return append([]byte{}, block...)
+	case r.config.RFC6962:
+		return r.hash([]byte{0x00}, block)
+	default:
+		return r.hash(block)
+	}
+}
+
+func (r *referenceTree) node(left, right []byte) []byte {
+	if r.config.SortSiblingPairs && bytes.Compare(left, right) >= 0 {
+		left, right = right, left
+	}
+	if r.config.RFC6962 {
+		return r.hash([]byte{0x01}, left, right)
+	}
+	return r.hash(left, right)
+}
+
+// rootAndProofs computes the root of the tree of the data blocks and the proofs of all of them.
+func (r *referenceTree) rootAndProofs(blocks [][]byte) ([]byte, []goldenTestProof) {
+	if len(blocks) == 0 {
+		return r.hash(nil), nil
+	}
+	leaves := make([][]byte, len(blocks))
+	for i, block := range blocks {
+		leaves[i] = r.leaf(block)
+	}
+	proofs := make([]goldenTestProof, len(leaves))
+	if r.config.RFC6962 {
+		for i := range leaves {
+			proofs[i] = r.auditPath(i, leaves)
+		}
+		return r.mth(leaves), proofs
+	}
+
+	level := leaves
+	for len(level) > 1 {
+		if len(level)&1 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		for i := range proofs {
+			idx := i >> len(proofs[i].Siblings)
+			if idx&1 == 0 {
+				proofs[i].Path |= 1 << len(proofs[i].Siblings)
+			}
+			proofs[i].Siblings = append(proofs[i].Siblings, level[idx^1])
+		}
+		next := make([][]byte, len(level)/2)
+		for i := range next {
+			next[i] = r.node(level[2*i], level[2*i+1])
+		}
+		level = next
+	}
+	return level[0], proofs
+}
+
+// split returns the largest power of two smaller than n, n > 1.
+func split(n int) int {
+	k := 1
+	for k<<1 < n {
+		k <<= 1
+	}
+	return k
+}
+
+// mth is the Merkle Tree Hash of RFC 6962.
+func (r *referenceTree) mth(leaves [][]byte) []byte {
+	if len(leaves) == 1 {
+		return leaves[0]
+	}
+	k := split(len(leaves))
+	return r.node(r.mth(leaves[:k]), r.mth(leaves[k:]))
+}
+
+// auditPath is the Merkle audit path of RFC 6962, bottom-up.
+func (r *referenceTree) auditPath(m int, leaves [][]byte) goldenTestProof {
+	if len(leaves) == 1 {
+		return goldenTestProof{}
+	}
+	k := split(len(leaves))
+	if m < k {
+		proof := r.auditPath(m, leaves[:k])
+		proof.Path |= 1 << len(proof.Siblings)
+		proof.Siblings = append(proof.Siblings, r.mth(leaves[k:]))
+		return proof
+	}
+	proof := r.auditPath(m-k, leaves[k:])
+	proof.Siblings = append(proof.Siblings, r.mth(leaves[:k]))
+	return proof
+}
+
+// generateGoldenTestVectors generates the golden test vectors, for every combination of the configuration
+// flags and trees of various sizes, and for the other hash algorithms.
+func generateGoldenTestVectors() ([]goldenTestVector, error) {
+	var configs []goldenTestConfig
+	for flags := 0; flags < 8; flags++ {
+		configs = append(configs, goldenTestConfig{
+			HashAlgorithm:      hash.AlgoSHA256,
+			SortSiblingPairs:   flags&1 != 0,
+			DisableLeafHashing: flags&2 != 0,
+			RFC6962:            flags&4 != 0,
+		})
+	}
+	for _, algo := range []string{hash.AlgoSHA512_256, hash.AlgoSHA3_256, hash.AlgoBLAKE2b256, hash.AlgoBLAKE3, hash.AlgoKeccak256} {
+		configs = append(configs, goldenTestConfig{HashAlgorithm: algo}, goldenTestConfig{HashAlgorithm: algo, SortSiblingPairs: true, RFC6962: true})
+	}
+
+	var vectors []goldenTestVector
+	for _, config := range configs {
+		ref, err := newReferenceTree(config)
+		if err != nil {
+			return nil, err
+		}
+		sizes := []int{0, 1, 2, 3, 4, 5, 7, 8, 13}
+		if config.HashAlgorithm != hash.AlgoSHA256 {
+			sizes = []int{1, 2, 5, 13}
+		}
+		name := "test_" + config.HashAlgorithm
+		if config.SortSiblingPairs {
+			name += "_sorted"
+		}
+		if config.DisableLeafHashing {
+			name += "_no_leaf_hashing"
+		}
+		if config.RFC6962 {
+			name += "_rfc6962"
+		}
+		for _, size := range sizes {
+			// The data blocks are hashes, as the file hashes of a fileset
+			blocks := make([][]byte, size)
+			vector := goldenTestVector{Config: config, Blocks: make([]hexBytes, size), Proofs: []goldenTestProof{}}
+			for i := range blocks {
+				block := sha256.Sum256([]byte(fmt.Sprintf("vrfs-test-vector-block-%d", i)))
+				blocks[i] = block[:]
+				vector.Blocks[i] = block[:]
+			}
+			root, proofs := ref.rootAndProofs(blocks)
+			vector.Root = root
+			vector.Proofs = append(vector.Proofs, proofs...)
+			vector.Name = fmt.Sprintf("%s_%d", name, size)
+			vectors = append(vectors, vector)
+		}
+	}
+	return vectors, nil
+}
+
+// loadGoldenTestVectors loads the golden test vectors, after rewriting them if requested.
+func loadGoldenTestVectors(t *testing.T) []goldenTestVector {
+	t.Helper()
+	if *updateGoldenTestVectors {
+		vectors, err := generateGoldenTestVectors()
+		if err != nil {
+			t.Fatalf("generateGoldenTestVectors() error = %v", err)
+		}
+		data, err := json.MarshalIndent(vectors, "", "  ")
+		if err != nil {
+			t.Fatalf("json.Marshal() error = %v", err)
+		}
+		if err := os.WriteFile(goldenTestVectorsPath, append(data, '\n'), 0o644); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	data, err := os.ReadFile(goldenTestVectorsPath)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	var vectors []goldenTestVector
+	if err := json.Unmarshal(data, &vectors); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	return vectors
+}
+
+func TestGoldenTestVectors(t *testing.T) {
+	vectors := loadGoldenTestVectors(t)
+	flagCombinations := make(map[goldenTestConfig]bool)
+	for _, tt := range vectors {
+		flagCombinations[goldenTestConfig{
+			SortSiblingPairs:   tt.Config.SortSiblingPairs,
+			DisableLeafHashing: tt.Config.DisableLeafHashing,
+			RFC6962:            tt.Config.RFC6962,
+		}] = true
+
+		t.Run(tt.Name, func(t *testing.T) {
+			// The vectors are the ones of the reference implementation
+			ref, err := newReferenceTree(tt.Config)
+			if err != nil {
+				t.Fatalf("newReferenceTree() error = %v", err)
+			}
+			blocks := make([][]byte, len(tt.Blocks))
+			dataBlocks := make([]IDataBlock, len(tt.Blocks))
+			for i := range tt.Blocks {
+				blocks[i] = tt.Blocks[i]
+				dataBlocks[i] = &DataBlock{Data: tt.Blocks[i]}
+			}
+			root, proofs := ref.rootAndProofs(blocks)
+			if !bytes.Equal(root, tt.Root) || len(proofs) != len(tt.Proofs) {
+				t.Fatalf("reference root = %x, want %x", root, tt.Root)
+			}
+			for i := range proofs {
+				if !equalGoldenTestProofs(proofs[i], tt.Proofs[i]) {
+					t.Fatalf("reference proof #%d = %v, want %v", i, proofs[i], tt.Proofs[i])
+				}
+			}
+
+			for _, mode := range []TypeConfigMode{ModeProofGen, ModeTreeBuild, ModeProofGenAndTreeBuild} {
+				for _, runInParallel := range []bool{false, true} {
+					config := tt.Config.config(mode, runInParallel)
+					m, err := New(config, dataBlocks)
+					if err != nil {
+						t.Fatalf("New() mode %d error = %v", mode, err)
+					}
+					if !bytes.Equal(m.Root, tt.Root) {
+						t.Fatalf("New() mode %d root = %x, want %x", mode, m.Root, tt.Root)
+					}
+					for i, block := range dataBlocks {
+						proof, err := m.ProofByIndex(i)
+						if err != nil {
+							t.Fatalf("ProofByIndex() error = %v", err)
+						}
+						got := goldenTestProof{Path: proof.Path}
+						for _, sibling := range proof.Siblings {
+							got.Siblings = append(got.Siblings, sibling)
+						}
+						if !equalGoldenTestProofs(got, tt.Proofs[i]) {
+							t.Fatalf("ProofByIndex() mode %d #%d = %v, want %v", mode, i, got, tt.Proofs[i])
+						}
+						assertGoldenTestProof(t, block, proof, tt.Root, config)
+					}
+				}
+			}
+		})
+	}
+	if len(flagCombinations) != 8 {
+		t.Errorf("golden test vectors cover %d combinations of the configuration flags, want 8", len(flagCombinations))
+	}
+}
+
+// assertGoldenTestProof checks that a proof verifies, with or without its envelope, and that it does not
+// once tampered.
+func assertGoldenTestProof(t *testing.T, block IDataBlock, proof *Proof, root []byte, config *Config) {
+	t.Helper()
+	legacy := &Proof{Siblings: proof.Siblings, Path: proof.Path}
+	for _, p := range []*Proof{proof, legacy} {
+		if ok, err := Verify(block, p, root, config); err != nil || !ok {
+			t.Fatalf("Verify() got = %v, error = %v, envelope %v", ok, err, p.Envelope)
+		}
+		for i := range p.Siblings {
+			tampered := &Proof{Siblings: cloneNodes(p.Siblings), Path: p.Path, Envelope: p.Envelope}
+			tampered.Siblings[i][0] ^= 0x01
+			if ok, _ := Verify(block, tampered, root, config); ok {
+				t.Fatalf("Verify() tampered sibling #%d got = %v, envelope %v", i, ok, p.Envelope)
+			}
+		}
+	}
+}
+
+func equalGoldenTestProofs(p1, p2 goldenTestProof) bool {
+	if p1.Path != p2.Path || len(p1.Siblings) != len(p2.Siblings) {
+		return false
+	}
+	for i := range p1.Siblings {
+		if !bytes.Equal(p1.Siblings[i], p2.Siblings[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func TestGoldenTestVectors_catyclops(t *testing.T) {
+	file, err := os.Open(catyclopsOutputsPath)
+	if os.IsNotExist(err) {
+		t.Skipf("%s not available", catyclopsOutputsPath)
+	}
+	if err != nil {
+		t.Fatalf("Open() error = %v", err)
+	}
+	defer file.Close()
+
+	// The outputs are made of the logs of the upload, as comments, followed by the JSON proofs
+	var (
+		blocks    []IDataBlock
+		root      []byte
+		jsonLines []string
+		scanner   = bufio.NewScanner(file)
+	)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "// File "):
+			// The leaves are the hex strings of the file hashes
+			fields := strings.Fields(line)
+			blocks = append(blocks, &DataBlock{Data: []byte(fields[4])})
+		case strings.HasPrefix(line, "// Merkle tree root:"):
+			if root, err = hex.DecodeString(strings.Trim(strings.Fields(line)[4], "'")); err != nil {
+				t.Fatalf("DecodeString() error = %v", err)
+			}
+		case !strings.HasPrefix(line, "//"):
+			jsonLines = append(jsonLines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatalf("Scan() error = %v", err)
+	}
+	var proofs []*Proof
+	if err := json.Unmarshal([]byte(strings.Join(jsonLines, "\n")), &proofs); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	if len(blocks) != 201 || len(proofs) != len(blocks) {
+		t.Fatalf("outputs of %d files and %d proofs, want 201", len(blocks), len(proofs))
+	}
+
+	config := &Config{DisableLeafHashing: true}
+	m, err := New(config, blocks)
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if !bytes.Equal(m.Root, root) {
+		t.Fatalf("New() root = %x, want %x", m.Root, root)
+	}
+	for i, block := range blocks {
+		if !equalGoldenTestProofs(toGoldenTestProof(m.Proofs[i]), toGoldenTestProof(proofs[i])) {
+			t.Errorf("New() proof #%d = %v, want %v", i, m.Proofs[i], proofs[i])
+		}
+		assertGoldenTestProof(t, block, proofs[i], root, config)
+	}
+}
+
+func toGoldenTestProof(proof *Proof) goldenTestProof {
+	golden := goldenTestProof{Path: proof.Path}
+	for _, sibling := range proof.Siblings {
+		golden.Siblings = append(golden.Siblings, sibling)
+	}
+	return golden
+}
@@ -235,6 +235,10 @@ type UploadDoneRequest struct {
 	FilesetId string `protobuf:"bytes,2,opt,name=fileset_id,json=filesetId,proto3" json:"fileset_id,omitempty"`
 	// The MerkleTree root hash of the fileset, generated & stored by the client
 	MtRoot []byte `protobuf:"bytes,3,opt,name=mt_root,json=mtRoot,proto3" json:"mt_root,omitempty"`
+	// The name of the hash algorithm used for computing the file hashes and the MerkleTree, default if empty
+	HashAlgo string `protobuf:"bytes,4,opt,name=hash_algo,json=hashAlgo,proto3" json:"hash_algo,omitempty"`
+	// The size of the file chunks if the file leaves are file chunk tree roots, 0 for whole file hashes
+	ChunkSize uint32 `protobuf:"varint,5,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
 }
 
 func (x *UploadDoneRequest) Reset() {
@@ -290,6 +294,20 @@ func (x *UploadDoneRequest) GetMtRoot() []byte {
 	return nil
 }
 
+func (x *UploadDoneRequest) GetHashAlgo() string {
+	if x != nil {
+		return x.HashAlgo
+	}
+	return ""
+}
+
+func (x *UploadDoneRequest) GetChunkSize() uint32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
 // UploadDoneResponse is the request message for confirming files have been uploaded to a remote file storage
 type UploadDoneResponse struct {
 	state         protoimpl.MessageState
@@ -425,6 +443,14 @@ type DownloadFileInfoResponse struct {
 	BucketId string `protobuf:"bytes,1,opt,name=bucket_id,json=bucketId,proto3" json:"bucket_id,omitempty"`
 	// The MerkleTree proof to confirm the expected file hash consistency once downloaded
 	MtProof *MTProof `protobuf:"bytes,2,opt,name=mt_proof,json=mtProof,proto3" json:"mt_proof,omitempty"`
+	// The name of the hash algorithm of the fileset, to be used for verifying the file
+	HashAlgo string `protobuf:"bytes,3,opt,name=hash_algo,json=hashAlgo,proto3" json:"hash_algo,omitempty"`
+	// The size of the file chunks if the file leaf is a file chunk tree root, 0 for a whole file hash
+	ChunkSize uint32 `protobuf:"varint,4,opt,name=chunk_size,json=chunkSize,proto3" json:"chunk_size,omitempty"`
+	// The version of the fileset manifest whose entries are the leaves, 0 for the filesets uploaded without manifest
+	ManifestVersion uint32 `protobuf:"varint,5,opt,name=manifest_version,json=manifestVersion,proto3" json:"manifest_version,omitempty"`
+	// The fileset manifest entry of the file, its leaf being verified by the MerkleTree proof
+	ManifestEntry *FilesetManifestEntry `protobuf:"bytes,6,opt,name=manifest_entry,json=manifestEntry,proto3" json:"manifest_entry,omitempty"`
 }
 
 func (x *DownloadFileInfoResponse) Reset() {
@@ -473,6 +499,119 @@ func (x *DownloadFileInfoResponse) GetMtProof() *MTProof {
 	return nil
 }
 
+func (x *DownloadFileInfoResponse) GetHashAlgo() string {
+	if x != nil {
+		return x.HashAlgo
+	}
+	return ""
+}
+
+func (x *DownloadFileInfoResponse) GetChunkSize() uint32 {
+	if x != nil {
+		return x.ChunkSize
+	}
+	return 0
+}
+
+func (x *DownloadFileInfoResponse) GetManifestVersion() uint32 {
+	if x != nil {
+		return x.ManifestVersion
+	}
+	return 0
+}
+
+func (x *DownloadFileInfoResponse) GetManifestEntry() *FilesetManifestEntry {
+	if x != nil {
+		return x.ManifestEntry
+	}
+	return nil
+}
+
+// FilesetManifestEntry describes a file of a fileset, as listed in the fileset manifest
+type FilesetManifestEntry struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Path of the file relative to the fileset root dir, slash separated
+	Path string `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
+	// Size of the file in bytes
+	Size uint64 `protobuf:"varint,2,opt,name=size,proto3" json:"size,omitempty"`
+	// Permission bits of the file
+	Mode uint32 `protobuf:"varint,3,opt,name=mode,proto3" json:"mode,omitempty"`
+	// Modification time of the file in Unix nanoseconds, 0 if not recorded
+	ModTime int64 `protobuf:"varint,4,opt,name=mod_time,json=modTime,proto3" json:"mod_time,omitempty"`
+	// Hash of the file content, or its file chunk tree root
+	ContentHash []byte `protobuf:"bytes,5,opt,name=content_hash,json=contentHash,proto3" json:"content_hash,omitempty"`
+}
+
+func (x *FilesetManifestEntry) Reset() {
+	*x = FilesetManifestEntry{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[8]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FilesetManifestEntry) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FilesetManifestEntry) ProtoMessage() {}
+
+func (x *FilesetManifestEntry) ProtoReflect() protoreflect.Message {
+	mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[8]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FilesetManifestEntry.ProtoReflect.Descriptor instead.
+func (*FilesetManifestEntry) Descriptor() ([]byte, []int) {
+	return file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *FilesetManifestEntry) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *FilesetManifestEntry) GetSize() uint64 {
+	if x != nil {
+		return x.Size
+	}
+	return 0
+}
+
+func (x *FilesetManifestEntry) GetMode() uint32 {
+	if x != nil {
+		return x.Mode
+	}
+	return 0
+}
+
+func (x *FilesetManifestEntry) GetModTime() int64 {
+	if x != nil {
+		return x.ModTime
+	}
+	return 0
+}
+
+func (x *FilesetManifestEntry) GetContentHash() []byte {
+	if x != nil {
+		return x.ContentHash
+	}
+	return nil
+}
+
 // MTProof is a Merkle Tree proof message
 type MTProof struct {
 	state         protoimpl.MessageState
@@ -483,12 +622,14 @@ type MTProof struct {
 	Siblings [][]byte `protobuf:"bytes,1,rep,name=siblings,proto3" json:"siblings,omitempty"`
 	// Path variable indicating whether the neighbor is on the left or right
 	Path uint32 `protobuf:"varint,2,opt,name=path,proto3" json:"path,omitempty"`
+	// Envelope describing the tree of the proof, missing for the proofs of former versions
+	Envelope *MTProofEnvelope `protobuf:"bytes,3,opt,name=envelope,proto3" json:"envelope,omitempty"`
 }
 
 func (x *MTProof) Reset() {
 	*x = MTProof{}
 	if protoimpl.UnsafeEnabled {
-		mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[8]
+		mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[9]
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		ms.StoreMessageInfo(mi)
 	}
@@ -501,7 +642,7 @@ func (x *MTProof) String() string {
 func (*MTProof) ProtoMessage() {}
 
 func (x *MTProof) ProtoReflect() protoreflect.Message {
-	mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[8]
+	mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[9]
 	if protoimpl.UnsafeEnabled && x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 		if ms.LoadMessageInfo() == nil {
@@ -514,7 +655,7 @@ func (x *MTProof) ProtoReflect() protoreflect.Message {
 
 // Deprecated: Use MTProof.ProtoReflect.Descriptor instead.
 func (*MTProof) Descriptor() ([]byte, []int) {
-	return file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDescGZIP(), []int{8}
+	return file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDescGZIP(), []int{9}
 }
 
 func (x *MTProof) GetSiblings() [][]byte {
@@ -531,6 +672,292 @@ func (x *MTProof) GetPath() uint32 {
 	return 0
 }
 
+func (x *MTProof) GetEnvelope() *MTProofEnvelope {
+	if x != nil {
+		return x.Envelope
+	}
+	return nil
+}
+
+// MTProofEnvelope describes the Merkle Tree a proof has been generated for, for verifiers to refuse
+// the proofs of trees of other parameters. The sides of the siblings are derived from the leaf index
+type MTProofEnvelope struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Version of the envelope
+	Version uint32 `protobuf:"varint,1,opt,name=version,proto3" json:"version,omitempty"`
+	// Index of the proven leaf
+	LeafIndex uint64 `protobuf:"varint,2,opt,name=leaf_index,json=leafIndex,proto3" json:"leaf_index,omitempty"`
+	// Number of leaves of the tree
+	TreeSize uint64 `protobuf:"varint,3,opt,name=tree_size,json=treeSize,proto3" json:"tree_size,omitempty"`
+	// The name of the hash algorithm of the tree
+	HashAlgo string `protobuf:"bytes,4,opt,name=hash_algo,json=hashAlgo,proto3" json:"hash_algo,omitempty"`
+	// Flags of the tree configuration: sorted sibling pairs (1), disabled leaf hashing (2), RFC 6962 (4)
+	Flags uint32 `protobuf:"varint,5,opt,name=flags,proto3" json:"flags,omitempty"`
+}
+
+func (x *MTProofEnvelope) Reset() {
+	*x = MTProofEnvelope{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[10]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MTProofEnvelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MTProofEnvelope) ProtoMessage() {}
+
+func (x *MTProofEnvelope) ProtoReflect() protoreflect.Message {
+	mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[10]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MTProofEnvelope.ProtoReflect.Descriptor instead.
+func (*MTProofEnvelope) Descriptor() ([]byte, []int) {
+	return file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *MTProofEnvelope) GetVersion() uint32 {
+	if x != nil {
+		return x.Version
+	}
+	return 0
+}
+
+func (x *MTProofEnvelope) GetLeafIndex() uint64 {
+	if x != nil {
+		return x.LeafIndex
+	}
+	return 0
+}
+
+func (x *MTProofEnvelope) GetTreeSize() uint64 {
+	if x != nil {
+		return x.TreeSize
+	}
+	return 0
+}
+
+func (x *MTProofEnvelope) GetHashAlgo() string {
+	if x != nil {
+		return x.HashAlgo
+	}
+	return ""
+}
+
+func (x *MTProofEnvelope) GetFlags() uint32 {
+	if x != nil {
+		return x.Flags
+	}
+	return 0
+}
+
+// FilesetConsistencyRequest is the request message for retrieving the consistency proof between two versions of a fileset
+type FilesetConsistencyRequest struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Tenant ID to which the filesets belong
+	TenantId string `protobuf:"bytes,1,opt,name=tenant_id,json=tenantId,proto3" json:"tenant_id,omitempty"`
+	// ID of the older version of the fileset
+	OldFilesetId string `protobuf:"bytes,2,opt,name=old_fileset_id,json=oldFilesetId,proto3" json:"old_fileset_id,omitempty"`
+	// ID of the newer version of the fileset
+	NewFilesetId string `protobuf:"bytes,3,opt,name=new_fileset_id,json=newFilesetId,proto3" json:"new_fileset_id,omitempty"`
+}
+
+func (x *FilesetConsistencyRequest) Reset() {
+	*x = FilesetConsistencyRequest{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[11]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FilesetConsistencyRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FilesetConsistencyRequest) ProtoMessage() {}
+
+func (x *FilesetConsistencyRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[11]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FilesetConsistencyRequest.ProtoReflect.Descriptor instead.
+func (*FilesetConsistencyRequest) Descriptor() ([]byte, []int) {
+	return file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *FilesetConsistencyRequest) GetTenantId() string {
+	if x != nil {
+		return x.TenantId
+	}
+	return ""
+}
+
+func (x *FilesetConsistencyRequest) GetOldFilesetId() string {
+	if x != nil {
+		return x.OldFilesetId
+	}
+	return ""
+}
+
+func (x *FilesetConsistencyRequest) GetNewFilesetId() string {
+	if x != nil {
+		return x.NewFilesetId
+	}
+	return ""
+}
+
+// FilesetConsistencyResponse is the response message providing the consistency proof between two versions of a fileset
+type FilesetConsistencyResponse struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// The MerkleTree consistency proof between the older and the newer fileset roots
+	MtProof *MTConsistencyProof `protobuf:"bytes,1,opt,name=mt_proof,json=mtProof,proto3" json:"mt_proof,omitempty"`
+	// The name of the hash algorithm of both filesets, to be used for verifying the proof
+	HashAlgo string `protobuf:"bytes,2,opt,name=hash_algo,json=hashAlgo,proto3" json:"hash_algo,omitempty"`
+}
+
+func (x *FilesetConsistencyResponse) Reset() {
+	*x = FilesetConsistencyResponse{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[12]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *FilesetConsistencyResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*FilesetConsistencyResponse) ProtoMessage() {}
+
+func (x *FilesetConsistencyResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[12]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use FilesetConsistencyResponse.ProtoReflect.Descriptor instead.
+func (*FilesetConsistencyResponse) Descriptor() ([]byte, []int) {
+	return file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *FilesetConsistencyResponse) GetMtProof() *MTConsistencyProof {
+	if x != nil {
+		return x.MtProof
+	}
+	return nil
+}
+
+func (x *FilesetConsistencyResponse) GetHashAlgo() string {
+	if x != nil {
+		return x.HashAlgo
+	}
+	return ""
+}
+
+// MTConsistencyProof is a Merkle Tree consistency proof message, in the format of the merkletree lib:
+// it is not an RFC 6962 / RFC 9162 consistency proof
+type MTConsistencyProof struct {
+	state         protoimpl.MessageState
+	sizeCache     protoimpl.SizeCache
+	unknownFields protoimpl.UnknownFields
+
+	// Number of leaves of the older tree
+	OldSize uint64 `protobuf:"varint,1,opt,name=old_size,json=oldSize,proto3" json:"old_size,omitempty"`
+	// Number of leaves of the newer tree
+	NewSize uint64 `protobuf:"varint,2,opt,name=new_size,json=newSize,proto3" json:"new_size,omitempty"`
+	// Last leaf of the older tree followed by the sibling nodes of its path in the newer tree
+	Nodes [][]byte `protobuf:"bytes,3,rep,name=nodes,proto3" json:"nodes,omitempty"`
+}
+
+func (x *MTConsistencyProof) Reset() {
+	*x = MTConsistencyProof{}
+	if protoimpl.UnsafeEnabled {
+		mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[13]
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		ms.StoreMessageInfo(mi)
+	}
+}
+
+func (x *MTConsistencyProof) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MTConsistencyProof) ProtoMessage() {}
+
+func (x *MTConsistencyProof) ProtoReflect() protoreflect.Message {
+	mi := &file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[13]
+	if protoimpl.UnsafeEnabled && x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MTConsistencyProof.ProtoReflect.Descriptor instead.
+func (*MTConsistencyProof) Descriptor() ([]byte, []int) {
+	return file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *MTConsistencyProof) GetOldSize() uint64 {
+	if x != nil {
+		return x.OldSize
+	}
+	return 0
+}
+
+func (x *MTConsistencyProof) GetNewSize() uint64 {
+	if x != nil {
+		return x.NewSize
+	}
+	return 0
+}
+
+func (x *MTConsistencyProof) GetNodes() [][]byte {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
 var File_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto protoreflect.FileDescriptor
 
 var file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDesc = []byte{
@@ -550,58 +977,122 @@ var file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDesc = []byte{
 	0x0a, 0x14, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65,
 	0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74,
 	0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x75, 0x63, 0x6b, 0x65,
-	0x74, 0x49, 0x64, 0x22, 0x68, 0x0a, 0x11, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x44, 0x6f, 0x6e,
-	0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x65, 0x6e, 0x61,
-	0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x65, 0x6e,
-	0x61, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74,
-	0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x66, 0x69, 0x6c, 0x65, 0x73,
-	0x65, 0x74, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x74, 0x5f, 0x72, 0x6f, 0x6f, 0x74, 0x18,
-	0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x6d, 0x74, 0x52, 0x6f, 0x6f, 0x74, 0x22, 0x46, 0x0a,
-	0x12, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x44, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f,
-	0x6e, 0x73, 0x65, 0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x05, 0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x6d,
-	0x65, 0x73, 0x73, 0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65,
-	0x73, 0x73, 0x61, 0x67, 0x65, 0x22, 0x74, 0x0a, 0x17, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61,
-	0x64, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74,
-	0x12, 0x1b, 0x0a, 0x09, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20,
-	0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x1d, 0x0a,
-	0x0a, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28,
-	0x09, 0x52, 0x09, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a,
-	0x66, 0x69, 0x6c, 0x65, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05,
-	0x52, 0x09, 0x66, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x22, 0x61, 0x0a, 0x18, 0x44,
-	0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52,
-	0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x62, 0x75, 0x63, 0x6b, 0x65,
-	0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x75, 0x63, 0x6b,
-	0x65, 0x74, 0x49, 0x64, 0x12, 0x28, 0x0a, 0x08, 0x6d, 0x74, 0x5f, 0x70, 0x72, 0x6f, 0x6f, 0x66,
-	0x18, 0x02, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x4d, 0x54,
-	0x50, 0x72, 0x6f, 0x6f, 0x66, 0x52, 0x07, 0x6d, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x22, 0x39,
-	0x0a, 0x07, 0x4d, 0x54, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x69, 0x62,
-	0x6c, 0x69, 0x6e, 0x67, 0x73, 0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x08, 0x73, 0x69, 0x62,
-	0x6c, 0x69, 0x6e, 0x67, 0x73, 0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20,
-	0x01, 0x28, 0x0d, 0x52, 0x04, 0x70, 0x61, 0x74, 0x68, 0x32, 0xa4, 0x02, 0x0a, 0x1b, 0x56, 0x65,
-	0x72, 0x69, 0x66, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x65, 0x6d, 0x6f, 0x74, 0x65, 0x46, 0x69,
-	0x6c, 0x65, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x12, 0x45, 0x0a, 0x0c, 0x55, 0x70, 0x6c,
-	0x6f, 0x61, 0x64, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x19, 0x2e, 0x76, 0x72, 0x66, 0x73,
-	0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x55, 0x70, 0x6c, 0x6f,
-	0x61, 0x64, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
-	0x12, 0x3f, 0x0a, 0x0a, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x44, 0x6f, 0x6e, 0x65, 0x12, 0x17,
-	0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x44, 0x6f, 0x6e, 0x65,
-	0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x55,
-	0x70, 0x6c, 0x6f, 0x61, 0x64, 0x44, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
-	0x65, 0x12, 0x51, 0x0a, 0x10, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x46, 0x69, 0x6c,
-	0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x1d, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x44, 0x6f, 0x77,
-	0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71,
-	0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x44, 0x6f, 0x77, 0x6e,
-	0x6c, 0x6f, 0x61, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73, 0x70,
-	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x2a, 0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x11, 0x2e, 0x76,
-	0x72, 0x66, 0x73, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
-	0x0f, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x70, 0x6c, 0x79,
-	0x42, 0x43, 0x5a, 0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6a,
-	0x61, 0x38, 0x38, 0x61, 0x2f, 0x76, 0x72, 0x66, 0x73, 0x2d, 0x67, 0x6f, 0x2d, 0x6d, 0x65, 0x72,
-	0x6b, 0x6c, 0x74, 0x72, 0x65, 0x65, 0x2f, 0x6c, 0x69, 0x62, 0x73, 0x2f, 0x72, 0x70, 0x63, 0x61,
-	0x70, 0x69, 0x2f, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x76, 0x31, 0x2f, 0x76, 0x72, 0x66,
-	0x73, 0x2d, 0x61, 0x70, 0x69, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
+	0x74, 0x49, 0x64, 0x22, 0xa4, 0x01, 0x0a, 0x11, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x44, 0x6f,
+	0x6e, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x65, 0x6e,
+	0x61, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x65,
+	0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x65,
+	0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09, 0x66, 0x69, 0x6c, 0x65,
+	0x73, 0x65, 0x74, 0x49, 0x64, 0x12, 0x17, 0x0a, 0x07, 0x6d, 0x74, 0x5f, 0x72, 0x6f, 0x6f, 0x74,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x06, 0x6d, 0x74, 0x52, 0x6f, 0x6f, 0x74, 0x12, 0x1b,
+	0x0a, 0x09, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x61, 0x6c, 0x67, 0x6f, 0x18, 0x04, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x68, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f, 0x12, 0x1d, 0x0a, 0x0a, 0x63,
+	0x68, 0x75, 0x6e, 0x6b, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52,
+	0x09, 0x63, 0x68, 0x75, 0x6e, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x22, 0x46, 0x0a, 0x12, 0x55, 0x70,
+	0x6c, 0x6f, 0x61, 0x64, 0x44, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65,
+	0x12, 0x16, 0x0a, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x18, 0x01, 0x20, 0x01, 0x28, 0x05,
+	0x52, 0x06, 0x73, 0x74, 0x61, 0x74, 0x75, 0x73, 0x12, 0x18, 0x0a, 0x07, 0x6d, 0x65, 0x73, 0x73,
+	0x61, 0x67, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x07, 0x6d, 0x65, 0x73, 0x73, 0x61,
+	0x67, 0x65, 0x22, 0x74, 0x0a, 0x17, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x46, 0x69,
+	0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x12, 0x1b, 0x0a,
+	0x09, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09,
+	0x52, 0x08, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x66, 0x69,
+	0x6c, 0x65, 0x73, 0x65, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x09,
+	0x66, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x49, 0x64, 0x12, 0x1d, 0x0a, 0x0a, 0x66, 0x69, 0x6c,
+	0x65, 0x5f, 0x69, 0x6e, 0x64, 0x65, 0x78, 0x18, 0x03, 0x20, 0x01, 0x28, 0x05, 0x52, 0x09, 0x66,
+	0x69, 0x6c, 0x65, 0x49, 0x6e, 0x64, 0x65, 0x78, 0x22, 0x8b, 0x02, 0x0a, 0x18, 0x44, 0x6f, 0x77,
+	0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x1b, 0x0a, 0x09, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x5f,
+	0x69, 0x64, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x62, 0x75, 0x63, 0x6b, 0x65, 0x74,
+	0x49, 0x64, 0x12, 0x28, 0x0a, 0x08, 0x6d, 0x74, 0x5f, 0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x02,
+	0x20, 0x01, 0x28, 0x0b, 0x32, 0x0d, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x4d, 0x54, 0x50, 0x72,
+	0x6f, 0x6f, 0x66, 0x52, 0x07, 0x6d, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x1b, 0x0a, 0x09,
+	0x68, 0x61, 0x73, 0x68, 0x5f, 0x61, 0x6c, 0x67, 0x6f, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52,
+	0x08, 0x68, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f, 0x12, 0x1d, 0x0a, 0x0a, 0x63, 0x68, 0x75,
+	0x6e, 0x6b, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x09, 0x63,
+	0x68, 0x75, 0x6e, 0x6b, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x29, 0x0a, 0x10, 0x6d, 0x61, 0x6e, 0x69,
+	0x66, 0x65, 0x73, 0x74, 0x5f, 0x76, 0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x05, 0x20, 0x01,
+	0x28, 0x0d, 0x52, 0x0f, 0x6d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x56, 0x65, 0x72, 0x73,
+	0x69, 0x6f, 0x6e, 0x12, 0x41, 0x0a, 0x0e, 0x6d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x5f,
+	0x65, 0x6e, 0x74, 0x72, 0x79, 0x18, 0x06, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x1a, 0x2e, 0x76, 0x72,
+	0x66, 0x73, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65,
+	0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x52, 0x0d, 0x6d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73,
+	0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x22, 0x90, 0x01, 0x0a, 0x14, 0x46, 0x69, 0x6c, 0x65, 0x73,
+	0x65, 0x74, 0x4d, 0x61, 0x6e, 0x69, 0x66, 0x65, 0x73, 0x74, 0x45, 0x6e, 0x74, 0x72, 0x79, 0x12,
+	0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x04, 0x70,
+	0x61, 0x74, 0x68, 0x12, 0x12, 0x0a, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x04, 0x52, 0x04, 0x73, 0x69, 0x7a, 0x65, 0x12, 0x12, 0x0a, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x18,
+	0x03, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04, 0x6d, 0x6f, 0x64, 0x65, 0x12, 0x19, 0x0a, 0x08, 0x6d,
+	0x6f, 0x64, 0x5f, 0x74, 0x69, 0x6d, 0x65, 0x18, 0x04, 0x20, 0x01, 0x28, 0x03, 0x52, 0x07, 0x6d,
+	0x6f, 0x64, 0x54, 0x69, 0x6d, 0x65, 0x12, 0x21, 0x0a, 0x0c, 0x63, 0x6f, 0x6e, 0x74, 0x65, 0x6e,
+	0x74, 0x5f, 0x68, 0x61, 0x73, 0x68, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0c, 0x52, 0x0b, 0x63, 0x6f,
+	0x6e, 0x74, 0x65, 0x6e, 0x74, 0x48, 0x61, 0x73, 0x68, 0x22, 0x6c, 0x0a, 0x07, 0x4d, 0x54, 0x50,
+	0x72, 0x6f, 0x6f, 0x66, 0x12, 0x1a, 0x0a, 0x08, 0x73, 0x69, 0x62, 0x6c, 0x69, 0x6e, 0x67, 0x73,
+	0x18, 0x01, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x08, 0x73, 0x69, 0x62, 0x6c, 0x69, 0x6e, 0x67, 0x73,
+	0x12, 0x12, 0x0a, 0x04, 0x70, 0x61, 0x74, 0x68, 0x18, 0x02, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x04,
+	0x70, 0x61, 0x74, 0x68, 0x12, 0x31, 0x0a, 0x08, 0x65, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65,
+	0x18, 0x03, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x15, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x4d, 0x54,
+	0x50, 0x72, 0x6f, 0x6f, 0x66, 0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x52, 0x08, 0x65,
+	0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x22, 0x9a, 0x01, 0x0a, 0x0f, 0x4d, 0x54, 0x50, 0x72,
+	0x6f, 0x6f, 0x66, 0x45, 0x6e, 0x76, 0x65, 0x6c, 0x6f, 0x70, 0x65, 0x12, 0x18, 0x0a, 0x07, 0x76,
+	0x65, 0x72, 0x73, 0x69, 0x6f, 0x6e, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x07, 0x76, 0x65,
+	0x72, 0x73, 0x69, 0x6f, 0x6e, 0x12, 0x1d, 0x0a, 0x0a, 0x6c, 0x65, 0x61, 0x66, 0x5f, 0x69, 0x6e,
+	0x64, 0x65, 0x78, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x09, 0x6c, 0x65, 0x61, 0x66, 0x49,
+	0x6e, 0x64, 0x65, 0x78, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x72, 0x65, 0x65, 0x5f, 0x73, 0x69, 0x7a,
+	0x65, 0x18, 0x03, 0x20, 0x01, 0x28, 0x04, 0x52, 0x08, 0x74, 0x72, 0x65, 0x65, 0x53, 0x69, 0x7a,
+	0x65, 0x12, 0x1b, 0x0a, 0x09, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x61, 0x6c, 0x67, 0x6f, 0x18, 0x04,
+	0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x68, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f, 0x12, 0x14,
+	0x0a, 0x05, 0x66, 0x6c, 0x61, 0x67, 0x73, 0x18, 0x05, 0x20, 0x01, 0x28, 0x0d, 0x52, 0x05, 0x66,
+	0x6c, 0x61, 0x67, 0x73, 0x22, 0x84, 0x01, 0x0a, 0x19, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74,
+	0x43, 0x6f, 0x6e, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65,
+	0x73, 0x74, 0x12, 0x1b, 0x0a, 0x09, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x5f, 0x69, 0x64, 0x18,
+	0x01, 0x20, 0x01, 0x28, 0x09, 0x52, 0x08, 0x74, 0x65, 0x6e, 0x61, 0x6e, 0x74, 0x49, 0x64, 0x12,
+	0x24, 0x0a, 0x0e, 0x6f, 0x6c, 0x64, 0x5f, 0x66, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x5f, 0x69,
+	0x64, 0x18, 0x02, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6f, 0x6c, 0x64, 0x46, 0x69, 0x6c, 0x65,
+	0x73, 0x65, 0x74, 0x49, 0x64, 0x12, 0x24, 0x0a, 0x0e, 0x6e, 0x65, 0x77, 0x5f, 0x66, 0x69, 0x6c,
+	0x65, 0x73, 0x65, 0x74, 0x5f, 0x69, 0x64, 0x18, 0x03, 0x20, 0x01, 0x28, 0x09, 0x52, 0x0c, 0x6e,
+	0x65, 0x77, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x49, 0x64, 0x22, 0x6e, 0x0a, 0x1a, 0x46,
+	0x69, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63,
+	0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x33, 0x0a, 0x08, 0x6d, 0x74, 0x5f,
+	0x70, 0x72, 0x6f, 0x6f, 0x66, 0x18, 0x01, 0x20, 0x01, 0x28, 0x0b, 0x32, 0x18, 0x2e, 0x76, 0x72,
+	0x66, 0x73, 0x2e, 0x4d, 0x54, 0x43, 0x6f, 0x6e, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x79,
+	0x50, 0x72, 0x6f, 0x6f, 0x66, 0x52, 0x07, 0x6d, 0x74, 0x50, 0x72, 0x6f, 0x6f, 0x66, 0x12, 0x1b,
+	0x0a, 0x09, 0x68, 0x61, 0x73, 0x68, 0x5f, 0x61, 0x6c, 0x67, 0x6f, 0x18, 0x02, 0x20, 0x01, 0x28,
+	0x09, 0x52, 0x08, 0x68, 0x61, 0x73, 0x68, 0x41, 0x6c, 0x67, 0x6f, 0x22, 0x60, 0x0a, 0x12, 0x4d,
+	0x54, 0x43, 0x6f, 0x6e, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x50, 0x72, 0x6f, 0x6f,
+	0x66, 0x12, 0x19, 0x0a, 0x08, 0x6f, 0x6c, 0x64, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x01, 0x20,
+	0x01, 0x28, 0x04, 0x52, 0x07, 0x6f, 0x6c, 0x64, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x19, 0x0a, 0x08,
+	0x6e, 0x65, 0x77, 0x5f, 0x73, 0x69, 0x7a, 0x65, 0x18, 0x02, 0x20, 0x01, 0x28, 0x04, 0x52, 0x07,
+	0x6e, 0x65, 0x77, 0x53, 0x69, 0x7a, 0x65, 0x12, 0x14, 0x0a, 0x05, 0x6e, 0x6f, 0x64, 0x65, 0x73,
+	0x18, 0x03, 0x20, 0x03, 0x28, 0x0c, 0x52, 0x05, 0x6e, 0x6f, 0x64, 0x65, 0x73, 0x32, 0xfd, 0x02,
+	0x0a, 0x1b, 0x56, 0x65, 0x72, 0x69, 0x66, 0x69, 0x61, 0x62, 0x6c, 0x65, 0x52, 0x65, 0x6d, 0x6f,
+	0x74, 0x65, 0x46, 0x69, 0x6c, 0x65, 0x53, 0x74, 0x6f, 0x72, 0x61, 0x67, 0x65, 0x12, 0x45, 0x0a,
+	0x0c, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x12, 0x19, 0x2e,
+	0x76, 0x72, 0x66, 0x73, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x75, 0x63, 0x6b, 0x65,
+	0x74, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1a, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e,
+	0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x42, 0x75, 0x63, 0x6b, 0x65, 0x74, 0x52, 0x65, 0x73, 0x70,
+	0x6f, 0x6e, 0x73, 0x65, 0x12, 0x3f, 0x0a, 0x0a, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x44, 0x6f,
+	0x6e, 0x65, 0x12, 0x17, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64,
+	0x44, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x18, 0x2e, 0x76, 0x72,
+	0x66, 0x73, 0x2e, 0x55, 0x70, 0x6c, 0x6f, 0x61, 0x64, 0x44, 0x6f, 0x6e, 0x65, 0x52, 0x65, 0x73,
+	0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x51, 0x0a, 0x10, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61,
+	0x64, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f, 0x12, 0x1d, 0x2e, 0x76, 0x72, 0x66, 0x73,
+	0x2e, 0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66,
+	0x6f, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x1e, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e,
+	0x44, 0x6f, 0x77, 0x6e, 0x6c, 0x6f, 0x61, 0x64, 0x46, 0x69, 0x6c, 0x65, 0x49, 0x6e, 0x66, 0x6f,
+	0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73, 0x65, 0x12, 0x57, 0x0a, 0x12, 0x46, 0x69, 0x6c, 0x65,
+	0x73, 0x65, 0x74, 0x43, 0x6f, 0x6e, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x12, 0x1f,
+	0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x43, 0x6f, 0x6e,
+	0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a,
+	0x20, 0x2e, 0x76, 0x72, 0x66, 0x73, 0x2e, 0x46, 0x69, 0x6c, 0x65, 0x73, 0x65, 0x74, 0x43, 0x6f,
+	0x6e, 0x73, 0x69, 0x73, 0x74, 0x65, 0x6e, 0x63, 0x79, 0x52, 0x65, 0x73, 0x70, 0x6f, 0x6e, 0x73,
+	0x65, 0x12, 0x2a, 0x0a, 0x04, 0x50, 0x69, 0x6e, 0x67, 0x12, 0x11, 0x2e, 0x76, 0x72, 0x66, 0x73,
+	0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x71, 0x75, 0x65, 0x73, 0x74, 0x1a, 0x0f, 0x2e, 0x76,
+	0x72, 0x66, 0x73, 0x2e, 0x50, 0x69, 0x6e, 0x67, 0x52, 0x65, 0x70, 0x6c, 0x79, 0x42, 0x43, 0x5a,
+	0x41, 0x67, 0x69, 0x74, 0x68, 0x75, 0x62, 0x2e, 0x63, 0x6f, 0x6d, 0x2f, 0x6a, 0x61, 0x38, 0x38,
+	0x61, 0x2f, 0x76, 0x72, 0x66, 0x73, 0x2d, 0x67, 0x6f, 0x2d, 0x6d, 0x65, 0x72, 0x6b, 0x6c, 0x74,
+	0x72, 0x65, 0x65, 0x2f, 0x6c, 0x69, 0x62, 0x73, 0x2f, 0x72, 0x70, 0x63, 0x61, 0x70, 0x69, 0x2f,
+	0x70, 0x72, 0x6f, 0x74, 0x6f, 0x73, 0x2f, 0x76, 0x31, 0x2f, 0x76, 0x72, 0x66, 0x73, 0x2d, 0x61,
+	0x70, 0x69, 0x62, 0x06, 0x70, 0x72, 0x6f, 0x74, 0x6f, 0x33,
 }
 
 var (
@@ -616,33 +1107,43 @@ func file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDescGZIP() []byte {
 	return file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDescData
 }
 
-var file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes = make([]protoimpl.MessageInfo, 14)
 var file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_goTypes = []interface{}{
-	(*PingRequest)(nil),              // 0: vrfs.PingRequest
-	(*PingReply)(nil),                // 1: vrfs.PingReply
-	(*UploadBucketRequest)(nil),      // 2: vrfs.UploadBucketRequest
-	(*UploadBucketResponse)(nil),     // 3: vrfs.UploadBucketResponse
-	(*UploadDoneRequest)(nil),        // 4: vrfs.UploadDoneRequest
-	(*UploadDoneResponse)(nil),       // 5: vrfs.UploadDoneResponse
-	(*DownloadFileInfoRequest)(nil),  // 6: vrfs.DownloadFileInfoRequest
-	(*DownloadFileInfoResponse)(nil), // 7: vrfs.DownloadFileInfoResponse
-	(*MTProof)(nil),                  // 8: vrfs.MTProof
+	(*PingRequest)(nil),                // 0: vrfs.PingRequest
+	(*PingReply)(nil),                  // 1: vrfs.PingReply
+	(*UploadBucketRequest)(nil),        // 2: vrfs.UploadBucketRequest
+	(*UploadBucketResponse)(nil),       // 3: vrfs.UploadBucketResponse
+	(*UploadDoneRequest)(nil),          // 4: vrfs.UploadDoneRequest
+	(*UploadDoneResponse)(nil),         // 5: vrfs.UploadDoneResponse
+	(*DownloadFileInfoRequest)(nil),    // 6: vrfs.DownloadFileInfoRequest
+	(*DownloadFileInfoResponse)(nil),   // 7: vrfs.DownloadFileInfoResponse
+	(*FilesetManifestEntry)(nil),       // 8: vrfs.FilesetManifestEntry
+	(*MTProof)(nil),                    // 9: vrfs.MTProof
+	(*MTProofEnvelope)(nil),            // 10: vrfs.MTProofEnvelope
+	(*FilesetConsistencyRequest)(nil),  // 11: vrfs.FilesetConsistencyRequest
+	(*FilesetConsistencyResponse)(nil), // 12: vrfs.FilesetConsistencyResponse
+	(*MTConsistencyProof)(nil),         // 13: vrfs.MTConsistencyProof
 }
 var file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_depIdxs = []int32{
-	8, // 0: vrfs.DownloadFileInfoResponse.mt_proof:type_name -> vrfs.MTProof
-	2, // 1: vrfs.VerifiableRemoteFileStorage.UploadBucket:input_type -> vrfs.UploadBucketRequest
-	4, // 2: vrfs.VerifiableRemoteFileStorage.UploadDone:input_type -> vrfs.UploadDoneRequest
-	6, // 3: vrfs.VerifiableRemoteFileStorage.DownloadFileInfo:input_type -> vrfs.DownloadFileInfoRequest
-	0, // 4: vrfs.VerifiableRemoteFileStorage.Ping:input_type -> vrfs.PingRequest
-	3, // 5: vrfs.VerifiableRemoteFileStorage.UploadBucket:output_type -> vrfs.UploadBucketResponse
-	5, // 6: vrfs.VerifiableRemoteFileStorage.UploadDone:output_type -> vrfs.UploadDoneResponse
-	7, // 7: vrfs.VerifiableRemoteFileStorage.DownloadFileInfo:output_type -> vrfs.DownloadFileInfoResponse
-	1, // 8: vrfs.VerifiableRemoteFileStorage.Ping:output_type -> vrfs.PingReply
-	5, // [5:9] is the sub-list for method output_type
-	1, // [1:5] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	9,  // 0: vrfs.DownloadFileInfoResponse.mt_proof:type_name -> vrfs.MTProof
+	8,  // 1: vrfs.DownloadFileInfoResponse.manifest_entry:type_name -> vrfs.FilesetManifestEntry
+	10, // 2: vrfs.MTProof.envelope:type_name -> vrfs.MTProofEnvelope
+	13, // 3: vrfs.FilesetConsistencyResponse.mt_proof:type_name -> vrfs.MTConsistencyProof
+	2,  // 4: vrfs.VerifiableRemoteFileStorage.UploadBucket:input_type -> vrfs.UploadBucketRequest
+	4,  // 5: vrfs.VerifiableRemoteFileStorage.UploadDone:input_type -> vrfs.UploadDoneRequest
+	6,  // 6: vrfs.VerifiableRemoteFileStorage.DownloadFileInfo:input_type -> vrfs.DownloadFileInfoRequest
+	11, // 7: vrfs.VerifiableRemoteFileStorage.FilesetConsistency:input_type -> vrfs.FilesetConsistencyRequest
+	0,  // 8: vrfs.VerifiableRemoteFileStorage.Ping:input_type -> vrfs.PingRequest
+	3,  // 9: vrfs.VerifiableRemoteFileStorage.UploadBucket:output_type -> vrfs.UploadBucketResponse
+	5,  // 10: vrfs.VerifiableRemoteFileStorage.UploadDone:output_type -> vrfs.UploadDoneResponse
+	7,  // 11: vrfs.VerifiableRemoteFileStorage.DownloadFileInfo:output_type -> vrfs.DownloadFileInfoResponse
+	12, // 12: vrfs.VerifiableRemoteFileStorage.FilesetConsistency:output_type -> vrfs.FilesetConsistencyResponse
+	1,  // 13: vrfs.VerifiableRemoteFileStorage.Ping:output_type -> vrfs.PingReply
+	9,  // [9:14] is the sub-list for method output_type
+	4,  // [4:9] is the sub-list for method input_type
+	4,  // [4:4] is the sub-list for extension type_name
+	4,  // [4:4] is the sub-list for extension extendee
+	0,  // [0:4] is the sub-list for field type_name
 }
 
 func init() { file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_init() }
@@ -748,6 +1249,18 @@ func file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_init() {
 			}
 		}
 		file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[8].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FilesetManifestEntry); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[9].Exporter = func(v interface{}, i int) interface{} {
 			switch v := v.(*MTProof); i {
 			case 0:
 				return &v.state
@@ -759,6 +1272,54 @@ func file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_init() {
 				return nil
 			}
 		}
+		file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[10].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MTProofEnvelope); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[11].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FilesetConsistencyRequest); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[12].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*FilesetConsistencyResponse); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
+		file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_msgTypes[13].Exporter = func(v interface{}, i int) interface{} {
+			switch v := v.(*MTConsistencyProof); i {
+			case 0:
+				return &v.state
+			case 1:
+				return &v.sizeCache
+			case 2:
+				return &v.unknownFields
+			default:
+				return nil
+			}
+		}
 	}
 	type x struct{}
 	out := protoimpl.TypeBuilder{
@@ -766,7 +1327,7 @@ func file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_init() {
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: file_libs_rpcapi_protos_v1_vrfs_api_vrfs_proto_rawDesc,
 			NumEnums:      0,
-			NumMessages:   9,
+			NumMessages:   14,
 			NumExtensions: 0,
 			NumServices:   1,
 		},
@@ -29,6 +29,9 @@ type VerifiableRemoteFileStorageClient interface {
 	// Get the download info to retrieve a file from the files storage server as well as
 	// the MerkleTree proofs to confirm it has not been tampered
 	DownloadFileInfo(ctx context.Context, in *DownloadFileInfoRequest, opts ...grpc.CallOption) (*DownloadFileInfoResponse, error)
+	// Get the proof that a fileset is an append-only extension of an older version of it,
+	// i.e. that the files of the older fileset have not been rewritten
+	FilesetConsistency(ctx context.Context, in *FilesetConsistencyRequest, opts ...grpc.CallOption) (*FilesetConsistencyResponse, error)
 	// Dummy ping request: check that the service is available & responsive
 	Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingReply, error)
 }
@@ -68,6 +71,15 @@ func (c *verifiableRemoteFileStorageClient) DownloadFileInfo(ctx context.Context
 	return out, nil
 }
 
+func (c *verifiableRemoteFileStorageClient) FilesetConsistency(ctx context.Context, in *FilesetConsistencyRequest, opts ...grpc.CallOption) (*FilesetConsistencyResponse, error) {
+	out := new(FilesetConsistencyResponse)
+	err := c.cc.Invoke(ctx, "/vrfs.VerifiableRemoteFileStorage/FilesetConsistency", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 func (c *verifiableRemoteFileStorageClient) Ping(ctx context.Context, in *PingRequest, opts ...grpc.CallOption) (*PingReply, error) {
 	out := new(PingReply)
 	err := c.cc.Invoke(ctx, "/vrfs.VerifiableRemoteFileStorage/Ping", in, out, opts...)
@@ -88,6 +100,9 @@ type VerifiableRemoteFileStorageServer interface {
 	// Get the download info to retrieve a file from the files storage server as well as
 	// the MerkleTree proofs to confirm it has not been tampered
 	DownloadFileInfo(context.Context, *DownloadFileInfoRequest) (*DownloadFileInfoResponse, error)
+	// Get the proof that a fileset is an append-only extension of an older version of it,
+	// i.e. that the files of the older fileset have not been rewritten
+	FilesetConsistency(context.Context, *FilesetConsistencyRequest) (*FilesetConsistencyResponse, error)
 	// Dummy ping request: check that the service is available & responsive
 	Ping(context.Context, *PingRequest) (*PingReply, error)
 	mustEmbedUnimplementedVerifiableRemoteFileStorageServer()
@@ -106,6 +121,9 @@ func (UnimplementedVerifiableRemoteFileStorageServer) UploadDone(context.Context
 func (UnimplementedVerifiableRemoteFileStorageServer) DownloadFileInfo(context.Context, *DownloadFileInfoRequest) (*DownloadFileInfoResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method DownloadFileInfo not implemented")
 }
+func (UnimplementedVerifiableRemoteFileStorageServer) FilesetConsistency(context.Context, *FilesetConsistencyRequest) (*FilesetConsistencyResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FilesetConsistency not implemented")
+}
 func (UnimplementedVerifiableRemoteFileStorageServer) Ping(context.Context, *PingRequest) (*PingReply, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Ping not implemented")
 }
@@ -177,6 +195,24 @@ func _VerifiableRemoteFileStorage_DownloadFileInfo_Handler(srv interface{}, ctx
 	return interceptor(ctx, in, info, handler)
 }
 
+func _VerifiableRemoteFileStorage_FilesetConsistency_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FilesetConsistencyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(VerifiableRemoteFileStorageServer).FilesetConsistency(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/vrfs.VerifiableRemoteFileStorage/FilesetConsistency",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(VerifiableRemoteFileStorageServer).FilesetConsistency(ctx, req.(*FilesetConsistencyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 func _VerifiableRemoteFileStorage_Ping_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
 	in := new(PingRequest)
 	if err := dec(in); err != nil {
@@ -214,6 +250,10 @@ var VerifiableRemoteFileStorage_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "DownloadFileInfo",
 			Handler:    _VerifiableRemoteFileStorage_DownloadFileInfo_Handler,
 		},
+		{
+			MethodName: "FilesetConsistency",
+			Handler:    _VerifiableRemoteFileStorage_FilesetConsistency_Handler,
+		},
 		{
 			MethodName: "Ping",
 			Handler:    _VerifiableRemoteFileStorage_Ping_Handler,
@@ -28,6 +28,8 @@ type FileServiceClient interface {
 	BucketFileHashes(ctx context.Context, in *BucketFileHashesRequest, opts ...grpc.CallOption) (*BucketFileHashesResponse, error)
 	// Download method initiates the download of a file content, part of a given bucket
 	Download(ctx context.Context, in *FileDownloadRequest, opts ...grpc.CallOption) (FileService_DownloadClient, error)
+	// FileChunkProofs method retrieves the file chunk tree root of a file and the proofs of a range of its chunks
+	FileChunkProofs(ctx context.Context, in *FileChunkProofsRequest, opts ...grpc.CallOption) (*FileChunkProofsResponse, error)
 }
 
 type fileServiceClient struct {
@@ -113,6 +115,15 @@ func (x *fileServiceDownloadClient) Recv() (*FileDownloadResponse, error) {
 	return m, nil
 }
 
+func (c *fileServiceClient) FileChunkProofs(ctx context.Context, in *FileChunkProofsRequest, opts ...grpc.CallOption) (*FileChunkProofsResponse, error) {
+	out := new(FileChunkProofsResponse)
+	err := c.cc.Invoke(ctx, "/fileserver.FileService/FileChunkProofs", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // FileServiceServer is the server API for FileService service.
 // All implementations must embed UnimplementedFileServiceServer
 // for forward compatibility
@@ -123,6 +134,8 @@ type FileServiceServer interface {
 	BucketFileHashes(context.Context, *BucketFileHashesRequest) (*BucketFileHashesResponse, error)
 	// Download method initiates the download of a file content, part of a given bucket
 	Download(*FileDownloadRequest, FileService_DownloadServer) error
+	// FileChunkProofs method retrieves the file chunk tree root of a file and the proofs of a range of its chunks
+	FileChunkProofs(context.Context, *FileChunkProofsRequest) (*FileChunkProofsResponse, error)
 	mustEmbedUnimplementedFileServiceServer()
 }
 
@@ -139,6 +152,9 @@ func (UnimplementedFileServiceServer) BucketFileHashes(context.Context, *BucketF
 func (UnimplementedFileServiceServer) Download(*FileDownloadRequest, FileService_DownloadServer) error {
 	return status.Errorf(codes.Unimplemented, "method Download not implemented")
 }
+func (UnimplementedFileServiceServer) FileChunkProofs(context.Context, *FileChunkProofsRequest) (*FileChunkProofsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FileChunkProofs not implemented")
+}
 func (UnimplementedFileServiceServer) mustEmbedUnimplementedFileServiceServer() {}
 
 // UnsafeFileServiceServer may be embedded to opt out of forward compatibility for this service.
@@ -217,6 +233,24 @@ func (x *fileServiceDownloadServer) Send(m *FileDownloadResponse) error {
 	return x.ServerStream.SendMsg(m)
 }
 
+func _FileService_FileChunkProofs_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FileChunkProofsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(FileServiceServer).FileChunkProofs(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/fileserver.FileService/FileChunkProofs",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(FileServiceServer).FileChunkProofs(ctx, req.(*FileChunkProofsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
 // FileService_ServiceDesc is the grpc.ServiceDesc for FileService service.
 // It's only intended for direct use with grpc.RegisterService,
 // and not to be introspected or modified (even as a copy)
@@ -228,6 +262,10 @@ var FileService_ServiceDesc = grpc.ServiceDesc{
 			MethodName: "BucketFileHashes",
 			Handler:    _FileService_BucketFileHashes_Handler,
 		},
+		{
+			MethodName: "FileChunkProofs",
+			Handler:    _FileService_FileChunkProofs_Handler,
+		},
 	},
 	Streams: []grpc.StreamDesc{
 		{
@@ -34,6 +34,8 @@ func Run(cfg *config.Config) error {
 	if err != nil {
 		return fmt.Errorf("failed to init the VRFS service\n%w", err)
 	}
+	// Released on return, once the gRPC server is gracefully stopped
+	defer vrfsServer.Close()
 	pbvrfs.RegisterVerifiableRemoteFileStorageServer(grpcServer, vrfsServer)
 
 	listen, err := net.Listen("tcp", cfg.GRPC.Port)
@@ -63,5 +65,7 @@ func Run(cfg *config.Config) error {
 	case <-ctx.Done():
 	}
 
+	// Wait for the running requests to complete before releasing the service, whose worker pool they use
+	grpcServer.GracefulStop()
 	return nil
 }
@@ -19,8 +19,8 @@ require github.com/ja88a/vrfs-go-merkletree/libs/config v0.0.0
 require github.com/ja88a/vrfs-go-merkletree/libs/logger v0.0.0
 
 require (
-	github.com/ja88a/vrfs-go-merkletree/libs/merkletree v0.0.0
 	github.com/ja88a/vrfs-go-merkletree/libs/db v0.0.0
+	github.com/ja88a/vrfs-go-merkletree/libs/merkletree v0.0.0
 	google.golang.org/grpc v1.59.0
 )
 
@@ -33,14 +33,17 @@ require (
 	github.com/golang/protobuf v1.5.3 // indirect
 	github.com/ilyakaznacheev/cleanenv v1.5.0 // indirect
 	github.com/joho/godotenv v1.5.1 // indirect
+	github.com/klauspost/cpuid/v2 v2.0.9 // indirect
 	github.com/mattn/go-colorable v0.1.13 // indirect
 	github.com/mattn/go-isatty v0.0.19 // indirect
 	github.com/redis/go-redis/v9 v9.3.0 // indirect
 	github.com/rs/zerolog v1.31.0 // indirect
+	golang.org/x/crypto v0.14.0 // indirect
 	golang.org/x/net v0.14.0 // indirect
-	golang.org/x/sys v0.12.0 // indirect
-	golang.org/x/text v0.12.0 // indirect
+	golang.org/x/sys v0.13.0 // indirect
+	golang.org/x/text v0.13.0 // indirect
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20230822172742-b8732ec3820d // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
+	lukechampine.com/blake3 v1.1.7 // indirect
 	olympos.io/encoding/edn v0.0.0-20201019073823-d3554ca0b0a3 // indirect
 )
@@ -1,10 +1,12 @@
 package service
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strconv"
 	"time"
 
 	"google.golang.org/grpc"
@@ -13,6 +15,7 @@ import (
 	"google.golang.org/grpc/status"
 
 	mt "github.com/ja88a/vrfs-go-merkletree/libs/merkletree"
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
 	mtutils "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/utils"
 
 	pb "github.com/ja88a/vrfs-go-merkletree/libs/rpcapi/protos/v1/vrfs-api"
@@ -30,6 +33,8 @@ type VerifiableRemoteFileStorageServer struct {
 	cfg      *config.Config
 	fsClient pbfs.FileServiceClient
 	db       *cache.CacheClient
+	// wp is the worker pool shared by the MerkleTrees computed concurrently by the requests
+	wp *mt.WorkerPool
 }
 
 // Init the service execution context
@@ -48,9 +53,26 @@ func New(l *logger.Logger, cfg *config.Config) (*VerifiableRemoteFileStorageServ
 		cfg:      cfg,
 		fsClient: pbfs.NewFileServiceClient(fsConn),
 		db:       kvdb,
+		wp:       mt.NewWorkerPool(0),
 	}, nil
 }
 
+// Release the service execution context, once the service is stopped: no request must be running anymore,
+// since the requests computing MerkleTrees use the shared worker pool being closed
+func (g *VerifiableRemoteFileStorageServer) Close() {
+	g.wp.Close()
+}
+
+// MerkleTree config of the filesets, based on the specified hash algorithm and run by the service shared worker pool
+func (g *VerifiableRemoteFileStorageServer) filesetTreeConfig(hashAlgo string) *mt.Config {
+	mtConfig := mt.MerkleTreeDefaultConfig(false)
+	if hashAlgo != "" {
+		mtConfig.HashAlgorithm = hashAlgo
+	}
+	mtConfig.WorkerPool = g.wp
+	return mtConfig
+}
+
 // Handle the requests for file bucket where files can be uploaded on the Remote File Storage server
 func (g *VerifiableRemoteFileStorageServer) UploadBucket(ctx context.Context, in *pb.UploadBucketRequest) (*pb.UploadBucketResponse, error) {
 	g.l.Debug("Received bucket req for fileset '%v' of Tenant: %v", in.GetFilesetId(), in.GetTenantId())
@@ -71,56 +93,121 @@ func (g *VerifiableRemoteFileStorageServer) Ping(ctx context.Context, in *pb.Pin
 
 // Handle the requests for notifying that a fileset has been uploaded, its consistency verified and confirmed to the client
 func (g *VerifiableRemoteFileStorageServer) UploadDone(ctx context.Context, in *pb.UploadDoneRequest) (*pb.UploadDoneResponse, error) {
-	g.l.Debug("Received UploadDone req from '%v' for fileset '%v' with MT root '%x'", in.GetTenantId(), in.GetFilesetId(), in.GetMtRoot())
+	g.l.Debug("Received UploadDone req from '%v' for fileset '%v' with MT root '%x' (hash: '%v', chunk size: %d)", in.GetTenantId(), in.GetFilesetId(), in.GetMtRoot(), in.GetHashAlgo(), in.GetChunkSize())
 	bucketId := computeBucketId(in.GetTenantId(), in.GetFilesetId())
 
+	// Check that the hash algorithm used by the client is supported
+	hashAlgo := in.GetHashAlgo()
+	if hashAlgo == "" {
+		hashAlgo = hash.DefaultAlgo
+	}
+	if _, err := hash.HashFuncByName(hashAlgo); err != nil {
+		respMsg := fmt.Sprintf("Unsupported hash algorithm '%v' for fileset '%v'\n%v", hashAlgo, in.GetFilesetId(), err)
+		g.l.Error(respMsg)
+		return &pb.UploadDoneResponse{Status: 400, Message: respMsg}, status.Error(codes.InvalidArgument, respMsg)
+	}
+
 	ctxFS, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
 
-	// Request to the FS for providing the list of the file hashes present in its bucket
+	// Request to the FS for providing the list of the file hashes present in its bucket,
+	// or their file chunk tree roots if the client relies on file chunks
 	// Alternative: download all the bucket files and compute the file hashes here! But we'll avoid consuming unnecessary network bandwidth
-	resp, err := g.fsClient.BucketFileHashes(ctxFS, &pbfs.BucketFileHashesRequest{BucketId: bucketId})
+	resp, err := g.fsClient.BucketFileHashes(ctxFS, &pbfs.BucketFileHashesRequest{BucketId: bucketId, HashAlgo: hashAlgo, ChunkSize: in.GetChunkSize()})
 	if err != nil {
 		respErr := fmt.Errorf("failed to retrieve files hashes from FS for fileset '%v' (bucket: '%v')\n%w", in.GetFilesetId(), bucketId, err)
 		g.l.Error(fmt.Sprint(respErr))
 		return &pb.UploadDoneResponse{Status: 500, Message: fmt.Sprint(respErr)}, respErr
 	}
 
-	// Compute the MerkleTree root
+	// Compute the MerkleTree, its nodes being kept for serving the proofs of any file later on
 	fileHashes := resp.GetFileHashes()
-	tree, err := mtutils.GenerateMerkleTree(fileHashes, true)
+	tree, err := mtutils.GenerateMerkleTreeWithConfig(fileHashes, g.filesetTreeConfig(hashAlgo))
 	if err != nil {
 		respErr := fmt.Errorf("failed to compute merkletree from files hashes of fileset '%v' (%v)\n%v", in.GetFilesetId(), bucketId, err)
 		g.l.Error(fmt.Sprint(respErr))
 		return &pb.UploadDoneResponse{Status: 500, Message: fmt.Sprint(respErr)}, respErr
 	}
-	if len(tree.Proofs) == 0 {
-		respErr := fmt.Errorf("failed to generate merkletree proofs for fileset '%v' (bucket: %v)", in.GetFilesetId(), bucketId)
+
+	// Rebuild the fileset root out of the fileset manifest entries, if the client uploaded its manifest:
+	// the leaves rebuilt by the FS out of the stored files must be the ones of the manifest
+	manifestData := resp.GetManifest()
+	if len(manifestData) > 0 {
+		manifest, err := mtutils.ParseFilesetManifest(manifestData)
+		if err == nil && (manifest.HashAlgo != hashAlgo || manifest.ChunkSize != in.GetChunkSize()) {
+			err = fmt.Errorf("hash algorithm '%v' and chunk size %d of the manifest differ", manifest.HashAlgo, manifest.ChunkSize)
+		}
+		if err != nil {
+			respMsg := fmt.Sprintf("Unsupported fileset manifest for fileset '%v' (bucket: '%v')\n%v", in.GetFilesetId(), bucketId, err)
+			g.l.Error(respMsg)
+			return &pb.UploadDoneResponse{Status: 400, Message: respMsg}, status.Error(codes.InvalidArgument, respMsg)
+		}
+		manifestRoot, err := manifest.Root()
+		if err != nil {
+			respMsg := fmt.Sprintf("Failed to compute the merkletree root of the manifest of fileset '%v' (bucket: '%v')\n%v", in.GetFilesetId(), bucketId, err)
+			g.l.Error(respMsg)
+			return &pb.UploadDoneResponse{Status: 500, Message: respMsg}, status.Error(codes.Internal, respMsg)
+		}
+		if !bytes.Equal(manifestRoot, tree.Root) {
+			respMsg := fmt.Sprintf("VRFS stored files do not match the manifest of fileset '%v' (bucket: %v) - Manifest root: '%x' Generated root: '%x'", in.GetFilesetId(), bucketId, manifestRoot, tree.Root)
+			g.l.Error(respMsg)
+			return &pb.UploadDoneResponse{Status: 419, Message: respMsg}, nil
+		}
+	}
+
+	// Compare the MerkleTree roots to confirm that filesets match, or not,
+	// before persisting anything: a mismatching upload must not replace the data of a verified fileset
+	if hex.EncodeToString(tree.Root) != hex.EncodeToString(in.GetMtRoot()) {
+		respErr := fmt.Errorf("VRFS MerkleTree roots differ for fileset '%v' (bucket: %v) - Generated root: '%x'", in.GetFilesetId(), bucketId, tree.Root)
 		g.l.Error(fmt.Sprint(respErr))
-		return &pb.UploadDoneResponse{Status: 500, Message: fmt.Sprint(respErr)}, nil
+		return &pb.UploadDoneResponse{Status: 419, Message: fmt.Sprint(respErr)}, nil
 	}
 
-	// Persist the MerkleTree proofs for each leaf node, for later retrieval by clients
-	dbKey := computeDbKeyMtProofs(in.GetTenantId(), in.GetFilesetId())
-	mtProofsJson, err := json.Marshal(tree.Proofs)
+	// Persist the MerkleTree nodes, O(n) in size, for later retrieval of the file proofs by clients
+	// and of the file hashes, i.e. the MerkleTree leaves, for consistency proofs between fileset versions
+	dbKey := computeDbKeyMtTree(in.GetTenantId(), in.GetFilesetId())
+	mtTreeBin, err := tree.MarshalBinary()
 	if err != nil {
-		respMsg := fmt.Sprintf("Failed to marshall MT Proofs to JSON for fileset '%v' Key: #%v\n%v", in.FilesetId, dbKey, err)
+		respMsg := fmt.Sprintf("Failed to marshall the MerkleTree to binary for fileset '%v' Key: #%v\n%v", in.FilesetId, dbKey, err)
 		g.l.Error(respMsg)
+		return &pb.UploadDoneResponse{Status: 500, Message: fmt.Sprint(respMsg)}, status.Error(codes.Internal, respMsg)
 	}
-	err = g.db.Set(dbKey, mtProofsJson, 0)
+	err = g.db.Set(dbKey, mtTreeBin, 0)
 	if err != nil {
-		respMsg := fmt.Sprintf("Failed to persist MT Proofs in DB for fileset '%v' Key: #%v\n%v", in.FilesetId, dbKey, err)
+		respMsg := fmt.Sprintf("Failed to persist the MerkleTree in DB for fileset '%v' Key: #%v\n%v", in.FilesetId, dbKey, err)
 		g.l.Error(respMsg)
 		return &pb.UploadDoneResponse{Status: 500, Message: fmt.Sprint(respMsg)}, status.Error(codes.DataLoss, respMsg)
 	}
 
-	g.l.Debug("%d MerkleTree proofs persisted in DB for fileset '%v' - key: '%v'", len(tree.Proofs), in.GetFilesetId(), dbKey)
+	g.l.Debug("MerkleTree of %d leaves persisted in DB for fileset '%v' - key: '%v' size: %d", tree.NumLeaves, in.GetFilesetId(), dbKey, len(mtTreeBin))
 
-	// Compare the MerkleTree roots to confirm that filesets match, or not
-	if hex.EncodeToString(tree.Root) != hex.EncodeToString(in.GetMtRoot()) {
-		respErr := fmt.Errorf("VRFS MerkleTree roots differ for fileset '%v' (bucket: %v) - Generated root: '%x'", in.GetFilesetId(), bucketId, tree.Root)
-		g.l.Error(fmt.Sprint(respErr))
-		return &pb.UploadDoneResponse{Status: 419, Message: fmt.Sprint(respErr)}, nil
+	// Persist the hash algorithm of the fileset, for clients to verify its files and proofs with the same one
+	dbKeyHashAlgo := computeDbKeyHashAlgo(in.GetTenantId(), in.GetFilesetId())
+	err = g.db.Set(dbKeyHashAlgo, hashAlgo, 0)
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to persist the hash algorithm in DB for fileset '%v' Key: #%v\n%v", in.FilesetId, dbKeyHashAlgo, err)
+		g.l.Error(respMsg)
+		return &pb.UploadDoneResponse{Status: 500, Message: fmt.Sprint(respMsg)}, status.Error(codes.DataLoss, respMsg)
+	}
+
+	// Persist the file chunk size of the fileset, for clients to verify its files chunk per chunk
+	dbKeyChunkSize := computeDbKeyChunkSize(in.GetTenantId(), in.GetFilesetId())
+	err = g.db.Set(dbKeyChunkSize, strconv.FormatUint(uint64(in.GetChunkSize()), 10), 0)
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to persist the file chunk size in DB for fileset '%v' Key: #%v\n%v", in.FilesetId, dbKeyChunkSize, err)
+		g.l.Error(respMsg)
+		return &pb.UploadDoneResponse{Status: 500, Message: fmt.Sprint(respMsg)}, status.Error(codes.DataLoss, respMsg)
+	}
+
+	// Persist the fileset manifest, for clients to verify the downloaded files against their manifest entry
+	if len(manifestData) > 0 {
+		dbKeyManifest := computeDbKeyManifest(in.GetTenantId(), in.GetFilesetId())
+		err = g.db.Set(dbKeyManifest, manifestData, 0)
+		if err != nil {
+			respMsg := fmt.Sprintf("Failed to persist the fileset manifest in DB for fileset '%v' Key: #%v\n%v", in.FilesetId, dbKeyManifest, err)
+			g.l.Error(respMsg)
+			return &pb.UploadDoneResponse{Status: 500, Message: fmt.Sprint(respMsg)}, status.Error(codes.DataLoss, respMsg)
+		}
 	}
 
 	g.l.Info("Files in FS bucket '%v' for fileset '%v' have their merkle tree root matching the client one: %x", bucketId, in.FilesetId, tree.Root)
@@ -128,11 +215,152 @@ func (g *VerifiableRemoteFileStorageServer) UploadDone(ctx context.Context, in *
 	return &pb.UploadDoneResponse{Status: 200, Message: "MerkleTree roots match - Files Upload successful"}, nil
 }
 
-// Utility method for computing the KV store's entry key for a set of MerkleTree proofs
+// Utility method for computing the KV store's entry key for the MerkleTree of a fileset
+func computeDbKeyMtTree(tenantId string, fileSetId string) string {
+	return tenantId + "_" + fileSetId + "_mttree"
+}
+
+// Retrieve the MerkleTree of a fileset persisted in DB on upload.
+// No MerkleTree, nor error, is returned for the filesets uploaded before the MerkleTrees got persisted
+func (g *VerifiableRemoteFileStorageServer) getFilesetTree(tenantId string, fileSetId string) (*mt.MerkleTree, error) {
+	dbKey := computeDbKeyMtTree(tenantId, fileSetId)
+	mtTreeDB, err := g.db.GetString(dbKey)
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to retrieve the MerkleTree for fileset '%v' from db \n%v", fileSetId, err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.DataLoss, respMsg)
+	}
+	if mtTreeDB == "" {
+		return nil, nil
+	}
+	tree, err := mt.Load(&mt.Config{RunInParallel: true, WorkerPool: g.wp}, []byte(mtTreeDB))
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to load the MerkleTree from DB for fileset '%v' Tenant: '%v'\n%v", fileSetId, tenantId, err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.Internal, respMsg)
+	}
+	return tree, nil
+}
+
+// Retrieve the MerkleTree proof of a file, out of the fileset MerkleTree persisted in DB, or out of the
+// MerkleTree proofs persisted for the filesets uploaded before the MerkleTrees got persisted
+func (g *VerifiableRemoteFileStorageServer) getFilesetFileProof(tenantId string, fileSetId string, fileIndex int) (*mt.Proof, error) {
+	tree, err := g.getFilesetTree(tenantId, fileSetId)
+	if err != nil {
+		return nil, err
+	}
+	if tree != nil {
+		if fileIndex < 0 || fileIndex >= tree.NumLeaves {
+			respMsg := fmt.Sprintf("File index %d is out of range for fileset '%v' (%d files)", fileIndex, fileSetId, tree.NumLeaves)
+			g.l.Warn(respMsg)
+			return nil, status.Error(codes.OutOfRange, respMsg)
+		}
+		// The proof is generated by the file index, files of the same content sharing the same leaf
+		fileMtProof, err := tree.ProofByIndex(fileIndex)
+		if err != nil {
+			respMsg := fmt.Sprintf("Failed to generate the MerkleTree proof of file #%d for fileset '%v' Tenant: '%v'\n%v", fileIndex, fileSetId, tenantId, err)
+			g.l.Error(respMsg)
+			return nil, status.Error(codes.Internal, respMsg)
+		}
+		return fileMtProof, nil
+	}
+
+	// Retrieve MT Proofs from the DB
+	dbKey := computeDbKeyMtProofs(tenantId, fileSetId)
+	mtProofsDB, err := g.db.GetString(dbKey)
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to retrieve MT Proofs for fileset '%v' file #%d from db \n%v", fileSetId, fileIndex, err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.DataLoss, respMsg)
+	}
+	if mtProofsDB == "" {
+		respMsg := fmt.Sprintf("No MerkleTree Proofs available in DB for fileset '%v' Tenant: '%v'", fileSetId, tenantId)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.FailedPrecondition, respMsg)
+	}
+
+	// Convert the MT Proofs
+	mtProofs, err := decodeMtProofs([]byte(mtProofsDB))
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to unmarshall MerkleTree Proofs from DB for fileset '%v' Tenant: '%v'\n%v", fileSetId, tenantId, err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.Internal, respMsg)
+	}
+	if fileIndex < 0 || fileIndex >= len(mtProofs) {
+		respMsg := fmt.Sprintf("File index %d is out of range for fileset '%v' (%d files)", fileIndex, fileSetId, len(mtProofs))
+		g.l.Warn(respMsg)
+		return nil, status.Error(codes.OutOfRange, respMsg)
+	}
+	return mtProofs[fileIndex], nil
+}
+
+// Utility method for computing the KV store's entry key for a set of MerkleTree proofs,
+// persisted for the filesets uploaded before the MerkleTrees got persisted
 func computeDbKeyMtProofs(tenantId string, fileSetId string) string {
 	return tenantId + "_" + fileSetId + "_mtproofs"
 }
 
+// Decode the MerkleTree proofs of a fileset persisted in DB in the binary format, or in JSON
+// for the filesets persisted before the binary format was introduced
+func decodeMtProofs(mtProofsDB []byte) ([]*mt.Proof, error) {
+	if len(mtProofsDB) > 0 && (mtProofsDB[0] == '[' || mtProofsDB[0] == 'n') {
+		var mtProofs []*mt.Proof
+		err := json.Unmarshal(mtProofsDB, &mtProofs)
+		return mtProofs, err
+	}
+	var mtProofs mt.ProofSet
+	err := mtProofs.UnmarshalBinary(mtProofsDB)
+	return mtProofs, err
+}
+
+// Utility method for computing the KV store's entry key for the name of the hash algorithm of a fileset
+func computeDbKeyHashAlgo(tenantId string, fileSetId string) string {
+	return tenantId + "_" + fileSetId + "_hashalgo"
+}
+
+// Retrieve the name of the hash algorithm of a fileset persisted in DB on upload.
+// Filesets uploaded before the hash algorithm got persisted rely on the default one
+func (g *VerifiableRemoteFileStorageServer) getFilesetHashAlgo(tenantId string, fileSetId string) (string, error) {
+	dbKey := computeDbKeyHashAlgo(tenantId, fileSetId)
+	hashAlgo, err := g.db.GetString(dbKey)
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to retrieve the hash algorithm for fileset '%v' from db \n%v", fileSetId, err)
+		g.l.Error(respMsg)
+		return "", status.Error(codes.DataLoss, respMsg)
+	}
+	if hashAlgo == "" {
+		hashAlgo = hash.DefaultAlgo
+	}
+	return hashAlgo, nil
+}
+
+// Utility method for computing the KV store's entry key for the file chunk size of a fileset
+func computeDbKeyChunkSize(tenantId string, fileSetId string) string {
+	return tenantId + "_" + fileSetId + "_chunksize"
+}
+
+// Retrieve the file chunk size of a fileset persisted in DB on upload.
+// Filesets with whole file hashes as leaves, including the ones uploaded before, have a chunk size of 0
+func (g *VerifiableRemoteFileStorageServer) getFilesetChunkSize(tenantId string, fileSetId string) (uint32, error) {
+	dbKey := computeDbKeyChunkSize(tenantId, fileSetId)
+	chunkSizeDB, err := g.db.GetString(dbKey)
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to retrieve the file chunk size for fileset '%v' from db \n%v", fileSetId, err)
+		g.l.Error(respMsg)
+		return 0, status.Error(codes.DataLoss, respMsg)
+	}
+	if chunkSizeDB == "" {
+		return 0, nil
+	}
+	chunkSize, err := strconv.ParseUint(chunkSizeDB, 10, 32)
+	if err != nil {
+		respMsg := fmt.Sprintf("Unsupported file chunk size '%v' in DB for fileset '%v'\n%v", chunkSizeDB, fileSetId, err)
+		g.l.Error(respMsg)
+		return 0, status.Error(codes.Internal, respMsg)
+	}
+	return uint32(chunkSize), nil
+}
+
 // Get the download info to retrieve a file from the files storage server as well as
 // the MerkleTree proofs to confirm it has not been tampered while being stored or transferred
 func (g *VerifiableRemoteFileStorageServer) DownloadFileInfo(ctx context.Context, in *pb.DownloadFileInfoRequest) (*pb.DownloadFileInfoResponse, error) {
@@ -141,29 +369,23 @@ func (g *VerifiableRemoteFileStorageServer) DownloadFileInfo(ctx context.Context
 	// Target FS fileset bucket
 	bucketId := computeBucketId(in.GetTenantId(), in.GetFilesetId())
 
-	// Retrieve MT Proofs from the DB
-	dbKey := computeDbKeyMtProofs(in.GetTenantId(), in.GetFilesetId())
-	mtProofsDB, err := g.db.GetString(dbKey)
+	// Retrieve the MT Proof of the file
+	fileMtProof, err := g.getFilesetFileProof(in.GetTenantId(), in.GetFilesetId(), int(in.GetFileIndex()))
 	if err != nil {
-		respMsg := fmt.Sprintf("Failed to retrieve MT Proofs for fileset '%v' file #%d from db \n%v", in.FilesetId, in.FileIndex, err)
-		g.l.Error(respMsg)
-		return &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: nil}, status.Error(codes.DataLoss, respMsg)
+		return &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: nil}, err
 	}
-	if mtProofsDB == "" {
-		respMsg := fmt.Sprintf("No MerkleTree Proofs available in DB for fileset '%v' Tenant: '%v'", in.GetFilesetId(), in.GetTenantId())
-		g.l.Error(respMsg)
-		return &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: nil}, status.Error(codes.FailedPrecondition, respMsg)
+
+	// Retrieve the hash algorithm the fileset has been computed with
+	hashAlgo, err := g.getFilesetHashAlgo(in.GetTenantId(), in.GetFilesetId())
+	if err != nil {
+		return &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: nil}, err
 	}
 
-	// Convert the MT Proofs
-	var mtProofs []*mt.Proof
-	err = json.Unmarshal([]byte(mtProofsDB), &mtProofs)
+	// Retrieve the file chunk size, if the file leaf is a file chunk tree root
+	chunkSize, err := g.getFilesetChunkSize(in.GetTenantId(), in.GetFilesetId())
 	if err != nil {
-		respMsg := fmt.Sprintf("Failed to unmarshall JSON MerkleTree Proofs from DB for fileset '%v' Tenant: '%v'", in.GetFilesetId(), in.GetTenantId())
-		g.l.Error(respMsg)
-		return &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: nil}, status.Error(codes.Internal, respMsg)
+		return &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: nil}, err
 	}
-	fileMtProof := mtProofs[in.FileIndex]
 
 	if g.cfg.Log.Level == "debug" {
 		for index, sibling := range fileMtProof.Siblings {
@@ -175,6 +397,157 @@ func (g *VerifiableRemoteFileStorageServer) DownloadFileInfo(ctx context.Context
 		Siblings: fileMtProof.Siblings,
 		Path:     fileMtProof.Path,
 	}
+	// The proofs persisted by former versions have no envelope
+	if envelope := fileMtProof.Envelope; envelope != nil {
+		pbMtProof.Envelope = &pb.MTProofEnvelope{
+			Version:   envelope.Version,
+			LeafIndex: envelope.LeafIndex,
+			TreeSize:  envelope.TreeSize,
+			HashAlgo:  envelope.HashAlgorithm,
+			Flags:     envelope.Flags,
+		}
+	}
+
+	// The leaf of a fileset uploaded with its manifest is the one of the file manifest entry
+	manifest, err := g.getFilesetManifest(in.GetTenantId(), in.GetFilesetId())
+	if err != nil {
+		return &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: nil}, err
+	}
+	resp := &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: pbMtProof, HashAlgo: hashAlgo, ChunkSize: chunkSize}
+	if manifest != nil {
+		if int(in.GetFileIndex()) >= len(manifest.Entries) {
+			respMsg := fmt.Sprintf("File index %d is out of range for the manifest of fileset '%v' (%d files)", in.GetFileIndex(), in.GetFilesetId(), len(manifest.Entries))
+			g.l.Warn(respMsg)
+			return &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: nil}, status.Error(codes.OutOfRange, respMsg)
+		}
+		entry := manifest.Entries[in.GetFileIndex()]
+		resp.ManifestVersion = manifest.Version
+		resp.ManifestEntry = &pb.FilesetManifestEntry{
+			Path:        entry.Path,
+			Size:        entry.Size,
+			Mode:        entry.Mode,
+			ModTime:     entry.ModTime,
+			ContentHash: entry.ContentHash,
+		}
+	}
+
+	return resp, nil
+}
+
+// Utility method for computing the KV store's entry key for the manifest of a fileset
+func computeDbKeyManifest(tenantId string, fileSetId string) string {
+	return tenantId + "_" + fileSetId + "_manifest"
+}
+
+// Retrieve the manifest of a fileset persisted in DB on upload.
+// No manifest, nor error, is returned for the filesets uploaded without manifest
+func (g *VerifiableRemoteFileStorageServer) getFilesetManifest(tenantId string, fileSetId string) (*mtutils.FilesetManifest, error) {
+	dbKey := computeDbKeyManifest(tenantId, fileSetId)
+	manifestDB, err := g.db.GetString(dbKey)
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to retrieve the manifest for fileset '%v' from db \n%v", fileSetId, err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.DataLoss, respMsg)
+	}
+	if manifestDB == "" {
+		return nil, nil
+	}
+	manifest, err := mtutils.ParseFilesetManifest([]byte(manifestDB))
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to load the manifest from DB for fileset '%v' Tenant: '%v'\n%v", fileSetId, tenantId, err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.Internal, respMsg)
+	}
+	return manifest, nil
+}
+
+// Get the proof that a fileset is an append-only extension of an older version of it, i.e. that the files
+// of the older fileset are the first ones of the newer fileset and have not been rewritten
+func (g *VerifiableRemoteFileStorageServer) FilesetConsistency(ctx context.Context, in *pb.FilesetConsistencyRequest) (*pb.FilesetConsistencyResponse, error) {
+	g.l.Info("Handle a FilesetConsistency req from '%v' for filesets '%v' and '%v'", in.GetTenantId(), in.GetOldFilesetId(), in.GetNewFilesetId())
+
+	// Retrieve the MerkleTrees of both filesets from the DB, their leaves being the file hashes
+	oldTree, err := g.getUploadedFilesetTree(in.GetTenantId(), in.GetOldFilesetId())
+	if err != nil {
+		return nil, err
+	}
+	tree, err := g.getUploadedFilesetTree(in.GetTenantId(), in.GetNewFilesetId())
+	if err != nil {
+		return nil, err
+	}
+	oldFileHashes, newFileHashes := oldTree.Leaves, tree.Leaves
+
+	// Both filesets must have been computed with the same hash algorithm
+	oldHashAlgo, err := g.getFilesetHashAlgo(in.GetTenantId(), in.GetOldFilesetId())
+	if err != nil {
+		return nil, err
+	}
+	hashAlgo, err := g.getFilesetHashAlgo(in.GetTenantId(), in.GetNewFilesetId())
+	if err != nil {
+		return nil, err
+	}
+	if oldHashAlgo != hashAlgo {
+		respMsg := fmt.Sprintf("Filesets '%v' and '%v' rely on different hash algorithms: '%v' and '%v'", in.GetOldFilesetId(), in.GetNewFilesetId(), oldHashAlgo, hashAlgo)
+		g.l.Warn(respMsg)
+		return nil, status.Error(codes.FailedPrecondition, respMsg)
+	}
+
+	// Both filesets must have the same kind of file leaves
+	oldChunkSize, err := g.getFilesetChunkSize(in.GetTenantId(), in.GetOldFilesetId())
+	if err != nil {
+		return nil, err
+	}
+	chunkSize, err := g.getFilesetChunkSize(in.GetTenantId(), in.GetNewFilesetId())
+	if err != nil {
+		return nil, err
+	}
+	if oldChunkSize != chunkSize {
+		respMsg := fmt.Sprintf("Filesets '%v' and '%v' rely on different file chunk sizes: %d and %d", in.GetOldFilesetId(), in.GetNewFilesetId(), oldChunkSize, chunkSize)
+		g.l.Warn(respMsg)
+		return nil, status.Error(codes.FailedPrecondition, respMsg)
+	}
+
+	// Check that the older fileset is a prefix of the newer one
+	if len(oldFileHashes) > len(newFileHashes) {
+		respMsg := fmt.Sprintf("Fileset '%v' (%d files) is not an extension of fileset '%v' (%d files)", in.GetNewFilesetId(), len(newFileHashes), in.GetOldFilesetId(), len(oldFileHashes))
+		g.l.Warn(respMsg)
+		return nil, status.Error(codes.FailedPrecondition, respMsg)
+	}
+	for i, fileHash := range oldFileHashes {
+		if !bytes.Equal(fileHash, newFileHashes[i]) {
+			respMsg := fmt.Sprintf("Fileset '%v' is not an append-only extension of fileset '%v': file #%d differs", in.GetNewFilesetId(), in.GetOldFilesetId(), i)
+			g.l.Warn(respMsg)
+			return nil, status.Error(codes.FailedPrecondition, respMsg)
+		}
+	}
 
-	return &pb.DownloadFileInfoResponse{BucketId: bucketId, MtProof: pbMtProof}, nil
+	// Generate the consistency proof out of the newer fileset MerkleTree
+	mtProof, err := tree.ConsistencyProof(len(oldFileHashes))
+	if err != nil {
+		respMsg := fmt.Sprintf("Failed to generate the consistency proof between filesets '%v' and '%v'\n%v", in.GetOldFilesetId(), in.GetNewFilesetId(), err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.FailedPrecondition, respMsg)
+	}
+
+	pbMtProof := &pb.MTConsistencyProof{
+		OldSize: uint64(mtProof.OldSize),
+		NewSize: uint64(mtProof.NewSize),
+		Nodes:   mtProof.Nodes,
+	}
+
+	return &pb.FilesetConsistencyResponse{MtProof: pbMtProof, HashAlgo: hashAlgo}, nil
+}
+
+// Retrieve the MerkleTree of a fileset persisted in DB on upload, NotFound if the fileset has not been uploaded
+func (g *VerifiableRemoteFileStorageServer) getUploadedFilesetTree(tenantId string, fileSetId string) (*mt.MerkleTree, error) {
+	tree, err := g.getFilesetTree(tenantId, fileSetId)
+	if err != nil {
+		return nil, err
+	}
+	if tree == nil {
+		respMsg := fmt.Sprintf("No MerkleTree available in DB for fileset '%v' Tenant: '%v'", fileSetId, tenantId)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.NotFound, respMsg)
+	}
+	return tree, nil
 }
@@ -12,6 +12,7 @@ import (
 	"google.golang.org/grpc/reflection"
 
 	"github.com/ja88a/vrfs-go-merkletree/vrfs-fs/service"
+	"github.com/ja88a/vrfs-go-merkletree/vrfs-fs/storage"
 
 	config "github.com/ja88a/vrfs-go-merkletree/libs/config"
 	pb "github.com/ja88a/vrfs-go-merkletree/libs/rpcapi/protos/v1/vrfs-fs"
@@ -26,7 +27,11 @@ func Run(cfg *config.Config) {
 
 	grpcServer := grpc.NewServer()
 	reflection.Register(grpcServer)
-	uploadServer := service.New(logger, cfg)
+	store, err := storage.New(&cfg.FilesStorage)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	uploadServer := service.New(logger, cfg, store)
 	pb.RegisterFileServiceServer(grpcServer, uploadServer)
 	listen, err := net.Listen("tcp", cfg.GRPC.Port)
 	if err != nil {
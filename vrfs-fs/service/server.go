@@ -1,110 +1,261 @@
 package service
 
 import (
-	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"os"
-	"path/filepath"
+	"path"
 
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	config "github.com/ja88a/vrfs-go-merkletree/libs/config"
 	logger "github.com/ja88a/vrfs-go-merkletree/libs/logger"
+	hash "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/hash"
 	mtutils "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/utils"
 	rpcfile "github.com/ja88a/vrfs-go-merkletree/libs/rpcapi/file"
 	pb "github.com/ja88a/vrfs-go-merkletree/libs/rpcapi/protos/v1/vrfs-fs"
+	storage "github.com/ja88a/vrfs-go-merkletree/vrfs-fs/storage"
 )
 
 // Execution context of the service
 type FileStorageService struct {
 	pb.UnimplementedFileServiceServer
-	l   *logger.Logger
-	cfg *config.Config
+	l     *logger.Logger
+	cfg   *config.Config
+	store storage.BlobStore
 }
 
-// Init the service execution context
-func New(l *logger.Logger, cfg *config.Config) *FileStorageService {
+// Init the service execution context, the bucket files being stored in the specified blob store
+func New(l *logger.Logger, cfg *config.Config, store storage.BlobStore) *FileStorageService {
 	return &FileStorageService{
-		l:   l,
-		cfg: cfg,
+		l:     l,
+		cfg:   cfg,
+		store: store,
 	}
 }
 
 // Upload is the method for handling the file upload requests
 // Manages large files upload by supporting the streaming of data chunks
 func (g *FileStorageService) Upload(stream pb.FileService_UploadServer) error {
-	file := NewFile()
+	var blob storage.BlobWriter
 	var fileSize uint32
 	fileSize = 0
+	var bucketId, fileName string
 
-	// Ensure the file closure in case of interruptions
+	// Discard the received content in case of interruptions, leaving any stored file untouched
 	defer func() {
-		if err := file.OutputFile.Close(); err != nil {
-			g.l.Error("Failed to close output file '%v'\nError: %v", file.FilePath, err)
+		if blob == nil {
+			return
+		}
+		if err := blob.Abort(); err != nil {
+			g.l.Error("Failed to abort blob '%v' of bucket '%v'\nError: %v", fileName, bucketId, err)
 		}
 	}()
 
 	// Data stream handling
 	for {
 		req, err := stream.Recv()
-		if file.FilePath == "" {
-			bucketFilePath := g.computeBucketFilePath(req.GetBucketId())
-			file.SetFile(req.GetFileName(), bucketFilePath)
-		}
 		if err == io.EOF {
 			break
 		}
 		if err != nil {
 			return g.logError(status.Error(codes.Internal, err.Error()))
 		}
+		if blob == nil {
+			bucketId = req.GetBucketId()
+			if fileName, blob, err = g.putUploadFile(req); err != nil {
+				return g.logError(err)
+			}
+		}
 		chunk := req.GetChunk()
 		fileSize += uint32(len(chunk))
 		//g.l.Debug("Received a chunk with size: %d", fileSize)
-		if err := file.Write(chunk); err != nil {
+		if _, err := blob.Write(chunk); err != nil {
 			return g.logError(status.Error(codes.Internal, err.Error()))
 		}
 	}
 
-	fileName := filepath.Base(file.FilePath)
-	g.l.Debug("Saved file: %s, size: %d", file.FilePath, fileSize)
+	if blob == nil {
+		return g.logError(status.Error(codes.InvalidArgument, "no file data received"))
+	}
+
+	// The blob is only stored once closed
+	err := blob.Close()
+	blob = nil
+	if err != nil {
+		return g.logError(status.Error(codes.Internal, fmt.Sprintf("failed to store file '%v' in bucket '%v'\n%v", fileName, bucketId, err)))
+	}
+	g.l.Debug("Saved file: %s, bucket: %s, size: %d", fileName, bucketId, fileSize)
 
 	return stream.SendAndClose(&pb.FileUploadResponse{FileName: fileName, Size: fileSize})
 }
 
-// Utility method for computing the file path of a storage bucket out of its ID
-func (g *FileStorageService) computeBucketFilePath(bucketId string) string {
-	return g.cfg.FilesStorage.Location + "/" + bucketId
+// Open the bucket blob into which the data of an upload request are written, returning its path relative to the bucket.
+// Files are stored under their slash separated path relative to the fileset root dir, preserving the fileset subdirs,
+// and the fileset manifest under its reserved name at the bucket root
+func (g *FileStorageService) putUploadFile(req *pb.FileUploadRequest) (string, storage.BlobWriter, error) {
+	filePath := req.GetFilePath()
+	if filePath == "" {
+		filePath = req.GetFileName()
+	}
+	if req.GetManifest() {
+		filePath = mtutils.FilesetManifestName
+	}
+
+	// Only valid bucket IDs and paths relative to the bucket are supported, not absolute ones nor ones escaping it,
+	// and the reserved name of the manifest is only used by the manifest upload requests
+	err := validateBucketId(req.GetBucketId())
+	if err == nil && !req.GetManifest() {
+		err = validateBucketFilePath(filePath)
+	}
+	if err != nil {
+		return "", nil, status.Error(codes.InvalidArgument, fmt.Sprintf("unsupported file '%v' for bucket '%v'\n%v", filePath, req.GetBucketId(), err))
+	}
+
+	blob, err := g.store.Put(req.GetBucketId(), filePath)
+	if err != nil {
+		return "", nil, status.Error(storagePathErrorCode(err, codes.Internal), fmt.Sprintf("failed to store file '%v' in bucket '%v'\n%v", filePath, req.GetBucketId(), err))
+	}
+	return filePath, blob, nil
+}
+
+// Retrieve the fileset manifest uploaded to a bucket, with its canonical encoding.
+// No manifest, nor error, is returned for the buckets uploaded without manifest
+func (g *FileStorageService) getBucketManifest(bucketId string) (*mtutils.FilesetManifest, []byte, error) {
+	if err := validateBucketId(bucketId); err != nil {
+		return nil, nil, err
+	}
+	blob, err := g.store.Get(bucketId, mtutils.FilesetManifestName)
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+	defer blob.Close()
+	manifestData, err := io.ReadAll(blob)
+	if err != nil {
+		return nil, nil, err
+	}
+	manifest, err := mtutils.ParseFilesetManifest(manifestData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return manifest, manifestData, nil
+}
+
+// List the file paths of a bucket in the order of the fileset leaves: the order of the fileset manifest entries
+// if a manifest was uploaded to the bucket, the lexical order of the bucket file paths otherwise
+func (g *FileStorageService) listBucketFilePaths(bucketId string) ([]string, error) {
+	manifest, _, err := g.getBucketManifest(bucketId)
+	if err != nil {
+		return nil, err
+	}
+	return g.bucketFilePaths(bucketId, manifest)
+}
+
+// List the paths of the files of a bucket relative to the bucket, the ones of its fileset manifest entries
+// if a manifest is specified, else the ones of the stored blobs. Manifest entries must all be stored
+func (g *FileStorageService) bucketFilePaths(bucketId string, manifest *mtutils.FilesetManifest) ([]string, error) {
+	if err := validateBucketId(bucketId); err != nil {
+		return nil, err
+	}
+	if manifest == nil {
+		return g.store.List(bucketId)
+	}
+	filePaths := make([]string, len(manifest.Entries))
+	for i, entry := range manifest.Entries {
+		if err := validateBucketFilePath(entry.Path); err != nil {
+			return nil, err
+		}
+		if _, err := g.store.Stat(bucketId, entry.Path); err != nil {
+			return nil, err
+		}
+		filePaths[i] = entry.Path
+	}
+	return filePaths, nil
+}
+
+// Get the function opening the stored files of a bucket, for hashing them
+func (g *FileStorageService) bucketFileOpener(bucketId string) mtutils.FileOpenFunc {
+	return func(filePath string) (io.ReadCloser, error) {
+		return g.store.Get(bucketId, filePath)
+	}
 }
 
 // BucketFileHashes is a method for handling the requests for retrieving the file hashes of a given storage bucket
 func (g *FileStorageService) BucketFileHashes(ctx context.Context, req *pb.BucketFileHashesRequest) (*pb.BucketFileHashesResponse, error) {
-	g.l.Info("Handle request for the file hashes of bucket '%v'", req.GetBucketId())
-	bucketFilePath := g.computeBucketFilePath(req.GetBucketId())
+	g.l.Info("Handle request for the file hashes of bucket '%v' (hash: '%v', chunk size: %d)", req.GetBucketId(), req.GetHashAlgo(), req.GetChunkSize())
+	if err := validateBucketId(req.GetBucketId()); err != nil {
+		respMsg := fmt.Sprintf("unsupported bucket for the file hashes\n%v", err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.InvalidArgument, respMsg)
+	}
+
+	// Check that the requested hash algorithm is supported
+	if _, err := hash.HashFuncByName(req.GetHashAlgo()); err != nil {
+		respMsg := fmt.Sprintf("unsupported hash algorithm '%v' for the file hashes of bucket '%v'\n%v", req.GetHashAlgo(), req.GetBucketId(), err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.InvalidArgument, respMsg)
+	}
 
-	// List the file paths
-	filePaths, err := mtutils.ListDirFilePaths(bucketFilePath)
+	// The leaves of a fileset uploaded with its manifest are the ones of its entries, rebuilt out of the stored files
+	manifest, manifestData, err := g.getBucketManifest(req.GetBucketId())
 	if err != nil {
-		respErr := fmt.Errorf("failed to list files in bucket '%v' (dir: %v)\n%v", req.GetBucketId(), bucketFilePath, err)
-		g.l.Error(fmt.Sprint(respErr))
-		return nil, respErr
+		respMsg := fmt.Sprintf("unsupported fileset manifest of bucket '%v'\n%v", req.GetBucketId(), err)
+		g.l.Error(respMsg)
+		return nil, status.Error(storagePathErrorCode(err, codes.FailedPrecondition), respMsg)
+	}
+	if manifest != nil {
+		hashAlgo := req.GetHashAlgo()
+		if hashAlgo == "" {
+			hashAlgo = hash.DefaultAlgo
+		}
+		if manifest.HashAlgo != hashAlgo || manifest.ChunkSize != req.GetChunkSize() {
+			respMsg := fmt.Sprintf("hash algorithm '%v' and chunk size %d do not match the ones of the fileset manifest of bucket '%v' ('%v', %d)", hashAlgo, req.GetChunkSize(), req.GetBucketId(), manifest.HashAlgo, manifest.ChunkSize)
+			g.l.Error(respMsg)
+			return nil, status.Error(codes.InvalidArgument, respMsg)
+		}
+	}
+
+	// List the file paths, in the order of the manifest entries if any
+	filePaths, err := g.bucketFilePaths(req.GetBucketId(), manifest)
+	if err != nil {
+		respMsg := fmt.Sprintf("failed to list files in bucket '%v'\n%v", req.GetBucketId(), err)
+		g.l.Error(respMsg)
+		return nil, status.Error(storagePathErrorCode(err, codes.NotFound), respMsg)
 	}
 
-	// Compute the hash for each file
-	fileHashes, err := mtutils.ComputeFileHashes(filePaths)
+	openFile := g.bucketFileOpener(req.GetBucketId())
+	if manifest != nil {
+		leaves, err := manifest.ComputeStoredLeavesWith(openFile, filePaths, g.cfg.FilesStorage.HashWorkers)
+		if err != nil {
+			respErr := fmt.Errorf("failed to compute the fileset manifest leaves for bucket '%v'\n%v", req.GetBucketId(), err)
+			g.l.Error(fmt.Sprint(respErr))
+			return nil, respErr
+		}
+		return &pb.BucketFileHashesResponse{FileHashes: leaves, Manifest: manifestData}, nil
+	}
+
+	// Compute the hash for each file, or its file chunk tree root if a chunk size is specified
+	var fileHashes [][]byte
+	if req.GetChunkSize() > 0 {
+		fileHashes, err = mtutils.ComputeFileChunkRootsWith(openFile, filePaths, req.GetHashAlgo(), int(req.GetChunkSize()), g.cfg.FilesStorage.HashWorkers)
+	} else {
+		fileHashes, err = mtutils.ComputeFileHashesWith(openFile, filePaths, req.GetHashAlgo(), g.cfg.FilesStorage.HashWorkers)
+	}
 	if err != nil {
-		respErr := fmt.Errorf("failed to compute file hashes for bucket '%v' (dir: %v)\n%v", req.GetBucketId(), bucketFilePath, err)
+		respErr := fmt.Errorf("failed to compute file hashes for bucket '%v'\n%v", req.GetBucketId(), err)
 		g.l.Error(fmt.Sprint(respErr))
 		return nil, respErr
 	}
 
 	// Debugging
 	// if g.cfg.Log.Level == "debug" {
-	// 	sHashList := fmt.Sprintf("Computed file hashes for bucket '%v' : \n", bucketFilePath)
+	// 	sHashList := fmt.Sprintf("Computed file hashes for bucket '%v' : \n", req.GetBucketId())
 	// 	for i := 0; i < len(fileHashes); i++ {
-	// 		sHashList += fmt.Sprintf("\nFile %3d Hash: %x File: %v\n", i, fileHashes[i], filepath.Base(filePaths[i]))
+	// 		sHashList += fmt.Sprintf("\nFile %3d Hash: %x File: %v\n", i, fileHashes[i], path.Base(filePaths[i]))
 	// 	}
 	// 	g.l.Debug(sHashList)
 	// }
@@ -121,25 +272,30 @@ func (g *FileStorageService) Download(req *pb.FileDownloadRequest, server pb.Fil
 	if bucketId == "" || fileIndex < 0 {
 		return status.Error(codes.InvalidArgument, fmt.Sprintf("Valid bucket ID (%v) and file index (%d) are required", bucketId, fileIndex))
 	}
-	bucketFilePath := g.computeBucketFilePath(bucketId)
-	filePaths, err := mtutils.ListDirFilePaths(bucketFilePath)
+	if err := validateBucketId(bucketId); err != nil {
+		respMsg := fmt.Sprintf("Unsupported bucket for downloading file #%d\n%v", fileIndex, err)
+		g.l.Warn(respMsg)
+		return status.Error(codes.InvalidArgument, respMsg)
+	}
+	filePaths, err := g.listBucketFilePaths(bucketId)
 	if err != nil {
-		respMsg := fmt.Sprintf("No files found in '%v'\n%v", bucketFilePath, err)
+		respMsg := fmt.Sprintf("No files found in bucket '%v'\n%v", bucketId, err)
 		g.l.Warn(respMsg)
-		return status.Error(codes.NotFound, respMsg)
+		return status.Error(storagePathErrorCode(err, codes.NotFound), respMsg)
 	}
-	if fileIndex > len(filePaths) {
-		respMsg := fmt.Sprintf("File index is out of range for bucket '%v' (%d)\n%v", bucketFilePath, len(filePaths), err)
+	if fileIndex >= len(filePaths) {
+		respMsg := fmt.Sprintf("File index %d is out of range for bucket '%v' (%d)", fileIndex, bucketId, len(filePaths))
 		g.l.Warn(respMsg)
 		return status.Error(codes.NotFound, respMsg)
 	}
 
-	srcFile, err := getFile(filePaths, fileIndex)
+	srcFile, blob, err := g.getFile(bucketId, filePaths[fileIndex])
 	if err != nil {
-		respMsg := fmt.Sprintf("Unsupported file at index %d - local path: %v\n%v", fileIndex, filePaths[fileIndex], err)
+		respMsg := fmt.Sprintf("Unsupported file at index %d - bucket path: %v\n%v", fileIndex, filePaths[fileIndex], err)
 		g.l.Warn(respMsg)
-		return status.Error(codes.NotFound, respMsg)
+		return status.Error(storagePathErrorCode(err, codes.NotFound), respMsg)
 	}
+	defer blob.Close()
 
 	err = server.SendHeader(srcFile.Metadata())
 	if err != nil {
@@ -171,14 +327,81 @@ Loop:
 	return nil
 }
 
-// getFile is an internal method for converting locally stored files into a streamable content 
-// with corresponding metadata, for clients' file download operations
-func getFile(filePaths []string, fileIndex int) (*rpcfile.File, error) {
-	filePath := filePaths[fileIndex]
-	fileData, err := os.ReadFile(filePath)
+// FileChunkProofs is the method for handling the requests for the proofs of a range of chunks of a file,
+// for clients to verify the file chunks while they are downloaded
+func (g *FileStorageService) FileChunkProofs(ctx context.Context, req *pb.FileChunkProofsRequest) (*pb.FileChunkProofsResponse, error) {
+	g.l.Info("Handle file chunk proofs request: bucket '%v' file #%d chunks [%d, %d] (hash: '%v', chunk size: %d)", req.GetBucketId(), req.GetFileIndex(), req.GetFirstChunk(), req.GetLastChunk(), req.GetHashAlgo(), req.GetChunkSize())
+
+	fileIndex := int(req.GetFileIndex())
+	bucketId := req.GetBucketId()
+	if bucketId == "" || fileIndex < 0 || req.GetChunkSize() == 0 || req.GetFirstChunk() > req.GetLastChunk() {
+		return nil, status.Error(codes.InvalidArgument, fmt.Sprintf("Valid bucket ID (%v), file index (%d), chunk size (%d) and chunk range [%d, %d] are required", bucketId, fileIndex, req.GetChunkSize(), req.GetFirstChunk(), req.GetLastChunk()))
+	}
+	if _, err := hash.HashFuncByName(req.GetHashAlgo()); err != nil {
+		respMsg := fmt.Sprintf("unsupported hash algorithm '%v' for the file chunks of bucket '%v'\n%v", req.GetHashAlgo(), bucketId, err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.InvalidArgument, respMsg)
+	}
+	if err := validateBucketId(bucketId); err != nil {
+		respMsg := fmt.Sprintf("Unsupported bucket for the chunk proofs of file #%d\n%v", fileIndex, err)
+		g.l.Warn(respMsg)
+		return nil, status.Error(codes.InvalidArgument, respMsg)
+	}
+	filePaths, err := g.listBucketFilePaths(bucketId)
 	if err != nil {
-		return nil, err
+		respMsg := fmt.Sprintf("No files found in bucket '%v'\n%v", bucketId, err)
+		g.l.Warn(respMsg)
+		return nil, status.Error(storagePathErrorCode(err, codes.NotFound), respMsg)
+	}
+	if fileIndex >= len(filePaths) {
+		respMsg := fmt.Sprintf("File index %d is out of range for bucket '%v' (%d)", fileIndex, bucketId, len(filePaths))
+		g.l.Warn(respMsg)
+		return nil, status.Error(codes.NotFound, respMsg)
+	}
+
+	// Compute the file chunk tree, streaming the file content
+	blob, err := g.store.Get(bucketId, filePaths[fileIndex])
+	if err != nil {
+		respMsg := fmt.Sprintf("Unsupported file at index %d - bucket path: %v\n%v", fileIndex, filePaths[fileIndex], err)
+		g.l.Warn(respMsg)
+		return nil, status.Error(storagePathErrorCode(err, codes.NotFound), respMsg)
+	}
+	defer blob.Close()
+	chunkTree, err := mtutils.NewFileChunkTree(blob, req.GetHashAlgo(), int(req.GetChunkSize()))
+	if err != nil {
+		respMsg := fmt.Sprintf("failed to compute the file chunk tree of file #%d in bucket '%v'\n%v", fileIndex, bucketId, err)
+		g.l.Error(respMsg)
+		return nil, status.Error(codes.Internal, respMsg)
+	}
+	if int(req.GetLastChunk()) >= chunkTree.NumChunks() {
+		respMsg := fmt.Sprintf("chunk range [%d, %d] is out of range for file #%d in bucket '%v' (%d chunks)", req.GetFirstChunk(), req.GetLastChunk(), fileIndex, bucketId, chunkTree.NumChunks())
+		g.l.Warn(respMsg)
+		return nil, status.Error(codes.OutOfRange, respMsg)
+	}
+
+	proofs := make([]*pb.FileChunkProof, 0, req.GetLastChunk()-req.GetFirstChunk()+1)
+	for i := int(req.GetFirstChunk()); i <= int(req.GetLastChunk()); i++ {
+		proof, err := chunkTree.ChunkProof(i)
+		if err != nil {
+			return nil, status.Error(codes.Internal, err.Error())
+		}
+		proofs = append(proofs, &pb.FileChunkProof{Siblings: proof.Siblings, Path: proof.Path})
+	}
+
+	return &pb.FileChunkProofsResponse{ChunkRoot: chunkTree.Root, FileSize: uint64(chunkTree.FileSize), Proofs: proofs}, nil
+}
+
+// getFile is an internal method for converting stored files into a streamable content 
+// with corresponding metadata, for clients' file download operations. The returned blob must be closed
+func (g *FileStorageService) getFile(bucketId string, filePath string) (*rpcfile.File, io.Closer, error) {
+	info, err := g.store.Stat(bucketId, filePath)
+	if err != nil {
+		return nil, nil, err
+	}
+	blob, err := g.store.Get(bucketId, filePath)
+	if err != nil {
+		return nil, nil, err
 	}
-	fileName := filepath.Base(filePath)
-	return rpcfile.NewFile(fileName, filepath.Ext(fileName), len(fileData), bytes.NewReader(fileData)), nil
+	fileName := path.Base(filePath)
+	return rpcfile.NewFile(fileName, path.Ext(fileName), int(info.Size), blob), blob, nil
 }
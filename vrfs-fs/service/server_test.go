@@ -0,0 +1,420 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	config "github.com/ja88a/vrfs-go-merkletree/libs/config"
+	logger "github.com/ja88a/vrfs-go-merkletree/libs/logger"
+	mtutils "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/utils"
+	pb "github.com/ja88a/vrfs-go-merkletree/libs/rpcapi/protos/v1/vrfs-fs"
+	storage "github.com/ja88a/vrfs-go-merkletree/vrfs-fs/storage"
+)
+
+const testBucketId = "tmock_fs-0123456789abcdef"
+
+// testUploadStream is an upload stream replaying the requests of a client, then failing with recvErr if set.
+type testUploadStream struct {
+	grpc.ServerStream
+	reqs    []*pb.FileUploadRequest
+	resp    *pb.FileUploadResponse
+	recvErr error
+}
+
+func (s *testUploadStream) Recv() (*pb.FileUploadRequest, error) {
+	if len(s.reqs) == 0 {
+		if s.recvErr != nil {
+			return nil, s.recvErr
+		}
+		return nil, io.EOF
+	}
+	req := s.reqs[0]
+	s.reqs = s.reqs[1:]
+	return req, nil
+}
+
+func (s *testUploadStream) SendAndClose(resp *pb.FileUploadResponse) error {
+	s.resp = resp
+	return nil
+}
+
+// testDownloadStream is a download stream collecting the file content sent to a client.
+type testDownloadStream struct {
+	grpc.ServerStream
+	header metadata.MD
+	data   bytes.Buffer
+}
+
+func (s *testDownloadStream) SendHeader(header metadata.MD) error {
+	s.header = header
+	return nil
+}
+
+func (s *testDownloadStream) Send(resp *pb.FileDownloadResponse) error {
+	s.data.Write(resp.GetChunk())
+	return nil
+}
+
+// newTestService creates a service storing its files in the `storage` dir of a temporary dir, whose parent dir
+// is returned for checking that no file gets written out of the storage location.
+func newTestService(t *testing.T) (*FileStorageService, string) {
+	t.Helper()
+	rootDir := t.TempDir()
+	cfg := &config.Config{}
+	cfg.FilesStorage.Location = filepath.Join(rootDir, "storage")
+	if err := os.MkdirAll(cfg.FilesStorage.Location, 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	return New(logger.New("error"), cfg, storage.NewDiskStore(cfg.FilesStorage.Location)), rootDir
+}
+
+// upload uploads a file of the specified bucket ID, file name and path.
+func upload(g *FileStorageService, bucketId string, fileName string, filePath string, content string) (*pb.FileUploadResponse, error) {
+	stream := &testUploadStream{reqs: []*pb.FileUploadRequest{
+		{BucketId: bucketId, FileName: fileName, FilePath: filePath, Chunk: []byte(content)},
+	}}
+	err := g.Upload(stream)
+	return stream.resp, err
+}
+
+// listRootDirFiles lists the regular files found in the parent dir of the storage location, relative to it.
+func listRootDirFiles(t *testing.T, rootDir string) []string {
+	t.Helper()
+	var files []string
+	err := filepath.WalkDir(rootDir, func(path string, entry os.DirEntry, err error) error {
+		if err != nil || !entry.Type().IsRegular() {
+			return err
+		}
+		relPath, err := filepath.Rel(rootDir, path)
+		files = append(files, filepath.ToSlash(relPath))
+		return err
+	})
+	if err != nil {
+		t.Fatalf("WalkDir() error = %v", err)
+	}
+	return files
+}
+
+func assertStatusCode(t *testing.T, name string, err error, want codes.Code) {
+	t.Helper()
+	if got := status.Code(err); got != want {
+		t.Errorf("%s error = %v, want code %v", name, err, want)
+	}
+}
+
+var hostileBucketIds = []string{
+	"",
+	".",
+	"..",
+	"../tmock_fs-0123",
+	"tmock/../../escape",
+	"/tmp/escape",
+	".hidden",
+	"-flag",
+	"_underscore",
+	"tmock_fs-01\x00",
+	"tmock_fs 01",
+	"tmock\\..\\escape",
+	strings.Repeat("a", maxBucketIdLength+1),
+}
+
+var hostileFilePaths = []string{
+	".",
+	"..",
+	"../escape.txt",
+	"../../escape.txt",
+	"docs/../../escape.txt",
+	"docs/./readme.md",
+	"/etc/passwd",
+	"/escape.txt",
+	"docs//readme.md",
+	"docs/",
+	"..\\escape.txt",
+	"docs\\..\\..\\escape.txt",
+	"nul\x00.txt",
+	"line\nfeed.txt",
+	strings.Repeat("a", maxFilePathElementLength+1),
+	strings.Repeat("a/", maxFilePathLength/2) + "a",
+}
+
+func TestUpload(t *testing.T) {
+	g, rootDir := newTestService(t)
+
+	// Files are stored under their relative path, preserving the fileset subdirs
+	for _, filePath := range []string{"readme.md", "docs/readme.md", "docs/sub/readme.md", "..readme.md"} {
+		resp, err := upload(g, testBucketId, filepath.Base(filePath), filePath, filePath)
+		if err != nil {
+			t.Fatalf("Upload() '%v' error = %v", filePath, err)
+		}
+		if resp.GetFileName() != filePath || resp.GetSize() != uint32(len(filePath)) {
+			t.Errorf("Upload() '%v' response = %v", filePath, resp)
+		}
+		data, err := os.ReadFile(filepath.Join(g.cfg.FilesStorage.Location, testBucketId, filepath.FromSlash(filePath)))
+		if err != nil || string(data) != filePath {
+			t.Errorf("Upload() '%v' stored data = %q, error = %v", filePath, data, err)
+		}
+	}
+
+	// The file name is used for the clients not sending the file path
+	if _, err := upload(g, testBucketId, "legacy.txt", "", "legacy"); err != nil {
+		t.Fatalf("Upload() legacy error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(g.cfg.FilesStorage.Location, testBucketId, "legacy.txt")); err != nil {
+		t.Errorf("Upload() legacy file not stored: %v", err)
+	}
+
+	// An empty stream stores no file
+	assertStatusCode(t, "Upload() empty stream", g.Upload(&testUploadStream{}), codes.InvalidArgument)
+
+	if got := len(listRootDirFiles(t, rootDir)); got != 5 {
+		t.Errorf("Upload() stored %d files, want 5", got)
+	}
+}
+
+func TestUpload_hostileInputs(t *testing.T) {
+	g, rootDir := newTestService(t)
+
+	for _, bucketId := range hostileBucketIds {
+		_, err := upload(g, bucketId, "file.txt", "file.txt", "hostile")
+		assertStatusCode(t, "Upload() bucket "+strings.ToValidUTF8(bucketId, "?"), err, codes.InvalidArgument)
+	}
+	for _, filePath := range hostileFilePaths {
+		_, err := upload(g, testBucketId, "file.txt", filePath, "hostile")
+		assertStatusCode(t, "Upload() path "+filePath, err, codes.InvalidArgument)
+		_, err = upload(g, testBucketId, filePath, "", "hostile")
+		assertStatusCode(t, "Upload() name "+filePath, err, codes.InvalidArgument)
+	}
+
+	// Symbolic links in a bucket are never followed, e.g. a subdir linked out of the storage location
+	outDir := filepath.Join(rootDir, "out")
+	if err := os.MkdirAll(outDir, 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	bucketDir := filepath.Join(g.cfg.FilesStorage.Location, testBucketId)
+	if err := os.MkdirAll(bucketDir, 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.Symlink(outDir, filepath.Join(bucketDir, "link")); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	_, err := upload(g, testBucketId, "file.txt", "link/file.txt", "hostile")
+	assertStatusCode(t, "Upload() symlinked dir", err, codes.InvalidArgument)
+
+	linkedBucketId := testBucketId + "-linked"
+	if err := os.Symlink(outDir, filepath.Join(g.cfg.FilesStorage.Location, linkedBucketId)); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	_, err = upload(g, linkedBucketId, "file.txt", "file.txt", "hostile")
+	assertStatusCode(t, "Upload() symlinked bucket", err, codes.InvalidArgument)
+
+	if files := listRootDirFiles(t, rootDir); len(files) != 0 {
+		t.Errorf("Upload() of hostile inputs stored files %v", files)
+	}
+}
+
+func TestDownload_hostileInputs(t *testing.T) {
+	g, rootDir := newTestService(t)
+	if _, err := upload(g, testBucketId, "a.txt", "a.txt", "stored"); err != nil {
+		t.Fatalf("Upload() error = %v", err)
+	}
+
+	stream := &testDownloadStream{}
+	if err := g.Download(&pb.FileDownloadRequest{BucketId: testBucketId, FileIndex: 0}, stream); err != nil {
+		t.Fatalf("Download() error = %v", err)
+	}
+	if stream.data.String() != "stored" {
+		t.Errorf("Download() data = %q, want %q", stream.data.String(), "stored")
+	}
+
+	for _, bucketId := range hostileBucketIds {
+		err := g.Download(&pb.FileDownloadRequest{BucketId: bucketId, FileIndex: 0}, &testDownloadStream{})
+		assertStatusCode(t, "Download() bucket "+strings.ToValidUTF8(bucketId, "?"), err, codes.InvalidArgument)
+		_, err = g.FileChunkProofs(context.Background(), &pb.FileChunkProofsRequest{BucketId: bucketId, ChunkSize: 4})
+		assertStatusCode(t, "FileChunkProofs() bucket "+strings.ToValidUTF8(bucketId, "?"), err, codes.InvalidArgument)
+		_, err = g.BucketFileHashes(context.Background(), &pb.BucketFileHashesRequest{BucketId: bucketId})
+		assertStatusCode(t, "BucketFileHashes() bucket "+strings.ToValidUTF8(bucketId, "?"), err, codes.InvalidArgument)
+	}
+
+	// A file linked out of the storage location is refused, not read
+	outFile := filepath.Join(rootDir, "secret.txt")
+	if err := os.WriteFile(outFile, []byte("secret"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.Symlink(outFile, filepath.Join(g.cfg.FilesStorage.Location, testBucketId, "b.txt")); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	stream = &testDownloadStream{}
+	err := g.Download(&pb.FileDownloadRequest{BucketId: testBucketId, FileIndex: 1}, stream)
+	assertStatusCode(t, "Download() symlinked file", err, codes.InvalidArgument)
+	if stream.data.Len() != 0 {
+		t.Errorf("Download() symlinked file data = %q, want none", stream.data.String())
+	}
+	_, err = g.FileChunkProofs(context.Background(), &pb.FileChunkProofsRequest{BucketId: testBucketId, FileIndex: 1, ChunkSize: 4})
+	assertStatusCode(t, "FileChunkProofs() symlinked file", err, codes.InvalidArgument)
+	_, err = g.BucketFileHashes(context.Background(), &pb.BucketFileHashesRequest{BucketId: testBucketId})
+	assertStatusCode(t, "BucketFileHashes() symlinked file", err, codes.InvalidArgument)
+
+	// A bucket linked out of the storage location is refused
+	outDir := filepath.Join(rootDir, "out")
+	if err := os.MkdirAll(outDir, 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "a.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	linkedBucketId := testBucketId + "-linked"
+	if err := os.Symlink(outDir, filepath.Join(g.cfg.FilesStorage.Location, linkedBucketId)); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+	err = g.Download(&pb.FileDownloadRequest{BucketId: linkedBucketId, FileIndex: 0}, &testDownloadStream{})
+	assertStatusCode(t, "Download() symlinked bucket", err, codes.InvalidArgument)
+}
+
+func TestValidateBucketFilePath(t *testing.T) {
+	for _, filePath := range []string{"a", "a.txt", "docs/readme.md", "..a", "a..", "a b/c-d_e.f", "é/ü.txt"} {
+		if err := validateBucketFilePath(filePath); err != nil {
+			t.Errorf("validateBucketFilePath(%q) error = %v", filePath, err)
+		}
+	}
+	for _, filePath := range append(hostileFilePaths, "", mtutils.FilesetManifestName) {
+		if err := validateBucketFilePath(filePath); err == nil {
+			t.Errorf("validateBucketFilePath(%q) error = nil, want an error", filePath)
+		}
+	}
+}
+
+func TestBlobStoreBackends(t *testing.T) {
+	files := map[string]string{
+		"readme.md":      "fileset readme",
+		"docs/readme.md": "docs readme",
+		"docs/empty.txt": "",
+	}
+	localDir := t.TempDir()
+	for filePath, content := range files {
+		localPath := filepath.Join(localDir, filepath.FromSlash(filePath))
+		if err := os.MkdirAll(filepath.Dir(localPath), 0o700); err != nil {
+			t.Fatalf("MkdirAll() error = %v", err)
+		}
+		if err := os.WriteFile(localPath, []byte(content), 0o600); err != nil {
+			t.Fatalf("WriteFile() error = %v", err)
+		}
+	}
+	localPaths, err := mtutils.ListDirFilePaths(localDir)
+	if err != nil {
+		t.Fatalf("ListDirFilePaths() error = %v", err)
+	}
+	wantHashes, err := mtutils.ComputeFileHashes(localPaths, "")
+	if err != nil {
+		t.Fatalf("ComputeFileHashes() error = %v", err)
+	}
+	manifest, err := mtutils.ComputeFilesetManifest(localDir, "", 4, false)
+	if err != nil {
+		t.Fatalf("ComputeFilesetManifest() error = %v", err)
+	}
+	manifestData, err := manifest.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	wantLeaves, err := manifest.Leaves()
+	if err != nil {
+		t.Fatalf("Leaves() error = %v", err)
+	}
+
+	for _, backend := range []string{storage.BackendDisk, storage.BackendMemory} {
+		t.Run(backend, func(t *testing.T) {
+			cfg := &config.Config{}
+			cfg.FilesStorage.Backend = backend
+			cfg.FilesStorage.Location = t.TempDir()
+			store, err := storage.New(&cfg.FilesStorage)
+			if err != nil {
+				t.Fatalf("storage.New() error = %v", err)
+			}
+			g := New(logger.New("error"), cfg, store)
+
+			// Bucket uploaded without manifest: whole file hashes, in the order of the file paths
+			for filePath, content := range files {
+				if _, err := upload(g, testBucketId, filepath.Base(filePath), filePath, content); err != nil {
+					t.Fatalf("Upload() '%v' error = %v", filePath, err)
+				}
+			}
+			resp, err := g.BucketFileHashes(context.Background(), &pb.BucketFileHashesRequest{BucketId: testBucketId})
+			if err != nil {
+				t.Fatalf("BucketFileHashes() error = %v", err)
+			}
+			if !equalHashes(resp.GetFileHashes(), wantHashes) {
+				t.Errorf("BucketFileHashes() = %x, want %x", resp.GetFileHashes(), wantHashes)
+			}
+			for i, localPath := range localPaths {
+				relPath, _ := filepath.Rel(localDir, localPath)
+				stream := &testDownloadStream{}
+				if err := g.Download(&pb.FileDownloadRequest{BucketId: testBucketId, FileIndex: int32(i)}, stream); err != nil {
+					t.Fatalf("Download() #%d error = %v", i, err)
+				}
+				if want := files[filepath.ToSlash(relPath)]; stream.data.String() != want {
+					t.Errorf("Download() #%d data = %q, want %q", i, stream.data.String(), want)
+				}
+			}
+
+			// An interrupted re-upload leaves the stored file untouched
+			stream := &testUploadStream{
+				reqs:    []*pb.FileUploadRequest{{BucketId: testBucketId, FileName: "readme.md", FilePath: "readme.md", Chunk: []byte("partial")}},
+				recvErr: errors.New("client disconnected"),
+			}
+			assertStatusCode(t, "Upload() interrupted", g.Upload(stream), codes.Internal)
+			downloaded := &testDownloadStream{}
+			if err := g.Download(&pb.FileDownloadRequest{BucketId: testBucketId, FileIndex: int32(len(localPaths) - 1)}, downloaded); err != nil {
+				t.Fatalf("Download() error = %v", err)
+			}
+			if downloaded.data.String() != files["readme.md"] {
+				t.Errorf("Download() after an interrupted upload = %q, want %q", downloaded.data.String(), files["readme.md"])
+			}
+
+			// Bucket uploaded with its manifest: the manifest entry leaves, rebuilt out of the stored files
+			stream = &testUploadStream{reqs: []*pb.FileUploadRequest{{BucketId: testBucketId, Manifest: true, Chunk: manifestData}}}
+			if err := g.Upload(stream); err != nil || stream.resp.GetFileName() != mtutils.FilesetManifestName {
+				t.Fatalf("Upload() manifest = %v, error = %v", stream.resp, err)
+			}
+
+			// Fileset files can not be uploaded under the reserved name of the manifest
+			_, err = upload(g, testBucketId, mtutils.FilesetManifestName, mtutils.FilesetManifestName, "not a manifest")
+			assertStatusCode(t, "Upload() reserved manifest name", err, codes.InvalidArgument)
+			resp, err = g.BucketFileHashes(context.Background(), &pb.BucketFileHashesRequest{BucketId: testBucketId, ChunkSize: 4})
+			if err != nil {
+				t.Fatalf("BucketFileHashes() with manifest error = %v", err)
+			}
+			if !equalHashes(resp.GetFileHashes(), wantLeaves) || !bytes.Equal(resp.GetManifest(), manifestData) {
+				t.Errorf("BucketFileHashes() with manifest = %x, want %x", resp.GetFileHashes(), wantLeaves)
+			}
+			proofs, err := g.FileChunkProofs(context.Background(), &pb.FileChunkProofsRequest{BucketId: testBucketId, FileIndex: 1, ChunkSize: 4, LastChunk: 1})
+			if err != nil {
+				t.Fatalf("FileChunkProofs() error = %v", err)
+			}
+			if proofs.GetFileSize() != uint64(len(files[manifest.Entries[1].Path])) || len(proofs.GetProofs()) != 2 {
+				t.Errorf("FileChunkProofs() = %v", proofs)
+			}
+		})
+	}
+}
+
+func equalHashes(a [][]byte, b [][]byte) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if !bytes.Equal(a[i], b[i]) {
+			return false
+		}
+	}
+	return true
+}
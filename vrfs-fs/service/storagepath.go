@@ -0,0 +1,73 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+
+	mtutils "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/utils"
+	storage "github.com/ja88a/vrfs-go-merkletree/vrfs-fs/storage"
+)
+
+var (
+	// ErrInvalidBucketId is the error for a bucket ID not matching the bucket ID grammar.
+	ErrInvalidBucketId = errors.New("invalid bucket ID")
+	// ErrInvalidFilePath is the error for a bucket file path not relative to the bucket dir, escaping it
+	// or made of unsupported characters.
+	ErrInvalidFilePath = errors.New("invalid bucket file path")
+)
+
+// Max length of a bucket ID, leaving room for a storage backend to prefix or suffix it within a name of 255 bytes
+const maxBucketIdLength = 200
+
+// Max length of a bucket file path, and of each of its elements
+const (
+	maxFilePathLength        = 1024
+	maxFilePathElementLength = 255
+)
+
+// Grammar of the bucket IDs, e.g. `tenant_fs-<fileset root>`: ASCII letters, digits, `_` and `-`,
+// starting with a letter or a digit so that a bucket ID is never a relative path element nor a flag
+var bucketIdRegexp = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_-]*$`)
+
+// Validate a bucket ID against the bucket ID grammar
+func validateBucketId(bucketId string) error {
+	if len(bucketId) > maxBucketIdLength || !bucketIdRegexp.MatchString(bucketId) {
+		return fmt.Errorf("%w '%v': it must be made of up to %d ASCII letters, digits, `_` or `-`, starting with a letter or a digit", ErrInvalidBucketId, bucketId, maxBucketIdLength)
+	}
+	return nil
+}
+
+// Validate a bucket file path: a slash separated path relative to the bucket dir, without any empty, `.` or `..`
+// element, nor any backslash or control character. The reserved name of the fileset manifest is refused
+func validateBucketFilePath(filePath string) error {
+	if len(filePath) > maxFilePathLength || !mtutils.ValidManifestPath(filePath) {
+		return fmt.Errorf("%w '%v': it must be a slash separated path relative to the fileset root dir, without any `.` or `..` element", ErrInvalidFilePath, filePath)
+	}
+	if filePath == mtutils.FilesetManifestName {
+		return fmt.Errorf("%w '%v': it is the reserved name of the fileset manifest", ErrInvalidFilePath, filePath)
+	}
+	for _, c := range filePath {
+		if c < 0x20 || c == 0x7f || c == '\\' {
+			return fmt.Errorf("%w %q: unsupported character %q", ErrInvalidFilePath, filePath, c)
+		}
+	}
+	for _, element := range strings.Split(filePath, "/") {
+		if len(element) > maxFilePathElementLength {
+			return fmt.Errorf("%w '%v': path elements are limited to %d bytes", ErrInvalidFilePath, filePath, maxFilePathElementLength)
+		}
+	}
+	return nil
+}
+
+// Get the gRPC status code of a storage path error: InvalidArgument for the errors of the storage path sanitization
+// and for the stored blobs that can not be served, the specified default code otherwise
+func storagePathErrorCode(err error, defaultCode codes.Code) codes.Code {
+	if errors.Is(err, ErrInvalidBucketId) || errors.Is(err, ErrInvalidFilePath) || errors.Is(err, storage.ErrUnsupportedBlob) {
+		return codes.InvalidArgument
+	}
+	return defaultCode
+}
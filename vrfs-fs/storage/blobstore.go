@@ -0,0 +1,76 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	config "github.com/ja88a/vrfs-go-merkletree/libs/config"
+)
+
+// BlobStore is the storage backend of the files of the FS buckets, each file being stored as a blob identified
+// by its bucket ID and its slash separated path relative to the bucket.
+//
+// The bucket IDs and the file paths are validated by the callers. Implementations must be safe for concurrent use
+type BlobStore interface {
+	// Put opens the writer of a blob, replacing any existing blob of the same path once closed.
+	// An aborted writer leaves any existing blob untouched
+	Put(bucketId string, filePath string) (BlobWriter, error)
+
+	// Get opens the reader of the content of a blob
+	Get(bucketId string, filePath string) (io.ReadCloser, error)
+
+	// List returns the paths of the blobs of a bucket, in the lexical order of their slash separated paths
+	List(bucketId string) ([]string, error)
+
+	// Stat returns the info of a blob
+	Stat(bucketId string, filePath string) (*BlobInfo, error)
+
+	// Delete removes a blob
+	Delete(bucketId string, filePath string) error
+}
+
+// BlobWriter writes the content of a blob, stored once closed
+type BlobWriter interface {
+	io.WriteCloser
+
+	// Abort discards the written content instead of storing it. It has no effect once the writer is closed
+	Abort() error
+}
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	// Slash separated path of the blob relative to its bucket
+	Path string
+	// Size of the blob content in bytes
+	Size int64
+}
+
+var (
+	// ErrNotFound is the error for a bucket or a blob not found in the store.
+	ErrNotFound = errors.New("blob not found")
+	// ErrUnsupportedBlob is the error for a stored blob that can not be served, e.g. a symbolic link
+	// or a special file found in a bucket dir.
+	ErrUnsupportedBlob = errors.New("unsupported blob")
+	// ErrUnsupportedBackend is the error for a files storage backend not supported.
+	ErrUnsupportedBackend = errors.New("unsupported files storage backend")
+)
+
+// Names of the supported files storage backends
+const (
+	// BackendDisk is the backend storing the blobs as files under the storage location, the default one
+	BackendDisk = "disk"
+	// BackendMemory is the backend holding the blobs in memory, e.g. for tests
+	BackendMemory = "memory"
+)
+
+// New creates the blob store of the backend selected in the files storage settings, the disk one if not set
+func New(cfg *config.FilesStorage) (BlobStore, error) {
+	switch cfg.Backend {
+	case "", BackendDisk:
+		return NewDiskStore(cfg.Location), nil
+	case BackendMemory:
+		return NewMemoryStore(), nil
+	}
+	return nil, fmt.Errorf("%w '%v': it must be either '%v' or '%v'", ErrUnsupportedBackend, cfg.Backend, BackendDisk, BackendMemory)
+}
@@ -0,0 +1,218 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+
+	config "github.com/ja88a/vrfs-go-merkletree/libs/config"
+)
+
+// testBlobStores returns a blob store of every backend.
+func testBlobStores(t *testing.T) map[string]BlobStore {
+	return map[string]BlobStore{
+		BackendDisk:   NewDiskStore(t.TempDir()),
+		BackendMemory: NewMemoryStore(),
+	}
+}
+
+func putTestBlob(t *testing.T, store BlobStore, bucketId string, filePath string, content string) {
+	t.Helper()
+	w, err := store.Put(bucketId, filePath)
+	if err != nil {
+		t.Fatalf("Put() '%v' error = %v", filePath, err)
+	}
+	if _, err := io.WriteString(w, content); err != nil {
+		t.Fatalf("Write() '%v' error = %v", filePath, err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close() '%v' error = %v", filePath, err)
+	}
+}
+
+func getTestBlob(store BlobStore, bucketId string, filePath string) (string, error) {
+	r, err := store.Get(bucketId, filePath)
+	if err != nil {
+		return "", err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	return string(content), err
+}
+
+func TestBlobStore(t *testing.T) {
+	for name, store := range testBlobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			blobs := map[string]string{
+				"a.txt":     "root file",
+				"a/b.txt":   "sub file",
+				"a/b/c.txt": "sub sub file",
+				"a-b/c.txt": "",
+			}
+			for filePath, content := range blobs {
+				putTestBlob(t, store, "bucket-1", filePath, content)
+			}
+			putTestBlob(t, store, "bucket-2", "a.txt", "other bucket")
+
+			// Blobs are listed in the order of their slash separated paths
+			filePaths, err := store.List("bucket-1")
+			if want := []string{"a-b/c.txt", "a.txt", "a/b.txt", "a/b/c.txt"}; err != nil || fmt.Sprint(filePaths) != fmt.Sprint(want) {
+				t.Errorf("List() = %v, error = %v, want %v", filePaths, err, want)
+			}
+			for filePath, content := range blobs {
+				if got, err := getTestBlob(store, "bucket-1", filePath); err != nil || got != content {
+					t.Errorf("Get() '%v' = %q, error = %v, want %q", filePath, got, err, content)
+				}
+				if info, err := store.Stat("bucket-1", filePath); err != nil || info.Path != filePath || info.Size != int64(len(content)) {
+					t.Errorf("Stat() '%v' = %+v, error = %v", filePath, info, err)
+				}
+			}
+
+			// A blob is replaced once its writer is closed
+			w, err := store.Put("bucket-2", "a.txt")
+			if err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if _, err := io.WriteString(w, "replaced"); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() error = %v", err)
+			}
+			if got, err := getTestBlob(store, "bucket-2", "a.txt"); err != nil || got != "replaced" {
+				t.Errorf("Get() replaced = %q, error = %v", got, err)
+			}
+
+			if err := store.Delete("bucket-1", "a/b.txt"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if _, err := store.Get("bucket-1", "a/b.txt"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() deleted blob error = %v, wantErr %v", err, ErrNotFound)
+			}
+
+			// Missing blobs and buckets are not found
+			if _, err := store.Stat("bucket-1", "missing.txt"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Stat() missing blob error = %v, wantErr %v", err, ErrNotFound)
+			}
+			if err := store.Delete("bucket-1", "missing.txt"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Delete() missing blob error = %v, wantErr %v", err, ErrNotFound)
+			}
+			if _, err := store.List("missing-bucket"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("List() missing bucket error = %v, wantErr %v", err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestBlobStore_abortedPut(t *testing.T) {
+	for name, store := range testBlobStores(t) {
+		t.Run(name, func(t *testing.T) {
+			putTestBlob(t, store, "bucket", "a.txt", "stored")
+
+			// The stored blob is untouched while a new content is being written, and once aborted
+			w, err := store.Put("bucket", "a.txt")
+			if err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if _, err := io.WriteString(w, "partial"); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if got, err := getTestBlob(store, "bucket", "a.txt"); err != nil || got != "stored" {
+				t.Errorf("Get() while writing = %q, error = %v, want %q", got, err, "stored")
+			}
+			if filePaths, err := store.List("bucket"); err != nil || fmt.Sprint(filePaths) != "[a.txt]" {
+				t.Errorf("List() while writing = %v, error = %v", filePaths, err)
+			}
+			if err := w.Abort(); err != nil {
+				t.Fatalf("Abort() error = %v", err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatalf("Close() after Abort() error = %v", err)
+			}
+			if got, err := getTestBlob(store, "bucket", "a.txt"); err != nil || got != "stored" {
+				t.Errorf("Get() once aborted = %q, error = %v, want %q", got, err, "stored")
+			}
+
+			// An aborted new blob is not stored
+			w, err = store.Put("bucket", "b.txt")
+			if err != nil {
+				t.Fatalf("Put() error = %v", err)
+			}
+			if _, err := io.WriteString(w, "partial"); err != nil {
+				t.Fatalf("Write() error = %v", err)
+			}
+			if err := w.Abort(); err != nil {
+				t.Fatalf("Abort() error = %v", err)
+			}
+			if _, err := store.Stat("bucket", "b.txt"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Stat() aborted blob error = %v, wantErr %v", err, ErrNotFound)
+			}
+			if filePaths, err := store.List("bucket"); err != nil || fmt.Sprint(filePaths) != "[a.txt]" {
+				t.Errorf("List() once aborted = %v, error = %v", filePaths, err)
+			}
+		})
+	}
+}
+
+func TestDiskStore_symlinks(t *testing.T) {
+	rootDir := t.TempDir()
+	location := filepath.Join(rootDir, "storage")
+	store := NewDiskStore(location)
+	putTestBlob(t, store, "bucket", "a.txt", "stored")
+
+	outDir := filepath.Join(rootDir, "out")
+	if err := os.MkdirAll(outDir, 0o700); err != nil {
+		t.Fatalf("MkdirAll() error = %v", err)
+	}
+	if err := os.WriteFile(filepath.Join(outDir, "secret.txt"), []byte("secret"), 0o600); err != nil {
+		t.Fatalf("WriteFile() error = %v", err)
+	}
+	if err := os.Symlink(outDir, filepath.Join(location, "bucket", "link")); err != nil {
+		t.Fatalf("Symlink() error = %v", err)
+	}
+
+	// Symbolic links are never followed, whether reading or writing
+	if _, err := store.Get("bucket", "link/secret.txt"); !errors.Is(err, ErrUnsupportedBlob) {
+		t.Errorf("Get() error = %v, wantErr %v", err, ErrUnsupportedBlob)
+	}
+	if _, err := store.Stat("bucket", "link/secret.txt"); !errors.Is(err, ErrUnsupportedBlob) {
+		t.Errorf("Stat() error = %v, wantErr %v", err, ErrUnsupportedBlob)
+	}
+	if _, err := store.Put("bucket", "link/new.txt"); !errors.Is(err, ErrUnsupportedBlob) {
+		t.Errorf("Put() error = %v, wantErr %v", err, ErrUnsupportedBlob)
+	}
+	if err := store.Delete("bucket", "link/secret.txt"); !errors.Is(err, ErrUnsupportedBlob) {
+		t.Errorf("Delete() error = %v, wantErr %v", err, ErrUnsupportedBlob)
+	}
+	if _, err := store.List("bucket"); !errors.Is(err, ErrUnsupportedBlob) {
+		t.Errorf("List() error = %v, wantErr %v", err, ErrUnsupportedBlob)
+	}
+	if _, err := os.Stat(filepath.Join(outDir, "new.txt")); !os.IsNotExist(err) {
+		t.Errorf("Put() created a file out of the storage location: %v", err)
+	}
+
+	// The temporary files of the blob writers are never left behind
+	entries, err := os.ReadDir(filepath.Join(location, "bucket"))
+	if err != nil || len(entries) != 2 {
+		t.Errorf("ReadDir() = %v, error = %v, want the blob and the link only", entries, err)
+	}
+
+	// Paths escaping the storage location are refused
+	if _, err := store.Get("..", "out/secret.txt"); !errors.Is(err, ErrUnsupportedBlob) {
+		t.Errorf("Get() escaping path error = %v, wantErr %v", err, ErrUnsupportedBlob)
+	}
+}
+
+func TestNew(t *testing.T) {
+	for _, backend := range []string{"", BackendDisk, BackendMemory} {
+		if _, err := New(&config.FilesStorage{Backend: backend, Location: t.TempDir()}); err != nil {
+			t.Errorf("New() backend '%v' error = %v", backend, err)
+		}
+	}
+	if _, err := New(&config.FilesStorage{Backend: "s3"}); !errors.Is(err, ErrUnsupportedBackend) {
+		t.Errorf("New() error = %v, wantErr %v", err, ErrUnsupportedBackend)
+	}
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	mtutils "github.com/ja88a/vrfs-go-merkletree/libs/merkletree/utils"
+)
+
+// Blob store of the files stored on the local disk, under the storage location: a bucket is a dir
+// and its blobs are files of its dir and subdirs
+type diskStore struct {
+	// Root dir of the bucket dirs
+	location string
+}
+
+// Init the blob store of the files stored under the specified location
+func NewDiskStore(location string) BlobStore {
+	return &diskStore{location: filepath.Clean(location)}
+}
+
+// Suffix of the temporary files written by the blob writers, never listed as blobs
+const diskTempSuffix = ".vrfs-tmp"
+
+// Compute the local path of a blob, ensuring it is in the storage location
+func (s *diskStore) blobPath(bucketId string, filePath string) (string, error) {
+	blobPath := filepath.Join(s.location, bucketId, filepath.FromSlash(filePath))
+	if relPath, err := filepath.Rel(s.location, blobPath); err != nil || !filepath.IsLocal(relPath) {
+		return "", fmt.Errorf("%w '%v' of bucket '%v': it is out of the storage location", ErrUnsupportedBlob, filePath, bucketId)
+	}
+	if strings.HasSuffix(blobPath, diskTempSuffix) {
+		return "", fmt.Errorf("%w '%v' of bucket '%v': the '%v' suffix is reserved to the temporary files", ErrUnsupportedBlob, filePath, bucketId, diskTempSuffix)
+	}
+	return blobPath, nil
+}
+
+// Check that none of the elements of a local path, from the storage location down to the file, is a symbolic link,
+// so that reads and writes never escape the storage location. Missing elements are accepted if `mustExist` is not set,
+// for files to be created. The info of the file is returned, nil if missing
+func (s *diskStore) checkPath(localPath string, mustExist bool) (os.FileInfo, error) {
+	relPath, err := filepath.Rel(s.location, localPath)
+	if err != nil || !filepath.IsLocal(relPath) {
+		return nil, fmt.Errorf("%w '%v': it is out of the storage location", ErrUnsupportedBlob, localPath)
+	}
+
+	var info os.FileInfo
+	currentPath := s.location
+	for _, element := range strings.Split(relPath, string(filepath.Separator)) {
+		currentPath = filepath.Join(currentPath, element)
+		info, err = os.Lstat(currentPath)
+		if os.IsNotExist(err) {
+			if mustExist {
+				return nil, fmt.Errorf("%w '%v'", ErrNotFound, localPath)
+			}
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if info.Mode()&os.ModeSymlink != 0 {
+			return nil, fmt.Errorf("%w '%v': symbolic links are not supported", ErrUnsupportedBlob, currentPath)
+		}
+	}
+	return info, nil
+}
+
+// Check that a blob is a regular file, returning its info
+func (s *diskStore) checkBlob(blobPath string) (os.FileInfo, error) {
+	info, err := s.checkPath(blobPath, true)
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%w '%v': it is not a regular file", ErrUnsupportedBlob, blobPath)
+	}
+	return info, nil
+}
+
+// diskBlobWriter writes the content of a blob into a temporary file of the blob dir, renamed over the blob
+// file once closed so that the stored blob is only replaced by a complete content
+type diskBlobWriter struct {
+	store    *diskStore
+	file     *os.File
+	blobPath string
+	closed   bool
+}
+
+func (w *diskBlobWriter) Write(p []byte) (int, error) {
+	return w.file.Write(p)
+}
+
+// Close the temporary file and rename it over the blob file, removing it on failure
+func (w *diskBlobWriter) Close() (err error) {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	defer func() {
+		if err != nil {
+			os.Remove(w.file.Name())
+		}
+	}()
+	if err = w.file.Close(); err != nil {
+		return err
+	}
+	// The blob path is checked again, its dir may have been replaced by a symbolic link in the meantime
+	if info, err := w.store.checkPath(w.blobPath, false); err != nil {
+		return err
+	} else if info != nil && !info.Mode().IsRegular() {
+		return fmt.Errorf("%w '%v': it is not a regular file", ErrUnsupportedBlob, w.blobPath)
+	}
+	return os.Rename(w.file.Name(), w.blobPath)
+}
+
+// Close and remove the temporary file, leaving the blob file untouched
+func (w *diskBlobWriter) Abort() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.file.Close()
+	return os.Remove(w.file.Name())
+}
+
+// Open the writer of the file of a blob, creating its missing parent dirs.
+// The content is written to a temporary file, only replacing the blob file once closed
+func (s *diskStore) Put(bucketId string, filePath string) (BlobWriter, error) {
+	blobPath, err := s.blobPath(bucketId, filePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.checkPath(blobPath, false)
+	if err != nil {
+		return nil, err
+	}
+	if info != nil && !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%w '%v': it is not a regular file", ErrUnsupportedBlob, blobPath)
+	}
+	if err := os.MkdirAll(filepath.Dir(blobPath), os.ModePerm); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(filepath.Dir(blobPath), "."+filepath.Base(blobPath)+".*"+diskTempSuffix)
+	if err != nil {
+		return nil, err
+	}
+	// Blobs are readable by all, as the files created by `os.Create`, not only by the owner as the temporary files
+	if err := file.Chmod(0o644); err != nil {
+		file.Close()
+		os.Remove(file.Name())
+		return nil, err
+	}
+	return &diskBlobWriter{store: s, file: file, blobPath: blobPath}, nil
+}
+
+// Open the file of a blob
+func (s *diskStore) Get(bucketId string, filePath string) (io.ReadCloser, error) {
+	blobPath, err := s.blobPath(bucketId, filePath)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.checkBlob(blobPath); err != nil {
+		return nil, err
+	}
+	return os.Open(blobPath)
+}
+
+// List the files of a bucket dir and its subdirs, in the order of `utils.ListDirFilePaths`
+func (s *diskStore) List(bucketId string) ([]string, error) {
+	bucketDir, err := s.blobPath(bucketId, "")
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.checkPath(bucketDir, true)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("%w '%v': the bucket is not a dir", ErrUnsupportedBlob, bucketDir)
+	}
+
+	localPaths, err := mtutils.ListDirFilePaths(bucketDir)
+	if err != nil {
+		return nil, err
+	}
+	filePaths := make([]string, 0, len(localPaths))
+	for _, localPath := range localPaths {
+		// Skip the files of the blobs being written
+		if strings.HasSuffix(localPath, diskTempSuffix) {
+			continue
+		}
+		if _, err := s.checkBlob(localPath); err != nil {
+			return nil, err
+		}
+		relPath, err := filepath.Rel(bucketDir, localPath)
+		if err != nil {
+			return nil, err
+		}
+		filePaths = append(filePaths, filepath.ToSlash(relPath))
+	}
+	return filePaths, nil
+}
+
+// Get the info of the file of a blob
+func (s *diskStore) Stat(bucketId string, filePath string) (*BlobInfo, error) {
+	blobPath, err := s.blobPath(bucketId, filePath)
+	if err != nil {
+		return nil, err
+	}
+	info, err := s.checkBlob(blobPath)
+	if err != nil {
+		return nil, err
+	}
+	return &BlobInfo{Path: filePath, Size: info.Size()}, nil
+}
+
+// Remove the file of a blob
+func (s *diskStore) Delete(bucketId string, filePath string) error {
+	blobPath, err := s.blobPath(bucketId, filePath)
+	if err != nil {
+		return err
+	}
+	if _, err := s.checkBlob(blobPath); err != nil {
+		return err
+	}
+	return os.Remove(blobPath)
+}
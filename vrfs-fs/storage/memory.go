@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+)
+
+// Blob store of the files held in memory, e.g. for tests: a bucket is a map of the blob contents per path
+type memoryStore struct {
+	mu      sync.RWMutex
+	buckets map[string]map[string][]byte
+}
+
+// Init an empty in-memory blob store
+func NewMemoryStore() BlobStore {
+	return &memoryStore{buckets: make(map[string]map[string][]byte)}
+}
+
+// memoryBlobWriter buffers the content of a blob, stored once closed
+type memoryBlobWriter struct {
+	store    *memoryStore
+	bucketId string
+	filePath string
+	buffer   bytes.Buffer
+	closed   bool
+}
+
+func (w *memoryBlobWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, fmt.Errorf("blob '%v' of bucket '%v' is already closed", w.filePath, w.bucketId)
+	}
+	return w.buffer.Write(p)
+}
+
+func (w *memoryBlobWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	w.store.mu.Lock()
+	defer w.store.mu.Unlock()
+	bucket, ok := w.store.buckets[w.bucketId]
+	if !ok {
+		bucket = make(map[string][]byte)
+		w.store.buckets[w.bucketId] = bucket
+	}
+	bucket[w.filePath] = w.buffer.Bytes()
+	return nil
+}
+
+// Discard the content of the blob
+func (w *memoryBlobWriter) Abort() error {
+	w.closed = true
+	w.buffer.Reset()
+	return nil
+}
+
+// Open the writer of a blob, its content being stored once closed
+func (s *memoryStore) Put(bucketId string, filePath string) (BlobWriter, error) {
+	return &memoryBlobWriter{store: s, bucketId: bucketId, filePath: filePath}, nil
+}
+
+// Get the content of a blob, never modified once stored
+func (s *memoryStore) get(bucketId string, filePath string) ([]byte, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	content, ok := s.buckets[bucketId][filePath]
+	if !ok {
+		return nil, fmt.Errorf("%w '%v' in bucket '%v'", ErrNotFound, filePath, bucketId)
+	}
+	return content, nil
+}
+
+// Open the reader of the content of a blob
+func (s *memoryStore) Get(bucketId string, filePath string) (io.ReadCloser, error) {
+	content, err := s.get(bucketId, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+// List the paths of the blobs of a bucket, sorted
+func (s *memoryStore) List(bucketId string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	bucket, ok := s.buckets[bucketId]
+	if !ok {
+		return nil, fmt.Errorf("%w: no bucket '%v'", ErrNotFound, bucketId)
+	}
+	filePaths := make([]string, 0, len(bucket))
+	for filePath := range bucket {
+		filePaths = append(filePaths, filePath)
+	}
+	sort.Strings(filePaths)
+	return filePaths, nil
+}
+
+// Get the info of a blob
+func (s *memoryStore) Stat(bucketId string, filePath string) (*BlobInfo, error) {
+	content, err := s.get(bucketId, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return &BlobInfo{Path: filePath, Size: int64(len(content))}, nil
+}
+
+// Remove a blob, and its bucket once empty
+func (s *memoryStore) Delete(bucketId string, filePath string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	bucket := s.buckets[bucketId]
+	if _, ok := bucket[filePath]; !ok {
+		return fmt.Errorf("%w '%v' in bucket '%v'", ErrNotFound, filePath, bucketId)
+	}
+	delete(bucket, filePath)
+	if len(bucket) == 0 {
+		delete(s.buckets, bucketId)
+	}
+	return nil
+}